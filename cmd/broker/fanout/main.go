@@ -22,13 +22,19 @@ import (
 
 	"cloud.google.com/go/pubsub"
 
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/broker/config/volume"
 	"github.com/google/knative-gcp/pkg/broker/handler"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
 	metadataClient "github.com/google/knative-gcp/pkg/gclient/metadata"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils"
 	"github.com/google/knative-gcp/pkg/utils/appcredentials"
 	"github.com/google/knative-gcp/pkg/utils/clients"
+	"github.com/google/knative-gcp/pkg/utils/delivery"
+	"github.com/google/knative-gcp/pkg/utils/idtoken"
 	"github.com/google/knative-gcp/pkg/utils/mainhelper"
 
 	"go.uber.org/zap"
@@ -38,6 +44,9 @@ const (
 	component        = "broker-fanout"
 	metricNamespace  = "trigger"
 	poolResyncPeriod = 15 * time.Second
+	// abortPeriod is how long the events still in flight after the drain
+	// timeout are given to be aborted on shutdown.
+	abortPeriod = 5 * time.Second
 )
 
 type envConfig struct {
@@ -47,6 +56,17 @@ type envConfig struct {
 	HandlerConcurrency     int    `envconfig:"HANDLER_CONCURRENCY"`
 	MaxConcurrencyPerEvent int    `envconfig:"MAX_CONCURRENCY_PER_EVENT"`
 
+	// TargetsConfigServer is the address of the controller server streaming the
+	// targets config. The targets config is read from TargetsConfigPath if it's empty.
+	TargetsConfigServer string `envconfig:"TARGETS_CONFIG_SERVER"`
+	// TargetsConfigServerCA is the PEM certificate of the CA of the server.
+	TargetsConfigServerCA string `envconfig:"TARGETS_CONFIG_SERVER_CA"`
+	// TargetsConfigServerToken is the path of the service account token the pod
+	// authenticates with to the server.
+	TargetsConfigServerToken string `envconfig:"TARGETS_CONFIG_SERVER_TOKEN" default:"/var/run/cloud-run-events/targets-config-server/token"`
+	BrokerCellNamespace      string `envconfig:"BROKER_CELL_NAMESPACE"`
+	BrokerCellName           string `envconfig:"BROKER_CELL_NAME"`
+
 	// MaxStaleDuration is the max duration of the handler pool without being synced.
 	// With the internal pool resync period being 15s, it requires at least 4
 	// continuous sync failures (or no sync at all) to be stale.
@@ -54,6 +74,32 @@ type envConfig struct {
 
 	// Max to 10m.
 	TimeoutPerEvent time.Duration `envconfig:"TIMEOUT_PER_EVENT"`
+
+	// ClaimCheckStore is the URL of the store of event payloads offloaded by the ingress.
+	ClaimCheckStore string `envconfig:"CLAIM_CHECK_STORE"`
+
+	// BreakerFailureThreshold is the number of consecutive delivery failures
+	// that opens the circuit breaker of a subscriber. Zero disables the breakers.
+	BreakerFailureThreshold int `envconfig:"BREAKER_FAILURE_THRESHOLD" default:"5"`
+	// BreakerOpenDuration is how long an open circuit breaker waits before
+	// probing the subscriber again.
+	BreakerOpenDuration time.Duration `envconfig:"BREAKER_OPEN_DURATION" default:"30s"`
+
+	// RetryableClientErrorCodes is the comma separated list of 4xx status codes
+	// of subscribers that are retried. Other 4xx aren't retried.
+	RetryableClientErrorCodes string `envconfig:"RETRYABLE_CLIENT_ERROR_CODES" default:"408,409,429"`
+
+	// DedupCacheSize is the number of events each broker with a deduplication
+	// window remembers to drop duplicates.
+	DedupCacheSize int `envconfig:"DEDUP_CACHE_SIZE"`
+
+	// AdminToken is the bearer token authenticating the requests to the admin API
+	// on the health check port. The admin API is disabled if it's empty.
+	AdminToken string `envconfig:"ADMIN_TOKEN"`
+
+	// DrainTimeout is how long the events in flight are given to finish on
+	// shutdown, after the handlers stop pulling messages.
+	DrainTimeout time.Duration `envconfig:"DRAIN_TIMEOUT" default:"20s"`
 }
 
 func main() {
@@ -79,17 +125,45 @@ func main() {
 		logger.Fatalf("failed to get default ProjectID: %v", err)
 	}
 
+	store, err := claimcheck.NewStore(ctx, env.ClaimCheckStore)
+	if err != nil {
+		logger.Fatal("Failed to create claim check store", zap.Error(err))
+	}
+
+	retryableCodes, err := delivery.ParseStatusCodes(env.RetryableClientErrorCodes)
+	if err != nil {
+		logger.Fatal("Failed to parse RETRYABLE_CLIENT_ERROR_CODES", zap.Error(err))
+	}
+
+	targets, err := newTargets(ctx, env, targetsUpdateCh)
+	if err != nil {
+		logger.Fatal("Failed to load targets config", zap.Error(err))
+	}
+
+	opts := append(buildHandlerOptions(env),
+		handler.WithClaimCheckStore(store),
+		handler.WithTokenSource(idtoken.NewSource(ctx)),
+		handler.WithClassifier(delivery.NewClassifier(retryableCodes)),
+	)
+	// The failed handlers and the detected loops are reported to the controller
+	// over the config stream, which acknowledges each version once the pool
+	// synced it.
+	if st, ok := targets.(*stream.Targets); ok {
+		opts = append(opts,
+			handler.WithSynced(st.Synced),
+			handler.WithReportHealth(reportHealth(st)),
+			handler.WithLoopDetected(st.ReportLoopDetected),
+		)
+	}
+
 	syncSignal := poolSyncSignal(ctx, targetsUpdateCh)
 	syncPool, err := InitializeSyncPool(
 		ctx,
 		clients.ProjectID(projectID),
 		metrics.PodName(env.PodName),
 		metrics.ContainerName(component),
-		[]volume.Option{
-			volume.WithPath(env.TargetsConfigPath),
-			volume.WithNotifyChan(targetsUpdateCh),
-		},
-		buildHandlerOptions(env)...,
+		targets,
+		opts...,
 	)
 	if err != nil {
 		logger.Fatal("Failed to create fanout sync pool", zap.Error(err))
@@ -98,11 +172,21 @@ func main() {
 		logger.Fatalw("Failed to start fanout sync pool", zap.Error(err))
 	}
 
-	// Context will be done if a TERM signal is issued.
+	// Context will be done if a TERM signal is issued, which stops the handlers
+	// from pulling messages.
 	<-ctx.Done()
-	// Wait a grace period for the handlers to shutdown.
-	time.Sleep(30 * time.Second)
-	logger.Info("Done waiting, exit.")
+	drain(syncPool, env.DrainTimeout, logger)
+	logger.Info("Done draining, exit.")
+}
+
+// drain waits for the events in flight of the handlers to finish or be
+// aborted after the drain timeout.
+func drain(pool handler.DrainPool, timeout time.Duration, logger *zap.SugaredLogger) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+abortPeriod)
+	defer cancel()
+	if err := pool.Drain(ctx); err != nil {
+		logger.Warnw("Failed to drain the handlers", zap.Error(err))
+	}
 }
 
 func poolSyncSignal(ctx context.Context, targetsUpdateCh chan struct{}) chan struct{} {
@@ -125,6 +209,51 @@ func poolSyncSignal(ctx context.Context, targetsUpdateCh chan struct{}) chan str
 	return ch
 }
 
+// reportHealth reports the failed handlers to the controller over the config
+// stream.
+func reportHealth(st *stream.Targets) func(handler.PoolHealth) {
+	return func(h handler.PoolHealth) {
+		failed := make([]*stream.HandlerHealth, 0, len(h.Failed))
+		for _, f := range h.Failed {
+			failed = append(failed, &stream.HandlerHealth{
+				Key:       f.Key,
+				LastError: f.LastError,
+				Restarts:  f.Restarts,
+			})
+		}
+		st.ReportFailedHandlers(failed)
+	}
+}
+
+// newTargets streams the targets config from the controller if TARGETS_CONFIG_SERVER
+// is set, and reads it from the mounted volume otherwise. The mounted volume is
+// also served until the first snapshot is streamed, so that the pod starts while
+// the controller is unavailable.
+func newTargets(ctx context.Context, env envConfig, targetsUpdateCh chan<- struct{}) (config.ReadonlyTargets, error) {
+	if env.TargetsConfigServer == "" {
+		return volume.NewTargetsFromFile(
+			volume.WithPath(env.TargetsConfigPath),
+			volume.WithNotifyChan(targetsUpdateCh),
+		)
+	}
+	fallbackCh := make(chan struct{})
+	fallback, err := volume.NewTargetsFromFile(
+		volume.WithPath(env.TargetsConfigPath),
+		volume.WithNotifyChan(fallbackCh),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return stream.NewTargetsFromServer(ctx, env.TargetsConfigServer,
+		[]byte(env.TargetsConfigServerCA), env.TargetsConfigServerToken,
+		stream.WithBrokerCell(env.BrokerCellNamespace, env.BrokerCellName),
+		stream.WithPod(env.PodName),
+		stream.WithNotifyChan(targetsUpdateCh),
+		stream.WithAckSynced(),
+		stream.WithFallback(fallback, fallbackCh),
+	)
+}
+
 func buildHandlerOptions(env envConfig) []handler.Option {
 	rs := pubsub.DefaultReceiveSettings
 	var opts []handler.Option
@@ -139,7 +268,16 @@ func buildHandlerOptions(env envConfig) []handler.Option {
 	if env.TimeoutPerEvent > 0 {
 		opts = append(opts, handler.WithTimeoutPerEvent(env.TimeoutPerEvent))
 	}
+	if env.DedupCacheSize > 0 {
+		opts = append(opts, handler.WithDedupCacheSize(env.DedupCacheSize))
+	}
 	opts = append(opts, handler.WithPubsubReceiveSettings(rs))
+	opts = append(opts, handler.WithBreakerSettings(deliver.BreakerSettings{
+		FailureThreshold: env.BreakerFailureThreshold,
+		OpenDuration:     env.BreakerOpenDuration,
+	}))
+	opts = append(opts, handler.WithAdminToken(env.AdminToken))
+	opts = append(opts, handler.WithDrainTimeout(env.DrainTimeout))
 	// The default CeClient is good?
 	return opts
 }
@@ -7,7 +7,7 @@ package main
 
 import (
 	"context"
-	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/handler"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils/clients"
@@ -15,18 +15,18 @@ import (
 
 // Injectors from wire.go:
 
-func InitializeSyncPool(ctx context.Context, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, targetsVolumeOpts []volume.Option, opts ...handler.Option) (*handler.FanoutPool, error) {
-	readonlyTargets, err := volume.NewTargetsFromFile(targetsVolumeOpts...)
+func InitializeSyncPool(ctx context.Context, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, targets config.ReadonlyTargets, opts ...handler.Option) (*handler.FanoutPool, error) {
+	client, err := clients.NewPubsubClient(ctx, projectID)
 	if err != nil {
 		return nil, err
 	}
-	client, err := clients.NewPubsubClient(ctx, projectID)
+	transport, err := handler.NewPubsubTransport(client, opts...)
 	if err != nil {
 		return nil, err
 	}
 	httpClient := _wireClientValue
 	v := _wireValue
-	retryClient, err := handler.NewRetryClient(ctx, client, v...)
+	retryClient, err := handler.NewRetryClient(ctx, transport, v...)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +34,7 @@ func InitializeSyncPool(ctx context.Context, projectID clients.ProjectID, podNam
 	if err != nil {
 		return nil, err
 	}
-	fanoutPool, err := handler.NewFanoutPool(readonlyTargets, client, httpClient, retryClient, deliveryReporter, opts...)
+	fanoutPool, err := handler.NewFanoutPool(targets, transport, httpClient, retryClient, deliveryReporter, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -17,6 +17,16 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/knative-gcp/pkg/broker/auth"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
+	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/ingress"
 	metadataClient "github.com/google/knative-gcp/pkg/gclient/metadata"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils"
@@ -31,6 +41,42 @@ type envConfig struct {
 	PodName   string `envconfig:"POD_NAME" required:"true"`
 	Port      int    `envconfig:"PORT" default:"8080"`
 	ProjectID string `envconfig:"PROJECT_ID"`
+
+	// AuthMode is how requests are authenticated: "none", "oidc" or "tokenreview".
+	AuthMode string `envconfig:"AUTH_MODE" default:"none"`
+	// AuthAudience is the audience the bearer tokens must have. It's required for OIDC tokens.
+	AuthAudience string `envconfig:"AUTH_AUDIENCE"`
+	// AuthOIDCIssuer is the issuer of the OIDC tokens.
+	AuthOIDCIssuer string `envconfig:"AUTH_OIDC_ISSUER" default:"https://accounts.google.com"`
+
+	// MaxRequestBytes is the maximum size of a request body. Defaults to the pubsub publish limit.
+	MaxRequestBytes int64 `envconfig:"MAX_REQUEST_BYTES" default:"10000000"`
+	// MaxMessageBytes is the maximum size of the pubsub message of an event. Defaults to the pubsub
+	// publish limit.
+	MaxMessageBytes int `envconfig:"MAX_MESSAGE_BYTES" default:"10000000"`
+
+	// ClaimCheckStore is the URL of the store for offloaded event payloads, either
+	// "gs://<bucket>/<prefix>" or "file:///<dir>". Payloads are not offloaded if it's empty.
+	ClaimCheckStore string `envconfig:"CLAIM_CHECK_STORE"`
+	// ClaimCheckTTL is how long the offloaded payloads are kept before they are deleted. It must
+	// be longer than events stay in the broker's queues, including the retry queue. Defaults to the
+	// message retention of the Pub/Sub subscriptions.
+	ClaimCheckTTL time.Duration `envconfig:"CLAIM_CHECK_TTL" default:"168h"`
+
+	// TargetsConfigServer is the address of the controller server streaming the
+	// targets config. The targets config is read from the mounted volume if it's empty.
+	TargetsConfigServer string `envconfig:"TARGETS_CONFIG_SERVER"`
+	// TargetsConfigServerCA is the PEM certificate of the CA of the server.
+	TargetsConfigServerCA string `envconfig:"TARGETS_CONFIG_SERVER_CA"`
+	// TargetsConfigServerToken is the path of the service account token the pod
+	// authenticates with to the server.
+	TargetsConfigServerToken string `envconfig:"TARGETS_CONFIG_SERVER_TOKEN" default:"/var/run/cloud-run-events/targets-config-server/token"`
+	BrokerCellNamespace      string `envconfig:"BROKER_CELL_NAMESPACE"`
+	BrokerCellName           string `envconfig:"BROKER_CELL_NAME"`
+
+	// DrainPeriod is how long the ingress fails the readiness probe on shutdown
+	// before it stops accepting requests.
+	DrainPeriod time.Duration `envconfig:"DRAIN_PERIOD" default:"10s"`
 }
 
 const (
@@ -42,7 +88,12 @@ const (
 // 1. It listens on port specified by "PORT" env var, or default 8080 if env var is not set
 // 2. It reads "PROJECT_ID" env var for pubsub project. If the env var is empty, it retrieves project ID from
 //    GCE metadata.
-// 3. It expects broker configmap mounted at "/var/run/cloud-run-events/broker/targets"
+// 3. It expects broker configmap mounted at "/var/run/cloud-run-events/broker/targets", unless
+//    "TARGETS_CONFIG_SERVER" env var is set to stream it from the controller.
+// 4. It authenticates requests with bearer tokens if "AUTH_MODE" env var is "oidc" or "tokenreview".
+// 5. It offloads large event payloads to the store at "CLAIM_CHECK_STORE" env var if set, and
+//    deletes them after "CLAIM_CHECK_TTL".
+// 6. On shutdown, it fails the readiness probe for "DRAIN_PERIOD" before it stops accepting requests.
 func main() {
 	appcredentials.MustExistOrUnsetEnv()
 
@@ -57,12 +108,40 @@ func main() {
 	}
 	logger.Desugar().Info("Starting ingress handler", zap.Any("envConfig", env), zap.Any("Project ID", projectID))
 
+	authenticator, err := newAuthenticator(env, res)
+	if err != nil {
+		logger.Desugar().Fatal("Failed to create authenticator", zap.Error(err))
+	}
+
+	store, err := claimcheck.NewStore(ctx, env.ClaimCheckStore)
+	if err != nil {
+		logger.Desugar().Fatal("Failed to create claim check store", zap.Error(err))
+	}
+	if store != nil {
+		go claimcheck.Expire(ctx, store, env.ClaimCheckTTL)
+	}
+
+	targets, err := newTargets(ctx, env)
+	if err != nil {
+		logger.Desugar().Fatal("Failed to load targets config", zap.Error(err))
+	}
+
 	ingress, err := InitializeHandler(
 		ctx,
 		clients.Port(env.Port),
 		clients.ProjectID(projectID),
 		metrics.PodName(env.PodName),
 		metrics.ContainerName(component),
+		authenticator,
+		ingress.SizeLimits{
+			MaxRequestBytes: env.MaxRequestBytes,
+			MaxMessageBytes: env.MaxMessageBytes,
+		},
+		// Fail the readiness probe for a while on shutdown so that no new requests
+		// are routed to the ingress before it stops accepting them.
+		ingress.DrainPeriod(env.DrainPeriod),
+		store,
+		targets,
 	)
 	if err != nil {
 		logger.Desugar().Fatal("Unable to create ingress handler: ", zap.Error(err))
@@ -73,3 +152,41 @@ func main() {
 		logger.Desugar().Fatal("failed to start ingress: ", zap.Error(err))
 	}
 }
+
+// newTargets streams the targets config from the controller if TARGETS_CONFIG_SERVER
+// is set, and reads it from the mounted volume otherwise. The mounted volume is
+// also served until the first snapshot is streamed, so that the pod starts while
+// the controller is unavailable.
+func newTargets(ctx context.Context, env envConfig) (config.ReadonlyTargets, error) {
+	if env.TargetsConfigServer == "" {
+		return volume.NewTargetsFromFile()
+	}
+	fallbackCh := make(chan struct{})
+	fallback, err := volume.NewTargetsFromFile(volume.WithNotifyChan(fallbackCh))
+	if err != nil {
+		return nil, err
+	}
+	return stream.NewTargetsFromServer(ctx, env.TargetsConfigServer,
+		[]byte(env.TargetsConfigServerCA), env.TargetsConfigServerToken,
+		stream.WithBrokerCell(env.BrokerCellNamespace, env.BrokerCellName),
+		stream.WithPod(env.PodName),
+		stream.WithFallback(fallback, fallbackCh),
+	)
+}
+
+// newAuthenticator creates the authenticator for the auth mode, or nil if authentication is disabled.
+func newAuthenticator(env envConfig, res *mainhelper.InitRes) (auth.Authenticator, error) {
+	switch env.AuthMode {
+	case "", "none":
+		return nil, nil
+	case "oidc":
+		if env.AuthAudience == "" {
+			return nil, fmt.Errorf("AUTH_AUDIENCE is required for OIDC authentication")
+		}
+		return auth.NewOIDCAuthenticator(env.AuthOIDCIssuer, env.AuthAudience, nil), nil
+	case "tokenreview":
+		return auth.NewTokenReviewAuthenticator(res.KubeClient.AuthenticationV1().TokenReviews(), env.AuthAudience), nil
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", env.AuthMode)
+	}
+}
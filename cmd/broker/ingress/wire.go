@@ -21,7 +21,9 @@ package main
 import (
 	"context"
 
-	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/auth"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/ingress"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils/clients"
@@ -34,10 +36,13 @@ func InitializeHandler(
 	projectID clients.ProjectID,
 	podName metrics.PodName,
 	containerName metrics.ContainerName,
+	authenticator auth.Authenticator,
+	limits ingress.SizeLimits,
+	drainPeriod ingress.DrainPeriod,
+	store claimcheck.Store,
+	targets config.ReadonlyTargets,
 ) (*ingress.Handler, error) {
 	panic(wire.Build(
 		ingress.HandlerSet,
-		wire.Value([]volume.Option(nil)),
-		volume.NewTargetsFromFile,
 	))
 }
@@ -7,34 +7,29 @@ package main
 
 import (
 	"context"
-	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/auth"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/ingress"
+	"github.com/google/knative-gcp/pkg/broker/transport/pubsub"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils/clients"
 )
 
 // Injectors from wire.go:
 
-func InitializeHandler(ctx context.Context, port clients.Port, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName) (*ingress.Handler, error) {
+func InitializeHandler(ctx context.Context, port clients.Port, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, authenticator auth.Authenticator, limits ingress.SizeLimits, drainPeriod ingress.DrainPeriod, store claimcheck.Store, targets config.ReadonlyTargets) (*ingress.Handler, error) {
 	httpMessageReceiver := clients.NewHTTPMessageReceiver(port)
-	v := _wireValue
-	readonlyTargets, err := volume.NewTargetsFromFile(v...)
-	if err != nil {
-		return nil, err
-	}
 	client, err := clients.NewPubsubClient(ctx, projectID)
 	if err != nil {
 		return nil, err
 	}
-	multiTopicDecoupleSink := ingress.NewMultiTopicDecoupleSink(ctx, readonlyTargets, client)
+	transport := pubsub.New(client)
+	multiTopicDecoupleSink := ingress.NewMultiTopicDecoupleSink(ctx, targets, transport, limits, store)
 	ingressReporter, err := metrics.NewIngressReporter(podName, containerName)
 	if err != nil {
 		return nil, err
 	}
-	handler := ingress.NewHandler(ctx, httpMessageReceiver, multiTopicDecoupleSink, ingressReporter)
+	handler := ingress.NewHandler(ctx, httpMessageReceiver, multiTopicDecoupleSink, targets, authenticator, limits, drainPeriod, ingressReporter)
 	return handler, nil
 }
-
-var (
-	_wireValue = []volume.Option(nil)
-)
@@ -0,0 +1,173 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/signals"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/handler"
+	"github.com/google/knative-gcp/pkg/broker/ingress"
+	"github.com/google/knative-gcp/pkg/broker/transport/memory"
+	"github.com/google/knative-gcp/pkg/metrics"
+	"github.com/google/knative-gcp/pkg/utils/clients"
+	"github.com/google/knative-gcp/pkg/utils/mainhelper"
+)
+
+const (
+	component        = "broker-local"
+	poolResyncPeriod = 15 * time.Second
+	// abortPeriod is how long the events still in flight after the drain
+	// timeout are given to be aborted on shutdown.
+	abortPeriod = 5 * time.Second
+)
+
+type envConfig struct {
+	PodName           string `envconfig:"POD_NAME" default:"broker-local"`
+	Port              int    `envconfig:"PORT" default:"8080"`
+	TargetsConfigPath string `envconfig:"TARGETS_CONFIG_PATH" default:"/var/run/cloud-run-events/broker/targets"`
+
+	// FanoutHealthCheckPort and RetryHealthCheckPort are the health check ports
+	// of the fanout and retry sync pools.
+	FanoutHealthCheckPort int `envconfig:"FANOUT_HEALTH_CHECK_PORT" default:"8081"`
+	RetryHealthCheckPort  int `envconfig:"RETRY_HEALTH_CHECK_PORT" default:"8082"`
+
+	// MaxStaleDuration is the max duration of the handler pools without being synced.
+	MaxStaleDuration time.Duration `envconfig:"MAX_STALE_DURATION" default:"1m"`
+
+	// DrainTimeout is how long the events in flight are given to finish on
+	// shutdown, after the ingress has stopped.
+	DrainTimeout time.Duration `envconfig:"DRAIN_TIMEOUT" default:"5s"`
+}
+
+// main runs the ingress, fanout and retry in one process for local development.
+// Events are decoupled through in-memory queues instead of Pub/Sub, so nothing
+// survives a restart. The queues are created from the targets config read from
+// "TARGETS_CONFIG_PATH".
+func main() {
+	var env envConfig
+	mainhelper.ProcessEnvConfigOrDie(&env)
+
+	loggingConfig, err := logging.NewConfigFromMap(map[string]string{})
+	if err != nil {
+		panic(err)
+	}
+	sl, _ := logging.NewLoggerFromConfig(loggingConfig, component)
+	logger := sl.Desugar()
+	defer logger.Sync()
+	ctx := logging.WithLogger(signals.NewContext(), sl)
+
+	targetsUpdateCh := make(chan struct{})
+	targets, err := volume.NewTargetsFromFile(
+		volume.WithPath(env.TargetsConfigPath),
+		volume.WithNotifyChan(targetsUpdateCh),
+	)
+	if err != nil {
+		logger.Fatal("Failed to load targets config", zap.Error(err))
+	}
+
+	t := memory.New()
+	t.SyncSubscriptions(targets)
+	fanoutSignal, retrySignal := poolSyncSignals(ctx, t, targets, targetsUpdateCh)
+
+	deliveryReporter, err := metrics.NewDeliveryReporter(metrics.PodName(env.PodName), metrics.ContainerName(component))
+	if err != nil {
+		logger.Fatal("Failed to create delivery reporter", zap.Error(err))
+	}
+	retryClient, err := handler.NewRetryClient(ctx, t, handler.DefaultCEClientOpts...)
+	if err != nil {
+		logger.Fatal("Failed to create retry client", zap.Error(err))
+	}
+
+	fanoutPool, err := handler.NewFanoutPool(targets, t, handler.DefaultHTTPClient, retryClient, deliveryReporter, handler.WithDrainTimeout(env.DrainTimeout))
+	if err != nil {
+		logger.Fatal("Failed to create fanout sync pool", zap.Error(err))
+	}
+	if _, err := handler.StartSyncPool(ctx, fanoutPool, fanoutSignal, env.MaxStaleDuration, env.FanoutHealthCheckPort); err != nil {
+		logger.Fatal("Failed to start fanout sync pool", zap.Error(err))
+	}
+
+	retryPool, err := handler.NewRetryPool(targets, t, handler.DefaultHTTPClient, deliveryReporter, handler.WithDrainTimeout(env.DrainTimeout))
+	if err != nil {
+		logger.Fatal("Failed to create retry sync pool", zap.Error(err))
+	}
+	if _, err := handler.StartSyncPool(ctx, retryPool, retrySignal, env.MaxStaleDuration, env.RetryHealthCheckPort); err != nil {
+		logger.Fatal("Failed to start retry sync pool", zap.Error(err))
+	}
+
+	ingressReporter, err := metrics.NewIngressReporter(metrics.PodName(env.PodName), metrics.ContainerName(component))
+	if err != nil {
+		logger.Fatal("Failed to create ingress reporter", zap.Error(err))
+	}
+	limits := ingress.DefaultSizeLimits()
+	h := ingress.NewHandler(
+		ctx,
+		clients.NewHTTPMessageReceiver(clients.Port(env.Port)),
+		ingress.NewMultiTopicDecoupleSink(ctx, targets, t, limits, nil),
+		targets,
+		nil,
+		limits,
+		// There is no load balancer to take the ingress out of, so it stops
+		// accepting requests right away on shutdown.
+		0,
+		ingressReporter,
+	)
+
+	logger.Info("Starting the local broker", zap.Int("port", env.Port))
+	if err := h.Start(ctx); err != nil {
+		logger.Fatal("Failed to start ingress", zap.Error(err))
+	}
+
+	// The handlers have stopped pulling messages along with the ingress.
+	drainCtx, cancel := context.WithTimeout(context.Background(), env.DrainTimeout+abortPeriod)
+	defer cancel()
+	for _, p := range []handler.DrainPool{fanoutPool, retryPool} {
+		if err := p.Drain(drainCtx); err != nil {
+			logger.Warn("Failed to drain the handlers", zap.Error(err))
+		}
+	}
+	logger.Info("Done draining, exit.")
+}
+
+// poolSyncSignals syncs the in-memory subscriptions on every targets config
+// update and then signals both the fanout and retry pools, so that the handlers
+// never start before their subscriptions exist.
+func poolSyncSignals(ctx context.Context, t *memory.Transport, targets config.ReadonlyTargets, targetsUpdateCh chan struct{}) (chan struct{}, chan struct{}) {
+	fanout := make(chan struct{}, 10)
+	retry := make(chan struct{}, 10)
+	ticker := time.NewTicker(poolResyncPeriod)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-targetsUpdateCh:
+				t.SyncSubscriptions(targets)
+			case <-ticker.C:
+			}
+			fanout <- struct{}{}
+			retry <- struct{}{}
+		}
+	}()
+	return fanout, retry
+}
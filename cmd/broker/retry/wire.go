@@ -21,7 +21,7 @@ package main
 import (
 	"context"
 
-	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/handler"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils/clients"
@@ -29,15 +29,15 @@ import (
 )
 
 // InitializeSyncPool initializes the retry sync pool. Uses the given projectID to initialize the
-// retry pool's pubsub client and uses targetsVolumeOpts to initialize the targets volume watcher.
+// retry pool's pubsub client and syncs its handlers with the given targets config.
 func InitializeSyncPool(
 	ctx context.Context,
 	projectID clients.ProjectID,
 	podName metrics.PodName,
 	containerName metrics.ContainerName,
-	targetsVolumeOpts []volume.Option,
+	targets config.ReadonlyTargets,
 	opts ...handler.Option) (*handler.RetryPool, error) {
 	// Implementation generated by wire. Providers for required RetryPool dependencies should be
 	// added here.
-	panic(wire.Build(handler.ProviderSet, volume.NewTargetsFromFile, metrics.NewDeliveryReporter))
+	panic(wire.Build(handler.ProviderSet, metrics.NewDeliveryReporter))
 }
@@ -7,7 +7,7 @@ package main
 
 import (
 	"context"
-	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/handler"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils/clients"
@@ -15,12 +15,12 @@ import (
 
 // Injectors from wire.go:
 
-func InitializeSyncPool(ctx context.Context, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, targetsVolumeOpts []volume.Option, opts ...handler.Option) (*handler.RetryPool, error) {
-	readonlyTargets, err := volume.NewTargetsFromFile(targetsVolumeOpts...)
+func InitializeSyncPool(ctx context.Context, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, targets config.ReadonlyTargets, opts ...handler.Option) (*handler.RetryPool, error) {
+	client, err := clients.NewPubsubClient(ctx, projectID)
 	if err != nil {
 		return nil, err
 	}
-	client, err := clients.NewPubsubClient(ctx, projectID)
+	transport, err := handler.NewPubsubTransport(client, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +29,7 @@ func InitializeSyncPool(ctx context.Context, projectID clients.ProjectID, podNam
 	if err != nil {
 		return nil, err
 	}
-	retryPool, err := handler.NewRetryPool(readonlyTargets, client, httpClient, deliveryReporter, opts...)
+	retryPool, err := handler.NewRetryPool(targets, transport, httpClient, deliveryReporter, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -63,6 +63,9 @@ func Controllers(
 	kedaPullsubscriptionController kedapullsubscription.Constructor,
 	topicController topic.Constructor,
 	channelController channel.Constructor,
+	brokerController broker.Constructor,
+	triggerController trigger.Constructor,
+	brokerCellController brokercell.Constructor,
 ) []injection.ControllerConstructor {
 	return []injection.ControllerConstructor{
 		injection.ControllerConstructor(auditlogsController),
@@ -75,9 +78,9 @@ func Controllers(
 		injection.ControllerConstructor(topicController),
 		injection.ControllerConstructor(channelController),
 		deployment.NewController,
-		broker.NewController,
-		trigger.NewController,
-		brokercell.NewController,
+		injection.ControllerConstructor(brokerController),
+		injection.ControllerConstructor(triggerController),
+		injection.ControllerConstructor(brokerCellController),
 	}
 }
 
@@ -19,6 +19,9 @@ import (
 	"context"
 
 	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
+	"github.com/google/knative-gcp/pkg/reconciler/broker"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/configserver"
 	"github.com/google/knative-gcp/pkg/reconciler/events/auditlogs"
 	"github.com/google/knative-gcp/pkg/reconciler/events/build"
 	"github.com/google/knative-gcp/pkg/reconciler/events/pubsub"
@@ -29,6 +32,7 @@ import (
 	"github.com/google/knative-gcp/pkg/reconciler/intevents/pullsubscription/static"
 	"github.com/google/knative-gcp/pkg/reconciler/intevents/topic"
 	"github.com/google/knative-gcp/pkg/reconciler/messaging/channel"
+	"github.com/google/knative-gcp/pkg/reconciler/trigger"
 	"github.com/google/wire"
 	"knative.dev/pkg/injection"
 )
@@ -48,5 +52,9 @@ func InitializeControllers(ctx context.Context) ([]injection.ControllerConstruct
 		keda.NewConstructor,
 		topic.NewConstructor,
 		channel.NewConstructor,
+		wire.Struct(new(configserver.Singleton)),
+		broker.NewConstructor,
+		trigger.NewConstructor,
+		brokercell.NewConstructor,
 	))
 }
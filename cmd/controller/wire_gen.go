@@ -9,6 +9,9 @@ import (
 	"cloud.google.com/go/iam/admin/apiv1"
 	"context"
 	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
+	"github.com/google/knative-gcp/pkg/reconciler/broker"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/configserver"
 	"github.com/google/knative-gcp/pkg/reconciler/events/auditlogs"
 	"github.com/google/knative-gcp/pkg/reconciler/events/build"
 	"github.com/google/knative-gcp/pkg/reconciler/events/pubsub"
@@ -19,6 +22,7 @@ import (
 	"github.com/google/knative-gcp/pkg/reconciler/intevents/pullsubscription/static"
 	"github.com/google/knative-gcp/pkg/reconciler/intevents/topic"
 	"github.com/google/knative-gcp/pkg/reconciler/messaging/channel"
+	"github.com/google/knative-gcp/pkg/reconciler/trigger"
 	"knative.dev/pkg/injection"
 )
 
@@ -48,6 +52,10 @@ func InitializeControllers(ctx context.Context) ([]injection.ControllerConstruct
 	kedaConstructor := keda.NewConstructor(iamPolicyManager, storeSingleton)
 	topicConstructor := topic.NewConstructor(iamPolicyManager, storeSingleton)
 	channelConstructor := channel.NewConstructor(iamPolicyManager, storeSingleton)
-	v2 := Controllers(constructor, storageConstructor, schedulerConstructor, pubsubConstructor, buildConstructor, staticConstructor, kedaConstructor, topicConstructor, channelConstructor)
+	singleton := &configserver.Singleton{}
+	brokerConstructor := broker.NewConstructor(singleton)
+	triggerConstructor := trigger.NewConstructor(singleton, storeSingleton)
+	brokercellConstructor := brokercell.NewConstructor(singleton, storeSingleton)
+	v2 := Controllers(constructor, storageConstructor, schedulerConstructor, pubsubConstructor, buildConstructor, staticConstructor, kedaConstructor, topicConstructor, channelConstructor, brokerConstructor, triggerConstructor, brokercellConstructor)
 	return v2, nil
 }
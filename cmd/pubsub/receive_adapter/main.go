@@ -34,6 +34,7 @@ import (
 	"github.com/google/knative-gcp/pkg/utils"
 	"github.com/google/knative-gcp/pkg/utils/appcredentials"
 	"github.com/google/knative-gcp/pkg/utils/clients"
+	"github.com/google/knative-gcp/pkg/utils/delivery"
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -62,6 +63,23 @@ type envConfig struct {
 	// Otherwise, only Sink is used (for either the sub.reply or sub.reply)
 	Transformer string `envconfig:"TRANSFORMER_URI"`
 
+	// Environment variable containing the audience of the ID tokens authenticating
+	// the deliveries to the sink. If empty, the deliveries are not authenticated.
+	SinkAudience string `envconfig:"SINK_AUDIENCE"`
+
+	// Environment variable containing the Google service account the ID tokens
+	// are minted for. If empty, the tokens are minted for the adapter's own
+	// service account.
+	SinkServiceAccount string `envconfig:"SINK_SERVICE_ACCOUNT"`
+
+	// Environment variable containing the comma separated list of the 4xx
+	// status codes of the sink that are retried. Other 4xx aren't retried.
+	RetryableClientErrorCodes string `envconfig:"RETRYABLE_CLIENT_ERROR_CODES" default:"408,409,429"`
+
+	// Environment variable specifying whether the events the sink rejects with
+	// a non-retryable status code are acked and dropped instead of nacked.
+	DropNonRetryable bool `envconfig:"DROP_NON_RETRYABLE" default:"false"`
+
 	// Environment variable specifying the type of adapter to use.
 	// Used for CE conversion.
 	AdapterType string `envconfig:"ADAPTER_TYPE"`
@@ -163,14 +181,23 @@ func main() {
 		logger.Error("Failed to convert base64 extensions to map: %v", zap.Error(err))
 	}
 
+	retryableCodes, err := delivery.ParseStatusCodes(env.RetryableClientErrorCodes)
+	if err != nil {
+		logger.Fatal("Failed to parse RETRYABLE_CLIENT_ERROR_CODES", zap.Error(err))
+	}
+
 	logger.Info("Initializing adapter", zap.String("projectID", projectID), zap.String("topicID", env.Topic), zap.String("subscriptionID", env.Subscription))
 
 	args := &AdapterArgs{
-		TopicID:        env.Topic,
-		ConverterType:  converters.ConverterType(env.AdapterType),
-		SinkURI:        env.Sink,
-		TransformerURI: env.Transformer,
-		Extensions:     extensions,
+		TopicID:            env.Topic,
+		ConverterType:      converters.ConverterType(env.AdapterType),
+		SinkURI:            env.Sink,
+		TransformerURI:     env.Transformer,
+		Extensions:         extensions,
+		SinkAudience:       env.SinkAudience,
+		SinkServiceAccount: env.SinkServiceAccount,
+		Classifier:         delivery.NewClassifier(retryableCodes),
+		DropNonRetryable:   env.DropNonRetryable,
 	}
 
 	adapter, err := InitializeAdapter(ctx,
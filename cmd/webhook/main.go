@@ -20,6 +20,7 @@ import (
 	"context"
 	"log"
 
+	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
 	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
 	configvalidation "github.com/google/knative-gcp/pkg/apis/configs/validation"
 	"github.com/google/knative-gcp/pkg/apis/events"
@@ -74,6 +75,13 @@ var types = map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
 	inteventsv1beta1.SchemeGroupVersion.WithKind("Topic"):             &inteventsv1beta1.Topic{},
 }
 
+// brokerTypes are the eventing.knative.dev types configured with annotations of the Google Cloud
+// Broker. The eventing webhook defaults and validates them, only the annotations are validated here.
+var brokerTypes = map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+	brokerv1beta1.SchemeGroupVersion.WithKind("Broker"):  &brokerv1beta1.Broker{},
+	brokerv1beta1.SchemeGroupVersion.WithKind("Trigger"): &brokerv1beta1.Trigger{},
+}
+
 type defaultingAdmissionController func(context.Context, configmap.Watcher) *controller.Impl
 
 func newDefaultingAdmissionConstructor(gcpas *gcpauth.StoreSingleton) defaultingAdmissionController {
@@ -139,6 +147,38 @@ func newValidationAdmissionController(ctx context.Context, cmw configmap.Watcher
 	)
 }
 
+type brokerValidationController func(context.Context, configmap.Watcher) *controller.Impl
+
+func newBrokerValidationConstructor(gcpas *gcpauth.StoreSingleton) brokerValidationController {
+	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+		return newBrokerValidationAdmissionController(ctx, cmw, gcpas.Store(ctx, cmw))
+	}
+}
+
+func newBrokerValidationAdmissionController(ctx context.Context, cmw configmap.Watcher, gcpas *gcpauth.Store) *controller.Impl {
+	// A function that infuses the context passed to Validate with custom metadata.
+	ctxFunc := func(ctx context.Context) context.Context {
+		return gcpas.ToContext(ctx)
+	}
+
+	return validation.NewAdmissionController(ctx,
+
+		// Name of the broker validation webhook.
+		"broker.validation.webhook.events.cloud.google.com",
+
+		// The path on which to serve the webhook.
+		"/broker-validation",
+
+		// The resources to validate.
+		brokerTypes,
+
+		ctxFunc,
+
+		// The eventing webhook checks the fields, so allow the unknown ones.
+		false,
+	)
+}
+
 func NewConfigValidationController(ctx context.Context, _ configmap.Watcher) *controller.Impl {
 	return configmaps.NewAdmissionController(ctx,
 
@@ -280,11 +320,13 @@ func Controllers(
 	conversionController conversionController,
 	defaultingAdmissionController defaultingAdmissionController,
 	validationController validationController,
+	brokerValidationController brokerValidationController,
 ) []injection.ControllerConstructor {
 	return []injection.ControllerConstructor{
 		certificates.NewController,
 		NewConfigValidationController,
 		injection.ControllerConstructor(validationController),
+		injection.ControllerConstructor(brokerValidationController),
 		injection.ControllerConstructor(defaultingAdmissionController),
 		injection.ControllerConstructor(conversionController),
 	}
@@ -30,5 +30,6 @@ func InitializeControllers(ctx context.Context) ([]injection.ControllerConstruct
 		newConversionConstructor,
 		newDefaultingAdmissionConstructor,
 		newValidationConstructor,
+		newBrokerValidationConstructor,
 	))
 }
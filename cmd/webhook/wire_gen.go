@@ -18,6 +18,7 @@ func InitializeControllers(ctx context.Context) ([]injection.ControllerConstruct
 	mainConversionController := newConversionConstructor(storeSingleton)
 	mainDefaultingAdmissionController := newDefaultingAdmissionConstructor(storeSingleton)
 	mainValidationController := newValidationConstructor(storeSingleton)
-	v := Controllers(mainConversionController, mainDefaultingAdmissionController, mainValidationController)
+	mainBrokerValidationController := newBrokerValidationConstructor(storeSingleton)
+	v := Controllers(mainConversionController, mainDefaultingAdmissionController, mainValidationController, mainBrokerValidationController)
 	return v, nil
 }
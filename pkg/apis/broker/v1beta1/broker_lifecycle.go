@@ -26,6 +26,7 @@ var brokerCondSet = apis.NewLivingConditionSet(
 	BrokerConditionBrokerCell,
 	BrokerConditionTopic,
 	BrokerConditionSubscription,
+	BrokerConditionDataPlane,
 )
 
 const (
@@ -37,6 +38,12 @@ const (
 	// BrokerConditionSubscription reports the status of the Broker's PubSub
 	// subscription. This condition is specific to the Google Cloud Broker.
 	BrokerConditionSubscription apis.ConditionType = "SubscriptionReady"
+	// BrokerConditionDataPlane reports whether the data plane pods of the Broker's
+	// BrokerCell applied the latest targets config of the Broker.
+	BrokerConditionDataPlane apis.ConditionType = "DataPlaneReady"
+	// BrokerConditionOrdering reports whether events of the Broker are delivered in order. It's
+	// only set if the Broker has the OrderingKeyExtensionAnnotation and doesn't affect readiness.
+	BrokerConditionOrdering apis.ConditionType = "OrderingEnabled"
 )
 
 // GetCondition returns the condition currently associated with the given type, or nil.
@@ -105,3 +112,23 @@ func (bs *BrokerStatus) MarkSubscriptionUnknown(reason, format string, args ...i
 func (bs *BrokerStatus) MarkSubscriptionReady() {
 	brokerCondSet.Manage(bs).MarkTrue(BrokerConditionSubscription)
 }
+
+func (bs *BrokerStatus) MarkDataPlaneUnknown(reason, format string, args ...interface{}) {
+	brokerCondSet.Manage(bs).MarkUnknown(BrokerConditionDataPlane, reason, format, args...)
+}
+
+func (bs *BrokerStatus) MarkDataPlaneReady() {
+	brokerCondSet.Manage(bs).MarkTrue(BrokerConditionDataPlane)
+}
+
+func (bs *BrokerStatus) MarkOrderingEnabled() {
+	brokerCondSet.Manage(bs).MarkTrue(BrokerConditionOrdering)
+}
+
+func (bs *BrokerStatus) MarkOrderingDisabled(reason, format string, args ...interface{}) {
+	brokerCondSet.Manage(bs).MarkFalse(BrokerConditionOrdering, reason, format, args...)
+}
+
+func (bs *BrokerStatus) ClearOrdering() {
+	brokerCondSet.Manage(bs).ClearCondition(BrokerConditionOrdering)
+}
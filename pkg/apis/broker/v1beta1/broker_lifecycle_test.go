@@ -150,6 +150,9 @@ func TestBrokerInitializeConditions(t *testing.T) {
 					}, {
 						Type:   BrokerConditionBrokerCell,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   BrokerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.BrokerConditionReady,
 						Status: corev1.ConditionUnknown,
@@ -184,6 +187,9 @@ func TestBrokerInitializeConditions(t *testing.T) {
 					}, {
 						Type:   BrokerConditionBrokerCell,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   BrokerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.BrokerConditionReady,
 						Status: corev1.ConditionUnknown,
@@ -218,6 +224,9 @@ func TestBrokerInitializeConditions(t *testing.T) {
 					}, {
 						Type:   BrokerConditionBrokerCell,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   BrokerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.BrokerConditionReady,
 						Status: corev1.ConditionUnknown,
@@ -328,6 +337,14 @@ func TestBrokerConditionStatus(t *testing.T) {
 		topicStatus:         corev1.ConditionTrue,
 		configStatus:        corev1.ConditionTrue,
 		wantConditionStatus: corev1.ConditionUnknown,
+	}, {
+		name:                "data plane unknown",
+		addressStatus:       true,
+		brokerCellStatus:    corev1.ConditionTrue,
+		subscriptionStatus:  corev1.ConditionTrue,
+		topicStatus:         corev1.ConditionTrue,
+		configStatus:        corev1.ConditionUnknown,
+		wantConditionStatus: corev1.ConditionUnknown,
 	}, {
 		name:                "all sad",
 		addressStatus:       false,
@@ -366,6 +383,11 @@ func TestBrokerConditionStatus(t *testing.T) {
 			} else {
 				bs.MarkTopicUnknown("Unable to create PubSub topic", "induced unknown")
 			}
+			if test.configStatus == corev1.ConditionTrue {
+				bs.MarkDataPlaneReady()
+			} else {
+				bs.MarkDataPlaneUnknown("TargetsConfigNotApplied", "induced unknown")
+			}
 
 			got := bs.GetTopLevelCondition().Status
 			if test.wantConditionStatus != got {
@@ -385,3 +407,31 @@ func TestBrokerConditionStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestBrokerOrderingCondition(t *testing.T) {
+	bs := &BrokerStatus{}
+	bs.SetAddress(&apis.URL{Scheme: "http", Host: "example.com"})
+	bs.MarkBrokerCellReady()
+	bs.MarkTopicReady()
+	bs.MarkSubscriptionReady()
+	bs.MarkDataPlaneReady()
+
+	bs.MarkOrderingDisabled("SubscriptionNotOrdered", "induced failure")
+	if !bs.IsReady() {
+		t.Error("broker is not ready when ordering is disabled")
+	}
+	got := bs.GetCondition(BrokerConditionOrdering)
+	if got == nil || got.Status != corev1.ConditionFalse || got.Severity != apis.ConditionSeverityInfo {
+		t.Errorf("unexpected ordering condition: %+v", got)
+	}
+
+	bs.MarkOrderingEnabled()
+	if got := bs.GetCondition(BrokerConditionOrdering); got == nil || got.Status != corev1.ConditionTrue {
+		t.Errorf("unexpected ordering condition: %+v", got)
+	}
+
+	bs.ClearOrdering()
+	if got := bs.GetCondition(BrokerConditionOrdering); got != nil {
+		t.Errorf("ordering condition was not cleared: %+v", got)
+	}
+}
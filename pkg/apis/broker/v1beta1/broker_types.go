@@ -30,6 +30,57 @@ const (
 	// BrokerClass is the annotation value to use when creating a
 	// Google Cloud Broker object.
 	BrokerClass = "googlecloud"
+
+	// IngressRateLimitAnnotation is the annotation key used to limit the rate of events accepted
+	// by the ingress for the Broker. The value is in the form of "<events-per-second>[,<burst>]".
+	// The limit applies to each ingress replica of the BrokerCell, not to the BrokerCell as a whole.
+	IngressRateLimitAnnotation = "events.cloud.google.com/ingress-rate-limit"
+
+	// NamespaceIngressRateLimitAnnotation is the annotation key used to limit the rate of events
+	// accepted by the ingress for all Brokers in the namespace of the Broker, in the same form as
+	// IngressRateLimitAnnotation. If several Brokers in a namespace set it, the lowest rate applies.
+	NamespaceIngressRateLimitAnnotation = "events.cloud.google.com/namespace-ingress-rate-limit"
+
+	// AllowedIdentitiesAnnotation is the annotation key used to list who may send events to the
+	// Broker when the ingress authenticates requests. The value is a comma separated list of
+	// identities, where an identity ending with "*" allows all identities with that prefix. When the
+	// ingress authenticates requests, the Brokers without the annotation reject all of them.
+	AllowedIdentitiesAnnotation = "events.cloud.google.com/ingress-allowed-identities"
+
+	// ClaimCheckThresholdAnnotation is the annotation key used to offload event payloads larger than
+	// the given number of bytes to the claim check store. The ingress then publishes only a reference
+	// to the payload, which is fetched again before the event is delivered.
+	ClaimCheckThresholdAnnotation = "events.cloud.google.com/claim-check-threshold"
+
+	// OrderingKeyExtensionAnnotation is the annotation key used to deliver events of the Broker in
+	// order. The value is the name of the CloudEvents extension holding the ordering key, e.g.
+	// "partitionkey". Events with the same key are delivered to each Trigger one at a time, in the
+	// order they were accepted by the ingress. An event whose delivery failed is retried before the
+	// later events with the same key are delivered, which wait meanwhile. Ordering only applies to
+	// Pub/Sub subscriptions created after the annotation is set.
+	OrderingKeyExtensionAnnotation = "events.cloud.google.com/ordering-key-extension"
+
+	// DeduplicationWindowAnnotation is the annotation key used to drop duplicate events of the
+	// Broker. The value is a duration up to 24h, e.g. "10m". An event with the same source and id as
+	// an event processed within the window is dropped before it is fanned out to the Triggers.
+	DeduplicationWindowAnnotation = "events.cloud.google.com/deduplication-window"
+
+	// HopLimitAnnotation is the annotation key used to lower the number of times an event may be
+	// replied to the Broker, from 1 up to the default of 255. Once an event exhausted its hops,
+	// the replies of the Triggers to it are dropped.
+	HopLimitAnnotation = "events.cloud.google.com/hop-limit"
+
+	// LoopDetectionAnnotation is the annotation key used to drop the events looping between the
+	// Triggers of the Broker when set to "true". The Triggers whose replies an event descends from
+	// are recorded in the event, and the event is dropped before it's delivered again to one of
+	// them. A Kubernetes event is recorded on the Trigger when a loop is detected.
+	LoopDetectionAnnotation = "events.cloud.google.com/loop-detection"
+
+	// MessageRetentionAnnotation is the annotation key declaring how long the Pub/Sub decoupling
+	// topic of the Broker retains its messages, e.g. "168h". The retention is configured on the topic
+	// itself, e.g. with "gcloud pubsub topics update --message-retention-duration". Triggers may only
+	// replay from a timestamp within the declared retention.
+	MessageRetentionAnnotation = "events.cloud.google.com/message-retention"
 )
 
 // +genclient
@@ -18,13 +18,147 @@ package v1beta1
 
 import (
 	"context"
+	"fmt"
 
 	"knative.dev/pkg/apis"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
 )
 
 // Validate verifies that the Broker is valid.
 func (b *Broker) Validate(ctx context.Context) *apis.FieldError {
-	// The Google Cloud Broker doesn't have any custom validations. The
-	// eventing webhook will run the usual validations.
+	// The eventing webhook will run the usual validations. Only the
+	// annotations specific to the Google Cloud Broker are validated here.
+	var errs *apis.FieldError
+	for _, key := range []string{IngressRateLimitAnnotation, NamespaceIngressRateLimitAnnotation} {
+		errs = errs.Also(b.validateRateLimit(key))
+	}
+	errs = errs.Also(b.validateAuthPolicy())
+	errs = errs.Also(b.validateClaimCheckThreshold())
+	errs = errs.Also(b.validateOrderingKeyExtension())
+	errs = errs.Also(b.validateDeduplicationWindow())
+	errs = errs.Also(b.validateHopLimit())
+	errs = errs.Also(b.validateLoopDetection())
+	errs = errs.Also(b.validateMessageRetention())
+	return errs.ViaField("metadata")
+}
+
+func (b *Broker) validateRateLimit(key string) *apis.FieldError {
+	v, ok := b.Annotations[key]
+	if !ok {
+		return nil
+	}
+	if _, err := config.ParseRateLimit(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid rate limit",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", key)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (b *Broker) validateAuthPolicy() *apis.FieldError {
+	v, ok := b.Annotations[AllowedIdentitiesAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := config.ParseAuthPolicy(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid allowed identities",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", AllowedIdentitiesAnnotation)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (b *Broker) validateClaimCheckThreshold() *apis.FieldError {
+	v, ok := b.Annotations[ClaimCheckThresholdAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := config.ParseClaimCheckThreshold(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid claim check threshold",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", ClaimCheckThresholdAnnotation)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (b *Broker) validateOrderingKeyExtension() *apis.FieldError {
+	v, ok := b.Annotations[OrderingKeyExtensionAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := config.ParseOrderingKeyExtension(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid ordering key extension",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", OrderingKeyExtensionAnnotation)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (b *Broker) validateDeduplicationWindow() *apis.FieldError {
+	v, ok := b.Annotations[DeduplicationWindowAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := config.ParseDeduplicationWindow(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid deduplication window",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", DeduplicationWindowAnnotation)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (b *Broker) validateHopLimit() *apis.FieldError {
+	v, ok := b.Annotations[HopLimitAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := config.ParseHopLimit(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid hop limit",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", HopLimitAnnotation)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (b *Broker) validateMessageRetention() *apis.FieldError {
+	v, ok := b.Annotations[MessageRetentionAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := ParseMessageRetention(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid message retention",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", MessageRetentionAnnotation)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (b *Broker) validateLoopDetection() *apis.FieldError {
+	v, ok := b.Annotations[LoopDetectionAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := config.ParseLoopDetection(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid loop detection",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", LoopDetectionAnnotation)},
+			Details: err.Error(),
+		}
+	}
 	return nil
 }
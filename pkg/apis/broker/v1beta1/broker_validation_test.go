@@ -19,11 +19,154 @@ package v1beta1
 import (
 	"context"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
 )
 
 func TestBroker_Validate(t *testing.T) {
-	b := Broker{}
-	if err := b.Validate(context.TODO()); err != nil {
-		t.Errorf("expected nil, got %v", err)
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        *apis.FieldError
+	}{{
+		name: "no annotations",
+	}, {
+		name: "valid rate limits",
+		annotations: map[string]string{
+			IngressRateLimitAnnotation:          "100",
+			NamespaceIngressRateLimitAnnotation: "1000,2000",
+		},
+	}, {
+		name: "invalid rate limit",
+		annotations: map[string]string{
+			IngressRateLimitAnnotation: "fast",
+		},
+		want: &apis.FieldError{
+			Message: "invalid rate limit",
+			Paths:   []string{"metadata.annotations[events.cloud.google.com/ingress-rate-limit]"},
+			Details: `invalid rate limit "fast": events per second must be a positive number`,
+		},
+	}, {
+		name: "invalid namespace rate limit",
+		annotations: map[string]string{
+			NamespaceIngressRateLimitAnnotation: "10,-1",
+		},
+		want: &apis.FieldError{
+			Message: "invalid rate limit",
+			Paths:   []string{"metadata.annotations[events.cloud.google.com/namespace-ingress-rate-limit]"},
+			Details: `invalid rate limit "10,-1": burst must be a positive integer`,
+		},
+	}, {
+		name: "valid allowed identities",
+		annotations: map[string]string{
+			AllowedIdentitiesAnnotation: "system:serviceaccount:ns1:*,producer@project.iam.gserviceaccount.com",
+		},
+	}, {
+		name: "invalid allowed identities",
+		annotations: map[string]string{
+			AllowedIdentitiesAnnotation: "a,,b",
+		},
+		want: &apis.FieldError{
+			Message: "invalid allowed identities",
+			Paths:   []string{"metadata.annotations[events.cloud.google.com/ingress-allowed-identities]"},
+			Details: `invalid auth policy "a,,b": empty identity`,
+		},
+	}, {
+		name: "valid claim check threshold",
+		annotations: map[string]string{
+			ClaimCheckThresholdAnnotation: "1048576",
+		},
+	}, {
+		name: "invalid claim check threshold",
+		annotations: map[string]string{
+			ClaimCheckThresholdAnnotation: "1Mi",
+		},
+		want: &apis.FieldError{
+			Message: "invalid claim check threshold",
+			Paths:   []string{"metadata.annotations[events.cloud.google.com/claim-check-threshold]"},
+			Details: `invalid claim check threshold "1Mi": must be a positive number of bytes`,
+		},
+	}, {
+		name: "valid ordering key extension",
+		annotations: map[string]string{
+			OrderingKeyExtensionAnnotation: "partitionkey",
+		},
+	}, {
+		name: "invalid ordering key extension",
+		annotations: map[string]string{
+			OrderingKeyExtensionAnnotation: "partition-key",
+		},
+		want: &apis.FieldError{
+			Message: "invalid ordering key extension",
+			Paths:   []string{"metadata.annotations[events.cloud.google.com/ordering-key-extension]"},
+			Details: `invalid ordering key extension "partition-key": must be 1 to 20 lowercase letters or digits`,
+		},
+	}, {
+		name: "valid deduplication window",
+		annotations: map[string]string{
+			DeduplicationWindowAnnotation: "10m",
+		},
+	}, {
+		name: "invalid deduplication window",
+		annotations: map[string]string{
+			DeduplicationWindowAnnotation: "2d",
+		},
+		want: &apis.FieldError{
+			Message: "invalid deduplication window",
+			Paths:   []string{"metadata.annotations[events.cloud.google.com/deduplication-window]"},
+			Details: `invalid deduplication window "2d": must be a positive duration up to 24h0m0s`,
+		},
+	}, {
+		name: "valid hop limit and loop detection",
+		annotations: map[string]string{
+			HopLimitAnnotation:      "10",
+			LoopDetectionAnnotation: "true",
+		},
+	}, {
+		name: "invalid hop limit",
+		annotations: map[string]string{
+			HopLimitAnnotation: "1000",
+		},
+		want: &apis.FieldError{
+			Message: "invalid hop limit",
+			Paths:   []string{"metadata.annotations[events.cloud.google.com/hop-limit]"},
+			Details: `invalid hop limit "1000": must be an integer from 1 to 255`,
+		},
+	}, {
+		name: "invalid loop detection",
+		annotations: map[string]string{
+			LoopDetectionAnnotation: "yes",
+		},
+		want: &apis.FieldError{
+			Message: "invalid loop detection",
+			Paths:   []string{"metadata.annotations[events.cloud.google.com/loop-detection]"},
+			Details: `invalid loop detection "yes": must be true or false`,
+		},
+	}, {
+		name: "valid message retention",
+		annotations: map[string]string{
+			MessageRetentionAnnotation: "168h",
+		},
+	}, {
+		name: "invalid message retention",
+		annotations: map[string]string{
+			MessageRetentionAnnotation: "1m",
+		},
+		want: &apis.FieldError{
+			Message: "invalid message retention",
+			Paths:   []string{"metadata.annotations[events.cloud.google.com/message-retention]"},
+			Details: `invalid message retention "1m": must be a duration from 10m0s to 744h0m0s`,
+		},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := Broker{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			got := b.Validate(context.TODO())
+			if diff := cmp.Diff(tc.want.Error(), got.Error()); diff != "" {
+				t.Errorf("Validate (-want, +got): %s", diff)
+			}
+		})
 	}
 }
@@ -34,6 +34,7 @@ func (t testHelper) ReadyBrokerStatus() *BrokerStatus {
 	bs.MarkSubscriptionReady()
 	bs.MarkTopicReady()
 	bs.MarkBrokerCellReady()
+	bs.MarkDataPlaneReady()
 	return bs
 }
 
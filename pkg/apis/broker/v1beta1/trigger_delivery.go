@@ -0,0 +1,66 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
+
+	"github.com/google/knative-gcp/pkg/utils"
+)
+
+// ParseDelivery parses the value of the DeliveryAnnotation into a delivery spec
+// with only the retry, backoff policy and backoff delay set.
+func ParseDelivery(value string) (*eventingduckv1beta1.DeliverySpec, error) {
+	var delivery struct {
+		Retry         *int32                                 `json:"retry"`
+		BackoffPolicy *eventingduckv1beta1.BackoffPolicyType `json:"backoffPolicy"`
+		BackoffDelay  *string                                `json:"backoffDelay"`
+	}
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&delivery); err != nil {
+		return nil, fmt.Errorf("delivery must be a JSON object with retry, backoffPolicy and backoffDelay fields: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("delivery must be a single JSON object")
+	}
+	if delivery.Retry != nil && *delivery.Retry < 0 {
+		return nil, fmt.Errorf("invalid retry %d: must not be negative", *delivery.Retry)
+	}
+	if delivery.BackoffPolicy != nil {
+		switch *delivery.BackoffPolicy {
+		case eventingduckv1beta1.BackoffPolicyExponential, eventingduckv1beta1.BackoffPolicyLinear:
+		default:
+			return nil, fmt.Errorf("invalid backoff policy %q: must be %q or %q", *delivery.BackoffPolicy,
+				eventingduckv1beta1.BackoffPolicyExponential, eventingduckv1beta1.BackoffPolicyLinear)
+		}
+	}
+	if delivery.BackoffDelay != nil {
+		if _, err := utils.ParseISO8601Duration(*delivery.BackoffDelay); err != nil {
+			return nil, err
+		}
+	}
+	return &eventingduckv1beta1.DeliverySpec{
+		Retry:         delivery.Retry,
+		BackoffPolicy: delivery.BackoffPolicy,
+		BackoffDelay:  delivery.BackoffDelay,
+	}, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
+	"knative.dev/pkg/ptr"
+)
+
+func TestParseDelivery(t *testing.T) {
+	linear := eventingduckv1beta1.BackoffPolicyLinear
+	cases := []struct {
+		name    string
+		value   string
+		want    *eventingduckv1beta1.DeliverySpec
+		wantErr bool
+	}{{
+		name:  "all fields",
+		value: `{"retry": 5, "backoffPolicy": "linear", "backoffDelay": "PT0.5S"}`,
+		want: &eventingduckv1beta1.DeliverySpec{
+			Retry:         ptr.Int32(5),
+			BackoffPolicy: &linear,
+			BackoffDelay:  ptr.String("PT0.5S"),
+		},
+	}, {
+		name:  "retry only",
+		value: `{"retry": 0}`,
+		want:  &eventingduckv1beta1.DeliverySpec{Retry: ptr.Int32(0)},
+	}, {
+		name:  "empty",
+		value: `{}`,
+		want:  &eventingduckv1beta1.DeliverySpec{},
+	}, {
+		name:    "not json",
+		value:   `retry: 5`,
+		wantErr: true,
+	}, {
+		name:    "trailing data",
+		value:   `{"retry": 5} {"retry": 6}`,
+		wantErr: true,
+	}, {
+		name:    "dead letter sink",
+		value:   `{"deadLetterSink": {"uri": "http://dead-letter.example.com"}}`,
+		wantErr: true,
+	}, {
+		name:    "negative retry",
+		value:   `{"retry": -1}`,
+		wantErr: true,
+	}, {
+		name:    "invalid backoff policy",
+		value:   `{"backoffPolicy": "random"}`,
+		wantErr: true,
+	}, {
+		name:    "invalid backoff delay",
+		value:   `{"backoffDelay": "1s"}`,
+		wantErr: true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseDelivery(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseDelivery error got=%v, wantErr=%v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ParseDelivery (-want,+got): %v", diff)
+			}
+		})
+	}
+}
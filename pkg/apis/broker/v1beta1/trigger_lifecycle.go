@@ -17,6 +17,8 @@ limitations under the License.
 package v1beta1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	eventingv1beta1 "knative.dev/eventing/pkg/apis/eventing/v1beta1"
 	"knative.dev/pkg/apis"
@@ -29,11 +31,30 @@ var triggerCondSet = apis.NewLivingConditionSet(
 	eventingv1beta1.TriggerConditionSubscriberResolved,
 	TriggerConditionTopic,
 	TriggerConditionSubscription,
+	TriggerConditionDataPlane,
 )
 
 const (
 	TriggerConditionTopic        apis.ConditionType = "TopicReady"
 	TriggerConditionSubscription apis.ConditionType = "SubscriptionReady"
+	// TriggerConditionDataPlane reports whether the data plane pods of the BrokerCell
+	// applied the latest targets config of the Trigger.
+	TriggerConditionDataPlane apis.ConditionType = "DataPlaneReady"
+	// TriggerConditionOrdering reports whether events are delivered to the Trigger in order. It's
+	// only set if the Broker of the Trigger orders events and doesn't affect readiness.
+	TriggerConditionOrdering apis.ConditionType = "OrderingEnabled"
+
+	// TriggerConditionPaused reports whether the delivery of events to the Trigger is paused with the
+	// PausedAnnotation. It's only set while the Trigger is paused and doesn't affect readiness.
+	TriggerConditionPaused apis.ConditionType = "Paused"
+	// TriggerConditionReplay reports the progress of the replay requested with the ReplayAnnotation. It's
+	// Unknown while the events are replayed and doesn't affect readiness.
+	TriggerConditionReplay apis.ConditionType = "Replayed"
+)
+
+const (
+	// replayingReason is the reason of the Replayed condition while the replay is in progress.
+	replayingReason = "Replaying"
 )
 
 // GetCondition returns the condition currently associated with the given type, or nil.
@@ -112,6 +133,71 @@ func (bs *TriggerStatus) MarkSubscriptionReady() {
 	triggerCondSet.Manage(bs).MarkTrue(TriggerConditionSubscription)
 }
 
+func (ts *TriggerStatus) MarkDataPlaneUnknown(reason, format string, args ...interface{}) {
+	triggerCondSet.Manage(ts).MarkUnknown(TriggerConditionDataPlane, reason, format, args...)
+}
+
+func (ts *TriggerStatus) MarkDataPlaneFailed(reason, format string, args ...interface{}) {
+	triggerCondSet.Manage(ts).MarkFalse(TriggerConditionDataPlane, reason, format, args...)
+}
+
+func (ts *TriggerStatus) MarkDataPlaneReady() {
+	triggerCondSet.Manage(ts).MarkTrue(TriggerConditionDataPlane)
+}
+
+func (ts *TriggerStatus) MarkOrderingEnabled() {
+	triggerCondSet.Manage(ts).MarkTrue(TriggerConditionOrdering)
+}
+
+func (ts *TriggerStatus) MarkOrderingDisabled(reason, format string, args ...interface{}) {
+	triggerCondSet.Manage(ts).MarkFalse(TriggerConditionOrdering, reason, format, args...)
+}
+
+func (ts *TriggerStatus) ClearOrdering() {
+	triggerCondSet.Manage(ts).ClearCondition(TriggerConditionOrdering)
+}
+
+func (ts *TriggerStatus) MarkPaused() {
+	triggerCondSet.Manage(ts).MarkTrue(TriggerConditionPaused)
+}
+
+func (ts *TriggerStatus) ClearPaused() {
+	triggerCondSet.Manage(ts).ClearCondition(TriggerConditionPaused)
+}
+
+// MarkReplaying marks the replay from the given source in progress. The start of the
+// replay is the last transition time of the condition, so the message must not change
+// until the replay is over.
+func (ts *TriggerStatus) MarkReplaying(source string) {
+	triggerCondSet.Manage(ts).MarkUnknown(TriggerConditionReplay, replayingReason, "Replaying the events since %s", source)
+}
+
+func (ts *TriggerStatus) MarkReplayed(source string) {
+	triggerCondSet.Manage(ts).MarkTrueWithReason(TriggerConditionReplay, "Replayed", "Replayed the events since %s", source)
+}
+
+// MarkReplayUnknown marks the replay not started yet because of a transient error.
+func (ts *TriggerStatus) MarkReplayUnknown(reason, format string, args ...interface{}) {
+	triggerCondSet.Manage(ts).MarkUnknown(TriggerConditionReplay, reason, format, args...)
+}
+
+func (ts *TriggerStatus) MarkReplayFailed(reason, format string, args ...interface{}) {
+	triggerCondSet.Manage(ts).MarkFalse(TriggerConditionReplay, reason, format, args...)
+}
+
+func (ts *TriggerStatus) ClearReplay() {
+	triggerCondSet.Manage(ts).ClearCondition(TriggerConditionReplay)
+}
+
+// ReplayStartTime returns when the replay in progress started, or false if no replay is in progress.
+func (ts *TriggerStatus) ReplayStartTime() (time.Time, bool) {
+	c := ts.GetCondition(TriggerConditionReplay)
+	if c == nil || !c.IsUnknown() || c.Reason != replayingReason {
+		return time.Time{}, false
+	}
+	return c.LastTransitionTime.Inner.Time, true
+}
+
 func (ts *TriggerStatus) MarkSubscriberResolvedSucceeded() {
 	triggerCondSet.Manage(ts).MarkTrue(eventingv1beta1.TriggerConditionSubscriberResolved)
 }
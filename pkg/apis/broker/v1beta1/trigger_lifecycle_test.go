@@ -147,6 +147,9 @@ func TestTriggerInitializeConditions(t *testing.T) {
 					Conditions: []apis.Condition{{
 						Type:   eventingv1beta1.TriggerConditionBroker,
 						Status: corev1.ConditionUnknown,
+					}, {
+						Type:   TriggerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.TriggerConditionDependency,
 						Status: corev1.ConditionUnknown,
@@ -184,6 +187,9 @@ func TestTriggerInitializeConditions(t *testing.T) {
 					Conditions: []apis.Condition{{
 						Type:   eventingv1beta1.TriggerConditionBroker,
 						Status: corev1.ConditionFalse,
+					}, {
+						Type:   TriggerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.TriggerConditionDependency,
 						Status: corev1.ConditionUnknown,
@@ -221,6 +227,9 @@ func TestTriggerInitializeConditions(t *testing.T) {
 					Conditions: []apis.Condition{{
 						Type:   eventingv1beta1.TriggerConditionBroker,
 						Status: corev1.ConditionTrue,
+					}, {
+						Type:   TriggerConditionDataPlane,
+						Status: corev1.ConditionUnknown,
 					}, {
 						Type:   eventingv1beta1.TriggerConditionDependency,
 						Status: corev1.ConditionUnknown,
@@ -263,6 +272,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		topicStatus              corev1.ConditionStatus
 		subscriptionStatus       corev1.ConditionStatus
 		subscriberResolvedStatus corev1.ConditionStatus
+		dataPlaneStatus          corev1.ConditionStatus
 		dependencyStatus         *duckv1.KResource
 		wantConditionStatus      corev1.ConditionStatus
 	}{{
@@ -271,6 +281,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         nil,
 		wantConditionStatus:      corev1.ConditionTrue,
 	}, {
@@ -279,6 +290,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         nil,
 		wantConditionStatus:      corev1.ConditionUnknown,
 	}, {
@@ -287,6 +299,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         nil,
 		wantConditionStatus:      corev1.ConditionUnknown,
 	}, {
@@ -295,6 +308,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         nil,
 		wantConditionStatus:      corev1.ConditionFalse,
 	}, {
@@ -303,6 +317,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionFalse,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         nil,
 		wantConditionStatus:      corev1.ConditionFalse,
 	}, {
@@ -311,6 +326,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionUnknown,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         nil,
 		wantConditionStatus:      corev1.ConditionUnknown,
 	}, {
@@ -319,6 +335,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionFalse,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         nil,
 		wantConditionStatus:      corev1.ConditionFalse,
 	}, {
@@ -327,6 +344,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionUnknown,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         nil,
 		wantConditionStatus:      corev1.ConditionUnknown,
 	}, {
@@ -335,6 +353,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionFalse,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         TestHelper.ReadyDependencyStatus(),
 		wantConditionStatus:      corev1.ConditionFalse,
 	}, {
@@ -343,6 +362,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionUnknown,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         TestHelper.ReadyDependencyStatus(),
 		wantConditionStatus:      corev1.ConditionUnknown,
 	}, {
@@ -351,6 +371,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         TestHelper.UnconfiguredDependencyStatus(),
 		wantConditionStatus:      corev1.ConditionUnknown,
 	}, {
@@ -359,6 +380,7 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         TestHelper.UnknownDependencyStatus(),
 		wantConditionStatus:      corev1.ConditionUnknown,
 	}, {
@@ -367,14 +389,25 @@ func TestTriggerConditionStatus(t *testing.T) {
 		subscriptionStatus:       corev1.ConditionTrue,
 		topicStatus:              corev1.ConditionTrue,
 		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         TestHelper.FalseDependencyStatus(),
 		wantConditionStatus:      corev1.ConditionFalse,
+	}, {
+		name:                     "data plane unknown",
+		brokerStatus:             TestHelper.ReadyBrokerStatus(),
+		subscriptionStatus:       corev1.ConditionTrue,
+		topicStatus:              corev1.ConditionTrue,
+		subscriberResolvedStatus: corev1.ConditionTrue,
+		dataPlaneStatus:          corev1.ConditionUnknown,
+		dependencyStatus:         nil,
+		wantConditionStatus:      corev1.ConditionUnknown,
 	}, {
 		name:                     "all sad",
 		brokerStatus:             TestHelper.FalseBrokerStatus(),
 		subscriptionStatus:       corev1.ConditionFalse,
 		topicStatus:              corev1.ConditionFalse,
 		subscriberResolvedStatus: corev1.ConditionFalse,
+		dataPlaneStatus:          corev1.ConditionTrue,
 		dependencyStatus:         TestHelper.FalseDependencyStatus(),
 		wantConditionStatus:      corev1.ConditionFalse,
 	}}
@@ -405,6 +438,11 @@ func TestTriggerConditionStatus(t *testing.T) {
 			} else {
 				ts.MarkSubscriberResolvedUnknown("Status of Subscriber URI is unknown", "induced failure")
 			}
+			if test.dataPlaneStatus == corev1.ConditionTrue {
+				ts.MarkDataPlaneReady()
+			} else {
+				ts.MarkDataPlaneUnknown("TargetsConfigNotApplied", "induced unknown")
+			}
 			if test.dependencyStatus == nil {
 				ts.MarkDependencySucceeded()
 			} else {
@@ -428,3 +466,109 @@ func TestTriggerConditionStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestTriggerOrderingCondition(t *testing.T) {
+	ts := &TriggerStatus{}
+	ts.PropagateBrokerStatus(TestHelper.ReadyBrokerStatus())
+	ts.MarkSubscriptionReady()
+	ts.MarkTopicReady()
+	ts.MarkSubscriberResolvedSucceeded()
+	ts.MarkDependencySucceeded()
+	ts.MarkDataPlaneReady()
+
+	ts.MarkOrderingDisabled("SubscriptionNotOrdered", "induced failure")
+	if !ts.IsReady() {
+		t.Error("trigger is not ready when ordering is disabled")
+	}
+	got := ts.GetCondition(TriggerConditionOrdering)
+	if got == nil || got.Status != corev1.ConditionFalse || got.Severity != apis.ConditionSeverityInfo {
+		t.Errorf("unexpected ordering condition: %+v", got)
+	}
+
+	ts.MarkOrderingEnabled()
+	if got := ts.GetCondition(TriggerConditionOrdering); got == nil || got.Status != corev1.ConditionTrue {
+		t.Errorf("unexpected ordering condition: %+v", got)
+	}
+
+	ts.ClearOrdering()
+	if got := ts.GetCondition(TriggerConditionOrdering); got != nil {
+		t.Errorf("ordering condition was not cleared: %+v", got)
+	}
+}
+
+func TestTriggerPausedCondition(t *testing.T) {
+	ts := &TriggerStatus{}
+	ts.PropagateBrokerStatus(TestHelper.ReadyBrokerStatus())
+	ts.MarkSubscriptionReady()
+	ts.MarkTopicReady()
+	ts.MarkSubscriberResolvedSucceeded()
+	ts.MarkDependencySucceeded()
+	ts.MarkDataPlaneReady()
+
+	ts.MarkPaused()
+	if !ts.IsReady() {
+		t.Error("trigger is not ready when paused")
+	}
+	if got := ts.GetCondition(TriggerConditionPaused); got == nil || got.Status != corev1.ConditionTrue {
+		t.Errorf("unexpected paused condition: %+v", got)
+	}
+
+	ts.ClearPaused()
+	if got := ts.GetCondition(TriggerConditionPaused); got != nil {
+		t.Errorf("paused condition was not cleared: %+v", got)
+	}
+}
+
+func TestTriggerReplayCondition(t *testing.T) {
+	ts := &TriggerStatus{}
+	ts.PropagateBrokerStatus(TestHelper.ReadyBrokerStatus())
+	ts.MarkSubscriptionReady()
+	ts.MarkTopicReady()
+	ts.MarkSubscriberResolvedSucceeded()
+	ts.MarkDependencySucceeded()
+	ts.MarkDataPlaneReady()
+
+	if _, ok := ts.ReplayStartTime(); ok {
+		t.Error("replay in progress without replay condition")
+	}
+
+	ts.MarkReplayUnknown("ReplaySubscriptionCreationFailed", "induced failure")
+	if _, ok := ts.ReplayStartTime(); ok {
+		t.Error("replay in progress before its subscription was created")
+	}
+
+	ts.MarkReplaying("pre-upgrade")
+	if !ts.IsReady() {
+		t.Error("trigger is not ready while replaying")
+	}
+	start, ok := ts.ReplayStartTime()
+	if !ok || start.IsZero() {
+		t.Errorf("unexpected replay start time: %v, %v", start, ok)
+	}
+	// The start of the replay doesn't change while it's in progress.
+	ts.MarkReplaying("pre-upgrade")
+	if got, _ := ts.ReplayStartTime(); !got.Equal(start) {
+		t.Errorf("replay start time got=%v, want=%v", got, start)
+	}
+
+	ts.MarkReplayed("pre-upgrade")
+	if got := ts.GetCondition(TriggerConditionReplay); got == nil || got.Status != corev1.ConditionTrue {
+		t.Errorf("unexpected replay condition: %+v", got)
+	}
+	if _, ok := ts.ReplayStartTime(); ok {
+		t.Error("replay still in progress after it completed")
+	}
+
+	ts.MarkReplayFailed("ReplaySeekFailed", "induced failure")
+	if got := ts.GetCondition(TriggerConditionReplay); got == nil || got.Status != corev1.ConditionFalse || got.Severity != apis.ConditionSeverityInfo {
+		t.Errorf("unexpected replay condition: %+v", got)
+	}
+	if !ts.IsReady() {
+		t.Error("trigger is not ready when the replay failed")
+	}
+
+	ts.ClearReplay()
+	if got := ts.GetCondition(TriggerConditionReplay); got != nil {
+		t.Errorf("replay condition was not cleared: %+v", got)
+	}
+}
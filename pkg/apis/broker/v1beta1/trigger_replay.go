@@ -0,0 +1,67 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// MinMessageRetention and MaxMessageRetention bound the message retention
+	// of Pub/Sub topics.
+	MinMessageRetention = 10 * time.Minute
+	MaxMessageRetention = 31 * 24 * time.Hour
+)
+
+// snapshotID matches the IDs of Pub/Sub snapshots, see
+// https://cloud.google.com/pubsub/docs/admin#resource_names.
+var snapshotID = regexp.MustCompile(`^[a-zA-Z][-a-zA-Z0-9_.~+%]{2,254}$`)
+
+// ReplaySource is where the replay requested with the ReplayAnnotation starts from.
+// +k8s:deepcopy-gen=false
+type ReplaySource struct {
+	// Time is the publish time of the first event replayed, if the replay
+	// starts from a point in time.
+	Time time.Time
+	// Snapshot is the ID of the Pub/Sub snapshot the replay starts from, if
+	// the replay starts from a snapshot.
+	Snapshot string
+}
+
+// ParseReplay parses the value of the ReplayAnnotation.
+func ParseReplay(value string) (*ReplaySource, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &ReplaySource{Time: t}, nil
+	}
+	if !snapshotID.MatchString(value) || strings.HasPrefix(value, "goog") {
+		return nil, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a Pub/Sub snapshot ID", value)
+	}
+	return &ReplaySource{Snapshot: value}, nil
+}
+
+// ParseMessageRetention parses the value of the MessageRetentionAnnotation.
+func ParseMessageRetention(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || d < MinMessageRetention || d > MaxMessageRetention {
+		return 0, fmt.Errorf("invalid message retention %q: must be a duration from %v to %v", value, MinMessageRetention, MaxMessageRetention)
+	}
+	return d, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseReplay(t *testing.T) {
+	cases := []struct {
+		value   string
+		want    *ReplaySource
+		wantErr bool
+	}{{
+		value: "2020-10-01T00:00:00Z",
+		want:  &ReplaySource{Time: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}, {
+		value: " 2020-10-01T02:00:00+02:00 ",
+		want:  &ReplaySource{Time: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}, {
+		value: "pre-upgrade",
+		want:  &ReplaySource{Snapshot: "pre-upgrade"},
+	}, {
+		value:   "",
+		wantErr: true,
+	}, {
+		value:   "2020-10-01",
+		wantErr: true,
+	}, {
+		value:   "google-snapshot",
+		wantErr: true,
+	}, {
+		value:   "projects/p/snapshots/s",
+		wantErr: true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseReplay(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseReplay error got=%v, wantErr=%v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })); diff != "" {
+				t.Errorf("ParseReplay (-want,+got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestParseMessageRetention(t *testing.T) {
+	cases := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{{
+		value: "168h",
+		want:  7 * 24 * time.Hour,
+	}, {
+		value: " 10m ",
+		want:  10 * time.Minute,
+	}, {
+		value:   "5m",
+		wantErr: true,
+	}, {
+		value:   "745h",
+		wantErr: true,
+	}, {
+		value:   "7d",
+		wantErr: true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseMessageRetention(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseMessageRetention error got=%v, wantErr=%v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ParseMessageRetention got=%v, want=%v", got, tc.want)
+			}
+		})
+	}
+}
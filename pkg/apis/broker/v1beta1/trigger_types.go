@@ -17,6 +17,9 @@ limitations under the License.
 package v1beta1
 
 import (
+	"context"
+	"strconv"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -24,6 +27,9 @@ import (
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/kmeta"
+
+	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
+	"github.com/google/knative-gcp/pkg/broker/config"
 )
 
 const (
@@ -33,6 +39,40 @@ const (
 	// InjectionAnnotation is the annotation key used to enable knative eventing injection for a namespace and automatically create a default broker.
 	// This will be used when the client creates a trigger paired with default broker and the default broker doesn't exist in the namespace
 	InjectionAnnotation = "knative-eventing-injection"
+	// FiltersAnnotation is the annotation key used to set structured filters on the Trigger in addition to
+	// the attribute filter. The value is a JSON array of CloudEvents subscriptions API filter dialects, e.g.
+	// [{"prefix": {"type": "com.example."}}, {"sql": "subject LIKE '%.png'"}].
+	FiltersAnnotation = "events.cloud.google.com/filters"
+	// TransformsAnnotation is the annotation key used to transform the attributes of the events delivered to
+	// the Trigger after they passed the filters. The value is a JSON array of operations applied in order, e.g.
+	// [{"set": {"type": "${type}.v2"}}, {"remove": ["subject"]}, {"rename": {"foo": "bar"}}].
+	TransformsAnnotation = "events.cloud.google.com/transforms"
+	// DeliveryAudienceAnnotation is the annotation key used to authenticate the deliveries to the subscriber
+	// with Google-signed OIDC ID tokens. The value is the audience of the tokens, e.g. the URL of a private
+	// Cloud Run service or the client ID of an IAP-protected endpoint. Without a service account, the audience
+	// must be in the deliveryAudiences of the namespace in the config-gcp-auth ConfigMap.
+	DeliveryAudienceAnnotation = "events.cloud.google.com/delivery-audience"
+	// DeliveryServiceAccountAnnotation is the annotation key used to set the Google service account the ID
+	// tokens are minted for. The data plane must be allowed to create ID tokens for it, and the service account
+	// must be in the deliveryServiceAccounts of the namespace in the config-gcp-auth ConfigMap. If not set, the
+	// tokens are minted for the service account of the data plane.
+	DeliveryServiceAccountAnnotation = "events.cloud.google.com/delivery-service-account"
+	// DeliveryAnnotation is the annotation key used to set the delivery options of the Trigger. The value is
+	// a JSON object with any of the retry, backoffPolicy and backoffDelay fields of the delivery spec, e.g.
+	// {"retry": 5, "backoffPolicy": "exponential", "backoffDelay": "PT0.5S"}. The fields set override those
+	// of the delivery spec of the Broker, the events still go to the dead letter sink of the Broker.
+	DeliveryAnnotation = "events.cloud.google.com/delivery"
+	// PausedAnnotation is the annotation key used to pause the delivery of events to the Trigger. While the
+	// value is "true", the events the Trigger receives are kept in its retry queue, and they're delivered
+	// once the annotation is removed or set to "false".
+	PausedAnnotation = "events.cloud.google.com/paused"
+	// ReplayAnnotation is the annotation key used to deliver again to the Trigger the events its Broker
+	// received in the past. The value is either an RFC 3339 timestamp to replay the events published since
+	// then, e.g. 2020-10-01T00:00:00Z, or the ID of a Pub/Sub snapshot of the decouple subscription of the
+	// Broker to replay from. Replaying from a timestamp requires the Pub/Sub topic of the Broker to retain
+	// its messages since then, as declared with the MessageRetentionAnnotation of the Broker. A replay runs
+	// once, remove the annotation before setting it again.
+	ReplayAnnotation = "events.cloud.google.com/replay"
 )
 
 // +genclient
@@ -114,6 +154,36 @@ func (t *Trigger) GetUntypedSpec() interface{} {
 	return t.Spec
 }
 
+// IsPaused returns true if the delivery of events to the Trigger is paused with the PausedAnnotation.
+func (t *Trigger) IsPaused() bool {
+	paused, _ := strconv.ParseBool(t.Annotations[PausedAnnotation])
+	return paused
+}
+
+// DeliveryAuth returns the authentication of the deliveries to the Trigger set with the
+// DeliveryAudienceAnnotation and the DeliveryServiceAccountAnnotation, or nil if neither is set. It
+// returns an error if the annotations are invalid or not allowed in the namespace of the Trigger by
+// the GCP auth defaults of the context.
+func (t *Trigger) DeliveryAuth(ctx context.Context) (*config.DeliveryAuth, error) {
+	audience, hasAudience := t.Annotations[DeliveryAudienceAnnotation]
+	serviceAccount, hasServiceAccount := t.Annotations[DeliveryServiceAccountAnnotation]
+	if !hasAudience && !hasServiceAccount {
+		return nil, nil
+	}
+	auth, err := config.ParseDeliveryAuth(audience, serviceAccount)
+	if err != nil {
+		return nil, err
+	}
+	var defaults *gcpauth.Defaults
+	if cfg := gcpauth.FromContext(ctx); cfg != nil {
+		defaults = cfg.GCPAuthDefaults
+	}
+	if err := defaults.DeliveryAuthAllowed(t.Namespace, auth.Audience, auth.ServiceAccount); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
 // GetConditionSet retrieves the condition set for this resource. Implements the KRShaped interface.
 func (*Trigger) GetConditionSet() apis.ConditionSet {
 	return triggerCondSet
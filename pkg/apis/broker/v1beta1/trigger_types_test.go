@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	eventingv1beta1 "knative.dev/eventing/pkg/apis/eventing/v1beta1"
 	"knative.dev/pkg/apis"
@@ -64,3 +65,20 @@ func TestTrigger_GetStatus(t *testing.T) {
 		t.Errorf("GetStatus=%v, want=%v", got, want)
 	}
 }
+
+func TestTrigger_IsPaused(t *testing.T) {
+	for _, tc := range []struct {
+		annotations map[string]string
+		want        bool
+	}{
+		{annotations: nil, want: false},
+		{annotations: map[string]string{PausedAnnotation: "true"}, want: true},
+		{annotations: map[string]string{PausedAnnotation: "false"}, want: false},
+		{annotations: map[string]string{PausedAnnotation: "invalid"}, want: false},
+	} {
+		tr := &Trigger{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+		if got := tr.IsPaused(); got != tc.want {
+			t.Errorf("IsPaused with annotations %v = %v, want %v", tc.annotations, got, tc.want)
+		}
+	}
+}
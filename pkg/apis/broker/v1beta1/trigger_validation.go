@@ -18,13 +18,138 @@ package v1beta1
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 
 	"knative.dev/pkg/apis"
+
+	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
+	"github.com/google/knative-gcp/pkg/broker/eventtransform"
 )
 
 // Validate the Trigger.
 func (t *Trigger) Validate(ctx context.Context) *apis.FieldError {
-	// The Google Cloud Broker doesn't have any custom validations. The
-	// eventing webhook will run the usual validations.
+	// The eventing webhook will run the usual validations. Only the
+	// annotations specific to the Google Cloud Broker are validated here.
+	errs := t.validateFilters()
+	errs = errs.Also(t.validateTransforms())
+	errs = errs.Also(t.validateDeliveryAuth(ctx))
+	errs = errs.Also(t.validateDelivery())
+	errs = errs.Also(t.validatePaused())
+	errs = errs.Also(t.validateReplay())
+	return errs.ViaField("metadata")
+}
+
+func (t *Trigger) validateFilters() *apis.FieldError {
+	v, ok := t.Annotations[FiltersAnnotation]
+	if !ok {
+		return nil
+	}
+	filters, err := eventfilter.Parse(v)
+	if err == nil {
+		_, err = eventfilter.Compile(filters)
+	}
+	if err != nil {
+		return &apis.FieldError{
+			Message: "invalid filters",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", FiltersAnnotation)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (t *Trigger) validateTransforms() *apis.FieldError {
+	v, ok := t.Annotations[TransformsAnnotation]
+	if !ok {
+		return nil
+	}
+	transforms, err := eventtransform.Parse(v)
+	if err == nil {
+		_, err = eventtransform.Compile(transforms)
+	}
+	if err != nil {
+		return &apis.FieldError{
+			Message: "invalid transforms",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", TransformsAnnotation)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (t *Trigger) validateDeliveryAuth(ctx context.Context) *apis.FieldError {
+	audience, hasAudience := t.Annotations[DeliveryAudienceAnnotation]
+	serviceAccount, hasServiceAccount := t.Annotations[DeliveryServiceAccountAnnotation]
+	if !hasAudience && !hasServiceAccount {
+		return nil
+	}
+	auth, err := config.ParseDeliveryAuth(audience, serviceAccount)
+	if err == nil {
+		// The allowed delivery authentications are only known when the webhook attached the GCP
+		// auth defaults, the reconcilers check them again.
+		if cfg := gcpauth.FromContext(ctx); cfg != nil {
+			err = cfg.GCPAuthDefaults.DeliveryAuthAllowed(t.Namespace, auth.Audience, auth.ServiceAccount)
+		}
+	}
+	if err != nil {
+		key := DeliveryAudienceAnnotation
+		if errors.Is(err, config.ErrInvalidServiceAccount) || (errors.Is(err, gcpauth.ErrDeliveryAuthNotAllowed) && auth.ServiceAccount != "") {
+			key = DeliveryServiceAccountAnnotation
+		}
+		return &apis.FieldError{
+			Message: "invalid delivery authentication",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", key)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (t *Trigger) validateDelivery() *apis.FieldError {
+	v, ok := t.Annotations[DeliveryAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := ParseDelivery(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid delivery",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", DeliveryAnnotation)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (t *Trigger) validatePaused() *apis.FieldError {
+	v, ok := t.Annotations[PausedAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := strconv.ParseBool(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid paused value",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", PausedAnnotation)},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (t *Trigger) validateReplay() *apis.FieldError {
+	v, ok := t.Annotations[ReplayAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := ParseReplay(v); err != nil {
+		return &apis.FieldError{
+			Message: "invalid replay",
+			Paths:   []string{fmt.Sprintf("annotations[%s]", ReplayAnnotation)},
+			Details: err.Error(),
+		}
+	}
 	return nil
 }
@@ -18,12 +18,145 @@ package v1beta1
 
 import (
 	"context"
+	"strings"
 	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
 )
 
 func TestTrigger_Validate(t *testing.T) {
-	trig := Trigger{}
-	if err := trig.Validate(context.TODO()); err != nil {
-		t.Errorf("expected nil, got %v", err)
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		wantErr     bool
+		// wantKey and wantMessage default to the filters error.
+		wantKey     string
+		wantMessage string
+	}{{
+		name: "no annotations",
+	}, {
+		name:        "valid filters",
+		annotations: map[string]string{FiltersAnnotation: `[{"prefix": {"type": "com.example."}}, {"sql": "subject LIKE '%.png'"}]`},
+	}, {
+		name:        "filters not json",
+		annotations: map[string]string{FiltersAnnotation: "type=com.example"},
+		wantErr:     true,
+	}, {
+		name:        "invalid sql",
+		annotations: map[string]string{FiltersAnnotation: `[{"sql": "subject LIKE"}]`},
+		wantErr:     true,
+	}, {
+		name:        "valid transforms",
+		annotations: map[string]string{TransformsAnnotation: `[{"set": {"type": "${type}.v2"}}, {"remove": ["subject"]}]`},
+	}, {
+		name:        "invalid transforms",
+		annotations: map[string]string{TransformsAnnotation: `[{"remove": ["id"]}]`},
+		wantErr:     true,
+		wantKey:     TransformsAnnotation,
+		wantMessage: "invalid transforms",
+	}, {
+		name:        "valid delivery audience",
+		annotations: map[string]string{DeliveryAudienceAnnotation: "https://subscriber-abc-uc.a.run.app"},
+	}, {
+		name: "valid delivery audience and service account",
+		annotations: map[string]string{
+			DeliveryAudienceAnnotation:       "https://subscriber-abc-uc.a.run.app",
+			DeliveryServiceAccountAnnotation: "invoker@my-project.iam.gserviceaccount.com",
+		},
+	}, {
+		name:        "delivery service account without audience",
+		annotations: map[string]string{DeliveryServiceAccountAnnotation: "invoker@my-project.iam.gserviceaccount.com"},
+		wantErr:     true,
+		wantKey:     DeliveryAudienceAnnotation,
+		wantMessage: "invalid delivery authentication",
+	}, {
+		name: "invalid delivery service account",
+		annotations: map[string]string{
+			DeliveryAudienceAnnotation:       "https://subscriber-abc-uc.a.run.app",
+			DeliveryServiceAccountAnnotation: "invoker",
+		},
+		wantErr:     true,
+		wantKey:     DeliveryServiceAccountAnnotation,
+		wantMessage: "invalid delivery authentication",
+	}, {
+		name:        "delivery audience not allowed",
+		annotations: map[string]string{DeliveryAudienceAnnotation: "https://other-abc-uc.a.run.app"},
+		wantErr:     true,
+		wantKey:     DeliveryAudienceAnnotation,
+		wantMessage: "invalid delivery authentication",
+	}, {
+		name: "delivery service account not allowed",
+		annotations: map[string]string{
+			DeliveryAudienceAnnotation:       "https://subscriber-abc-uc.a.run.app",
+			DeliveryServiceAccountAnnotation: "admin@my-project.iam.gserviceaccount.com",
+		},
+		wantErr:     true,
+		wantKey:     DeliveryServiceAccountAnnotation,
+		wantMessage: "invalid delivery authentication",
+	}, {
+		name:        "delivery",
+		annotations: map[string]string{DeliveryAnnotation: `{"retry": 5, "backoffPolicy": "linear", "backoffDelay": "PT0.5S"}`},
+	}, {
+		name:        "invalid delivery",
+		annotations: map[string]string{DeliveryAnnotation: `{"retry": 5, "deadLetterSink": {"uri": "http://dead-letter.example.com"}}`},
+		wantErr:     true,
+		wantKey:     DeliveryAnnotation,
+		wantMessage: "invalid delivery",
+	}, {
+		name:        "paused",
+		annotations: map[string]string{PausedAnnotation: "true"},
+	}, {
+		name:        "invalid paused",
+		annotations: map[string]string{PausedAnnotation: "yes"},
+		wantErr:     true,
+		wantKey:     PausedAnnotation,
+		wantMessage: "invalid paused value",
+	}, {
+		name:        "replay from time",
+		annotations: map[string]string{ReplayAnnotation: "2020-10-01T00:00:00Z"},
+	}, {
+		name:        "replay from snapshot",
+		annotations: map[string]string{ReplayAnnotation: "pre-upgrade"},
+	}, {
+		name:        "invalid replay",
+		annotations: map[string]string{ReplayAnnotation: "yesterday 10:00"},
+		wantErr:     true,
+		wantKey:     ReplayAnnotation,
+		wantMessage: "invalid replay",
+	}}
+	defaults, err := gcpauth.NewDefaultsConfigFromMap(map[string]string{
+		"default-auth-config": `
+  clusterDefaults:
+    deliveryServiceAccounts:
+    - invoker@my-project.iam.gserviceaccount.com
+    deliveryAudiences:
+    - https://subscriber-abc-uc.a.run.app
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := gcpauth.ToContext(context.Background(), &gcpauth.Config{GCPAuthDefaults: defaults})
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			trig := Trigger{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			err := trig.Validate(ctx)
+			if !tc.wantErr {
+				if err != nil {
+					t.Errorf("expected nil, got %v", err)
+				}
+				return
+			}
+			wantKey, wantMessage := FiltersAnnotation, "invalid filters"
+			if tc.wantKey != "" {
+				wantKey, wantMessage = tc.wantKey, tc.wantMessage
+			}
+			wantPath := "metadata.annotations[" + wantKey + "]"
+			if err == nil || !strings.HasPrefix(err.Error(), wantMessage+": "+wantPath+"\n") {
+				t.Errorf("got=%v, want %s error at %s", err, wantMessage, wantPath)
+			}
+		})
 	}
 }
@@ -17,9 +17,16 @@ limitations under the License.
 package gcpauth
 
 import (
+	"errors"
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 )
 
+// ErrDeliveryAuthNotAllowed is returned by DeliveryAuthAllowed if the Triggers of the namespace
+// may not authenticate their deliveries with the requested ID tokens.
+var ErrDeliveryAuthNotAllowed = errors.New("delivery authentication not allowed")
+
 // Defaults includes the default values to be populated by the Webhook.
 type Defaults struct {
 	// NamespaceDefaults are the GCP auth defaults to use in specific namespaces. The namespace is
@@ -44,6 +51,15 @@ type ScopedDefaults struct {
 	// attempt to setup Workload Identity between the two accounts. If it is unable to do so, then
 	// the CO will not become ready.
 	WorkloadIdentityMapping map[string]string `json:"workloadIdentityMapping,omitEmpty"`
+
+	// DeliveryServiceAccounts are the Google IAM Service Accounts that the broker data plane may
+	// impersonate to authenticate its deliveries to the Triggers of the namespace. The data plane
+	// must hold the Service Account Token Creator role on them.
+	DeliveryServiceAccounts []string `json:"deliveryServiceAccounts,omitempty"`
+
+	// DeliveryAudiences are the audiences of the ID tokens that the broker data plane may mint with
+	// its own credentials to authenticate its deliveries to the Triggers of the namespace.
+	DeliveryAudiences []string `json:"deliveryAudiences,omitempty"`
 }
 
 // scoped gets the scoped GCP Auth defaults for the given namespace.
@@ -69,3 +85,33 @@ func (d *Defaults) WorkloadIdentityGSA(ns, ksa string) string {
 	sd := d.scoped(ns)
 	return sd.WorkloadIdentityMapping[ksa]
 }
+
+// DeliveryAuthAllowed returns an error unless the Triggers of the namespace may have their
+// deliveries authenticated with ID tokens for the audience. The tokens are minted for the service
+// account if it's not empty, and for the data plane's own credentials otherwise. Nothing is
+// allowed by default, as the Triggers would otherwise borrow the identity of the data plane.
+func (d *Defaults) DeliveryAuthAllowed(ns, audience, serviceAccount string) error {
+	if d == nil {
+		return fmt.Errorf("%w: no GCP auth defaults", ErrDeliveryAuthNotAllowed)
+	}
+	sd := d.scoped(ns)
+	if serviceAccount != "" {
+		if !contains(sd.DeliveryServiceAccounts, serviceAccount) {
+			return fmt.Errorf("%w: service account %q is not in the delivery service accounts of namespace %q", ErrDeliveryAuthNotAllowed, serviceAccount, ns)
+		}
+		return nil
+	}
+	if !contains(sd.DeliveryAudiences, audience) {
+		return fmt.Errorf("%w: audience %q is not in the delivery audiences of namespace %q", ErrDeliveryAuthNotAllowed, audience, ns)
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
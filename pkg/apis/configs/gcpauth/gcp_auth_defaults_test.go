@@ -17,6 +17,7 @@ limitations under the License.
 package gcpauth
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -112,6 +113,81 @@ func TestNewDefaultsConfigFromConfigMap(t *testing.T) {
 	}
 }
 
+func TestDeliveryAuthAllowed(t *testing.T) {
+	_, example := ConfigMapsFromTestFile(t, configName, defaulterKey)
+	defaults, err := NewDefaultsConfigFromConfigMap(example)
+	if err != nil {
+		t.Fatalf("NewDefaultsConfigFromConfigMap(example) = %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		defaults       *Defaults
+		ns             string
+		audience       string
+		serviceAccount string
+		allowed        bool
+	}{{
+		name:     "cluster audience",
+		defaults: defaults,
+		ns:       clusterDefaultedNS,
+		audience: "https://cluster-subscriber.example.com",
+		allowed:  true,
+	}, {
+		name:           "cluster service account",
+		defaults:       defaults,
+		ns:             clusterDefaultedNS,
+		audience:       "https://any.example.com",
+		serviceAccount: "cluster-delivery-gsa@PROJECT.iam.gserviceaccount.com",
+		allowed:        true,
+	}, {
+		name:     "namespace audience",
+		defaults: defaults,
+		ns:       customizedNS,
+		audience: "https://ns-subscriber.example.com",
+		allowed:  true,
+	}, {
+		name:           "namespace service account",
+		defaults:       defaults,
+		ns:             customizedNS,
+		audience:       "https://any.example.com",
+		serviceAccount: "ns-delivery-gsa@PROJECT.iam.gserviceaccount.com",
+		allowed:        true,
+	}, {
+		name:     "cluster audience in customized namespace",
+		defaults: defaults,
+		ns:       customizedNS,
+		audience: "https://cluster-subscriber.example.com",
+	}, {
+		name:           "audience allowed without service account",
+		defaults:       defaults,
+		ns:             clusterDefaultedNS,
+		audience:       "https://cluster-subscriber.example.com",
+		serviceAccount: "other-gsa@PROJECT.iam.gserviceaccount.com",
+	}, {
+		name:     "empty namespace",
+		defaults: defaults,
+		ns:       emptyNS,
+		audience: "https://cluster-subscriber.example.com",
+	}, {
+		name:     "no defaults",
+		ns:       clusterDefaultedNS,
+		audience: "https://cluster-subscriber.example.com",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.defaults.DeliveryAuthAllowed(tc.ns, tc.audience, tc.serviceAccount)
+			if tc.allowed != (err == nil) {
+				t.Errorf("DeliveryAuthAllowed() = %v, want allowed %v", err, tc.allowed)
+			}
+			if err != nil && !errors.Is(err, ErrDeliveryAuthNotAllowed) {
+				t.Errorf("DeliveryAuthAllowed() = %v, want ErrDeliveryAuthNotAllowed", err)
+			}
+		})
+	}
+}
+
 func TestNewDefaultsConfigFromConfigMapWithError(t *testing.T) {
 	testCases := map[string]struct {
 		name   string
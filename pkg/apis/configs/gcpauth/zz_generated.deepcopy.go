@@ -63,6 +63,16 @@ func (in *ScopedDefaults) DeepCopyInto(out *ScopedDefaults) {
 			(*out)[key] = val
 		}
 	}
+	if in.DeliveryServiceAccounts != nil {
+		in, out := &in.DeliveryServiceAccounts, &out.DeliveryServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeliveryAudiences != nil {
+		in, out := &in.DeliveryAudiences, &out.DeliveryAudiences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -47,6 +47,17 @@ const (
 	// Pub/Sub subscription that Keda uses in order to decide when and by how much to scale out.
 	KedaAutoscalingSubscriptionSizeAnnotation = KEDA + "/subscriptionSize"
 
+	// DeliveryAudienceAnnotation is the annotation to authenticate the deliveries of the receive adapter
+	// to the sink with Google-signed OIDC ID tokens. The value is the audience of the tokens.
+	DeliveryAudienceAnnotation = "events.cloud.google.com/delivery-audience"
+	// DeliveryServiceAccountAnnotation is the annotation to specify the Google service account the ID tokens
+	// are minted for. If not set, the tokens are minted for the service account of the receive adapter.
+	DeliveryServiceAccountAnnotation = "events.cloud.google.com/delivery-service-account"
+	// DropNonRetryableAnnotation is the annotation to ack and drop the events the sink rejects with a
+	// non-retryable status code. If not "true", they are nacked and left to the retry and dead letter
+	// policies of the subscription.
+	DropNonRetryableAnnotation = "events.cloud.google.com/drop-non-retryable"
+
 	// defaultMinScale is the default minimum set of Pods the scaler should
 	// downscale the resource to.
 	defaultMinScale = "0"
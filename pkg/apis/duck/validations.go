@@ -19,6 +19,7 @@ package duck
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"regexp"
@@ -30,6 +31,8 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/apis"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
 )
 
 var (
@@ -90,6 +93,37 @@ func validateAnnotationNotExists(annotations map[string]string, annotation strin
 	return errs
 }
 
+// ValidateDeliveryAuthAnnotations validates the annotations authenticating the deliveries to the sink.
+func ValidateDeliveryAuthAnnotations(annotations map[string]string, errs *apis.FieldError) *apis.FieldError {
+	audience, hasAudience := annotations[DeliveryAudienceAnnotation]
+	serviceAccount, hasServiceAccount := annotations[DeliveryServiceAccountAnnotation]
+	if !hasAudience && !hasServiceAccount {
+		return errs
+	}
+	if _, err := config.ParseDeliveryAuth(audience, serviceAccount); err != nil {
+		annotation := DeliveryAudienceAnnotation
+		if errors.Is(err, config.ErrInvalidServiceAccount) {
+			annotation = DeliveryServiceAccountAnnotation
+		}
+		errs = errs.Also(&apis.FieldError{
+			Message: "invalid delivery authentication",
+			Paths:   []string{fmt.Sprintf("metadata.annotations[%s]", annotation)},
+			Details: err.Error(),
+		})
+	}
+	return errs
+}
+
+// ValidateDropNonRetryableAnnotation validates the annotation dropping the events the sink rejects.
+func ValidateDropNonRetryableAnnotation(annotations map[string]string, errs *apis.FieldError) *apis.FieldError {
+	if v, ok := annotations[DropNonRetryableAnnotation]; ok {
+		if _, err := strconv.ParseBool(v); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(v, fmt.Sprintf("metadata.annotations[%s]", DropNonRetryableAnnotation)))
+		}
+	}
+	return errs
+}
+
 // CheckImmutableClusterNameAnnotation checks non-empty cluster-name annotation is immutable.
 func CheckImmutableClusterNameAnnotation(current *metav1.ObjectMeta, original *metav1.ObjectMeta, errs *apis.FieldError) *apis.FieldError {
 	if _, ok := original.Annotations[ClusterNameAnnotation]; ok {
@@ -174,6 +174,79 @@ func TestCheckImmutableClusterNameAnnotation(t *testing.T) {
 	}
 }
 
+func TestValidateDeliveryAuthAnnotations(t *testing.T) {
+	testCases := map[string]struct {
+		annotations map[string]string
+		error       bool
+	}{
+		"no annotations": {},
+		"audience": {
+			annotations: map[string]string{
+				DeliveryAudienceAnnotation: "https://sink-abc-uc.a.run.app",
+			},
+		},
+		"audience and service account": {
+			annotations: map[string]string{
+				DeliveryAudienceAnnotation:       "https://sink-abc-uc.a.run.app",
+				DeliveryServiceAccountAnnotation: "invoker@my-project.iam.gserviceaccount.com",
+			},
+		},
+		"service account without audience": {
+			annotations: map[string]string{
+				DeliveryServiceAccountAnnotation: "invoker@my-project.iam.gserviceaccount.com",
+			},
+			error: true,
+		},
+		"invalid service account": {
+			annotations: map[string]string{
+				DeliveryAudienceAnnotation:       "https://sink-abc-uc.a.run.app",
+				DeliveryServiceAccountAnnotation: "invoker",
+			},
+			error: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			err := ValidateDeliveryAuthAnnotations(tc.annotations, nil)
+			if tc.error != (err != nil) {
+				t.Fatalf("Unexpected validation failure. Got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateDropNonRetryableAnnotation(t *testing.T) {
+	testCases := map[string]struct {
+		annotations map[string]string
+		error       bool
+	}{
+		"no annotations": {},
+		"true": {
+			annotations: map[string]string{
+				DropNonRetryableAnnotation: "true",
+			},
+		},
+		"false": {
+			annotations: map[string]string{
+				DropNonRetryableAnnotation: "false",
+			},
+		},
+		"invalid": {
+			annotations: map[string]string{
+				DropNonRetryableAnnotation: "always",
+			},
+			error: true,
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			err := ValidateDropNonRetryableAnnotation(tc.annotations, nil)
+			if tc.error != (err != nil) {
+				t.Fatalf("Unexpected validation failure. Got %v", err)
+			}
+		})
+	}
+}
 
 func TestValidateCredential(t *testing.T) {
 	testCases := []struct {
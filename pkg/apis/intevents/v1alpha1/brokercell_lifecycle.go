@@ -50,6 +50,12 @@ const (
 	// BrokerCellConditionTargetsConfig reports the readiness of the
 	// BrokerCell's targets configmap.
 	BrokerCellConditionTargetsConfig apis.ConditionType = "TargetsConfigReady"
+
+	// BrokerCellConditionHandlers reports whether the fanout and retry
+	// handlers of the BrokerCell's data plane pods are running. It's only set
+	// when the targets config is streamed to the pods and doesn't affect
+	// readiness.
+	BrokerCellConditionHandlers apis.ConditionType = "HandlersHealthy"
 )
 
 // GetCondition returns the condition currently associated with the given type, or nil.
@@ -155,6 +161,20 @@ func (bs *BrokerCellStatus) MarkTargetsConfigFailed(reason, format string, args
 	brokerCellCondSet.Manage(bs).MarkFalse(BrokerCellConditionTargetsConfig, reason, format, args...)
 }
 
+func (bs *BrokerCellStatus) MarkHandlersHealthy() {
+	brokerCellCondSet.Manage(bs).MarkTrue(BrokerCellConditionHandlers)
+}
+
+func (bs *BrokerCellStatus) MarkHandlersDegraded(reason, format string, args ...interface{}) {
+	brokerCellCondSet.Manage(bs).MarkFalse(BrokerCellConditionHandlers, reason, format, args...)
+}
+
+// ClearHandlersHealth removes the HandlersHealthy condition, once the health of
+// the handlers isn't reported anymore.
+func (bs *BrokerCellStatus) ClearHandlersHealth() {
+	brokerCellCondSet.Manage(bs).ClearCondition(BrokerCellConditionHandlers)
+}
+
 func (bs *BrokerCellStatus) SetIngressTemplate(address string) {
 	bs.IngressTemplate = address
 }
@@ -328,3 +328,29 @@ func TestBrokerCellConditionStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestBrokerCellHandlersCondition(t *testing.T) {
+	bs := &BrokerCellStatus{}
+	bs.PropagateFanoutAvailability(TestHelper.AvailableDeployment())
+	bs.PropagateIngressAvailability(TestHelper.AvailableEndpoints())
+	bs.PropagateRetryAvailability(TestHelper.AvailableDeployment())
+	bs.MarkTargetsConfigReady()
+
+	bs.MarkHandlersHealthy()
+	if got := bs.GetCondition(BrokerCellConditionHandlers); got == nil || got.Status != corev1.ConditionTrue {
+		t.Errorf("unexpected handlers condition: %+v", got)
+	}
+
+	bs.MarkHandlersDegraded("HandlersFailed", "induced failure")
+	if got := bs.GetCondition(BrokerCellConditionHandlers); got == nil || got.Status != corev1.ConditionFalse || got.Severity != apis.ConditionSeverityInfo {
+		t.Errorf("unexpected handlers condition: %+v", got)
+	}
+	if !bs.IsReady() {
+		t.Error("brokercell is not ready when handlers failed")
+	}
+
+	bs.ClearHandlersHealth()
+	if got := bs.GetCondition(BrokerCellConditionHandlers); got != nil {
+		t.Errorf("handlers condition was not cleared: %+v", got)
+	}
+}
@@ -40,6 +40,8 @@ const (
 
 func (current *PullSubscription) Validate(ctx context.Context) *apis.FieldError {
 	errs := current.Spec.Validate(ctx).ViaField("spec")
+	errs = duck.ValidateDeliveryAuthAnnotations(current.Annotations, errs)
+	errs = duck.ValidateDropNonRetryableAnnotation(current.Annotations, errs)
 	return duck.ValidateAutoscalingAnnotations(ctx, current.Annotations, errs)
 }
 
@@ -0,0 +1,68 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package auth authenticates the senders of events to the broker ingress.
+package auth
+
+import (
+	"context"
+	"errors"
+	nethttp "net/http"
+	"strings"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+var (
+	// ErrNoToken is returned when the request doesn't carry a bearer token.
+	ErrNoToken = errors.New("no bearer token")
+	// ErrInvalidToken is returned when the token can't be validated.
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Authenticator validates bearer tokens.
+type Authenticator interface {
+	// Authenticate validates the token and returns the identity of its holder.
+	Authenticate(ctx context.Context, token string) (string, error)
+}
+
+// BearerToken returns the bearer token in the Authorization header of the request.
+func BearerToken(request *nethttp.Request) (string, error) {
+	h := request.Header.Get("Authorization")
+	const prefix = "bearer "
+	if len(h) <= len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return "", ErrNoToken
+	}
+	return strings.TrimSpace(h[len(prefix):]), nil
+}
+
+// Allowed returns true if the policy allows the identity. A nil policy allows nobody, so that
+// enabling authentication doesn't let in every identity the authenticator accepts.
+func Allowed(policy *config.AuthPolicy, identity string) bool {
+	if policy == nil {
+		return false
+	}
+	for _, allowed := range policy.AllowedIdentities {
+		if strings.HasSuffix(allowed, "*") {
+			if strings.HasPrefix(identity, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+		} else if allowed == identity {
+			return true
+		}
+	}
+	return false
+}
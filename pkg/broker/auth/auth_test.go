@@ -0,0 +1,83 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"errors"
+	nethttp "net/http"
+	"testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+func TestBearerToken(t *testing.T) {
+	cases := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr error
+	}{
+		{name: "bearer", header: "Bearer abc", want: "abc"},
+		{name: "lowercase scheme", header: "bearer abc", want: "abc"},
+		{name: "missing", wantErr: ErrNoToken},
+		{name: "basic", header: "Basic abc", wantErr: ErrNoToken},
+		{name: "empty token", header: "Bearer ", wantErr: ErrNoToken},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := nethttp.NewRequest(nethttp.MethodPost, "http://example.com", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			got, err := BearerToken(req)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("BearerToken() error got=%v, want=%v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("BearerToken() got=%q, want=%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	policy := &config.AuthPolicy{
+		AllowedIdentities: []string{
+			"producer@project.iam.gserviceaccount.com",
+			"system:serviceaccount:ns1:*",
+		},
+	}
+	cases := []struct {
+		name     string
+		policy   *config.AuthPolicy
+		identity string
+		want     bool
+	}{
+		{name: "nil policy", identity: "anyone", want: false},
+		{name: "empty policy", policy: &config.AuthPolicy{}, identity: "anyone", want: false},
+		{name: "exact match", policy: policy, identity: "producer@project.iam.gserviceaccount.com", want: true},
+		{name: "prefix match", policy: policy, identity: "system:serviceaccount:ns1:producer", want: true},
+		{name: "no match", policy: policy, identity: "system:serviceaccount:ns2:producer", want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Allowed(tc.policy, tc.identity); got != tc.want {
+				t.Errorf("Allowed() got=%v, want=%v", got, tc.want)
+			}
+		})
+	}
+}
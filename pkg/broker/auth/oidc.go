@@ -0,0 +1,285 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	nethttp "net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2/jws"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// googleIssuer is the issuer of Google ID tokens.
+	googleIssuer = "https://accounts.google.com"
+
+	// keysMinRefreshInterval is the minimum interval between two fetches of the signing keys.
+	keysMinRefreshInterval = time.Minute
+	// keysFetchTimeout is the timeout of fetching the signing keys.
+	keysFetchTimeout = 10 * time.Second
+	// keysMaxAge is how long the signing keys are used before they are fetched again.
+	keysMaxAge = time.Hour
+	// clockSkew is the tolerated difference between the clocks of the issuer and the ingress.
+	clockSkew = time.Minute
+)
+
+// oidcAuthenticator validates OIDC ID tokens signed with RS256 by the keys of an issuer.
+type oidcAuthenticator struct {
+	issuer   string
+	audience string
+	client   *nethttp.Client
+
+	// fetches shares a fetch of the signing keys between the concurrent requests.
+	fetches singleflight.Group
+
+	// mu guards the fields below. It's not held while fetching the keys.
+	mu sync.Mutex
+	// keys maps key IDs to the signing keys of the issuer.
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	// attemptedAt is when the keys were last fetched, successfully or not,
+	// and fetchErr the error of that fetch.
+	attemptedAt time.Time
+	fetchErr    error
+}
+
+var _ Authenticator = (*oidcAuthenticator)(nil)
+
+// NewOIDCAuthenticator creates an Authenticator that validates OIDC ID tokens from the issuer. The
+// tokens must have the audience. The signing keys of the issuer are found through its discovery
+// document.
+func NewOIDCAuthenticator(issuer, audience string, client *nethttp.Client) Authenticator {
+	if client == nil {
+		client = nethttp.DefaultClient
+	}
+	return &oidcAuthenticator{
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		audience: audience,
+		client:   client,
+	}
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type oidcClaims struct {
+	Issuer        string          `json:"iss"`
+	Subject       string          `json:"sub"`
+	Audience      json.RawMessage `json:"aud"`
+	Expiry        int64           `json:"exp"`
+	IssuedAt      int64           `json:"iat"`
+	Email         string          `json:"email"`
+	EmailVerified bool            `json:"email_verified"`
+}
+
+// Authenticate implements Authenticator. The identity is the verified email of the token if set, or
+// else its subject.
+func (a *oidcAuthenticator) Authenticate(ctx context.Context, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: malformed JWT", ErrInvalidToken)
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", fmt.Errorf("%w: malformed header: %v", ErrInvalidToken, err)
+	}
+	if header.Algorithm != "RS256" {
+		return "", fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+	key, err := a.key(header.KeyID)
+	if err != nil {
+		return "", err
+	}
+	if err := jws.Verify(token, key); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims oidcClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("%w: malformed claims: %v", ErrInvalidToken, err)
+	}
+	if !a.validIssuer(claims.Issuer) {
+		return "", fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if !hasAudience(claims.Audience, a.audience) {
+		return "", fmt.Errorf("%w: audience doesn't include %q", ErrInvalidToken, a.audience)
+	}
+	now := time.Now()
+	if now.After(time.Unix(claims.Expiry, 0).Add(clockSkew)) {
+		return "", fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if now.Add(clockSkew).Before(time.Unix(claims.IssuedAt, 0)) {
+		return "", fmt.Errorf("%w: token issued in the future", ErrInvalidToken)
+	}
+	if claims.Email != "" && claims.EmailVerified {
+		return claims.Email, nil
+	}
+	if claims.Subject == "" {
+		return "", fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+	return claims.Subject, nil
+}
+
+func (a *oidcAuthenticator) validIssuer(iss string) bool {
+	// Google ID tokens may omit the scheme of the issuer.
+	return iss == a.issuer || (a.issuer == googleIssuer && iss == strings.TrimPrefix(googleIssuer, "https://"))
+}
+
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+	var multiple []string
+	if err := json.Unmarshal(raw, &multiple); err != nil {
+		return false
+	}
+	for _, aud := range multiple {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the signing key with the ID. The keys are fetched again if they are too old, or if
+// the ID is unknown and the keys were not fetched recently.
+func (a *oidcAuthenticator) key(kid string) (*rsa.PublicKey, error) {
+	a.mu.Lock()
+	key, ok := a.keys[kid]
+	fresh := time.Since(a.fetchedAt) < keysMaxAge
+	a.mu.Unlock()
+	if ok && fresh {
+		return key, nil
+	}
+	if _, err, _ := a.fetches.Do("", func() (interface{}, error) { return nil, a.refreshKeys() }); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	key, ok = a.keys[kid]
+	a.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+}
+
+// refreshKeys fetches the signing keys, unless they were fetched less than keysMinRefreshInterval
+// ago. In that case it returns the error of the last fetch, so that tokens with unknown key IDs
+// don't make the ingress hammer the issuer.
+func (a *oidcAuthenticator) refreshKeys() error {
+	a.mu.Lock()
+	if !a.attemptedAt.IsZero() && time.Since(a.attemptedAt) < keysMinRefreshInterval {
+		defer a.mu.Unlock()
+		return a.fetchErr
+	}
+	a.attemptedAt = time.Now()
+	a.mu.Unlock()
+
+	// The fetch is shared by several requests, so it doesn't use the context of any of them.
+	ctx, cancel := context.WithTimeout(context.Background(), keysFetchTimeout)
+	defer cancel()
+	keys, err := a.fetchKeys(ctx)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		a.fetchErr = fmt.Errorf("failed to fetch signing keys of %q: %w", a.issuer, err)
+		return a.fetchErr
+	}
+	a.keys = keys
+	a.fetchedAt = time.Now()
+	a.fetchErr = nil
+	return nil
+}
+
+func (a *oidcAuthenticator) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := a.getJSON(ctx, a.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("no jwks_uri in the discovery document")
+	}
+	var jwks struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := a.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.KeyType != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %q: %w", k.KeyID, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %q: %w", k.KeyID, err)
+		}
+		keys[k.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+func (a *oidcAuthenticator) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != nethttp.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(seg, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
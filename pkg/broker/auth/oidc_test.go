@@ -0,0 +1,223 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2/jws"
+)
+
+const testAudience = "http://broker-ingress.cloud-run-events.svc.cluster.local/ns/broker"
+
+// testIssuer serves the discovery document and the signing keys of an OIDC issuer.
+type testIssuer struct {
+	*httptest.Server
+	key        *rsa.PrivateKey
+	keyFetches int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := &testIssuer{key: key}
+	mux := nethttp.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   i.URL,
+			"jwks_uri": i.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		atomic.AddInt32(&i.keyFetches, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	i.Server = httptest.NewServer(mux)
+	t.Cleanup(i.Close)
+	return i
+}
+
+func (i *testIssuer) token(t *testing.T, kid string, claims *jws.ClaimSet) string {
+	token, err := jws.Encode(&jws.Header{Algorithm: "RS256", Typ: "JWT", KeyID: kid}, claims, i.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestOIDCAuthenticator(t *testing.T) {
+	issuer := newTestIssuer(t)
+	now := time.Now()
+	claims := func(mutate func(*jws.ClaimSet)) *jws.ClaimSet {
+		c := &jws.ClaimSet{
+			Iss: issuer.URL,
+			Sub: "1234",
+			Aud: testAudience,
+			Iat: now.Unix(),
+			Exp: now.Add(time.Hour).Unix(),
+		}
+		if mutate != nil {
+			mutate(c)
+		}
+		return c
+	}
+
+	cases := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr bool
+	}{{
+		name:  "subject",
+		token: issuer.token(t, "key1", claims(nil)),
+		want:  "1234",
+	}, {
+		name: "verified email",
+		token: issuer.token(t, "key1", claims(func(c *jws.ClaimSet) {
+			c.PrivateClaims = map[string]interface{}{"email": "producer@example.com", "email_verified": true}
+		})),
+		want: "producer@example.com",
+	}, {
+		name: "unverified email",
+		token: issuer.token(t, "key1", claims(func(c *jws.ClaimSet) {
+			c.PrivateClaims = map[string]interface{}{"email": "producer@example.com"}
+		})),
+		want: "1234",
+	}, {
+		name:    "wrong audience",
+		token:   issuer.token(t, "key1", claims(func(c *jws.ClaimSet) { c.Aud = "other" })),
+		wantErr: true,
+	}, {
+		name:    "wrong issuer",
+		token:   issuer.token(t, "key1", claims(func(c *jws.ClaimSet) { c.Iss = "https://example.com" })),
+		wantErr: true,
+	}, {
+		name:    "expired",
+		token:   issuer.token(t, "key1", claims(func(c *jws.ClaimSet) { c.Iat, c.Exp = now.Add(-2*time.Hour).Unix(), now.Add(-time.Hour).Unix() })),
+		wantErr: true,
+	}, {
+		name:    "unknown key",
+		token:   issuer.token(t, "key2", claims(nil)),
+		wantErr: true,
+	}, {
+		name:    "bad signature",
+		token:   issuer.token(t, "key1", claims(nil)) + "x",
+		wantErr: true,
+	}, {
+		name:    "malformed",
+		token:   "not-a-jwt",
+		wantErr: true,
+	}}
+
+	a := NewOIDCAuthenticator(issuer.URL, testAudience, issuer.Client())
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := a.Authenticate(context.Background(), tc.token)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Authenticate() error got=%v, wantErr=%v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Authenticate() error got=%v, want=%v", err, ErrInvalidToken)
+			}
+			if got != tc.want {
+				t.Errorf("Authenticate() got=%q, want=%q", got, tc.want)
+			}
+		})
+	}
+
+	// The keys are fetched once, and the unknown key doesn't cause another fetch right away.
+	if got := atomic.LoadInt32(&issuer.keyFetches); got != 1 {
+		t.Errorf("Key fetches got=%d, want=1", got)
+	}
+}
+
+func TestOIDCAuthenticatorConcurrentFetch(t *testing.T) {
+	issuer := newTestIssuer(t)
+	release := make(chan struct{})
+	blocked := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		<-release
+		issuer.Config.Handler.ServeHTTP(w, r)
+	})
+	slow := httptest.NewServer(blocked)
+	defer slow.Close()
+	a := NewOIDCAuthenticator(issuer.URL, testAudience, issuer.Client()).(*oidcAuthenticator)
+	a.client = slow.Client()
+	a.issuer = slow.URL
+	now := time.Now()
+	token := issuer.token(t, "key1", &jws.ClaimSet{Iss: slow.URL, Sub: "1234", Aud: testAudience, Iat: now.Unix(), Exp: now.Add(time.Hour).Unix()})
+
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := a.Authenticate(context.Background(), token)
+			errs <- err
+		}()
+	}
+	// The fetch in progress doesn't hold the lock.
+	time.Sleep(50 * time.Millisecond)
+	a.mu.Lock()
+	a.mu.Unlock()
+	close(release)
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Authenticate() got error: %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&issuer.keyFetches); got != 1 {
+		t.Errorf("Key fetches got=%d, want=1", got)
+	}
+}
+
+func TestOIDCAuthenticatorFetchFailure(t *testing.T) {
+	var fetches int32
+	issuer := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.WriteHeader(nethttp.StatusServiceUnavailable)
+	}))
+	defer issuer.Close()
+	a := NewOIDCAuthenticator(issuer.URL, testAudience, issuer.Client())
+	for i := 0; i < 3; i++ {
+		_, err := a.Authenticate(context.Background(), "eyJhbGciOiJSUzI1NiIsImtpZCI6ImtleTEifQ.e30.sig")
+		if err == nil || errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Authenticate() error got=%v, want fetch failure", err)
+		}
+	}
+	// The failed fetch isn't retried right away.
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("Discovery fetches got=%d, want=1", got)
+	}
+}
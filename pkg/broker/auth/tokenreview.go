@@ -0,0 +1,134 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authenticationv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+)
+
+const (
+	// reviewCacheTTL is how long the result of a successful token review is reused.
+	reviewCacheTTL = time.Minute
+	// reviewCacheSize is the maximum number of cached token reviews.
+	reviewCacheSize = 1024
+)
+
+// tokenReviewAuthenticator validates Kubernetes service account tokens with the TokenReview API.
+type tokenReviewAuthenticator struct {
+	client    authenticationv1client.TokenReviewInterface
+	audiences []string
+
+	mu sync.Mutex
+	// cache maps the hashes of tokens to the results of their successful reviews.
+	cache map[[sha256.Size]byte]reviewResult
+}
+
+type reviewResult struct {
+	identity string
+	expires  time.Time
+}
+
+var _ Authenticator = (*tokenReviewAuthenticator)(nil)
+
+// NewTokenReviewAuthenticator creates an Authenticator that validates Kubernetes tokens, e.g.
+// projected service account tokens, with the TokenReview API. If the audience is not empty, the
+// tokens must have it.
+func NewTokenReviewAuthenticator(client authenticationv1client.TokenReviewInterface, audience string) Authenticator {
+	a := &tokenReviewAuthenticator{
+		client: client,
+		cache:  make(map[[sha256.Size]byte]reviewResult),
+	}
+	if audience != "" {
+		a.audiences = []string{audience}
+	}
+	return a
+}
+
+// Authenticate implements Authenticator. The identity is the user name of the token, e.g.
+// "system:serviceaccount:<namespace>:<name>".
+func (a *tokenReviewAuthenticator) Authenticate(ctx context.Context, token string) (string, error) {
+	key := sha256.Sum256([]byte(token))
+	if identity, ok := a.cached(key); ok {
+		return identity, nil
+	}
+
+	review, err := a.client.Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: a.audiences,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to review token: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return "", fmt.Errorf("%w: %s", ErrInvalidToken, review.Status.Error)
+	}
+	if len(a.audiences) > 0 && !containsAny(review.Status.Audiences, a.audiences) {
+		return "", fmt.Errorf("%w: audience doesn't include %q", ErrInvalidToken, a.audiences[0])
+	}
+
+	identity := review.Status.User.Username
+	a.store(key, identity)
+	return identity, nil
+}
+
+func (a *tokenReviewAuthenticator) cached(key [sha256.Size]byte) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r, ok := a.cache[key]
+	if !ok || time.Now().After(r.expires) {
+		return "", false
+	}
+	return r.identity, true
+}
+
+func (a *tokenReviewAuthenticator) store(key [sha256.Size]byte, identity string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if len(a.cache) >= reviewCacheSize {
+		for k, r := range a.cache {
+			if now.After(r.expires) {
+				delete(a.cache, k)
+			}
+		}
+		if len(a.cache) >= reviewCacheSize {
+			// All entries are fresh, start over rather than growing without bound.
+			a.cache = make(map[[sha256.Size]byte]reviewResult)
+		}
+	}
+	a.cache[key] = reviewResult{identity: identity, expires: now.Add(reviewCacheTTL)}
+}
+
+func containsAny(got, want []string) bool {
+	for _, g := range got {
+		for _, w := range want {
+			if g == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+)
+
+func TestTokenReviewAuthenticator(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	reviews := 0
+	client.PrependReactor("create", "tokenreviews", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review := action.(clientgotesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		switch review.Spec.Token {
+		case "valid":
+			review.Status = authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "system:serviceaccount:ns1:producer"},
+				Audiences:     review.Spec.Audiences,
+			}
+		case "other-audience":
+			review.Status = authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "system:serviceaccount:ns1:producer"},
+				Audiences:     []string{"other"},
+			}
+		default:
+			review.Status = authenticationv1.TokenReviewStatus{Error: "invalid token"}
+		}
+		return true, review, nil
+	})
+
+	a := NewTokenReviewAuthenticator(client.AuthenticationV1().TokenReviews(), testAudience)
+	cases := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr bool
+	}{
+		{name: "valid", token: "valid", want: "system:serviceaccount:ns1:producer"},
+		{name: "valid cached", token: "valid", want: "system:serviceaccount:ns1:producer"},
+		{name: "wrong audience", token: "other-audience", wantErr: true},
+		{name: "invalid", token: "invalid", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := a.Authenticate(context.Background(), tc.token)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Authenticate() error got=%v, wantErr=%v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Authenticate() got=%q, want=%q", got, tc.want)
+			}
+		})
+	}
+	if reviews != 3 {
+		t.Errorf("Token reviews got=%d, want=3", reviews)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package claimcheck offloads large event payloads to a blob store. The event
+// keeps a reference to the payload in an extension, and the payload is put
+// back into the event before it's delivered.
+//
+// An offloaded payload may be delivered to any number of triggers, retried and
+// dead lettered independently, so no single delivery knows when it's no longer
+// needed. Payloads are instead deleted once they are older than a TTL, see
+// Expire.
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/logging"
+)
+
+// Extension is the CloudEvents extension holding the reference to the offloaded payload.
+const Extension = "kgcpclaimcheck"
+
+// ErrNotFound is returned when the payload of a reference doesn't exist.
+var ErrNotFound = errors.New("payload not found")
+
+// Store stores event payloads.
+type Store interface {
+	// Put stores the data under the key and returns a reference to it.
+	Put(ctx context.Context, key string, data []byte) (string, error)
+	// Get returns the data of a reference returned by Put.
+	Get(ctx context.Context, ref string) ([]byte, error)
+	// DeleteBefore deletes the data stored before t and returns how many
+	// payloads were deleted.
+	DeleteBefore(ctx context.Context, t time.Time) (int, error)
+}
+
+// expiryInterval is how often Expire deletes the expired payloads.
+const expiryInterval = time.Hour
+
+// NewStore creates a Store from a URL. "gs://<bucket>/<prefix>" stores payloads in a GCS bucket,
+// and "file:///<dir>" stores them in a local directory. An empty URL returns a nil Store.
+func NewStore(ctx context.Context, rawURL string) (Store, error) {
+	if rawURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid store URL %q: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case gcsScheme:
+		return NewGCSStore(ctx, u.Host, u.Path)
+	case fileScheme:
+		return NewFileStore(u.Path)
+	default:
+		return nil, fmt.Errorf("unsupported store URL %q", rawURL)
+	}
+}
+
+// Expire deletes the payloads older than the ttl from the store every hour until the context is
+// done. The ttl must be longer than events stay in the broker's queues, including the retry queue,
+// as the events whose payload was deleted can't be delivered anymore. Events sent to a dead letter
+// sink carry their payload, so they don't depend on the store.
+func Expire(ctx context.Context, store Store, ttl time.Duration) {
+	ticker := time.NewTicker(expiryInterval)
+	defer ticker.Stop()
+	for {
+		expireOnce(ctx, store, ttl)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func expireOnce(ctx context.Context, store Store, ttl time.Duration) {
+	n, err := store.DeleteBefore(ctx, time.Now().Add(-ttl))
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to delete expired claim check payloads", zap.Error(err))
+	}
+	if n > 0 {
+		logging.FromContext(ctx).Info("Deleted expired claim check payloads", zap.Int("count", n))
+	}
+}
+
+// Offload moves the data of the event to the store if it's larger than the threshold, and sets the
+// reference to it in the Extension. It returns true if the data was offloaded.
+func Offload(ctx context.Context, store Store, event *cev2.Event, keyPrefix string, threshold int64) (bool, error) {
+	if int64(len(event.Data())) <= threshold {
+		return false, nil
+	}
+	ref, err := store.Put(ctx, path.Join(keyPrefix, uuid.New().String()), event.Data())
+	if err != nil {
+		return false, fmt.Errorf("failed to offload event payload: %w", err)
+	}
+	event.DataEncoded = nil
+	event.SetExtension(Extension, ref)
+	return true, nil
+}
+
+// Rehydrate puts the offloaded data back into the event and removes the Extension. It's a no-op if
+// the data of the event was not offloaded.
+func Rehydrate(ctx context.Context, store Store, event *cev2.Event) error {
+	ref, ok := Reference(event)
+	if !ok {
+		return nil
+	}
+	if store == nil {
+		return fmt.Errorf("no store to rehydrate the event payload from %q", ref)
+	}
+	data, err := store.Get(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("failed to rehydrate event payload: %w", err)
+	}
+	event.DataEncoded = data
+	Strip(event)
+	return nil
+}
+
+// Reference returns the reference to the offloaded data of the event.
+func Reference(event *cev2.Event) (string, bool) {
+	v, ok := event.Extensions()[Extension]
+	if !ok {
+		return "", false
+	}
+	ref, ok := v.(string)
+	return ref, ok
+}
+
+// Strip removes the Extension from the event. The ingress strips the extension from incoming
+// events so that senders can't make the broker read arbitrary payloads from the store.
+func Strip(event *cev2.Event) {
+	event.SetExtension(Extension, nil)
+}
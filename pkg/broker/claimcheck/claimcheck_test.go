@@ -0,0 +1,125 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	cev2 "github.com/cloudevents/sdk-go/v2"
+)
+
+func newTestEvent(t *testing.T, data string) *cev2.Event {
+	e := cev2.NewEvent()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("type")
+	if err := e.SetData(cev2.TextPlain, data); err != nil {
+		t.Fatal(err)
+	}
+	return &e
+}
+
+func TestOffloadAndRehydrate(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small := newTestEvent(t, "small")
+	if offloaded, err := Offload(ctx, store, small, "ns/broker", 10); err != nil || offloaded {
+		t.Errorf("Offload() got=(%v, %v), want=(false, nil)", offloaded, err)
+	}
+	if _, ok := Reference(small); ok {
+		t.Error("Small event has a reference")
+	}
+
+	data := strings.Repeat("x", 100)
+	large := newTestEvent(t, data)
+	if offloaded, err := Offload(ctx, store, large, "ns/broker", 10); err != nil || !offloaded {
+		t.Fatalf("Offload() got=(%v, %v), want=(true, nil)", offloaded, err)
+	}
+	if len(large.Data()) != 0 {
+		t.Errorf("Offloaded event still has %d bytes of data", len(large.Data()))
+	}
+	ref, ok := Reference(large)
+	if !ok || !strings.HasPrefix(ref, "file://") {
+		t.Errorf("Reference() got=(%q, %v), want a file reference", ref, ok)
+	}
+	if large.DataContentType() != cev2.TextPlain {
+		t.Errorf("DataContentType() got=%q, want=%q", large.DataContentType(), cev2.TextPlain)
+	}
+
+	if err := Rehydrate(ctx, store, large); err != nil {
+		t.Fatalf("Rehydrate() failed: %v", err)
+	}
+	if !bytes.Equal(large.Data(), []byte(data)) {
+		t.Errorf("Rehydrated data got=%q, want=%q", large.Data(), data)
+	}
+	if _, ok := Reference(large); ok {
+		t.Error("Rehydrated event still has a reference")
+	}
+
+	// Rehydrating an event that was not offloaded is a no-op, even without a store.
+	if err := Rehydrate(ctx, nil, small); err != nil {
+		t.Errorf("Rehydrate() got=%v, want=nil", err)
+	}
+}
+
+func TestRehydrateWithoutStore(t *testing.T) {
+	e := newTestEvent(t, "")
+	e.SetExtension(Extension, "file:///tmp/payload")
+	if err := Rehydrate(context.Background(), nil, e); err == nil {
+		t.Error("Rehydrate() got=nil, want error")
+	}
+}
+
+func TestStrip(t *testing.T) {
+	e := newTestEvent(t, "data")
+	e.SetExtension(Extension, "file:///etc/passwd")
+	Strip(e)
+	if _, ok := Reference(e); ok {
+		t.Error("Stripped event still has a reference")
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	cases := []struct {
+		name    string
+		url     string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "empty", url: "", wantNil: true},
+		{name: "file", url: "file://" + t.TempDir()},
+		{name: "unsupported", url: "s3://bucket", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewStore(context.Background(), tc.url)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewStore() error got=%v, wantErr=%v", err, tc.wantErr)
+			}
+			if !tc.wantErr && (got == nil) != tc.wantNil {
+				t.Errorf("NewStore() got=%v, wantNil=%v", got, tc.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const fileScheme = "file"
+
+// fileStore stores payloads as files in a local directory. References are file URLs.
+type fileStore struct {
+	dir string
+}
+
+var _ Store = (*fileStore)(nil)
+
+// NewFileStore creates a Store that keeps payloads in the directory.
+func NewFileStore(dir string) (Store, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(abs, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &fileStore{dir: abs}, nil
+}
+
+// Put implements Store.
+func (s *fileStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(p, data, 0600); err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: fileScheme, Path: filepath.ToSlash(p)}).String(), nil
+}
+
+// Get implements Store.
+func (s *fileStore) Get(ctx context.Context, ref string) ([]byte, error) {
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != fileScheme {
+		return nil, fmt.Errorf("invalid file reference %q", ref)
+	}
+	p := filepath.FromSlash(u.Path)
+	if !s.contains(p) {
+		return nil, fmt.Errorf("reference %q is outside of the store", ref)
+	}
+	data, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, ref)
+	}
+	return data, err
+}
+
+// DeleteBefore implements Store. It deletes the files last modified before t.
+func (s *fileStore) DeleteBefore(ctx context.Context, t time.Time) (int, error) {
+	deleted := 0
+	err := filepath.Walk(s.dir, func(p string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			// Deleted by another sweep.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || !info.ModTime().Before(t) {
+			return nil
+		}
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		deleted++
+		return ctx.Err()
+	})
+	return deleted, err
+}
+
+// path returns the path of the file for the key.
+func (s *fileStore) path(key string) (string, error) {
+	p := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !s.contains(p) {
+		return "", fmt.Errorf("key %q is outside of the store", key)
+	}
+	return p, nil
+}
+
+func (s *fileStore) contains(p string) bool {
+	return strings.HasPrefix(filepath.Clean(p), s.dir+string(filepath.Separator))
+}
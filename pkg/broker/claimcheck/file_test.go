@@ -0,0 +1,102 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := store.Put(ctx, "ns/broker/1", []byte("payload"))
+	if err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	if want := "file://" + dir + "/ns/broker/1"; ref != want {
+		t.Errorf("Put() got=%q, want=%q", ref, want)
+	}
+	data, err := store.Get(ctx, ref)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if string(data) != "payload" {
+		t.Errorf("Get() got=%q, want=%q", data, "payload")
+	}
+
+	if _, err := store.Get(ctx, "file://"+dir+"/ns/broker/2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of missing payload got=%v, want=%v", err, ErrNotFound)
+	}
+	for _, ref := range []string{
+		"file:///etc/passwd",
+		"file://" + dir + "/../secret",
+		"gs://bucket/object",
+	} {
+		if _, err := store.Get(ctx, ref); err == nil {
+			t.Errorf("Get(%q) got=nil, want error", ref)
+		}
+	}
+	if _, err := store.Put(ctx, "../escape", []byte("payload")); err == nil {
+		t.Error("Put() of a key outside of the store got=nil, want error")
+	}
+}
+
+func TestFileStoreDeleteBefore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldRef, err := store.Put(ctx, "ns/broker/old", []byte("payload"))
+	if err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	newRef, err := store.Put(ctx, "ns/broker/new", []byte("payload"))
+	if err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "ns/broker/old"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := store.DeleteBefore(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteBefore() failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("DeleteBefore() got=%d, want=1", n)
+	}
+	if _, err := store.Get(ctx, oldRef); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of expired payload got=%v, want=%v", err, ErrNotFound)
+	}
+	if _, err := store.Get(ctx, newRef); err != nil {
+		t.Errorf("Get() of payload not expired yet failed: %v", err)
+	}
+}
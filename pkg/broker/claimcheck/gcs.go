@@ -0,0 +1,127 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+const gcsScheme = "gs"
+
+// gcsStore stores payloads as objects in a GCS bucket. References are gs:// URLs.
+type gcsStore struct {
+	bucket *storage.BucketHandle
+	name   string
+	prefix string
+}
+
+var _ Store = (*gcsStore)(nil)
+
+// NewGCSStore creates a Store that keeps payloads in the bucket, with object names starting with
+// the prefix.
+func NewGCSStore(ctx context.Context, bucket, prefix string) (Store, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	return &gcsStore{
+		bucket: client.Bucket(bucket),
+		name:   bucket,
+		prefix: strings.Trim(prefix, "/"),
+	}, nil
+}
+
+// Put implements Store.
+func (s *gcsStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	name := path.Join(s.prefix, key)
+	w := s.bucket.Object(name).NewWriter(ctx)
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: gcsScheme, Host: s.name, Path: "/" + name}).String(), nil
+}
+
+// Get implements Store.
+func (s *gcsStore) Get(ctx context.Context, ref string) ([]byte, error) {
+	name, err := s.objectName(ref)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.bucket.Object(name).NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// DeleteBefore implements Store. It deletes the objects under the prefix created before t.
+func (s *gcsStore) DeleteBefore(ctx context.Context, t time.Time) (int, error) {
+	q := &storage.Query{}
+	if s.prefix != "" {
+		q.Prefix = s.prefix + "/"
+	}
+	deleted := 0
+	it := s.bucket.Objects(ctx, q)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return deleted, nil
+		}
+		if err != nil {
+			return deleted, err
+		}
+		if !attrs.Created.Before(t) {
+			continue
+		}
+		// Another ingress replica may have deleted the object already.
+		if err := s.bucket.Object(attrs.Name).Delete(ctx); err != nil && err != storage.ErrObjectNotExist {
+			return deleted, err
+		}
+		deleted++
+	}
+}
+
+// objectName returns the name of the object of a reference. The object must be in the bucket and
+// under the prefix of the store.
+func (s *gcsStore) objectName(ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != gcsScheme {
+		return "", fmt.Errorf("invalid GCS reference %q", ref)
+	}
+	name := strings.TrimPrefix(path.Clean(u.Path), "/")
+	if u.Host != s.name || (s.prefix != "" && !strings.HasPrefix(name, s.prefix+"/")) {
+		return "", fmt.Errorf("reference %q is outside of the store", ref)
+	}
+	return name, nil
+}
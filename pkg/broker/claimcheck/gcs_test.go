@@ -0,0 +1,43 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import "testing"
+
+func TestGCSStoreObjectName(t *testing.T) {
+	s := &gcsStore{name: "bucket", prefix: "claimcheck"}
+	cases := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "gs://bucket/claimcheck/ns/broker/1", want: "claimcheck/ns/broker/1"},
+		{ref: "gs://other/claimcheck/ns/broker/1", wantErr: true},
+		{ref: "gs://bucket/other/1", wantErr: true},
+		{ref: "gs://bucket/claimcheck/../other/1", wantErr: true},
+		{ref: "file:///claimcheck/1", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := s.objectName(tc.ref)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("objectName(%q) error got=%v, wantErr=%v", tc.ref, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("objectName(%q) got=%q, want=%q", tc.ref, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseAuthPolicy parses an auth policy from a comma separated list of allowed identities.
+func ParseAuthPolicy(s string) (*AuthPolicy, error) {
+	var identities []string
+	for _, id := range strings.Split(s, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return nil, fmt.Errorf("invalid auth policy %q: empty identity", s)
+		}
+		identities = append(identities, id)
+	}
+	return &AuthPolicy{AllowedIdentities: identities}, nil
+}
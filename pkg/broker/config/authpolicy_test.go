@@ -0,0 +1,60 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestParseAuthPolicy(t *testing.T) {
+	cases := []struct {
+		name    string
+		s       string
+		want    *AuthPolicy
+		wantErr bool
+	}{{
+		name: "single identity",
+		s:    "producer@project.iam.gserviceaccount.com",
+		want: &AuthPolicy{AllowedIdentities: []string{"producer@project.iam.gserviceaccount.com"}},
+	}, {
+		name: "multiple identities",
+		s:    "system:serviceaccount:ns1:*, producer@project.iam.gserviceaccount.com",
+		want: &AuthPolicy{AllowedIdentities: []string{"system:serviceaccount:ns1:*", "producer@project.iam.gserviceaccount.com"}},
+	}, {
+		name:    "empty",
+		s:       "",
+		wantErr: true,
+	}, {
+		name:    "empty identity",
+		s:       "a,,b",
+		wantErr: true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseAuthPolicy(tc.s)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseAuthPolicy(%q) error got=%v, wantErr=%v", tc.s, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("ParseAuthPolicy(%q) (-want,+got): %v", tc.s, diff)
+			}
+		})
+	}
+}
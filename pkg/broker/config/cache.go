@@ -30,15 +30,23 @@ type CachedTargets struct {
 
 var _ ReadonlyTargets = (*CachedTargets)(nil)
 
+// cachedValue is the value stored in CachedTargets. The target index
+// is built from the TargetsConfig when it's stored so that both are
+// always loaded together.
+type cachedValue struct {
+	config  *TargetsConfig
+	indexes map[string]*targetIndex
+}
+
 // Store atomically stores a TargetsConfig.
 func (ct *CachedTargets) Store(t *TargetsConfig) {
-	ct.Value.Store(t)
+	ct.Value.Store(&cachedValue{config: t, indexes: buildIndexes(t)})
 }
 
 // Load atomically loads a stored TargetsConfig.
 // If there was no TargetsConfig stored, nil will be returned.
 func (ct *CachedTargets) Load() *TargetsConfig {
-	return ct.Value.Load().(*TargetsConfig)
+	return ct.Value.Load().(*cachedValue).config
 }
 
 // RangeAllTargets ranges over all targets.
@@ -92,6 +100,17 @@ func (ct *CachedTargets) GetBrokerByKey(key string) (*Broker, bool) {
 	return b, ok
 }
 
+// RangeCandidateTargets ranges over the targets of a broker that may accept
+// an event with the given attributes. Targets requiring a different exact
+// value for an indexed attribute are skipped. The given targets still need to
+// be filtered.
+// Do not modify the given Target copy.
+func (ct *CachedTargets) RangeCandidateTargets(brokerKey string, attrs map[string]string, f func(*Target) bool) {
+	if idx, ok := ct.Value.Load().(*cachedValue).indexes[brokerKey]; ok {
+		idx.rangeCandidates(attrs, f)
+	}
+}
+
 // RangeBrokers ranges over all brokers.
 // Do not modify the given Broker copy.
 func (ct *CachedTargets) RangeBrokers(f func(*Broker) bool) {
@@ -106,10 +125,11 @@ func (ct *CachedTargets) RangeBrokers(f func(*Broker) bool) {
 	}
 }
 
-// Bytes serializes all the targets.
+// Bytes serializes all the targets. The serialization is deterministic so
+// that unchanged targets can be compared by their bytes.
 func (ct *CachedTargets) Bytes() ([]byte, error) {
 	val := ct.Load()
-	return proto.Marshal(val)
+	return proto.MarshalOptions{Deterministic: true}.Marshal(val)
 }
 
 // String returns the text format of all the targets.
@@ -139,3 +159,9 @@ func (ct *CachedTargets) EqualsString(s string) bool {
 	}
 	return proto.Equal(self, &other)
 }
+
+// Generation returns the generation of the targets config, 0 if it's not
+// known, e.g. the targets config is read from the ConfigMaps.
+func (ct *CachedTargets) Generation() int64 {
+	return ct.Load().GetGeneration()
+}
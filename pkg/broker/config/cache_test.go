@@ -478,3 +478,15 @@ func TestGetBrokerOrTarget(t *testing.T) {
 		}
 	})
 }
+
+func TestCachedTargetsGeneration(t *testing.T) {
+	targets := &CachedTargets{}
+	targets.Store(&TargetsConfig{})
+	if got := targets.Generation(); got != 0 {
+		t.Errorf("CachedTargets.Generation() without generation got=%d, want=0", got)
+	}
+	targets.Store(&TargetsConfig{Generation: 3})
+	if got := targets.Generation(); got != 3 {
+		t.Errorf("CachedTargets.Generation() got=%d, want=3", got)
+	}
+}
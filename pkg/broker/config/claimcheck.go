@@ -0,0 +1,33 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseClaimCheckThreshold parses the size in bytes above which event payloads
+// are offloaded to the claim check store.
+func ParseClaimCheckThreshold(s string) (int64, error) {
+	bytes, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || bytes <= 0 {
+		return 0, fmt.Errorf("invalid claim check threshold %q: must be a positive number of bytes", s)
+	}
+	return bytes, nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "testing"
+
+func TestParseClaimCheckThreshold(t *testing.T) {
+	cases := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{s: "1048576", want: 1048576},
+		{s: " 1024 ", want: 1024},
+		{s: "0", wantErr: true},
+		{s: "-1", wantErr: true},
+		{s: "1MB", wantErr: true},
+		{s: "", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseClaimCheckThreshold(tc.s)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseClaimCheckThreshold(%q) error got=%v, wantErr=%v", tc.s, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("ParseClaimCheckThreshold(%q) got=%d, want=%d", tc.s, got, tc.want)
+		}
+	}
+}
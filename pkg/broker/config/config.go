@@ -19,6 +19,8 @@ package config
 import (
 	"fmt"
 	"strings"
+
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 // ReadonlyTargets provides "read" functions for brokers and targets.
@@ -40,6 +42,11 @@ type ReadonlyTargets interface {
 	// RangeBrokers ranges over all brokers.
 	// Do not modify the given Broker copy.
 	RangeBrokers(func(*Broker) bool)
+	// RangeCandidateTargets ranges over the targets of a broker that may accept
+	// an event with the given attributes. It's a shortcut to skip the targets
+	// that can't match the event without evaluating their filters.
+	// Do not modify the given Target copy.
+	RangeCandidateTargets(brokerKey string, attrs map[string]string, f func(*Target) bool)
 	// Bytes serializes all the targets.
 	Bytes() ([]byte, error)
 	// String returns the text format of all the targets.
@@ -50,6 +57,9 @@ type ReadonlyTargets interface {
 	// EqualsString checks if the current targets config equals the given
 	// targets config in string.
 	EqualsString(string) bool
+	// Generation returns the generation of the targets config, 0 if it's
+	// not known.
+	Generation() int64
 }
 
 // BrokerMutation provides functions to mutate a Broker.
@@ -63,6 +73,22 @@ type BrokerMutation interface {
 	SetDecoupleQueue(q *Queue) BrokerMutation
 	// SetState sets the broker state.
 	SetState(s State) BrokerMutation
+	// SetRateLimit sets the broker ingress rate limit.
+	SetRateLimit(l *RateLimit) BrokerMutation
+	// SetNamespaceRateLimit sets the ingress rate limit of the broker namespace.
+	SetNamespaceRateLimit(l *RateLimit) BrokerMutation
+	// SetAuthPolicy sets the policy of who may send events to the broker.
+	SetAuthPolicy(p *AuthPolicy) BrokerMutation
+	// SetClaimCheckThreshold sets the size above which event payloads are offloaded.
+	SetClaimCheckThreshold(bytes int64) BrokerMutation
+	// SetOrderingKeyExtension sets the extension holding the ordering key of events.
+	SetOrderingKeyExtension(extension string) BrokerMutation
+	// SetDeduplicationWindow sets the window within which duplicate events are dropped.
+	SetDeduplicationWindow(window *durationpb.Duration) BrokerMutation
+	// SetHopLimit sets the number of times an event may be replied to the broker.
+	SetHopLimit(limit int32) BrokerMutation
+	// SetLoopDetection sets whether events looping back to a target are dropped.
+	SetLoopDetection(enabled bool) BrokerMutation
 	// UpsertTargets upserts Targets to the broker.
 	// The targets' namespace and broker will be forced to be
 	// the same as the broker's namespace and name.
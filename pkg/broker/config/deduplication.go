@@ -0,0 +1,39 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// MaxDeduplicationWindow is the longest window within which duplicate events
+// are dropped. Longer windows would need too much memory to remember events.
+const MaxDeduplicationWindow = 24 * time.Hour
+
+// ParseDeduplicationWindow parses the window within which events with the same
+// source and id are processed once, e.g. "10m".
+func ParseDeduplicationWindow(s string) (*durationpb.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil || d <= 0 || d > MaxDeduplicationWindow {
+		return nil, fmt.Errorf("invalid deduplication window %q: must be a positive duration up to %v", s, MaxDeduplicationWindow)
+	}
+	return durationpb.New(d), nil
+}
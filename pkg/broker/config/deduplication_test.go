@@ -0,0 +1,48 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeduplicationWindow(t *testing.T) {
+	cases := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{s: "10m", want: 10 * time.Minute},
+		{s: " 1h30m ", want: 90 * time.Minute},
+		{s: "24h", want: 24 * time.Hour},
+		{s: "", wantErr: true},
+		{s: "10", wantErr: true},
+		{s: "0s", wantErr: true},
+		{s: "-1m", wantErr: true},
+		{s: "25h", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseDeduplicationWindow(tc.s)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseDeduplicationWindow(%q) error got=%v, wantErr=%v", tc.s, err, tc.wantErr)
+		}
+		if got.AsDuration() != tc.want {
+			t.Errorf("ParseDeduplicationWindow(%q) got=%v, want=%v", tc.s, got.AsDuration(), tc.want)
+		}
+	}
+}
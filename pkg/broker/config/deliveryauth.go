@@ -0,0 +1,46 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidServiceAccount is returned by ParseDeliveryAuth if the service
+// account is not a Google service account email.
+var ErrInvalidServiceAccount = errors.New("invalid service account")
+
+// serviceAccountEmail matches the emails of Google service accounts, including
+// the default compute and App Engine service accounts.
+var serviceAccountEmail = regexp.MustCompile(`^[a-z0-9][-a-z0-9.]*@[a-z0-9][-a-z0-9.]*\.gserviceaccount\.com$`)
+
+// ParseDeliveryAuth parses the audience and the optional service account of
+// the ID tokens authenticating the deliveries to a target.
+func ParseDeliveryAuth(audience, serviceAccount string) (*DeliveryAuth, error) {
+	audience = strings.TrimSpace(audience)
+	serviceAccount = strings.TrimSpace(serviceAccount)
+	if audience == "" {
+		return nil, errors.New("the audience must not be empty")
+	}
+	if serviceAccount != "" && !serviceAccountEmail.MatchString(serviceAccount) {
+		return nil, fmt.Errorf("%w %q: must be the email of a Google service account", ErrInvalidServiceAccount, serviceAccount)
+	}
+	return &DeliveryAuth{Audience: audience, ServiceAccount: serviceAccount}, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestParseDeliveryAuth(t *testing.T) {
+	tests := []struct {
+		name                     string
+		audience, serviceAccount string
+		want                     *DeliveryAuth
+		wantErr                  bool
+		wantInvalidSA            bool
+	}{{
+		name:     "audience only",
+		audience: "https://subscriber-abc-uc.a.run.app",
+		want:     &DeliveryAuth{Audience: "https://subscriber-abc-uc.a.run.app"},
+	}, {
+		name:           "audience and service account",
+		audience:       " 1234.apps.googleusercontent.com ",
+		serviceAccount: "invoker@my-project.iam.gserviceaccount.com",
+		want: &DeliveryAuth{
+			Audience:       "1234.apps.googleusercontent.com",
+			ServiceAccount: "invoker@my-project.iam.gserviceaccount.com",
+		},
+	}, {
+		name:           "default compute service account",
+		audience:       "https://subscriber-abc-uc.a.run.app",
+		serviceAccount: "1234-compute@developer.gserviceaccount.com",
+		want: &DeliveryAuth{
+			Audience:       "https://subscriber-abc-uc.a.run.app",
+			ServiceAccount: "1234-compute@developer.gserviceaccount.com",
+		},
+	}, {
+		name:           "missing audience",
+		serviceAccount: "invoker@my-project.iam.gserviceaccount.com",
+		wantErr:        true,
+	}, {
+		name:           "not a google service account",
+		audience:       "https://subscriber-abc-uc.a.run.app",
+		serviceAccount: "someone@example.com",
+		wantErr:        true,
+		wantInvalidSA:  true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDeliveryAuth(tt.audience, tt.serviceAccount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDeliveryAuth error got=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrInvalidServiceAccount); got != tt.wantInvalidSA {
+				t.Errorf("ParseDeliveryAuth invalid service account error got=%v, want=%v", got, tt.wantInvalidSA)
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("ParseDeliveryAuth (-want,+got): %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultHopLimit is the number of times an event may be replied to a broker
+// which doesn't set its own hop limit. Brokers may only lower it.
+const DefaultHopLimit int32 = 255
+
+// ParseHopLimit parses the number of times an event may be replied to a broker,
+// from 1 up to DefaultHopLimit.
+func ParseHopLimit(s string) (int32, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+	if err != nil || n < 1 || n > int64(DefaultHopLimit) {
+		return 0, fmt.Errorf("invalid hop limit %q: must be an integer from 1 to %d", s, DefaultHopLimit)
+	}
+	return int32(n), nil
+}
+
+// ParseLoopDetection parses whether the events looping back to a target are
+// dropped, e.g. "true".
+func ParseLoopDetection(s string) (bool, error) {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return false, fmt.Errorf("invalid loop detection %q: must be true or false", s)
+	}
+	return enabled, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "testing"
+
+func TestParseHopLimit(t *testing.T) {
+	cases := []struct {
+		s       string
+		want    int32
+		wantErr bool
+	}{
+		{s: "10", want: 10},
+		{s: " 1 ", want: 1},
+		{s: "255", want: 255},
+		{s: "", wantErr: true},
+		{s: "0", wantErr: true},
+		{s: "-1", wantErr: true},
+		{s: "256", wantErr: true},
+		{s: "ten", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseHopLimit(tc.s)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseHopLimit(%q) error got=%v, wantErr=%v", tc.s, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("ParseHopLimit(%q) got=%v, want=%v", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestParseLoopDetection(t *testing.T) {
+	cases := []struct {
+		s       string
+		want    bool
+		wantErr bool
+	}{
+		{s: "true", want: true},
+		{s: " false ", want: false},
+		{s: "", wantErr: true},
+		{s: "yes", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseLoopDetection(tc.s)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseLoopDetection(%q) error got=%v, wantErr=%v", tc.s, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("ParseLoopDetection(%q) got=%v, want=%v", tc.s, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// indexedAttributes are the event attributes the targets of a broker are
+// indexed by, in order of preference. A target is indexed by the first of
+// these attributes it requires an exact value for.
+var indexedAttributes = []string{"type", "source"}
+
+// targetIndex indexes the targets of a broker by the exact values their
+// filters require for the indexed attributes.
+type targetIndex struct {
+	// byValue maps an indexed attribute and its value to the targets
+	// requiring that value.
+	byValue map[string]map[string][]*Target
+	// unindexed are the targets that don't require an exact value for
+	// any indexed attribute. They are candidates for every event.
+	unindexed []*Target
+}
+
+// buildIndexes builds the target index of each broker by broker key.
+func buildIndexes(tc *TargetsConfig) map[string]*targetIndex {
+	if tc == nil {
+		return nil
+	}
+	indexes := make(map[string]*targetIndex, len(tc.Brokers))
+	for k, b := range tc.Brokers {
+		idx := &targetIndex{byValue: make(map[string]map[string][]*Target)}
+		for _, t := range b.Targets {
+			idx.add(t)
+		}
+		indexes[k] = idx
+	}
+	return indexes
+}
+
+func (idx *targetIndex) add(t *Target) {
+	for _, attr := range indexedAttributes {
+		if v, ok := exactValue(t, attr); ok {
+			if idx.byValue[attr] == nil {
+				idx.byValue[attr] = make(map[string][]*Target)
+			}
+			idx.byValue[attr][v] = append(idx.byValue[attr][v], t)
+			return
+		}
+	}
+	idx.unindexed = append(idx.unindexed, t)
+}
+
+// exactValue returns the value the target requires for the attribute, if
+// any. An empty filter attribute only requires the attribute to exist, and
+// the exact filters nested in other dialects may not apply to every event,
+// so neither of them is used.
+func exactValue(t *Target, attr string) (string, bool) {
+	if v := t.FilterAttributes[attr]; v != "" {
+		return v, true
+	}
+	for _, f := range t.Filters {
+		if v, ok := f.Exact[attr]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (idx *targetIndex) rangeCandidates(attrs map[string]string, f func(*Target) bool) {
+	for _, t := range idx.unindexed {
+		if !f(t) {
+			return
+		}
+	}
+	for _, attr := range indexedAttributes {
+		v, ok := attrs[attr]
+		if !ok {
+			continue
+		}
+		for _, t := range idx.byValue[attr][v] {
+			if !f(t) {
+				return
+			}
+		}
+	}
+}
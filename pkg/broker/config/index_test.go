@@ -0,0 +1,120 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRangeCandidateTargets(t *testing.T) {
+	targets := []*Target{{
+		Name: "no-filter",
+	}, {
+		Name:             "type-attribute",
+		FilterAttributes: map[string]string{"type": "create"},
+	}, {
+		Name:             "other-type-attribute",
+		FilterAttributes: map[string]string{"type": "delete"},
+	}, {
+		Name:             "source-attribute",
+		FilterAttributes: map[string]string{"source": "storage"},
+	}, {
+		Name:             "other-source-attribute",
+		FilterAttributes: map[string]string{"source": "pubsub"},
+	}, {
+		Name:             "type-exists-attribute",
+		FilterAttributes: map[string]string{"type": ""},
+	}, {
+		Name:    "type-exact-filter",
+		Filters: []*Filter{{Exact: map[string]string{"type": "create"}}},
+	}, {
+		Name:    "other-type-exact-filter",
+		Filters: []*Filter{{Sql: "subject = 'a'"}, {Exact: map[string]string{"type": "delete"}}},
+	}, {
+		Name:    "type-prefix-filter",
+		Filters: []*Filter{{Prefix: map[string]string{"type": "delete"}}},
+	}, {
+		Name:    "type-nested-exact-filter",
+		Filters: []*Filter{{Not: &Filter{Exact: map[string]string{"type": "create"}}}},
+	}, {
+		Name:             "other-type-and-source-attributes",
+		FilterAttributes: map[string]string{"type": "delete", "source": "storage"},
+	}}
+	tc := &TargetsConfig{Brokers: map[string]*Broker{
+		"ns/broker": {Name: "broker", Namespace: "ns", Targets: map[string]*Target{}},
+		"ns/other":  {Name: "other", Namespace: "ns", Targets: map[string]*Target{"other": {Name: "other"}}},
+	}}
+	for _, t := range targets {
+		tc.Brokers["ns/broker"].Targets[t.Name] = t
+	}
+	ct := &CachedTargets{}
+	ct.Store(tc)
+
+	cases := []struct {
+		name      string
+		brokerKey string
+		attrs     map[string]string
+		want      []string
+	}{{
+		name:      "type and source",
+		brokerKey: "ns/broker",
+		attrs:     map[string]string{"type": "create", "source": "storage"},
+		want: []string{
+			"no-filter", "source-attribute", "type-attribute", "type-exact-filter",
+			"type-exists-attribute", "type-nested-exact-filter", "type-prefix-filter",
+		},
+	}, {
+		name:      "type only",
+		brokerKey: "ns/broker",
+		attrs:     map[string]string{"type": "delete"},
+		want: []string{
+			"no-filter", "other-type-and-source-attributes", "other-type-attribute", "other-type-exact-filter",
+			"type-exists-attribute", "type-nested-exact-filter", "type-prefix-filter",
+		},
+	}, {
+		name:      "unknown broker",
+		brokerKey: "ns/unknown",
+		attrs:     map[string]string{"type": "create"},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			ct.RangeCandidateTargets(tc.brokerKey, tc.attrs, func(t *Target) bool {
+				got = append(got, t.Name)
+				return true
+			})
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("RangeCandidateTargets (-want,+got): %v", diff)
+			}
+		})
+	}
+
+	t.Run("stop ranging", func(t *testing.T) {
+		count := 0
+		ct.RangeCandidateTargets("ns/broker", map[string]string{"type": "create"}, func(*Target) bool {
+			count++
+			return false
+		})
+		if count != 1 {
+			t.Errorf("ranged targets got=%d, want=1", count)
+		}
+	})
+}
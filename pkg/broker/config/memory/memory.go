@@ -21,6 +21,7 @@ import (
 
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 type brokerMutation struct {
@@ -52,6 +53,54 @@ func (m *brokerMutation) SetState(s config.State) config.BrokerMutation {
 	return m
 }
 
+func (m *brokerMutation) SetRateLimit(l *config.RateLimit) config.BrokerMutation {
+	m.delete = false
+	m.b.RateLimit = l
+	return m
+}
+
+func (m *brokerMutation) SetNamespaceRateLimit(l *config.RateLimit) config.BrokerMutation {
+	m.delete = false
+	m.b.NamespaceRateLimit = l
+	return m
+}
+
+func (m *brokerMutation) SetAuthPolicy(p *config.AuthPolicy) config.BrokerMutation {
+	m.delete = false
+	m.b.AuthPolicy = p
+	return m
+}
+
+func (m *brokerMutation) SetClaimCheckThreshold(bytes int64) config.BrokerMutation {
+	m.delete = false
+	m.b.ClaimCheckThreshold = bytes
+	return m
+}
+
+func (m *brokerMutation) SetOrderingKeyExtension(extension string) config.BrokerMutation {
+	m.delete = false
+	m.b.OrderingKeyExtension = extension
+	return m
+}
+
+func (m *brokerMutation) SetDeduplicationWindow(window *durationpb.Duration) config.BrokerMutation {
+	m.delete = false
+	m.b.DeduplicationWindow = window
+	return m
+}
+
+func (m *brokerMutation) SetHopLimit(limit int32) config.BrokerMutation {
+	m.delete = false
+	m.b.HopLimit = limit
+	return m
+}
+
+func (m *brokerMutation) SetLoopDetection(enabled bool) config.BrokerMutation {
+	m.delete = false
+	m.b.LoopDetection = enabled
+	return m
+}
+
 func (m *brokerMutation) UpsertTargets(targets ...*config.Target) config.BrokerMutation {
 	m.delete = false
 	if m.b.Targets == nil {
@@ -0,0 +1,36 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// extensionName matches the CloudEvents attribute naming convention.
+var extensionName = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+
+// ParseOrderingKeyExtension parses the name of the CloudEvents extension
+// holding the ordering key of events.
+func ParseOrderingKeyExtension(s string) (string, error) {
+	name := strings.TrimSpace(s)
+	if !extensionName.MatchString(name) {
+		return "", fmt.Errorf("invalid ordering key extension %q: must be 1 to 20 lowercase letters or digits", s)
+	}
+	return name, nil
+}
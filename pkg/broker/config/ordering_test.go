@@ -0,0 +1,43 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "testing"
+
+func TestParseOrderingKeyExtension(t *testing.T) {
+	cases := []struct {
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{s: "partitionkey", want: "partitionkey"},
+		{s: " key1 ", want: "key1"},
+		{s: "", wantErr: true},
+		{s: "PartitionKey", wantErr: true},
+		{s: "partition-key", wantErr: true},
+		{s: "averyveryverylongextension", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseOrderingKeyExtension(tc.s)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseOrderingKeyExtension(%q) error got=%v, wantErr=%v", tc.s, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("ParseOrderingKeyExtension(%q) got=%q, want=%q", tc.s, got, tc.want)
+		}
+	}
+}
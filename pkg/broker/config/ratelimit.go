@@ -0,0 +1,57 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseRateLimit parses a rate limit in the form of "<events-per-second>" or
+// "<events-per-second>,<burst>". If the burst is omitted, it defaults to the
+// number of events per second rounded up.
+func ParseRateLimit(s string) (*RateLimit, error) {
+	pieces := strings.Split(s, ",")
+	if len(pieces) > 2 {
+		return nil, fmt.Errorf("invalid rate limit %q: want '<events-per-second>[,<burst>]'", s)
+	}
+	rate, err := strconv.ParseFloat(strings.TrimSpace(pieces[0]), 64)
+	if err != nil || math.IsNaN(rate) || math.IsInf(rate, 0) || rate <= 0 {
+		return nil, fmt.Errorf("invalid rate limit %q: events per second must be a positive number", s)
+	}
+	var burst int64
+	if len(pieces) == 2 {
+		burst, err = strconv.ParseInt(strings.TrimSpace(pieces[1]), 10, 32)
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+	} else {
+		// Check the rounded up rate before converting it, a float too large for an
+		// int64 doesn't convert to a positive burst.
+		derived := math.Ceil(rate)
+		if derived > math.MaxInt32 {
+			return nil, fmt.Errorf("invalid rate limit %q: burst is too large", s)
+		}
+		burst = int64(derived)
+		if burst <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+	}
+	return &RateLimit{EventsPerSecond: rate, Burst: int32(burst)}, nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	cases := []struct {
+		name    string
+		s       string
+		want    *RateLimit
+		wantErr bool
+	}{{
+		name: "rate only",
+		s:    "100",
+		want: &RateLimit{EventsPerSecond: 100, Burst: 100},
+	}, {
+		name: "fractional rate",
+		s:    "0.5",
+		want: &RateLimit{EventsPerSecond: 0.5, Burst: 1},
+	}, {
+		name: "rate and burst",
+		s:    "10, 200",
+		want: &RateLimit{EventsPerSecond: 10, Burst: 200},
+	}, {
+		name: "0.0001",
+		s:    "0.0001",
+		want: &RateLimit{EventsPerSecond: 0.0001, Burst: 1},
+	}, {
+		name:    "empty",
+		s:       "",
+		wantErr: true,
+	}, {
+		name:    "negative rate",
+		s:       "-1",
+		wantErr: true,
+	}, {
+		name:    "NaN",
+		s:       "NaN",
+		wantErr: true,
+	}, {
+		name:    "1e400",
+		s:       "1e400",
+		wantErr: true,
+	}, {
+		name:    "rate too large for the burst",
+		s:       "1e300",
+		wantErr: true,
+	}, {
+		name:    "zero burst",
+		s:       "10,0",
+		wantErr: true,
+	}, {
+		name:    "too many pieces",
+		s:       "10,20,30",
+		wantErr: true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseRateLimit(tc.s)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseRateLimit(%q) error got=%v, wantErr=%v", tc.s, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("ParseRateLimit(%q) (-want,+got): %v", tc.s, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"hash/fnv"
+)
+
+// ShardIndex returns the index of the shard of the targets config holding the
+// brokers of the namespace. All the brokers of a namespace are in the same
+// shard because the namespace rate limit depends on all of them.
+func ShardIndex(namespace string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(namespace))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// Merge returns a targets config with the brokers of all the shards. The
+// brokers are not copied.
+func Merge(shards ...*TargetsConfig) *TargetsConfig {
+	merged := &TargetsConfig{Brokers: make(map[string]*Broker)}
+	for _, s := range shards {
+		for k, b := range s.GetBrokers() {
+			merged.Brokers[k] = b
+		}
+	}
+	return merged
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestShardIndex(t *testing.T) {
+	const shards = 16
+	seen := make(map[int]bool)
+	for _, ns := range []string{"default", "ns", "ns1", "ns2", "testnamespace", "team-a", "team-b"} {
+		i := ShardIndex(ns, shards)
+		if i < 0 || i >= shards {
+			t.Errorf("ShardIndex(%q) got=%d, want in [0, %d)", ns, i, shards)
+		}
+		if again := ShardIndex(ns, shards); again != i {
+			t.Errorf("ShardIndex(%q) got=%d then %d, want stable index", ns, i, again)
+		}
+		seen[i] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("ShardIndex assigned all namespaces to shards %v, want several shards", seen)
+	}
+	if got := ShardIndex("default", 1); got != 0 {
+		t.Errorf("ShardIndex with a single shard got=%d, want=0", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	b1 := &Broker{Namespace: "ns1", Name: "broker1"}
+	b2 := &Broker{Namespace: "ns2", Name: "broker2"}
+	got := Merge(
+		&TargetsConfig{Brokers: map[string]*Broker{b1.Key(): b1}},
+		&TargetsConfig{},
+		nil,
+		&TargetsConfig{Brokers: map[string]*Broker{b2.Key(): b2}},
+	)
+	want := &TargetsConfig{Brokers: map[string]*Broker{b1.Key(): b1, b2.Key(): b2}}
+	if !proto.Equal(got, want) {
+		t.Errorf("Merge got=%v, want=%v", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// TokenAudience is the audience of the service account tokens the data plane
+	// pods authenticate with to the TargetsWatcher server.
+	TokenAudience = "targets-config-server"
+
+	authorizationKey = "authorization"
+	bearerPrefix     = "Bearer "
+)
+
+// tokenFile implements credentials.PerRPCCredentials with the token read from a
+// file for each call, such as a projected service account token which the
+// kubelet rotates.
+type tokenFile string
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (f tokenFile) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(string(f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets config server token: %w", err)
+	}
+	return map[string]string{authorizationKey: bearerPrefix + strings.TrimSpace(string(b))}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.
+func (tokenFile) RequireTransportSecurity() bool {
+	return true
+}
+
+// BearerToken returns the bearer token of the call, or empty if it has none.
+func BearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(authorizationKey) {
+		if strings.HasPrefix(v, bearerPrefix) {
+			return strings.TrimPrefix(v, bearerPrefix)
+		}
+	}
+	return ""
+}
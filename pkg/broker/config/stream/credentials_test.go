@@ -0,0 +1,49 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	creds := tokenFile(path)
+	if _, err := creds.GetRequestMetadata(context.Background()); err == nil {
+		t.Error("GetRequestMetadata got no error for a missing token")
+	}
+
+	// The token is read again for each call as the kubelet rotates it.
+	for _, token := range []string{"token-1", "token-2"} {
+		if err := ioutil.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		md, err := creds.GetRequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("GetRequestMetadata got unexpected error: %v", err)
+		}
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.New(md))
+		if got := BearerToken(ctx); got != token {
+			t.Errorf("BearerToken got=%q, want=%q", got, token)
+		}
+	}
+}
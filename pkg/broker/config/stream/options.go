@@ -0,0 +1,64 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import "github.com/google/knative-gcp/pkg/broker/config"
+
+// Option is the option to load targets.
+type Option func(*Targets)
+
+// WithBrokerCell is the option to load the targets of the given brokercell.
+func WithBrokerCell(namespace, name string) Option {
+	return func(t *Targets) {
+		t.namespace = namespace
+		t.name = name
+	}
+}
+
+// WithPod is the option to acknowledge the applied targets as the given pod.
+func WithPod(pod string) Option {
+	return func(t *Targets) {
+		t.pod = pod
+	}
+}
+
+// WithNotifyChan is the option to notify the given channel
+// when the config cache was updated.
+func WithNotifyChan(ch chan<- struct{}) Option {
+	return func(t *Targets) {
+		t.notifyChan = ch
+	}
+}
+
+// WithAckSynced is the option to acknowledge each applied version only once
+// Synced is called with it, after the handlers were synced with the version.
+func WithAckSynced() Option {
+	return func(t *Targets) {
+		t.ackSynced = true
+	}
+}
+
+// WithFallback is the option to serve the targets config of the fallback until
+// the first snapshot is received from the server, reloading it when the given
+// channel is notified. The targets are then initialized without waiting for the
+// server.
+func WithFallback(fallback config.ReadonlyTargets, updates <-chan struct{}) Option {
+	return func(t *Targets) {
+		t.fallback = fallback
+		t.fallbackChan = updates
+	}
+}
@@ -0,0 +1,369 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// watcherBuffer is the number of updates buffered for a watcher. A watcher
+// falling further behind is disconnected and gets a new snapshot when it
+// reconnects.
+const watcherBuffer = 64
+
+// Authorizer checks that the caller of a watch may watch the targets config of
+// the brokercell of the request, and report as its pod. It returns a gRPC status
+// error if not.
+type Authorizer func(ctx context.Context, req *WatchRequest) error
+
+// Server implements TargetsWatcherServer. It holds the targets config of the
+// brokercells, updated shard by shard, and streams it to the data plane pods
+// of the brokercells.
+type Server struct {
+	shards    int
+	authorize Authorizer
+
+	mux   sync.Mutex
+	cells map[string]*cell
+	// appliedHandlers are called when a pod acknowledges a version or
+	// reports the replays that caught up.
+	appliedHandlers []func(bcKey string)
+	// healthHandlers are called when the failed handlers reported by the pods
+	// change.
+	healthHandlers []func(bcKey string)
+	// loopHandlers are called when a pod reports events dropped because they
+	// looped back to a target.
+	loopHandlers []func(bcKey, targetKey string, count int64)
+}
+
+var _ TargetsWatcherServer = (*Server)(nil)
+
+// cell is the targets config of a brokercell and its watchers.
+type cell struct {
+	// shards holds the last targets config set for each shard, nil until set.
+	shards []*config.TargetsConfig
+	// brokers holds the brokers of all the shards once they were all set.
+	brokers map[string]*config.Broker
+	version int64
+	// changed holds the version at which each broker last changed.
+	changed map[string]int64
+
+	watchers map[*watcher]struct{}
+}
+
+type watcher struct {
+	pod string
+	// applied is the version of the targets config applied by the pod.
+	applied int64
+	// replayed holds the keys of the targets whose replay caught up in the pod.
+	replayed []string
+	// failed holds the handlers of the pod which failed.
+	failed []*HandlerHealth
+	// loops holds the number of events the pod dropped because they looped
+	// back to each target.
+	loops   map[string]int64
+	updates chan *TargetsUpdate
+	// dropped is closed when the watcher falls behind.
+	dropped chan struct{}
+}
+
+// NewServer creates a Server for targets configs split in the given number
+// of shards. The watches are checked with the authorizer, if not nil.
+func NewServer(shards int, authorize Authorizer) *Server {
+	return &Server{shards: shards, authorize: authorize, cells: make(map[string]*cell)}
+}
+
+func (s *Server) cell(bcKey string) *cell {
+	c, ok := s.cells[bcKey]
+	if !ok {
+		c = &cell{
+			shards:   make([]*config.TargetsConfig, s.shards),
+			watchers: make(map[*watcher]struct{}),
+			changed:  make(map[string]int64),
+		}
+		s.cells[bcKey] = c
+	}
+	return c
+}
+
+// UpdateShard sets the targets config of a shard of the brokercell and sends
+// the brokers that changed to its watchers. Nothing is sent until all the
+// shards of the brokercell were set once. The targets config must not be
+// modified afterwards.
+func (s *Server) UpdateShard(bcKey string, shard int, targets *config.TargetsConfig) {
+	if shard < 0 || shard >= s.shards {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	c := s.cell(bcKey)
+	c.shards[shard] = targets
+	for _, t := range c.shards {
+		if t == nil {
+			return
+		}
+	}
+
+	brokers := config.Merge(c.shards...).Brokers
+	if c.brokers == nil {
+		c.brokers = brokers
+		c.version++
+		for k := range brokers {
+			c.changed[k] = c.version
+		}
+		c.broadcast(c.snapshot())
+		return
+	}
+
+	update := &TargetsUpdate{Brokers: make(map[string]*config.Broker)}
+	for k, b := range brokers {
+		if old, ok := c.brokers[k]; !ok || !proto.Equal(old, b) {
+			update.Brokers[k] = b
+		}
+	}
+	for k := range c.brokers {
+		if _, ok := brokers[k]; !ok {
+			update.DeletedBrokers = append(update.DeletedBrokers, k)
+		}
+	}
+	if len(update.Brokers) == 0 && len(update.DeletedBrokers) == 0 {
+		return
+	}
+	c.brokers = brokers
+	c.version++
+	update.Version = c.version
+	for k := range update.Brokers {
+		c.changed[k] = c.version
+	}
+	for _, k := range update.DeletedBrokers {
+		delete(c.changed, k)
+	}
+	c.broadcast(update)
+}
+
+// Broker returns a broker of the brokercell and the version at which it last
+// changed. It returns false if the brokercell doesn't have the broker or not
+// all its shards were set.
+func (s *Server) Broker(bcKey, brokerKey string) (*config.Broker, int64, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	c, ok := s.cells[bcKey]
+	if !ok {
+		return nil, 0, false
+	}
+	b, ok := c.brokers[brokerKey]
+	return b, c.changed[brokerKey], ok
+}
+
+// OnApplied registers a function called with the key of a brokercell each time
+// one of its pods acknowledges a version or reports the replays that caught up.
+func (s *Server) OnApplied(f func(bcKey string)) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.appliedHandlers = append(s.appliedHandlers, f)
+}
+
+// AppliedVersions returns the version of the targets config applied by each
+// pod watching the brokercell, the lowest one if a pod has several watches.
+func (s *Server) AppliedVersions(bcKey string) map[string]int64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	applied := make(map[string]int64)
+	if c, ok := s.cells[bcKey]; ok {
+		for w := range c.watchers {
+			if v, ok := applied[w.pod]; w.pod != "" && (!ok || w.applied < v) {
+				applied[w.pod] = w.applied
+			}
+		}
+	}
+	return applied
+}
+
+// ReplayedPods returns the pods watching the brokercell in which the replay of
+// the target caught up.
+func (s *Server) ReplayedPods(bcKey, targetKey string) map[string]bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	pods := make(map[string]bool)
+	if c, ok := s.cells[bcKey]; ok {
+		for w := range c.watchers {
+			for _, k := range w.replayed {
+				if k == targetKey && w.pod != "" {
+					pods[w.pod] = true
+				}
+			}
+		}
+	}
+	return pods
+}
+
+// OnHealthChanged registers a function called with the key of a brokercell each
+// time the failed handlers reported by its pods change.
+func (s *Server) OnHealthChanged(f func(bcKey string)) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.healthHandlers = append(s.healthHandlers, f)
+}
+
+// OnLoopDetected registers a function called with the key of a brokercell, the
+// key of a target and the number of events dropped because they looped back
+// to the target, each time a pod of the brokercell reports new loops. The
+// loops may be reported again when a pod watches again.
+func (s *Server) OnLoopDetected(f func(bcKey, targetKey string, count int64)) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.loopHandlers = append(s.loopHandlers, f)
+}
+
+// FailedHandlers returns the handlers which failed in each pod watching the
+// brokercell. Pods without failed handlers are omitted.
+func (s *Server) FailedHandlers(bcKey string) map[string][]*HandlerHealth {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	failed := make(map[string][]*HandlerHealth)
+	if c, ok := s.cells[bcKey]; ok {
+		for w := range c.watchers {
+			if w.pod != "" && len(w.failed) > 0 {
+				failed[w.pod] = append(failed[w.pod], w.failed...)
+			}
+		}
+	}
+	return failed
+}
+
+func (c *cell) snapshot() *TargetsUpdate {
+	return &TargetsUpdate{Version: c.version, Snapshot: true, Brokers: c.brokers}
+}
+
+// broadcast sends the update to all the watchers without blocking. The
+// watchers whose buffer is full are dropped.
+func (c *cell) broadcast(update *TargetsUpdate) {
+	for w := range c.watchers {
+		select {
+		case w.updates <- update:
+		default:
+			close(w.dropped)
+			delete(c.watchers, w)
+		}
+	}
+}
+
+// Watch implements TargetsWatcherServer.
+func (s *Server) Watch(stream TargetsWatcher_WatchServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if req.BrokercellNamespace == "" || req.BrokercellName == "" {
+		return status.Error(codes.InvalidArgument, "brokercell namespace and name are required")
+	}
+	if s.authorize != nil {
+		if err := s.authorize(stream.Context(), req); err != nil {
+			return err
+		}
+	}
+	bcKey := req.BrokercellNamespace + "/" + req.BrokercellName
+
+	w := &watcher{
+		pod:     req.Pod,
+		updates: make(chan *TargetsUpdate, watcherBuffer),
+		dropped: make(chan struct{}),
+	}
+	s.mux.Lock()
+	c := s.cell(bcKey)
+	if c.brokers != nil {
+		w.updates <- c.snapshot()
+	}
+	c.watchers[w] = struct{}{}
+	s.mux.Unlock()
+	defer func() {
+		s.mux.Lock()
+		delete(c.watchers, w)
+		hadFailed := len(w.failed) > 0
+		handlers := s.healthHandlers
+		s.mux.Unlock()
+		// The failed handlers of the pod are gone along with the watch.
+		if hadFailed {
+			for _, h := range handlers {
+				h(bcKey)
+			}
+		}
+	}()
+
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			ack, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			s.mux.Lock()
+			w.applied = ack.AppliedVersion
+			w.replayed = ack.ReplayedTargets
+			healthChanged := !sameHandlers(w.failed, ack.FailedHandlers)
+			w.failed = ack.FailedHandlers
+			handlers := s.appliedHandlers
+			if healthChanged {
+				handlers = append(handlers[:len(handlers):len(handlers)], s.healthHandlers...)
+			}
+			loops := make(map[string]int64)
+			for k, n := range ack.DetectedLoops {
+				if n > w.loops[k] {
+					loops[k] = n - w.loops[k]
+				}
+			}
+			w.loops = ack.DetectedLoops
+			loopHandlers := s.loopHandlers
+			s.mux.Unlock()
+			for _, h := range handlers {
+				h(bcKey)
+			}
+			for k, n := range loops {
+				for _, h := range loopHandlers {
+					h(bcKey, k, n)
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case update := <-w.updates:
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+		case <-w.dropped:
+			return status.Error(codes.ResourceExhausted, "watcher fell behind the targets config updates")
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+const bcKey = "ns/bc"
+
+func startServer(ctx context.Context, t *testing.T, srv *Server) TargetsWatcherClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterTargetsWatcherServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewTargetsWatcherClient(conn)
+}
+
+func startWatch(ctx context.Context, t *testing.T, client TargetsWatcherClient, pod string) (TargetsWatcher_WatchClient, <-chan *TargetsUpdate) {
+	t.Helper()
+	stream, err := client.Watch(ctx)
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	if err := stream.Send(&WatchRequest{BrokercellNamespace: "ns", BrokercellName: "bc", Pod: pod}); err != nil {
+		t.Fatalf("failed to send watch request: %v", err)
+	}
+	updates := make(chan *TargetsUpdate, 10)
+	go func() {
+		defer close(updates)
+		for {
+			u, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			updates <- u
+		}
+	}()
+	return stream, updates
+}
+
+func broker(namespace, name, address string) *config.Broker {
+	return &config.Broker{
+		Id:        namespace + "-" + name,
+		Namespace: namespace,
+		Name:      name,
+		Address:   address,
+	}
+}
+
+func targets(brokers ...*config.Broker) *config.TargetsConfig {
+	t := &config.TargetsConfig{Brokers: make(map[string]*config.Broker)}
+	for _, b := range brokers {
+		t.Brokers[b.Key()] = b
+	}
+	return t
+}
+
+func nextUpdate(t *testing.T, updates <-chan *TargetsUpdate) *TargetsUpdate {
+	t.Helper()
+	select {
+	case u, ok := <-updates:
+		if !ok {
+			t.Fatal("watch stopped")
+		}
+		return u
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for update")
+	}
+	return nil
+}
+
+func noUpdate(t *testing.T, updates <-chan *TargetsUpdate) {
+	t.Helper()
+	select {
+	case u := <-updates:
+		t.Fatalf("unexpected update: %v", u)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestServerUpdates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := NewServer(2, nil)
+	client := startServer(ctx, t, srv)
+	_, updates := startWatch(ctx, t, client, "pod")
+
+	b1, b2, b3 := broker("ns1", "b1", "a1"), broker("ns2", "b2", "a2"), broker("ns2", "b3", "a3")
+	srv.UpdateShard(bcKey, 0, targets(b1))
+	noUpdate(t, updates)
+
+	srv.UpdateShard(bcKey, 1, targets(b2, b3))
+	want := &TargetsUpdate{Version: 1, Snapshot: true, Brokers: targets(b1, b2, b3).Brokers}
+	if diff := cmp.Diff(want, nextUpdate(t, updates), protocmp.Transform()); diff != "" {
+		t.Errorf("snapshot (-want,+got): %v", diff)
+	}
+
+	// Setting the same brokers again doesn't send an update.
+	srv.UpdateShard(bcKey, 1, targets(b2, b3))
+	noUpdate(t, updates)
+
+	b2Updated := broker("ns2", "b2", "a2-updated")
+	srv.UpdateShard(bcKey, 1, targets(b2Updated))
+	want = &TargetsUpdate{Version: 2, Brokers: targets(b2Updated).Brokers, DeletedBrokers: []string{b3.Key()}}
+	if diff := cmp.Diff(want, nextUpdate(t, updates), protocmp.Transform()); diff != "" {
+		t.Errorf("delta (-want,+got): %v", diff)
+	}
+	for _, tc := range []struct {
+		broker  *config.Broker
+		version int64
+		ok      bool
+	}{{b1, 1, true}, {b2Updated, 2, true}, {b3, 0, false}} {
+		b, version, ok := srv.Broker(bcKey, tc.broker.Key())
+		if ok != tc.ok || version != tc.version || (ok && !proto.Equal(b, tc.broker)) {
+			t.Errorf("Broker(%q) got=(%v, %d, %v), want=(%v, %d, %v)", tc.broker.Key(), b, version, ok, tc.broker, tc.version, tc.ok)
+		}
+	}
+
+	// A new watch starts from a snapshot of the current version.
+	_, updates = startWatch(ctx, t, client, "other-pod")
+	want = &TargetsUpdate{Version: 2, Snapshot: true, Brokers: targets(b1, b2Updated).Brokers}
+	if diff := cmp.Diff(want, nextUpdate(t, updates), protocmp.Transform()); diff != "" {
+		t.Errorf("snapshot (-want,+got): %v", diff)
+	}
+}
+
+func TestServerAppliedVersions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := NewServer(1, nil)
+	srv.UpdateShard(bcKey, 0, targets(broker("ns", "b", "a")))
+	client := startServer(ctx, t, srv)
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	stream, updates := startWatch(watchCtx, t, client, "pod")
+	u := nextUpdate(t, updates)
+	applied := make(chan string, 1)
+	srv.OnApplied(func(bcKey string) { applied <- bcKey })
+	if err := stream.Send(&WatchRequest{AppliedVersion: u.Version}); err != nil {
+		t.Fatalf("failed to acknowledge version: %v", err)
+	}
+	waitForApplied(t, srv, map[string]int64{"pod": 1})
+	select {
+	case got := <-applied:
+		if got != bcKey {
+			t.Errorf("OnApplied got brokercell %q, want %q", got, bcKey)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("timed out waiting for OnApplied")
+	}
+
+	stopWatch()
+	waitForApplied(t, srv, map[string]int64{})
+}
+
+func waitForApplied(t *testing.T, srv *Server, want map[string]int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := srv.AppliedVersions(bcKey)
+		if cmp.Equal(want, got) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("applied versions (-want,+got): %v", cmp.Diff(want, got))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerSlowWatcherDropped(t *testing.T) {
+	srv := NewServer(1, nil)
+	srv.UpdateShard(bcKey, 0, targets())
+	w := &watcher{updates: make(chan *TargetsUpdate, 1), dropped: make(chan struct{})}
+	srv.cells[bcKey].watchers[w] = struct{}{}
+
+	srv.UpdateShard(bcKey, 0, targets(broker("ns", "b1", "a")))
+	srv.UpdateShard(bcKey, 0, targets(broker("ns", "b2", "a")))
+	select {
+	case <-w.dropped:
+	default:
+		t.Error("slow watcher was not dropped")
+	}
+	if len(srv.cells[bcKey].watchers) != 0 {
+		t.Error("slow watcher is still watching")
+	}
+}
+
+func TestServerWatchWithoutBrokerCell(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := startServer(ctx, t, NewServer(1, nil))
+	stream, err := client.Watch(ctx)
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	if err := stream.Send(&WatchRequest{Pod: "pod"}); err != nil {
+		t.Fatalf("failed to send watch request: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("watch error got=%v, want code %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestServerWatchUnauthorized(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var gotToken string
+	client := startServer(ctx, t, NewServer(1, func(ctx context.Context, req *WatchRequest) error {
+		gotToken = BearerToken(ctx)
+		return status.Error(codes.PermissionDenied, "not a pod of the brokercell")
+	}))
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer token")
+	_, updates := startWatch(ctx, t, client, "pod")
+	select {
+	case u, ok := <-updates:
+		if ok {
+			t.Errorf("unauthorized watch got update: %v", u)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("unauthorized watch wasn't stopped")
+	}
+	if gotToken != "token" {
+		t.Errorf("authorized token got=%q, want=%q", gotToken, "token")
+	}
+}
@@ -0,0 +1,423 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+	"knative.dev/pkg/logging"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+const (
+	minReconnectDelay = 100 * time.Millisecond
+	maxReconnectDelay = 10 * time.Second
+)
+
+// errVersionGap is returned when an update doesn't follow the applied version.
+var errVersionGap = errors.New("targets config update doesn't follow the applied version")
+
+// Targets implements config.ReadonlyTargets with data streamed by a
+// TargetsWatcher server. It applies the updates to the in memory cache
+// as they come, acknowledges the version applied to the server and
+// watches again from a new snapshot if the stream breaks. With a fallback,
+// it serves the targets config of the fallback until the first snapshot.
+type Targets struct {
+	config.CachedTargets
+	client     TargetsWatcherClient
+	namespace  string
+	name       string
+	pod        string
+	notifyChan chan<- struct{}
+	// fallback is served until the first snapshot, and reloaded when
+	// fallbackChan is notified.
+	fallback     config.ReadonlyTargets
+	fallbackChan <-chan struct{}
+	// ackSynced defers the acknowledgement of each version until Synced is
+	// called with it.
+	ackSynced bool
+
+	// mux guards the fields below, and the sends to the stream.
+	mux sync.Mutex
+	// streaming is true once a snapshot was received, and the fallback is
+	// no longer served.
+	streaming bool
+	// version is the version of the targets config in the cache, also
+	// stored as its generation.
+	version int64
+	// acked is the version acknowledged to the server, which is behind
+	// version until it's synced if ackSynced is set.
+	acked int64
+	// stream is the current watch, nil until the first one started.
+	stream TargetsWatcher_WatchClient
+	// replayed holds the keys of the targets whose replay caught up, sent
+	// with each acknowledgement.
+	replayed map[string]bool
+	// failed holds the handlers of the pod which failed, sent with each
+	// acknowledgement.
+	failed []*HandlerHealth
+	// loops holds the number of events dropped because they looped back to
+	// each target, sent with each acknowledgement.
+	loops map[string]int64
+}
+
+var _ config.ReadonlyTargets = (*Targets)(nil)
+
+// NewTargetsFromServer initializes the targets config from the TargetsWatcher
+// server at the given address. The server is verified with the given PEM CA
+// certificate, and the pod authenticates with the token read from the given
+// file for each watch.
+func NewTargetsFromServer(ctx context.Context, address string, caCert []byte, tokenPath string, opts ...Option) (config.ReadonlyTargets, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("invalid targets config server CA certificate")
+	}
+	conn, err := grpc.DialContext(ctx, address,
+		grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(pool, "")),
+		grpc.WithPerRPCCredentials(tokenFile(tokenPath)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial targets config server: %w", err)
+	}
+	t, err := NewTargets(ctx, NewTargetsWatcherClient(conn), opts...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	return t, nil
+}
+
+// NewTargets initializes the targets config from a TargetsWatcher client.
+// It blocks until the first snapshot is received, unless it has a fallback,
+// and keeps watching for updates until the context is done.
+func NewTargets(ctx context.Context, client TargetsWatcherClient, opts ...Option) (config.ReadonlyTargets, error) {
+	t := &Targets{client: client, replayed: make(map[string]bool), loops: make(map[string]int64)}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.fallback != nil {
+		if err := t.loadFallback(); err != nil {
+			return nil, err
+		}
+		go t.followFallback(ctx)
+		go t.run(ctx, nil, nil)
+		return t, nil
+	}
+	stream, cancel, err := t.watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go t.run(ctx, stream, cancel)
+	return t, nil
+}
+
+// watch starts a watch and applies its snapshot. The watch is stopped by
+// cancelling the returned function.
+func (t *Targets) watch(ctx context.Context) (TargetsWatcher_WatchClient, context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := t.startWatch(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return stream, cancel, nil
+}
+
+func (t *Targets) startWatch(ctx context.Context) (TargetsWatcher_WatchClient, error) {
+	stream, err := t.client.Watch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch targets config: %w", err)
+	}
+	if err := stream.Send(&WatchRequest{
+		BrokercellNamespace: t.namespace,
+		BrokercellName:      t.name,
+		Pod:                 t.pod,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to watch targets config: %w", err)
+	}
+	update, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive targets config snapshot: %w", err)
+	}
+	if !update.Snapshot {
+		return nil, fmt.Errorf("first targets config update at version %d is not a snapshot", update.Version)
+	}
+	if err := t.apply(stream, update); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// run applies the updates of the stream, and watches again when the stream breaks.
+// It starts watching if the stream is nil.
+func (t *Targets) run(ctx context.Context, stream TargetsWatcher_WatchClient, cancel context.CancelFunc) {
+	delay := minReconnectDelay
+	wait := stream != nil
+	for {
+		if stream != nil {
+			err := t.receive(stream)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			logging.FromContext(ctx).Warn("targets config watch stopped", zap.Error(err))
+		}
+
+		for {
+			if wait {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(delay):
+				}
+			}
+			wait = true
+			var err error
+			stream, cancel, err = t.watch(ctx)
+			if err == nil {
+				delay = minReconnectDelay
+				t.notify()
+				break
+			}
+			logging.FromContext(ctx).Warn("failed to watch targets config again", zap.Error(err), zap.Duration("retryIn", delay))
+			if delay *= 2; delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+		}
+	}
+}
+
+// receive applies the updates of the stream until it breaks.
+func (t *Targets) receive(stream TargetsWatcher_WatchClient) error {
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if err := t.apply(stream, update); err != nil {
+			return err
+		}
+		t.notify()
+	}
+}
+
+// apply stores the targets config after the update and acknowledges its version.
+func (t *Targets) apply(stream TargetsWatcher_WatchClient, update *TargetsUpdate) error {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if update.Snapshot {
+		t.Store(&config.TargetsConfig{Brokers: update.Brokers, Generation: update.Version})
+		t.streaming = true
+	} else {
+		if update.Version != t.version+1 {
+			return fmt.Errorf("%w: got version %d after %d", errVersionGap, update.Version, t.version)
+		}
+		brokers := make(map[string]*config.Broker)
+		for k, b := range t.Load().GetBrokers() {
+			brokers[k] = b
+		}
+		for k, b := range update.Brokers {
+			brokers[k] = b
+		}
+		for _, k := range update.DeletedBrokers {
+			delete(brokers, k)
+		}
+		t.Store(&config.TargetsConfig{Brokers: brokers, Generation: update.Version})
+	}
+	t.version = update.Version
+	t.stream = stream
+	if !t.ackSynced {
+		t.acked = t.version
+	} else if update.Snapshot && t.acked > t.version {
+		// The versions restarted with the snapshot.
+		t.acked = 0
+	}
+	// Forget the replays which are over.
+	for k := range t.replayed {
+		if target, ok := t.GetTargetByKey(k); !ok || target.Replay == nil {
+			delete(t.replayed, k)
+		}
+	}
+
+	if err := stream.Send(t.ack()); err != nil {
+		return fmt.Errorf("failed to acknowledge targets config version %d: %w", t.acked, err)
+	}
+	return nil
+}
+
+// Synced acknowledges the given version of the targets config once the
+// handlers were synced with it. It's only needed with WithAckSynced.
+func (t *Targets) Synced(version int64) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	// The fallback generations aren't versions of the server.
+	if !t.streaming || version <= t.acked || version > t.version {
+		return
+	}
+	t.acked = version
+	if t.stream != nil {
+		// If the stream is broken, the version is acknowledged again after
+		// the snapshot of the next watch is synced.
+		t.stream.Send(t.ack())
+	}
+}
+
+// ack returns the acknowledgement of the targets config applied. It must be
+// called with the lock held.
+func (t *Targets) ack() *WatchRequest {
+	req := &WatchRequest{AppliedVersion: t.acked}
+	for k := range t.replayed {
+		req.ReplayedTargets = append(req.ReplayedTargets, k)
+	}
+	sort.Strings(req.ReplayedTargets)
+	req.FailedHandlers = t.failed
+	if len(t.loops) > 0 {
+		req.DetectedLoops = make(map[string]int64, len(t.loops))
+		for k, n := range t.loops {
+			req.DetectedLoops[k] = n
+		}
+	}
+	return req
+}
+
+// ReportReplayed reports to the server whether the replay of the target caught
+// up in the pod. The report is sent again with the acknowledgements until it
+// changes or the replay is removed from the targets config.
+func (t *Targets) ReportReplayed(targetKey string, caughtUp bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.replayed[targetKey] == caughtUp {
+		return
+	}
+	if caughtUp {
+		t.replayed[targetKey] = true
+	} else {
+		delete(t.replayed, targetKey)
+	}
+	if t.stream != nil {
+		// If the stream is broken, the report is sent with the
+		// acknowledgement of the next watch.
+		t.stream.Send(t.ack())
+	}
+}
+
+// ReportLoopDetected reports to the server that an event looping back to the
+// target was dropped in the pod. The first loop of each target is sent right
+// away, and the later ones with the next acknowledgement.
+func (t *Targets) ReportLoopDetected(targetKey string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.loops[targetKey]++
+	if t.loops[targetKey] == 1 && t.stream != nil {
+		// If the stream is broken, the report is sent with the
+		// acknowledgement of the next watch.
+		t.stream.Send(t.ack())
+	}
+}
+
+// ReportFailedHandlers reports to the server the handlers of the pod which
+// failed, replacing the previous report. The report is sent again with the
+// acknowledgements until it changes.
+func (t *Targets) ReportFailedHandlers(handlers []*HandlerHealth) {
+	sort.Slice(handlers, func(i, j int) bool {
+		return handlers[i].Key < handlers[j].Key
+	})
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if sameHandlers(t.failed, handlers) {
+		return
+	}
+	t.failed = handlers
+	if t.stream != nil {
+		// If the stream is broken, the report is sent with the
+		// acknowledgement of the next watch.
+		t.stream.Send(t.ack())
+	}
+}
+
+func sameHandlers(a, b []*HandlerHealth) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// loadFallback stores the targets config of the fallback.
+func (t *Targets) loadFallback() error {
+	b, err := t.fallback.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to load the fallback targets config: %w", err)
+	}
+	var val config.TargetsConfig
+	if err := proto.Unmarshal(b, &val); err != nil {
+		return fmt.Errorf("failed to load the fallback targets config: %w", err)
+	}
+	t.Store(&val)
+	return nil
+}
+
+// followFallback reloads the fallback when it's updated until the first
+// snapshot is received.
+func (t *Targets) followFallback(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.fallbackChan:
+		}
+		t.mux.Lock()
+		reloaded := false
+		if !t.streaming {
+			if err := t.loadFallback(); err != nil {
+				logging.FromContext(ctx).Warn("failed to reload the fallback targets config", zap.Error(err))
+			} else {
+				reloaded = true
+			}
+		}
+		t.mux.Unlock()
+		if reloaded {
+			t.notify()
+		}
+	}
+}
+
+// notify notifies the external channel that the config cache was updated.
+func (t *Targets) notify() {
+	if t.notifyChan != nil {
+		t.notifyChan <- struct{}{}
+	}
+}
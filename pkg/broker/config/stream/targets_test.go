@@ -0,0 +1,380 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+func TestTargetsFromServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := NewServer(1, nil)
+	b1 := broker("ns", "b1", "a1")
+	srv.UpdateShard(bcKey, 0, targets(b1))
+	client := startServer(ctx, t, srv)
+
+	ch := make(chan struct{})
+	got, err := NewTargets(ctx, client, WithBrokerCell("ns", "bc"), WithPod("pod"), WithNotifyChan(ch))
+	if err != nil {
+		t.Fatalf("NewTargets() unexpected error: %v", err)
+	}
+	assertTargets(t, generation(targets(b1), 1), got)
+	waitForApplied(t, srv, map[string]int64{"pod": 1})
+
+	b2 := broker("ns", "b2", "a2")
+	srv.UpdateShard(bcKey, 0, targets(b2))
+	waitForNotify(t, ch)
+	assertTargets(t, generation(targets(b2), 2), got)
+	waitForApplied(t, srv, map[string]int64{"pod": 2})
+}
+
+func TestTargetsAckSynced(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := NewServer(1, nil)
+	b1 := broker("ns", "b1", "a1")
+	srv.UpdateShard(bcKey, 0, targets(b1))
+	client := startServer(ctx, t, srv)
+
+	ch := make(chan struct{})
+	got, err := NewTargets(ctx, client, WithBrokerCell("ns", "bc"), WithPod("pod"), WithNotifyChan(ch), WithAckSynced())
+	if err != nil {
+		t.Fatalf("NewTargets() unexpected error: %v", err)
+	}
+	assertTargets(t, generation(targets(b1), 1), got)
+	// The version isn't acknowledged until it's synced.
+	waitForApplied(t, srv, map[string]int64{"pod": 0})
+	got.(*Targets).Synced(1)
+	waitForApplied(t, srv, map[string]int64{"pod": 1})
+
+	b2 := broker("ns", "b2", "a2")
+	srv.UpdateShard(bcKey, 0, targets(b2))
+	waitForNotify(t, ch)
+	assertTargets(t, generation(targets(b2), 2), got)
+	// Versions which aren't applied yet are ignored.
+	got.(*Targets).Synced(3)
+	time.Sleep(100 * time.Millisecond)
+	waitForApplied(t, srv, map[string]int64{"pod": 1})
+	got.(*Targets).Synced(2)
+	waitForApplied(t, srv, map[string]int64{"pod": 2})
+}
+
+func TestTargetsFallback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The server has no snapshot until all its shards are set.
+	srv := NewServer(2, nil)
+	client := startServer(ctx, t, srv)
+	b1 := broker("ns", "b1", "a1")
+	fallback := memory.NewTargets(targets(b1))
+	fallbackCh := make(chan struct{})
+
+	ch := make(chan struct{})
+	got, err := NewTargets(ctx, client, WithBrokerCell("ns", "bc"), WithPod("pod"), WithNotifyChan(ch), WithFallback(fallback, fallbackCh))
+	if err != nil {
+		t.Fatalf("NewTargets() unexpected error: %v", err)
+	}
+	assertTargets(t, targets(b1), got)
+
+	// The fallback is reloaded until the first snapshot.
+	fallback.MutateBroker("ns", "b2", func(m config.BrokerMutation) { m.SetAddress("a2") })
+	fallbackCh <- struct{}{}
+	waitForNotify(t, ch)
+	if _, ok := got.GetBroker("ns", "b2"); !ok {
+		t.Error("broker added to the fallback is missing")
+	}
+
+	b3 := broker("ns", "b3", "a3")
+	srv.UpdateShard(bcKey, 0, targets(b3))
+	srv.UpdateShard(bcKey, 1, targets())
+	waitForNotify(t, ch)
+	assertTargets(t, generation(targets(b3), 1), got)
+	waitForApplied(t, srv, map[string]int64{"pod": 1})
+
+	// The fallback is ignored once streaming. The second notification is only
+	// received once the first one was handled.
+	fallback.MutateBroker("ns", "b4", func(m config.BrokerMutation) { m.SetAddress("a4") })
+	fallbackCh <- struct{}{}
+	fallbackCh <- struct{}{}
+	assertTargets(t, generation(targets(b3), 1), got)
+}
+
+func TestTargetsReportReplayed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := NewServer(1, nil)
+	b := broker("ns", "b", "a")
+	target := &config.Target{
+		Namespace: "ns",
+		Broker:    "b",
+		Name:      "t",
+		Replay:    &config.Replay{Queue: &config.Queue{Topic: "decouple", Subscription: "replay"}},
+	}
+	b.Targets = map[string]*config.Target{target.Name: target}
+	srv.UpdateShard(bcKey, 0, targets(b))
+	client := startServer(ctx, t, srv)
+
+	ch := make(chan struct{})
+	got, err := NewTargets(ctx, client, WithBrokerCell("ns", "bc"), WithPod("pod"), WithNotifyChan(ch))
+	if err != nil {
+		t.Fatalf("NewTargets() unexpected error: %v", err)
+	}
+	if srv.ReplayedPods(bcKey, target.Key())["pod"] {
+		t.Error("replay caught up before it was reported")
+	}
+	got.(*Targets).ReportReplayed(target.Key(), true)
+	waitForReplayed(t, srv, target.Key(), true)
+
+	// The report is withdrawn when events are replayed again.
+	got.(*Targets).ReportReplayed(target.Key(), false)
+	waitForReplayed(t, srv, target.Key(), false)
+	got.(*Targets).ReportReplayed(target.Key(), true)
+	waitForReplayed(t, srv, target.Key(), true)
+
+	// The report is dropped once the replay is over.
+	b2 := proto.Clone(b).(*config.Broker)
+	b2.Targets[target.Name].Replay = nil
+	srv.UpdateShard(bcKey, 0, targets(b2))
+	waitForNotify(t, ch)
+	waitForReplayed(t, srv, target.Key(), false)
+}
+
+func TestTargetsReportFailedHandlers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := NewServer(1, nil)
+	changed := make(chan string, 10)
+	srv.OnHealthChanged(func(bcKey string) {
+		changed <- bcKey
+	})
+	srv.UpdateShard(bcKey, 0, targets(broker("ns", "b", "a")))
+	client := startServer(ctx, t, srv)
+
+	got, err := NewTargets(ctx, client, WithBrokerCell("ns", "bc"), WithPod("pod"))
+	if err != nil {
+		t.Fatalf("NewTargets() unexpected error: %v", err)
+	}
+	failed := []*HandlerHealth{{Key: "ns/b", LastError: "permission denied", Restarts: 2}}
+	got.(*Targets).ReportFailedHandlers(failed)
+	waitForFailedHandlers(t, srv, map[string][]*HandlerHealth{"pod": failed})
+	if bc := <-changed; bc != bcKey {
+		t.Errorf("health changed for brokercell %q, want %q", bc, bcKey)
+	}
+
+	// The same report isn't sent again.
+	got.(*Targets).ReportFailedHandlers([]*HandlerHealth{{Key: "ns/b", LastError: "permission denied", Restarts: 2}})
+	got.(*Targets).ReportFailedHandlers(nil)
+	waitForFailedHandlers(t, srv, map[string][]*HandlerHealth{})
+	<-changed
+	select {
+	case <-changed:
+		t.Error("health changed without a new report")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTargetsReportLoopDetected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := NewServer(1, nil)
+	type loop struct {
+		bcKey, targetKey string
+		count            int64
+	}
+	loops := make(chan loop, 10)
+	srv.OnLoopDetected(func(bcKey, targetKey string, count int64) {
+		loops <- loop{bcKey, targetKey, count}
+	})
+	srv.UpdateShard(bcKey, 0, targets(broker("ns", "b", "a")))
+	client := startServer(ctx, t, srv)
+
+	ch := make(chan struct{})
+	got, err := NewTargets(ctx, client, WithBrokerCell("ns", "bc"), WithPod("pod"), WithNotifyChan(ch))
+	if err != nil {
+		t.Fatalf("NewTargets() unexpected error: %v", err)
+	}
+	// The first loop is reported right away.
+	got.(*Targets).ReportLoopDetected("ns/b/t")
+	got.(*Targets).ReportLoopDetected("ns/b/t")
+	if l := <-loops; l != (loop{bcKey, "ns/b/t", 1}) {
+		t.Errorf("loop detected got=%+v, want one loop of ns/b/t", l)
+	}
+	select {
+	case l := <-loops:
+		t.Errorf("loop detected before the next acknowledgement: %+v", l)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The later loops are reported with the next acknowledgement.
+	srv.UpdateShard(bcKey, 0, targets(broker("ns", "b", "b")))
+	waitForNotify(t, ch)
+	if l := <-loops; l != (loop{bcKey, "ns/b/t", 1}) {
+		t.Errorf("loop detected got=%+v, want one more loop of ns/b/t", l)
+	}
+}
+
+func waitForFailedHandlers(t *testing.T, srv *Server, want map[string][]*HandlerHealth) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := srv.FailedHandlers(bcKey)
+		if cmp.Equal(want, got, protocmp.Transform()) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("failed handlers (-want,+got): %v", cmp.Diff(want, got, protocmp.Transform()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func waitForReplayed(t *testing.T, srv *Server, targetKey string, want bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.ReplayedPods(bcKey, targetKey)["pod"] != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for replayed=%v", want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// fakeWatchClient is a TargetsWatcher_WatchClient receiving the given updates.
+type fakeWatchClient struct {
+	grpc.ClientStream
+	updates []*TargetsUpdate
+	sent    chan *WatchRequest
+}
+
+func (c *fakeWatchClient) Send(r *WatchRequest) error {
+	c.sent <- r
+	return nil
+}
+
+func (c *fakeWatchClient) Recv() (*TargetsUpdate, error) {
+	if len(c.updates) == 0 {
+		return nil, io.EOF
+	}
+	u := c.updates[0]
+	c.updates = c.updates[1:]
+	return u, nil
+}
+
+// fakeWatcherClient is a TargetsWatcherClient starting the given watches in order.
+type fakeWatcherClient struct {
+	watches chan *fakeWatchClient
+}
+
+func (c *fakeWatcherClient) Watch(ctx context.Context, _ ...grpc.CallOption) (TargetsWatcher_WatchClient, error) {
+	select {
+	case w := <-c.watches:
+		return w, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestTargetsWatchAgain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b1, b2, b3 := broker("ns", "b1", "a1"), broker("ns", "b2", "a2"), broker("ns", "b3", "a3")
+	sent := make(chan *WatchRequest, 100)
+	client := &fakeWatcherClient{watches: make(chan *fakeWatchClient, 2)}
+	client.watches <- &fakeWatchClient{
+		sent: sent,
+		updates: []*TargetsUpdate{
+			{Version: 1, Snapshot: true, Brokers: targets(b1).Brokers},
+			{Version: 2, Brokers: targets(b2).Brokers},
+			// Version 3 is missing so the targets are watched again.
+			{Version: 4, DeletedBrokers: []string{b1.Key()}},
+		},
+	}
+
+	ch := make(chan struct{})
+	got, err := NewTargets(ctx, client, WithBrokerCell("ns", "bc"), WithPod("pod"), WithNotifyChan(ch))
+	if err != nil {
+		t.Fatalf("NewTargets() unexpected error: %v", err)
+	}
+	waitForNotify(t, ch)
+	assertTargets(t, generation(targets(b1, b2), 2), got)
+
+	client.watches <- &fakeWatchClient{
+		sent:    sent,
+		updates: []*TargetsUpdate{{Version: 4, Snapshot: true, Brokers: targets(b2, b3).Brokers}},
+	}
+	waitForNotify(t, ch)
+	assertTargets(t, generation(targets(b2, b3), 4), got)
+
+	want := []*WatchRequest{
+		{BrokercellNamespace: "ns", BrokercellName: "bc", Pod: "pod"},
+		{AppliedVersion: 1},
+		{AppliedVersion: 2},
+		{BrokercellNamespace: "ns", BrokercellName: "bc", Pod: "pod"},
+		{AppliedVersion: 4},
+	}
+	var gotSent []*WatchRequest
+	for range want {
+		gotSent = append(gotSent, <-sent)
+	}
+	if diff := cmp.Diff(want, gotSent, protocmp.Transform()); diff != "" {
+		t.Errorf("sent requests (-want,+got): %v", diff)
+	}
+}
+
+func TestTargetsFirstUpdateNotSnapshot(t *testing.T) {
+	client := &fakeWatcherClient{watches: make(chan *fakeWatchClient, 1)}
+	client.watches <- &fakeWatchClient{
+		sent:    make(chan *WatchRequest, 10),
+		updates: []*TargetsUpdate{{Version: 1}},
+	}
+	if _, err := NewTargets(context.Background(), client); err == nil {
+		t.Error("NewTargets() expected error, got nil")
+	}
+}
+
+func generation(t *config.TargetsConfig, g int64) *config.TargetsConfig {
+	t.Generation = g
+	return t
+}
+
+func assertTargets(t *testing.T, want *config.TargetsConfig, got config.ReadonlyTargets) {
+	t.Helper()
+	if diff := cmp.Diff(want, got.(*Targets).Load(), protocmp.Transform()); diff != "" {
+		t.Errorf("targets (-want,+got): %v", diff)
+	}
+}
+
+func waitForNotify(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+}
@@ -0,0 +1,579 @@
+//
+//Copyright 2020 Google LLC
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.21.0
+// 	protoc        v3.8.0
+// source: pkg/broker/config/stream/watch.proto
+
+package stream
+
+import (
+	context "context"
+	reflect "reflect"
+	sync "sync"
+
+	proto "github.com/golang/protobuf/proto"
+	config "github.com/google/knative-gcp/pkg/broker/config"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+type WatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The namespace of the brokercell whose targets config is watched.
+	BrokercellNamespace string `protobuf:"bytes,1,opt,name=brokercell_namespace,json=brokercellNamespace,proto3" json:"brokercell_namespace,omitempty"`
+	// The name of the brokercell whose targets config is watched.
+	BrokercellName string `protobuf:"bytes,2,opt,name=brokercell_name,json=brokercellName,proto3" json:"brokercell_name,omitempty"`
+	// The name of the pod watching the targets config.
+	Pod string `protobuf:"bytes,3,opt,name=pod,proto3" json:"pod,omitempty"`
+	// The version of the targets config applied by the pod.
+	AppliedVersion int64 `protobuf:"varint,4,opt,name=applied_version,json=appliedVersion,proto3" json:"applied_version,omitempty"`
+	// The keys of the targets whose replay caught up in the pod, i.e. the pod
+	// received an event published after the replay started.
+	ReplayedTargets []string `protobuf:"bytes,5,rep,name=replayed_targets,json=replayedTargets,proto3" json:"replayed_targets,omitempty"`
+	// The handlers of the pod which failed and are waiting to be restarted.
+	FailedHandlers []*HandlerHealth `protobuf:"bytes,6,rep,name=failed_handlers,json=failedHandlers,proto3" json:"failed_handlers,omitempty"`
+	// The number of events the pod dropped because they looped back to a
+	// target, keyed by target key, since the pod started.
+	DetectedLoops map[string]int64 `protobuf:"bytes,7,rep,name=detected_loops,json=detectedLoops,proto3" json:"detected_loops,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_broker_config_stream_watch_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_broker_config_stream_watch_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_broker_config_stream_watch_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WatchRequest) GetBrokercellNamespace() string {
+	if x != nil {
+		return x.BrokercellNamespace
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetBrokercellName() string {
+	if x != nil {
+		return x.BrokercellName
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetPod() string {
+	if x != nil {
+		return x.Pod
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetAppliedVersion() int64 {
+	if x != nil {
+		return x.AppliedVersion
+	}
+	return 0
+}
+
+func (x *WatchRequest) GetReplayedTargets() []string {
+	if x != nil {
+		return x.ReplayedTargets
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetFailedHandlers() []*HandlerHealth {
+	if x != nil {
+		return x.FailedHandlers
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetDetectedLoops() map[string]int64 {
+	if x != nil {
+		return x.DetectedLoops
+	}
+	return nil
+}
+
+type HandlerHealth struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The key of the broker of a fanout handler, or of the target of a retry
+	// handler.
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// The last error of the handler.
+	LastError string `protobuf:"bytes,2,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	// The number of times the handler was restarted after failing.
+	Restarts int32 `protobuf:"varint,3,opt,name=restarts,proto3" json:"restarts,omitempty"`
+}
+
+func (x *HandlerHealth) Reset() {
+	*x = HandlerHealth{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_broker_config_stream_watch_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HandlerHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandlerHealth) ProtoMessage() {}
+
+func (x *HandlerHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_broker_config_stream_watch_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandlerHealth.ProtoReflect.Descriptor instead.
+func (*HandlerHealth) Descriptor() ([]byte, []int) {
+	return file_pkg_broker_config_stream_watch_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *HandlerHealth) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HandlerHealth) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *HandlerHealth) GetRestarts() int32 {
+	if x != nil {
+		return x.Restarts
+	}
+	return 0
+}
+
+type TargetsUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The version of the targets config after the update. It increases with
+	// each change of the targets config of the brokercell.
+	Version int64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// Whether the update is a snapshot of the whole targets config rather than
+	// the changes since the previous version.
+	Snapshot bool `protobuf:"varint,2,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	// The brokers added or changed, keyed by broker key. For a snapshot, all
+	// the brokers.
+	Brokers map[string]*config.Broker `protobuf:"bytes,3,rep,name=brokers,proto3" json:"brokers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// The keys of the brokers deleted.
+	DeletedBrokers []string `protobuf:"bytes,4,rep,name=deleted_brokers,json=deletedBrokers,proto3" json:"deleted_brokers,omitempty"`
+}
+
+func (x *TargetsUpdate) Reset() {
+	*x = TargetsUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_broker_config_stream_watch_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TargetsUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TargetsUpdate) ProtoMessage() {}
+
+func (x *TargetsUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_broker_config_stream_watch_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TargetsUpdate.ProtoReflect.Descriptor instead.
+func (*TargetsUpdate) Descriptor() ([]byte, []int) {
+	return file_pkg_broker_config_stream_watch_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *TargetsUpdate) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *TargetsUpdate) GetSnapshot() bool {
+	if x != nil {
+		return x.Snapshot
+	}
+	return false
+}
+
+func (x *TargetsUpdate) GetBrokers() map[string]*config.Broker {
+	if x != nil {
+		return x.Brokers
+	}
+	return nil
+}
+
+func (x *TargetsUpdate) GetDeletedBrokers() []string {
+	if x != nil {
+		return x.DeletedBrokers
+	}
+	return nil
+}
+
+var File_pkg_broker_config_stream_watch_proto protoreflect.FileDescriptor
+
+var file_pkg_broker_config_stream_watch_proto_rawDesc = []byte{
+	0x0a, 0x24, 0x70, 0x6b, 0x67, 0x2f, 0x62, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x2f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2f, 0x77, 0x61, 0x74, 0x63, 0x68,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x1a, 0x1f,
+	0x70, 0x6b, 0x67, 0x2f, 0x62, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x2f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
+	0xa2, 0x03, 0x0a, 0x0c, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x31, 0x0a, 0x14, 0x62, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x63, 0x65, 0x6c, 0x6c, 0x5f, 0x6e,
+	0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x13,
+	0x62, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x63, 0x65, 0x6c, 0x6c, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70,
+	0x61, 0x63, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x62, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x63, 0x65, 0x6c,
+	0x6c, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x62, 0x72,
+	0x6f, 0x6b, 0x65, 0x72, 0x63, 0x65, 0x6c, 0x6c, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x10, 0x0a, 0x03,
+	0x70, 0x6f, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x70, 0x6f, 0x64, 0x12, 0x27,
+	0x0a, 0x0f, 0x61, 0x70, 0x70, 0x6c, 0x69, 0x65, 0x64, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x61, 0x70, 0x70, 0x6c, 0x69, 0x65, 0x64,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x29, 0x0a, 0x10, 0x72, 0x65, 0x70, 0x6c, 0x61,
+	0x79, 0x65, 0x64, 0x5f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0f, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x79, 0x65, 0x64, 0x54, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x73, 0x12, 0x3e, 0x0a, 0x0f, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x5f, 0x68, 0x61, 0x6e,
+	0x64, 0x6c, 0x65, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x2e, 0x48, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x72, 0x48, 0x65, 0x61, 0x6c,
+	0x74, 0x68, 0x52, 0x0e, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x48, 0x61, 0x6e, 0x64, 0x6c, 0x65,
+	0x72, 0x73, 0x12, 0x4e, 0x0a, 0x0e, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x65, 0x64, 0x5f, 0x6c,
+	0x6f, 0x6f, 0x70, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x73, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x2e, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x65, 0x64, 0x4c, 0x6f, 0x6f, 0x70, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x52, 0x0d, 0x64, 0x65, 0x74, 0x65, 0x63, 0x74, 0x65, 0x64, 0x4c, 0x6f, 0x6f,
+	0x70, 0x73, 0x1a, 0x40, 0x0a, 0x12, 0x44, 0x65, 0x74, 0x65, 0x63, 0x74, 0x65, 0x64, 0x4c, 0x6f,
+	0x6f, 0x70, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x22, 0x5c, 0x0a, 0x0d, 0x48, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x72, 0x48,
+	0x65, 0x61, 0x6c, 0x74, 0x68, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x5f,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6c, 0x61, 0x73,
+	0x74, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x74, 0x61, 0x72,
+	0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x72, 0x65, 0x73, 0x74, 0x61, 0x72,
+	0x74, 0x73, 0x22, 0xf8, 0x01, 0x0a, 0x0d, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1a,
+	0x0a, 0x08, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x08, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x3c, 0x0a, 0x07, 0x62, 0x72,
+	0x6f, 0x6b, 0x65, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x55, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x07, 0x62, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x64, 0x65, 0x6c, 0x65,
+	0x74, 0x65, 0x64, 0x5f, 0x62, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0e, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72,
+	0x73, 0x1a, 0x4a, 0x0a, 0x0c, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x24, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x42, 0x72, 0x6f, 0x6b,
+	0x65, 0x72, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x32, 0x4a, 0x0a,
+	0x0e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x57, 0x61, 0x74, 0x63, 0x68, 0x65, 0x72, 0x12,
+	0x38, 0x0a, 0x05, 0x57, 0x61, 0x74, 0x63, 0x68, 0x12, 0x14, 0x2e, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15,
+	0x2e, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x55,
+	0x70, 0x64, 0x61, 0x74, 0x65, 0x28, 0x01, 0x30, 0x01, 0x42, 0x38, 0x5a, 0x36, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x6b,
+	0x6e, 0x61, 0x74, 0x69, 0x76, 0x65, 0x2d, 0x67, 0x63, 0x70, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x62,
+	0x72, 0x6f, 0x6b, 0x65, 0x72, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2f, 0x73, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_pkg_broker_config_stream_watch_proto_rawDescOnce sync.Once
+	file_pkg_broker_config_stream_watch_proto_rawDescData = file_pkg_broker_config_stream_watch_proto_rawDesc
+)
+
+func file_pkg_broker_config_stream_watch_proto_rawDescGZIP() []byte {
+	file_pkg_broker_config_stream_watch_proto_rawDescOnce.Do(func() {
+		file_pkg_broker_config_stream_watch_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_broker_config_stream_watch_proto_rawDescData)
+	})
+	return file_pkg_broker_config_stream_watch_proto_rawDescData
+}
+
+var file_pkg_broker_config_stream_watch_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_pkg_broker_config_stream_watch_proto_goTypes = []interface{}{
+	(*WatchRequest)(nil),  // 0: stream.WatchRequest
+	(*HandlerHealth)(nil), // 1: stream.HandlerHealth
+	(*TargetsUpdate)(nil), // 2: stream.TargetsUpdate
+	nil,                   // 3: stream.WatchRequest.DetectedLoopsEntry
+	nil,                   // 4: stream.TargetsUpdate.BrokersEntry
+	(*config.Broker)(nil), // 5: config.Broker
+}
+var file_pkg_broker_config_stream_watch_proto_depIdxs = []int32{
+	1, // 0: stream.WatchRequest.failed_handlers:type_name -> stream.HandlerHealth
+	3, // 1: stream.WatchRequest.detected_loops:type_name -> stream.WatchRequest.DetectedLoopsEntry
+	4, // 2: stream.TargetsUpdate.brokers:type_name -> stream.TargetsUpdate.BrokersEntry
+	5, // 3: stream.TargetsUpdate.BrokersEntry.value:type_name -> config.Broker
+	0, // 4: stream.TargetsWatcher.Watch:input_type -> stream.WatchRequest
+	2, // 5: stream.TargetsWatcher.Watch:output_type -> stream.TargetsUpdate
+	5, // [5:6] is the sub-list for method output_type
+	4, // [4:5] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_pkg_broker_config_stream_watch_proto_init() }
+func file_pkg_broker_config_stream_watch_proto_init() {
+	if File_pkg_broker_config_stream_watch_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_pkg_broker_config_stream_watch_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_broker_config_stream_watch_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HandlerHealth); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_broker_config_stream_watch_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TargetsUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_pkg_broker_config_stream_watch_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pkg_broker_config_stream_watch_proto_goTypes,
+		DependencyIndexes: file_pkg_broker_config_stream_watch_proto_depIdxs,
+		MessageInfos:      file_pkg_broker_config_stream_watch_proto_msgTypes,
+	}.Build()
+	File_pkg_broker_config_stream_watch_proto = out.File
+	file_pkg_broker_config_stream_watch_proto_rawDesc = nil
+	file_pkg_broker_config_stream_watch_proto_goTypes = nil
+	file_pkg_broker_config_stream_watch_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// TargetsWatcherClient is the client API for TargetsWatcher service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type TargetsWatcherClient interface {
+	// Watch sends a snapshot of the targets config of a brokercell, then the
+	// changes to it. The first request identifies the brokercell and the pod,
+	// the following ones acknowledge the versions applied by the pod.
+	Watch(ctx context.Context, opts ...grpc.CallOption) (TargetsWatcher_WatchClient, error)
+}
+
+type targetsWatcherClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTargetsWatcherClient(cc grpc.ClientConnInterface) TargetsWatcherClient {
+	return &targetsWatcherClient{cc}
+}
+
+func (c *targetsWatcherClient) Watch(ctx context.Context, opts ...grpc.CallOption) (TargetsWatcher_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_TargetsWatcher_serviceDesc.Streams[0], "/stream.TargetsWatcher/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &targetsWatcherWatchClient{stream}
+	return x, nil
+}
+
+type TargetsWatcher_WatchClient interface {
+	Send(*WatchRequest) error
+	Recv() (*TargetsUpdate, error)
+	grpc.ClientStream
+}
+
+type targetsWatcherWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *targetsWatcherWatchClient) Send(m *WatchRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *targetsWatcherWatchClient) Recv() (*TargetsUpdate, error) {
+	m := new(TargetsUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TargetsWatcherServer is the server API for TargetsWatcher service.
+type TargetsWatcherServer interface {
+	// Watch sends a snapshot of the targets config of a brokercell, then the
+	// changes to it. The first request identifies the brokercell and the pod,
+	// the following ones acknowledge the versions applied by the pod.
+	Watch(TargetsWatcher_WatchServer) error
+}
+
+// UnimplementedTargetsWatcherServer can be embedded to have forward compatible implementations.
+type UnimplementedTargetsWatcherServer struct {
+}
+
+func (*UnimplementedTargetsWatcherServer) Watch(TargetsWatcher_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+
+func RegisterTargetsWatcherServer(s *grpc.Server, srv TargetsWatcherServer) {
+	s.RegisterService(&_TargetsWatcher_serviceDesc, srv)
+}
+
+func _TargetsWatcher_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TargetsWatcherServer).Watch(&targetsWatcherWatchServer{stream})
+}
+
+type TargetsWatcher_WatchServer interface {
+	Send(*TargetsUpdate) error
+	Recv() (*WatchRequest, error)
+	grpc.ServerStream
+}
+
+type targetsWatcherWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *targetsWatcherWatchServer) Send(m *TargetsUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *targetsWatcherWatchServer) Recv() (*WatchRequest, error) {
+	m := new(WatchRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _TargetsWatcher_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "stream.TargetsWatcher",
+	HandlerType: (*TargetsWatcherServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _TargetsWatcher_Watch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pkg/broker/config/stream/watch.proto",
+}
@@ -21,6 +21,9 @@ import (
 	"io/ioutil"
 	"log"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/google/knative-gcp/pkg/broker/config"
@@ -32,8 +35,10 @@ const (
 )
 
 // Targets implements config.ReadonlyTargets with data
-// loaded from a file.
-// It also watches the file for any changes and will automatically
+// loaded from a file and its shards. The shards are the files
+// in the same directory named after the file with a numeric
+// suffix, e.g. targets-1, and their brokers are merged.
+// It also watches the files for any changes and will automatically
 // refresh the in memory cache.
 type Targets struct {
 	config.CachedTargets
@@ -88,10 +93,10 @@ func (t *Targets) watchWith(watcher *fsnotify.Watcher) error {
 				}
 				currentConfigFile, _ := filepath.EvalSymlinks(t.path)
 
-				// Re-sync if the file was updated/created or
+				// Re-sync if the file or a shard was updated/created or
 				// if the real file was replaced.
 				const writeOrCreateMask = fsnotify.Write | fsnotify.Create
-				if (filepath.Clean(event.Name) == configFile &&
+				if ((filepath.Clean(event.Name) == configFile || isShard(configFile, event.Name)) &&
 					event.Op&writeOrCreateMask != 0) ||
 					(currentConfigFile != "" && currentConfigFile != realConfigFile) {
 					realConfigFile = currentConfigFile
@@ -115,20 +120,65 @@ func (t *Targets) watchWith(watcher *fsnotify.Watcher) error {
 }
 
 func (t *Targets) sync() error {
-	b, err := t.readFile()
+	shards, err := t.shardFiles()
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		return fmt.Errorf("failed to list config shards: %w", err)
 	}
-
-	var val config.TargetsConfig
-	if err := proto.Unmarshal(b, &val); err != nil {
-		return fmt.Errorf("failed to unmarshal config file: %w", err)
+	vals := make([]*config.TargetsConfig, 0, len(shards)+1)
+	for _, path := range append([]string{t.path}, shards...) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		var val config.TargetsConfig
+		if err := proto.Unmarshal(b, &val); err != nil {
+			return fmt.Errorf("failed to unmarshal config file %s: %w", path, err)
+		}
+		vals = append(vals, &val)
 	}
 
-	t.Store(&val)
+	if len(vals) == 1 {
+		t.Store(vals[0])
+	} else {
+		t.Store(config.Merge(vals...))
+	}
 	return nil
 }
 
-func (t *Targets) readFile() ([]byte, error) {
-	return ioutil.ReadFile(t.path)
+// shardFiles returns the shards of the config file ordered by their index.
+func (t *Targets) shardFiles() ([]string, error) {
+	matches, err := filepath.Glob(t.path + "-*")
+	if err != nil {
+		return nil, err
+	}
+	configFile := filepath.Clean(t.path)
+	var shards []string
+	for _, m := range matches {
+		if isShard(configFile, m) {
+			shards = append(shards, m)
+		}
+	}
+	sort.Slice(shards, func(i, j int) bool {
+		return shardIndex(configFile, shards[i]) < shardIndex(configFile, shards[j])
+	})
+	return shards, nil
+}
+
+// isShard returns true if the file is a shard of the config file.
+func isShard(configFile, name string) bool {
+	return shardIndex(configFile, name) > 0
+}
+
+// shardIndex returns the index of the shard of the config file,
+// or -1 if the file is not a shard.
+func shardIndex(configFile, name string) int {
+	suffix := strings.TrimPrefix(filepath.Clean(name), configFile+"-")
+	if suffix == filepath.Clean(name) {
+		return -1
+	}
+	i, err := strconv.Atoi(suffix)
+	if err != nil || i <= 0 || strconv.Itoa(i) != suffix {
+		return -1
+	}
+	return i
 }
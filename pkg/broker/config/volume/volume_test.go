@@ -19,6 +19,7 @@ package volume
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -173,6 +174,60 @@ func TestSyncConfigFromFile(t *testing.T) {
 	}
 }
 
+func TestSyncShardedConfigFromFiles(t *testing.T) {
+	newShard := func(brokers ...string) *config.TargetsConfig {
+		c := &config.TargetsConfig{Brokers: make(map[string]*config.Broker)}
+		for _, name := range brokers {
+			b := &config.Broker{Id: "b-uid-" + name, Name: name, Namespace: "ns-" + name, State: config.State_READY}
+			c.Brokers[b.Key()] = b
+		}
+		return c
+	}
+	dir, err := ioutil.TempDir("", "configtest-*")
+	if err != nil {
+		t.Fatalf("unexpected error from creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "targets")
+	writeFile := func(name string, c *config.TargetsConfig) {
+		b, _ := proto.Marshal(c)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatalf("unexpected error from writing config file: %v", err)
+		}
+	}
+	writeFile("targets", newShard("broker1"))
+	writeFile("targets-3", newShard("broker2", "broker3"))
+	// Files that are not shards are ignored.
+	if err := ioutil.WriteFile(filepath.Join(dir, "targets-x"), []byte("not a shard"), 0644); err != nil {
+		t.Fatalf("unexpected error from writing file: %v", err)
+	}
+	if err := ioutil.WriteFile(path+".txt", []byte("not a shard"), 0644); err != nil {
+		t.Fatalf("unexpected error from writing file: %v", err)
+	}
+
+	ch := make(chan struct{}, 1)
+	targets, err := NewTargetsFromFile(WithPath(path), WithNotifyChan(ch))
+	if err != nil {
+		t.Fatalf("unexpected error from NewTargetsFromFile: %v", err)
+	}
+	want := newShard("broker1", "broker2", "broker3")
+	if got := targets.(*Targets).Load(); !proto.Equal(want, got) {
+		t.Errorf("initial targets got=%+v, want=%+v", got, want)
+	}
+
+	b, _ := proto.Marshal(newShard("broker4"))
+	atomicWriteFile(t, path+"-12", b)
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timeout waiting for the notification")
+	}
+	want = newShard("broker1", "broker2", "broker3", "broker4")
+	if got := targets.(*Targets).Load(); !proto.Equal(want, got) {
+		t.Errorf("updated targets got=%+v, want=%+v", got, want)
+	}
+}
+
 func atomicWriteFile(t *testing.T, file string, bytes []byte) {
 	t.Helper()
 	// In order to more closely replicate how K8s writes ConfigMaps to the file system, we will
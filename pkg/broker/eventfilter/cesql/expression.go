@@ -0,0 +1,382 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cesql implements a subset of CloudEvents SQL to filter events
+// by their attributes. See https://github.com/cloudevents/spec/blob/master/cesql.md.
+//
+// Values are strings, 32-bit integers or booleans. Attributes are always
+// strings and are cast to the type required by the operator. Evaluating an
+// expression fails if it references a missing attribute or a cast fails.
+package cesql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expression is a compiled CloudEvents SQL expression.
+type Expression struct {
+	expr string
+	root node
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.expr
+}
+
+// Matches evaluates the expression against the event attributes and reports
+// whether the result is true.
+func (e *Expression) Matches(attrs map[string]string) (bool, error) {
+	v, err := e.root.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	return toBool(v)
+}
+
+// value is either a string, an int32 or a bool.
+type value interface{}
+
+type node interface {
+	eval(attrs map[string]string) (value, error)
+}
+
+type literalNode struct {
+	value value
+}
+
+func (n *literalNode) eval(map[string]string) (value, error) {
+	return n.value, nil
+}
+
+type attributeNode struct {
+	name string
+}
+
+func (n *attributeNode) eval(attrs map[string]string) (value, error) {
+	v, ok := attrs[n.name]
+	if !ok {
+		return nil, fmt.Errorf("missing attribute %q", n.name)
+	}
+	return v, nil
+}
+
+type existsNode struct {
+	attribute string
+}
+
+func (n *existsNode) eval(attrs map[string]string) (value, error) {
+	_, ok := attrs[n.attribute]
+	return ok, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(attrs map[string]string) (value, error) {
+	b, err := evalBool(n.operand, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+type negateNode struct {
+	operand node
+}
+
+func (n *negateNode) eval(attrs map[string]string) (value, error) {
+	i, err := evalInt(n.operand, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return -i, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(attrs map[string]string) (value, error) {
+	switch n.op {
+	case "AND", "OR", "XOR":
+		return n.evalLogical(attrs)
+	case "=", "!=":
+		l, err := n.left.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		r, err := n.right.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		eq, err := equal(l, r)
+		if err != nil {
+			return nil, err
+		}
+		return eq == (n.op == "="), nil
+	}
+
+	l, err := evalInt(n.left, attrs)
+	if err != nil {
+		return nil, err
+	}
+	r, err := evalInt(n.right, attrs)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/", "%":
+		if r == 0 {
+			return nil, errors.New("division by zero")
+		}
+		if n.op == "/" {
+			return l / r, nil
+		}
+		return l % r, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func (n *binaryNode) evalLogical(attrs map[string]string) (value, error) {
+	l, err := evalBool(n.left, attrs)
+	if err != nil {
+		return nil, err
+	}
+	// Short circuit AND and OR.
+	if (n.op == "AND" && !l) || (n.op == "OR" && l) {
+		return l, nil
+	}
+	r, err := evalBool(n.right, attrs)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "XOR" {
+		return l != r, nil
+	}
+	return r, nil
+}
+
+type likeNode struct {
+	operand node
+	pattern *regexp.Regexp
+	negate  bool
+}
+
+// newLikeNode translates the LIKE pattern into a regular expression. % matches
+// any sequence of characters, _ matches a single character and \ escapes them.
+func newLikeNode(operand node, pattern string, negate bool) *likeNode {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '%':
+			b.WriteString("(?s:.*)")
+		case c == '_':
+			b.WriteString("(?s:.)")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return &likeNode{operand: operand, pattern: regexp.MustCompile(b.String()), negate: negate}
+}
+
+func (n *likeNode) eval(attrs map[string]string) (value, error) {
+	v, err := n.operand.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	return n.pattern.MatchString(toString(v)) != n.negate, nil
+}
+
+type inNode struct {
+	operand node
+	set     []node
+	negate  bool
+}
+
+func (n *inNode) eval(attrs map[string]string) (value, error) {
+	v, err := n.operand.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range n.set {
+		ev, err := e.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		eq, err := equal(v, ev)
+		if err != nil {
+			return nil, err
+		}
+		if eq {
+			return !n.negate, nil
+		}
+	}
+	return n.negate, nil
+}
+
+type function struct {
+	arity int
+	call  func(args []value) (value, error)
+}
+
+var functions = map[string]function{
+	"LENGTH": {arity: 1, call: func(args []value) (value, error) {
+		return int32(len([]rune(toString(args[0])))), nil
+	}},
+	"LOWER": {arity: 1, call: func(args []value) (value, error) {
+		return strings.ToLower(toString(args[0])), nil
+	}},
+	"UPPER": {arity: 1, call: func(args []value) (value, error) {
+		return strings.ToUpper(toString(args[0])), nil
+	}},
+}
+
+type functionNode struct {
+	fn   function
+	args []node
+}
+
+func (n *functionNode) eval(attrs map[string]string) (value, error) {
+	args := make([]value, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return n.fn.call(args)
+}
+
+func evalBool(n node, attrs map[string]string) (bool, error) {
+	v, err := n.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	return toBool(v)
+}
+
+func evalInt(n node, attrs map[string]string) (int32, error) {
+	v, err := n.eval(attrs)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(v)
+}
+
+// equal compares two values. If their types differ, both are cast to
+// boolean if either is a boolean, otherwise to integer.
+func equal(l, r value) (bool, error) {
+	switch {
+	case isBool(l) || isBool(r):
+		lb, err := toBool(l)
+		if err != nil {
+			return false, err
+		}
+		rb, err := toBool(r)
+		if err != nil {
+			return false, err
+		}
+		return lb == rb, nil
+	case isInt(l) || isInt(r):
+		li, err := toInt(l)
+		if err != nil {
+			return false, err
+		}
+		ri, err := toInt(r)
+		if err != nil {
+			return false, err
+		}
+		return li == ri, nil
+	}
+	return toString(l) == toString(r), nil
+}
+
+func isBool(v value) bool {
+	_, ok := v.(bool)
+	return ok
+}
+
+func isInt(v value) bool {
+	_, ok := v.(int32)
+	return ok
+}
+
+func toBool(v value) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("cannot cast %v to boolean", v)
+}
+
+func toInt(v value) (int32, error) {
+	switch v := v.(type) {
+	case int32:
+		return v, nil
+	case string:
+		i, err := strconv.ParseInt(v, 10, 32)
+		if err == nil {
+			return int32(i), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot cast %v to integer", v)
+}
+
+func toString(v value) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int32:
+		return strconv.Itoa(int(v))
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
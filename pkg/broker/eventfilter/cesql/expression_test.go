@@ -0,0 +1,90 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	attrs := map[string]string{
+		"type":    "com.example.object.created",
+		"source":  "//storage/bucket",
+		"subject": "image.png",
+		"size":    "42",
+		"flag":    "true",
+		"quoted":  "it's",
+	}
+	cases := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{expr: "type = 'com.example.object.created'", want: true},
+		{expr: "type = 'com.example.object.deleted'", want: false},
+		{expr: "type != 'com.example.object.deleted'", want: true},
+		{expr: "type <> 'com.example.object.created'", want: false},
+		{expr: "type LIKE 'com.example.%'", want: true},
+		{expr: "type LIKE 'com.example.object.create_'", want: true},
+		{expr: "type LIKE 'com.example'", want: false},
+		{expr: "type NOT LIKE '%.deleted'", want: true},
+		{expr: "subject LIKE '%\\_%'", want: false},
+		{expr: "subject IN ('a.png', 'image.png')", want: true},
+		{expr: "subject NOT IN ('a.png', 'image.png')", want: false},
+		{expr: "EXISTS subject", want: true},
+		{expr: "EXISTS missing", want: false},
+		{expr: "NOT EXISTS missing", want: true},
+		{expr: "size > 40 AND size <= 42", want: true},
+		{expr: "size = 42", want: true},
+		{expr: "size + 1 = 43", want: true},
+		{expr: "size * 2 - 4 = 80", want: true},
+		{expr: "size / 5 = 8 AND size % 5 = 2", want: true},
+		{expr: "-size < 0", want: true},
+		{expr: "flag", want: true},
+		{expr: "flag = TRUE", want: true},
+		{expr: "NOT flag", want: false},
+		{expr: "flag XOR TRUE", want: false},
+		{expr: "type = 'a' OR source = '//storage/bucket'", want: true},
+		{expr: "(type = 'a' OR type = 'b') AND EXISTS source", want: false},
+		{expr: "UPPER(subject) = 'IMAGE.PNG'", want: true},
+		{expr: "LOWER('ABC') = 'abc'", want: true},
+		{expr: "LENGTH(subject) = 9", want: true},
+		{expr: "quoted = 'it''s'", want: true},
+		{expr: "quoted = 'it\\'s'", want: true},
+		{expr: "type = 'a' AND missing = 'b'", want: false},
+		{expr: "type = 'com.example.object.created' OR missing = 'b'", want: true},
+		{expr: "missing = 'b'", wantErr: true},
+		{expr: "type > 3", wantErr: true},
+		{expr: "size / 0 = 1", wantErr: true},
+		{expr: "type", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) got error: %v", tc.expr, err)
+			}
+			got, err := e.Matches(attrs)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Matches() got error=%v, wantErr=%v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Matches() got=%v, want=%v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt
+	tokString
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	// text is the identifier, the integer digits, the unquoted string or the symbol.
+	text string
+	// pos is the byte offset of the token in the expression.
+	pos int
+}
+
+// keyword returns the upper case text of an identifier token so that
+// keywords can be matched case-insensitively.
+func (t token) keyword() string {
+	if t.kind != tokIdent {
+		return ""
+	}
+	return strings.ToUpper(t.text)
+}
+
+var twoCharSymbols = []string{"!=", "<>", "<=", ">="}
+
+const oneCharSymbols = "(),=<>+-*/%"
+
+// lex splits the expression into tokens.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case isIdentStart(c):
+			start := i
+			for i < len(expr) && isIdentPart(rune(expr[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: expr[start:i], pos: start})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(expr) && expr[i] >= '0' && expr[i] <= '9' {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokInt, text: expr[start:i], pos: start})
+		case c == '\'' || c == '"':
+			start := i
+			s, n, err := lexString(expr[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w at position %d", err, start)
+			}
+			i += n
+			tokens = append(tokens, token{kind: tokString, text: s, pos: start})
+		default:
+			if i+1 < len(expr) && containsString(twoCharSymbols, expr[i:i+2]) {
+				tokens = append(tokens, token{kind: tokSymbol, text: expr[i : i+2], pos: i})
+				i += 2
+				continue
+			}
+			if strings.ContainsRune(oneCharSymbols, c) {
+				tokens = append(tokens, token{kind: tokSymbol, text: string(c), pos: i})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(expr)}), nil
+}
+
+// lexString reads a quoted string at the beginning of s. The quote is
+// escaped by doubling it or with a backslash. Other backslashes are kept so
+// that they can escape the wildcards of LIKE patterns. It returns the
+// unquoted string and the number of bytes read.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == quote:
+			i++
+			b.WriteByte(quote)
+		case s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			i++
+			b.WriteByte(quote)
+		case s[i] == quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c rune) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
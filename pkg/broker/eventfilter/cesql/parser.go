@@ -0,0 +1,307 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parser is a recursive descent parser of CloudEvents SQL expressions.
+// Operators from the lowest to the highest precedence are:
+// OR, XOR, AND, NOT, comparisons (= != <> < <= > >= LIKE IN),
+// additive (+ -), multiplicative (* / %) and unary minus.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse compiles a CloudEvents SQL expression.
+func Parse(expr string) (*Expression, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return &Expression{expr: expr, root: root}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// acceptKeyword consumes the next token if it is the given keyword.
+func (p *parser) acceptKeyword(kw string) bool {
+	if p.peek().keyword() == kw {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// acceptSymbol consumes the next token if it is one of the given symbols.
+func (p *parser) acceptSymbol(symbols ...string) (string, bool) {
+	t := p.peek()
+	if t.kind == tokSymbol && containsString(symbols, t.text) {
+		p.next()
+		return t.text, true
+	}
+	return "", false
+}
+
+func (p *parser) expectSymbol(s string) error {
+	if _, ok := p.acceptSymbol(s); !ok {
+		t := p.peek()
+		return p.errorf(t, "expected %q", s)
+	}
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), t.pos)
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseBinary(p.parseXor, "OR")
+}
+
+func (p *parser) parseXor() (node, error) {
+	return p.parseBinary(p.parseAnd, "XOR")
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseBinary(p.parseNot, "AND")
+}
+
+// parseBinary parses a left associative chain of the given keyword operator.
+func (p *parser) parseBinary(operand func() (node, error), kw string) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword(kw) {
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: kw, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.acceptKeyword("NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.acceptSymbol("=", "!=", "<>", "<", "<=", ">", ">="); ok {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if op == "<>" {
+			op = "!="
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+
+	negate := false
+	if kw := p.peek().keyword(); kw == "NOT" {
+		// NOT is only valid here in front of LIKE or IN.
+		if next := p.tokens[p.pos+1].keyword(); next != "LIKE" && next != "IN" {
+			return left, nil
+		}
+		p.next()
+		negate = true
+	}
+	switch {
+	case p.acceptKeyword("LIKE"):
+		t := p.next()
+		if t.kind != tokString {
+			return nil, p.errorf(t, "expected a string pattern after LIKE")
+		}
+		return newLikeNode(left, t.text, negate), nil
+	case p.acceptKeyword("IN"):
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		var set []node
+		for {
+			n, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, n)
+			if _, ok := p.acceptSymbol(","); !ok {
+				break
+			}
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return &inNode{operand: left, set: set, negate: negate}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptSymbol("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptSymbol("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.acceptSymbol("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokInt:
+		v, err := strconv.ParseInt(t.text, 10, 32)
+		if err != nil {
+			return nil, p.errorf(t, "invalid integer %q", t.text)
+		}
+		return &literalNode{value: int32(v)}, nil
+	case tokString:
+		return &literalNode{value: t.text}, nil
+	case tokSymbol:
+		if t.text == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+	case tokIdent:
+		switch t.keyword() {
+		case "TRUE":
+			return &literalNode{value: true}, nil
+		case "FALSE":
+			return &literalNode{value: false}, nil
+		case "EXISTS":
+			attr := p.next()
+			if attr.kind != tokIdent {
+				return nil, p.errorf(attr, "expected an attribute name after EXISTS")
+			}
+			return &existsNode{attribute: attr.text}, nil
+		case "AND", "OR", "XOR", "NOT", "LIKE", "IN":
+			return nil, p.errorf(t, "unexpected keyword %q", t.text)
+		}
+		if _, ok := p.acceptSymbol("("); ok {
+			return p.parseFunction(t)
+		}
+		return &attributeNode{name: t.text}, nil
+	case tokEOF:
+		return nil, p.errorf(t, "unexpected end of expression")
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+func (p *parser) parseFunction(name token) (node, error) {
+	fn, ok := functions[strings.ToUpper(name.text)]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %q", name.text)
+	}
+	var args []node
+	if _, ok := p.acceptSymbol(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.acceptSymbol(","); !ok {
+				break
+			}
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(args) != fn.arity {
+		return nil, p.errorf(name, "function %q takes %d argument(s), got %d", name.text, fn.arity, len(args))
+	}
+	return &functionNode{fn: fn, args: args}, nil
+}
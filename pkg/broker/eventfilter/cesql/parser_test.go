@@ -0,0 +1,74 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		name string
+		expr string
+	}{
+		{name: "empty", expr: ""},
+		{name: "unterminated string", expr: "type = 'abc"},
+		{name: "unexpected character", expr: "type = #"},
+		{name: "missing operand", expr: "type ="},
+		{name: "trailing tokens", expr: "type = 'a' 'b'"},
+		{name: "unbalanced parenthesis", expr: "(type = 'a'"},
+		{name: "integer overflow", expr: "size > 4294967296"},
+		{name: "like without pattern", expr: "type LIKE 3"},
+		{name: "exists without attribute", expr: "EXISTS 'type'"},
+		{name: "in without parenthesis", expr: "type IN 'a'"},
+		{name: "unknown function", expr: "FOO(type) = 'a'"},
+		{name: "wrong arity", expr: "LOWER(type, source) = 'a'"},
+		{name: "keyword as operand", expr: "type = AND"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse(tc.expr); err == nil {
+				t.Errorf("Parse(%q) got nil error, want error", tc.expr)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []string{
+		"type = 'com.example'",
+		"type = \"com.example\"",
+		"type <> 'a' AND source != 'b'",
+		"NOT EXISTS subject OR subject LIKE 'a%'",
+		"type NOT LIKE 'a_c' XOR type NOT IN ('a', 'b')",
+		"(size + 1) * 2 >= -3 % 2",
+		"lower(type) = 'a'",
+		"type = 'it''s'",
+		"TRUE",
+	}
+	for _, expr := range cases {
+		t.Run(expr, func(t *testing.T) {
+			e, err := Parse(expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) got error: %v", expr, err)
+			}
+			if e.String() != expr {
+				t.Errorf("String() got=%q, want=%q", e.String(), expr)
+			}
+		})
+	}
+}
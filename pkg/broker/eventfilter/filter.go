@@ -0,0 +1,251 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventfilter implements the filter dialects of the CloudEvents
+// subscriptions API for broker targets.
+package eventfilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/cloudevents/sdk-go/v2/types"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter/cesql"
+)
+
+// Filter matches events by their attributes.
+type Filter interface {
+	// Matches reports whether the attributes match the filter.
+	Matches(attrs map[string]string) bool
+}
+
+// Parse parses a JSON array of filters, e.g.
+// [{"prefix": {"type": "com.example."}}, {"not": {"exact": {"source": "test"}}}].
+// The filters are not validated, use Compile for that.
+func Parse(s string) ([]*config.Filter, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, fmt.Errorf("filters must be a JSON array: %w", err)
+	}
+	filters := make([]*config.Filter, 0, len(raw))
+	for i, r := range raw {
+		f := &config.Filter{}
+		if err := protojson.Unmarshal(r, f); err != nil {
+			return nil, fmt.Errorf("invalid filter at index %d: %w", i, err)
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// Compile validates the filters and compiles them into a filter that
+// matches if all of them match.
+func Compile(filters []*config.Filter) (Filter, error) {
+	all := make(allFilter, 0, len(filters))
+	for i, f := range filters {
+		c, err := compile(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter at index %d: %w", i, err)
+		}
+		all = append(all, c)
+	}
+	return all, nil
+}
+
+func compile(f *config.Filter) (Filter, error) {
+	if f == nil {
+		return nil, errors.New("filter is empty")
+	}
+	var dialects []string
+	var compiled Filter
+	if f.Exact != nil {
+		dialects = append(dialects, "exact")
+		compiled = exactFilter(f.Exact)
+	}
+	if f.Prefix != nil {
+		dialects = append(dialects, "prefix")
+		compiled = prefixFilter(f.Prefix)
+	}
+	if f.Suffix != nil {
+		dialects = append(dialects, "suffix")
+		compiled = suffixFilter(f.Suffix)
+	}
+	if f.Not != nil {
+		dialects = append(dialects, "not")
+		c, err := compile(f.Not)
+		if err != nil {
+			return nil, fmt.Errorf("not: %w", err)
+		}
+		compiled = notFilter{c}
+	}
+	if f.All != nil {
+		dialects = append(dialects, "all")
+		c, err := compileList(f.All)
+		if err != nil {
+			return nil, fmt.Errorf("all: %w", err)
+		}
+		compiled = allFilter(c)
+	}
+	if f.Any != nil {
+		dialects = append(dialects, "any")
+		c, err := compileList(f.Any)
+		if err != nil {
+			return nil, fmt.Errorf("any: %w", err)
+		}
+		compiled = anyFilter(c)
+	}
+	if f.Sql != "" {
+		dialects = append(dialects, "sql")
+		expr, err := cesql.Parse(f.Sql)
+		if err != nil {
+			return nil, fmt.Errorf("sql: %w", err)
+		}
+		compiled = sqlFilter{expr}
+	}
+
+	switch len(dialects) {
+	case 0:
+		return nil, errors.New("filter is empty")
+	case 1:
+		return compiled, nil
+	default:
+		return nil, fmt.Errorf("filter must have exactly one dialect, got %s", strings.Join(dialects, ", "))
+	}
+}
+
+func compileList(filters []*config.Filter) ([]Filter, error) {
+	if len(filters) == 0 {
+		return nil, errors.New("filter list is empty")
+	}
+	compiled := make([]Filter, 0, len(filters))
+	for i, f := range filters {
+		c, err := compile(f)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// Attributes returns the context attributes and the extensions of the event
+// formatted as strings. Optional attributes are only present if they are set.
+func Attributes(e *event.Event) map[string]string {
+	attrs := map[string]string{
+		"specversion": e.SpecVersion(),
+		"id":          e.ID(),
+		"source":      e.Source(),
+		"type":        e.Type(),
+	}
+	if v := e.Subject(); v != "" {
+		attrs["subject"] = v
+	}
+	if v := e.Time(); !v.IsZero() {
+		attrs["time"] = types.FormatTime(v)
+	}
+	if v := e.DataSchema(); v != "" {
+		attrs["dataschema"] = v
+	}
+	if v := e.DataContentType(); v != "" {
+		attrs["datacontenttype"] = v
+	}
+	for k, v := range e.Extensions() {
+		if s, err := types.Format(v); err == nil {
+			attrs[k] = s
+		}
+	}
+	return attrs
+}
+
+type exactFilter map[string]string
+
+func (f exactFilter) Matches(attrs map[string]string) bool {
+	for k, want := range f {
+		if v, ok := attrs[k]; !ok || v != want {
+			return false
+		}
+	}
+	return true
+}
+
+type prefixFilter map[string]string
+
+func (f prefixFilter) Matches(attrs map[string]string) bool {
+	for k, prefix := range f {
+		if v, ok := attrs[k]; !ok || !strings.HasPrefix(v, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+type suffixFilter map[string]string
+
+func (f suffixFilter) Matches(attrs map[string]string) bool {
+	for k, suffix := range f {
+		if v, ok := attrs[k]; !ok || !strings.HasSuffix(v, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+type notFilter struct {
+	Filter
+}
+
+func (f notFilter) Matches(attrs map[string]string) bool {
+	return !f.Filter.Matches(attrs)
+}
+
+type allFilter []Filter
+
+func (f allFilter) Matches(attrs map[string]string) bool {
+	for _, c := range f {
+		if !c.Matches(attrs) {
+			return false
+		}
+	}
+	return true
+}
+
+type anyFilter []Filter
+
+func (f anyFilter) Matches(attrs map[string]string) bool {
+	for _, c := range f {
+		if c.Matches(attrs) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlFilter doesn't match if the expression fails to evaluate,
+// e.g. because it references a missing attribute.
+type sqlFilter struct {
+	expr *cesql.Expression
+}
+
+func (f sqlFilter) Matches(attrs map[string]string) bool {
+	ok, err := f.expr.Matches(attrs)
+	return err == nil && ok
+}
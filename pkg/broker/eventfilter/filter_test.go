@@ -0,0 +1,170 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseAndCompileError(t *testing.T) {
+	cases := []struct {
+		name    string
+		filters string
+	}{
+		{name: "not json", filters: "type=foo"},
+		{name: "not an array", filters: `{"exact": {"type": "foo"}}`},
+		{name: "unknown dialect", filters: `[{"regex": {"type": "foo"}}]`},
+		{name: "empty filter", filters: `[{}]`},
+		{name: "multiple dialects", filters: `[{"exact": {"type": "foo"}, "prefix": {"type": "foo"}}]`},
+		{name: "empty any", filters: `[{"any": []}]`},
+		{name: "invalid nested filter", filters: `[{"all": [{"exact": {"type": "foo"}}, {}]}]`},
+		{name: "invalid sql", filters: `[{"sql": "type = "}]`},
+		{name: "invalid sql in not", filters: `[{"not": {"sql": "type LIKE"}}]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filters, err := Parse(tc.filters)
+			if err == nil {
+				_, err = Compile(filters)
+			}
+			if err == nil {
+				t.Errorf("parsing and compiling %s got nil error, want error", tc.filters)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	cases := []struct {
+		name    string
+		filters string
+		want    bool
+	}{{
+		name:    "no filters",
+		filters: `[]`,
+		want:    true,
+	}, {
+		name:    "exact match",
+		filters: `[{"exact": {"type": "com.example.created", "source": "source"}}]`,
+		want:    true,
+	}, {
+		name:    "exact mismatch",
+		filters: `[{"exact": {"type": "com.example.created", "source": "other"}}]`,
+		want:    false,
+	}, {
+		name:    "exact missing attribute",
+		filters: `[{"exact": {"missing": ""}}]`,
+		want:    false,
+	}, {
+		name:    "prefix match",
+		filters: `[{"prefix": {"type": "com.example."}}]`,
+		want:    true,
+	}, {
+		name:    "prefix mismatch",
+		filters: `[{"prefix": {"type": "org.example."}}]`,
+		want:    false,
+	}, {
+		name:    "suffix match",
+		filters: `[{"suffix": {"subject": ".png"}}]`,
+		want:    true,
+	}, {
+		name:    "suffix mismatch",
+		filters: `[{"suffix": {"subject": ".jpg"}}]`,
+		want:    false,
+	}, {
+		name:    "not",
+		filters: `[{"not": {"suffix": {"subject": ".jpg"}}}]`,
+		want:    true,
+	}, {
+		name:    "any",
+		filters: `[{"any": [{"suffix": {"subject": ".jpg"}}, {"suffix": {"subject": ".png"}}]}]`,
+		want:    true,
+	}, {
+		name:    "all",
+		filters: `[{"all": [{"prefix": {"type": "com."}}, {"suffix": {"subject": ".jpg"}}]}]`,
+		want:    false,
+	}, {
+		name:    "extension",
+		filters: `[{"exact": {"count": "3"}}]`,
+		want:    true,
+	}, {
+		name:    "sql match",
+		filters: `[{"sql": "type LIKE 'com.%' AND count > 2"}]`,
+		want:    true,
+	}, {
+		name:    "sql missing attribute",
+		filters: `[{"sql": "missing = 'a'"}]`,
+		want:    false,
+	}, {
+		name:    "sql exists optional attribute",
+		filters: `[{"sql": "EXISTS subject AND NOT EXISTS dataschema"}]`,
+		want:    true,
+	}, {
+		name:    "all top level filters must match",
+		filters: `[{"prefix": {"type": "com."}}, {"sql": "source = 'other'"}]`,
+		want:    false,
+	}}
+
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("com.example.created")
+	e.SetSubject("image.png")
+	e.SetTime(time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC))
+	e.SetExtension("count", 3)
+	attrs := Attributes(&e)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filters, err := Parse(tc.filters)
+			if err != nil {
+				t.Fatalf("Parse(%s) got error: %v", tc.filters, err)
+			}
+			f, err := Compile(filters)
+			if err != nil {
+				t.Fatalf("Compile(%s) got error: %v", tc.filters, err)
+			}
+			if got := f.Matches(attrs); got != tc.want {
+				t.Errorf("Matches() got=%v, want=%v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("type")
+	e.SetTime(time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC))
+	e.SetExtension("count", 3)
+	want := map[string]string{
+		"specversion": "1.0",
+		"id":          "id",
+		"source":      "source",
+		"type":        "type",
+		"time":        "2020-07-01T00:00:00Z",
+		"count":       "3",
+	}
+	if diff := cmp.Diff(want, Attributes(&e)); diff != "" {
+		t.Errorf("Attributes (-want,+got): %v", diff)
+	}
+}
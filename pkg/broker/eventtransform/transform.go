@@ -0,0 +1,328 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventtransform implements the transformations of the attributes of
+// the events delivered to broker targets.
+package eventtransform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/cloudevents/sdk-go/v2/types"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
+)
+
+// Transformer transforms the attributes of events.
+type Transformer interface {
+	// Apply transforms the event in place.
+	Apply(e *event.Event) error
+}
+
+var (
+	// attributeName matches the CloudEvents attribute naming convention.
+	attributeName = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+	// reference matches a reference to an attribute in a template.
+	reference = regexp.MustCompile(`\$\{([^}]*)\}`)
+)
+
+// required are the attributes that can be set but not removed.
+var required = map[string]bool{"id": true, "source": true, "type": true}
+
+// Parse parses a JSON array of transformations, e.g.
+// [{"set": {"type": "${type}.v2"}}, {"remove": ["traceparent"]}].
+// The transformations are not validated, use Compile for that.
+func Parse(s string) ([]*config.Transform, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, fmt.Errorf("transformations must be a JSON array: %w", err)
+	}
+	transforms := make([]*config.Transform, 0, len(raw))
+	for i, r := range raw {
+		t := &config.Transform{}
+		if err := protojson.Unmarshal(r, t); err != nil {
+			return nil, fmt.Errorf("invalid transformation at index %d: %w", i, err)
+		}
+		transforms = append(transforms, t)
+	}
+	return transforms, nil
+}
+
+// Compile validates the transformations and compiles them into a transformer
+// that applies them in order.
+func Compile(transforms []*config.Transform) (Transformer, error) {
+	all := make(allTransformer, 0, len(transforms))
+	for i, t := range transforms {
+		c, err := compile(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transformation at index %d: %w", i, err)
+		}
+		all = append(all, c)
+	}
+	return all, nil
+}
+
+func compile(t *config.Transform) (Transformer, error) {
+	if t == nil {
+		return nil, errors.New("transformation is empty")
+	}
+	var operations []string
+	var compiled Transformer
+	if t.Set != nil {
+		operations = append(operations, "set")
+		c, err := compileSet(t.Set)
+		if err != nil {
+			return nil, fmt.Errorf("set: %w", err)
+		}
+		compiled = c
+	}
+	if t.Remove != nil {
+		operations = append(operations, "remove")
+		for _, name := range t.Remove {
+			if err := validateName(name, true); err != nil {
+				return nil, fmt.Errorf("remove: %w", err)
+			}
+		}
+		compiled = removeTransformer(t.Remove)
+	}
+	if t.Rename != nil {
+		operations = append(operations, "rename")
+		c, err := compileRename(t.Rename)
+		if err != nil {
+			return nil, fmt.Errorf("rename: %w", err)
+		}
+		compiled = c
+	}
+
+	switch len(operations) {
+	case 0:
+		return nil, errors.New("transformation is empty")
+	case 1:
+		return compiled, nil
+	default:
+		return nil, fmt.Errorf("transformation must have exactly one operation, got %s", strings.Join(operations, ", "))
+	}
+}
+
+// validateName returns an error if the attribute can't be transformed.
+// Attributes used by the broker itself are reserved.
+func validateName(name string, removed bool) error {
+	if !attributeName.MatchString(name) {
+		return fmt.Errorf("invalid attribute name %q: must be 1 to 20 lowercase letters or digits", name)
+	}
+	if name == "specversion" || strings.HasPrefix(name, "kgcp") {
+		return fmt.Errorf("attribute %q is reserved", name)
+	}
+	if removed && required[name] {
+		return fmt.Errorf("required attribute %q can't be removed", name)
+	}
+	return nil
+}
+
+func compileSet(values map[string]string) (setTransformer, error) {
+	if len(values) == 0 {
+		return nil, errors.New("no attributes to set")
+	}
+	set := make(setTransformer, 0, len(values))
+	for name, value := range values {
+		if err := validateName(name, false); err != nil {
+			return nil, err
+		}
+		tmpl, err := parseTemplate(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		set = append(set, setAttribute{name: name, value: tmpl})
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i].name < set[j].name })
+	return set, nil
+}
+
+func compileRename(names map[string]string) (renameTransformer, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no attributes to rename")
+	}
+	rename := make(renameTransformer, 0, len(names))
+	for from, to := range names {
+		if err := validateName(from, true); err != nil {
+			return nil, err
+		}
+		if err := validateName(to, false); err != nil {
+			return nil, err
+		}
+		rename = append(rename, [2]string{from, to})
+	}
+	sort.Slice(rename, func(i, j int) bool { return rename[i][0] < rename[j][0] })
+	return rename, nil
+}
+
+// template is a string referencing attributes of an event.
+type template struct {
+	// literals surround the references, so there is one more literal than
+	// references.
+	literals   []string
+	references []string
+}
+
+func parseTemplate(s string) (*template, error) {
+	t := &template{}
+	last := 0
+	for _, m := range reference.FindAllStringSubmatchIndex(s, -1) {
+		name := s[m[2]:m[3]]
+		if !attributeName.MatchString(name) {
+			return nil, fmt.Errorf("invalid attribute reference %q", s[m[0]:m[1]])
+		}
+		t.literals = append(t.literals, s[last:m[0]])
+		t.references = append(t.references, name)
+		last = m[1]
+	}
+	t.literals = append(t.literals, s[last:])
+	if strings.Contains(strings.Join(t.literals, ""), "${") {
+		return nil, fmt.Errorf("unterminated attribute reference in %q", s)
+	}
+	return t, nil
+}
+
+func (t *template) execute(attrs map[string]string) string {
+	var b strings.Builder
+	for i, name := range t.references {
+		b.WriteString(t.literals[i])
+		b.WriteString(attrs[name])
+	}
+	b.WriteString(t.literals[len(t.literals)-1])
+	return b.String()
+}
+
+type allTransformer []Transformer
+
+func (t allTransformer) Apply(e *event.Event) error {
+	for _, c := range t {
+		if err := c.Apply(e); err != nil {
+			return err
+		}
+	}
+	return e.Validate()
+}
+
+type setAttribute struct {
+	name  string
+	value *template
+}
+
+// setTransformer evaluates all the values before setting any attribute.
+type setTransformer []setAttribute
+
+func (t setTransformer) Apply(e *event.Event) error {
+	attrs := eventfilter.Attributes(e)
+	values := make([]string, len(t))
+	for i, a := range t {
+		values[i] = a.value.execute(attrs)
+	}
+	for i, a := range t {
+		if err := set(e, a.name, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type removeTransformer []string
+
+func (t removeTransformer) Apply(e *event.Event) error {
+	for _, name := range t {
+		if err := remove(e, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameTransformer holds the attribute names to rename, from and to.
+// Missing attributes are not renamed.
+type renameTransformer [][2]string
+
+func (t renameTransformer) Apply(e *event.Event) error {
+	attrs := eventfilter.Attributes(e)
+	for _, r := range t {
+		if _, ok := attrs[r[0]]; !ok {
+			continue
+		}
+		if err := remove(e, r[0]); err != nil {
+			return err
+		}
+	}
+	for _, r := range t {
+		value, ok := attrs[r[0]]
+		if !ok {
+			continue
+		}
+		if err := set(e, r[1], value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set sets the context attribute or extension of the event.
+func set(e *event.Event, name, value string) error {
+	var err error
+	switch name {
+	case "id":
+		err = e.Context.SetID(value)
+	case "source":
+		err = e.Context.SetSource(value)
+	case "type":
+		err = e.Context.SetType(value)
+	case "subject":
+		err = e.Context.SetSubject(value)
+	case "dataschema":
+		err = e.Context.SetDataSchema(value)
+	case "datacontenttype":
+		err = e.Context.SetDataContentType(value)
+	case "time":
+		var t time.Time
+		if t, err = types.ParseTime(value); err == nil {
+			err = e.Context.SetTime(t)
+		}
+	default:
+		err = e.Context.SetExtension(name, value)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set attribute %q: %w", name, err)
+	}
+	return nil
+}
+
+// remove removes the optional context attribute or extension of the event.
+func remove(e *event.Event, name string) error {
+	switch name {
+	case "subject", "dataschema", "datacontenttype":
+		return set(e, name, "")
+	case "time":
+		return e.Context.SetTime(time.Time{})
+	default:
+		return e.Context.SetExtension(name, nil)
+	}
+}
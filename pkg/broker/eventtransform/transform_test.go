@@ -0,0 +1,165 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventtransform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
+)
+
+func TestParseAndCompileError(t *testing.T) {
+	cases := []struct {
+		name       string
+		transforms string
+	}{
+		{name: "not json", transforms: "type=foo"},
+		{name: "not an array", transforms: `{"set": {"type": "foo"}}`},
+		{name: "unknown operation", transforms: `[{"copy": {"type": "foo"}}]`},
+		{name: "empty transformation", transforms: `[{}]`},
+		{name: "empty set", transforms: `[{"set": {}}]`},
+		{name: "multiple operations", transforms: `[{"set": {"foo": "bar"}, "remove": ["subject"]}]`},
+		{name: "invalid attribute name", transforms: `[{"set": {"Foo": "bar"}}]`},
+		{name: "reserved attribute", transforms: `[{"set": {"specversion": "0.3"}}]`},
+		{name: "broker extension", transforms: `[{"remove": ["kgcpnotbefore"]}]`},
+		{name: "remove required attribute", transforms: `[{"remove": ["type"]}]`},
+		{name: "rename required attribute", transforms: `[{"rename": {"id": "originalid"}}]`},
+		{name: "invalid rename target", transforms: `[{"rename": {"subject": "a-b"}}]`},
+		{name: "invalid reference", transforms: `[{"set": {"type": "${Type}"}}]`},
+		{name: "unterminated reference", transforms: `[{"set": {"type": "${type"}}]`},
+		{name: "invalid transformation after valid one", transforms: `[{"remove": ["subject"]}, {}]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			transforms, err := Parse(tc.transforms)
+			if err == nil {
+				_, err = Compile(transforms)
+			}
+			if err == nil {
+				t.Errorf("parsing and compiling %s got nil error, want error", tc.transforms)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	ts := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	newEvent := func() *event.Event {
+		e := event.New()
+		e.SetID("id")
+		e.SetSource("source")
+		e.SetType("type")
+		e.SetSubject("subject")
+		e.SetTime(ts)
+		e.SetExtension("foo", "bar")
+		return &e
+	}
+
+	cases := []struct {
+		name       string
+		transforms string
+		want       map[string]string
+		wantErr    bool
+	}{{
+		name:       "no transformations",
+		transforms: `[]`,
+		want: map[string]string{
+			"specversion": "1.0", "id": "id", "source": "source", "type": "type",
+			"subject": "subject", "time": "2020-08-01T12:00:00Z", "foo": "bar",
+		},
+	}, {
+		name:       "set with references",
+		transforms: `[{"set": {"type": "${type}.v2", "origin": "${source}/${subject}", "dataschema": "${missing}"}}]`,
+		want: map[string]string{
+			"specversion": "1.0", "id": "id", "source": "source", "type": "type.v2",
+			"subject": "subject", "time": "2020-08-01T12:00:00Z", "foo": "bar",
+			"origin": "source/subject",
+		},
+	}, {
+		name:       "set references attributes before the operation",
+		transforms: `[{"set": {"source": "${type}", "type": "${source}"}}]`,
+		want: map[string]string{
+			"specversion": "1.0", "id": "id", "source": "type", "type": "source",
+			"subject": "subject", "time": "2020-08-01T12:00:00Z", "foo": "bar",
+		},
+	}, {
+		name:       "set time",
+		transforms: `[{"set": {"time": "2020-08-02T00:00:00Z"}}]`,
+		want: map[string]string{
+			"specversion": "1.0", "id": "id", "source": "source", "type": "type",
+			"subject": "subject", "time": "2020-08-02T00:00:00Z", "foo": "bar",
+		},
+	}, {
+		name:       "remove",
+		transforms: `[{"remove": ["subject", "time", "foo", "missing"]}]`,
+		want: map[string]string{
+			"specversion": "1.0", "id": "id", "source": "source", "type": "type",
+		},
+	}, {
+		name:       "rename",
+		transforms: `[{"rename": {"foo": "subject", "subject": "origin", "missing": "other"}}]`,
+		want: map[string]string{
+			"specversion": "1.0", "id": "id", "source": "source", "type": "type",
+			"subject": "bar", "time": "2020-08-01T12:00:00Z", "origin": "subject",
+		},
+	}, {
+		name:       "operations in order",
+		transforms: `[{"rename": {"foo": "bar"}}, {"set": {"type": "${bar}"}}, {"remove": ["bar"]}]`,
+		want: map[string]string{
+			"specversion": "1.0", "id": "id", "source": "source", "type": "bar",
+			"subject": "subject", "time": "2020-08-01T12:00:00Z",
+		},
+	}, {
+		name:       "invalid time",
+		transforms: `[{"set": {"time": "${subject}"}}]`,
+		wantErr:    true,
+	}, {
+		name:       "empty required attribute",
+		transforms: `[{"set": {"id": "${missing}"}}]`,
+		wantErr:    true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			transforms, err := Parse(tc.transforms)
+			if err != nil {
+				t.Fatalf("Parse(%s) got error: %v", tc.transforms, err)
+			}
+			transformer, err := Compile(transforms)
+			if err != nil {
+				t.Fatalf("Compile(%s) got error: %v", tc.transforms, err)
+			}
+			e := newEvent()
+			err = transformer.Apply(e)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("Apply() got nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, eventfilter.Attributes(e)); diff != "" {
+				t.Errorf("Apply() attributes (-want,+got): %v", diff)
+			}
+		})
+	}
+}
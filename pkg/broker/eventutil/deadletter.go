@@ -0,0 +1,59 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+)
+
+const (
+	// deadLetterReasonAttribute records why the event was sent to the dead letter sink.
+	deadLetterReasonAttribute = "kgcpdlqreason"
+	// deadLetterAttemptsAttribute records how many delivery attempts were made
+	// before the event was sent to the dead letter sink.
+	deadLetterAttemptsAttribute = "kgcpdlqattempts"
+)
+
+// SetDeadLetterExtensions records the failure reason and the number of delivery
+// attempts in the event before sending it to the dead letter sink.
+func SetDeadLetterExtensions(event *event.Event, reason string, attempts int32) {
+	event.SetExtension(deadLetterReasonAttribute, reason)
+	event.SetExtension(deadLetterAttemptsAttribute, attempts)
+}
+
+// GetDeadLetterExtensions returns the failure reason and the number of delivery
+// attempts recorded in the event if they present.
+func GetDeadLetterExtensions(event *event.Event) (string, int32, bool) {
+	reasonRaw, ok := event.Extensions()[deadLetterReasonAttribute]
+	if !ok {
+		return "", 0, false
+	}
+	reason, err := cetypes.ToString(reasonRaw)
+	if err != nil {
+		return "", 0, false
+	}
+	attemptsRaw, ok := event.Extensions()[deadLetterAttemptsAttribute]
+	if !ok {
+		return "", 0, false
+	}
+	attempts, err := cetypes.ToInteger(attemptsRaw)
+	if err != nil {
+		return "", 0, false
+	}
+	return reason, attempts, true
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"testing"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+func TestGetDeadLetterExtensions(t *testing.T) {
+	cases := []struct {
+		name         string
+		reason       interface{}
+		attempts     interface{}
+		wantReason   string
+		wantAttempts int32
+		wantOK       bool
+	}{{
+		name: "no extensions",
+	}, {
+		name:   "missing attempts",
+		reason: "delivery failed",
+	}, {
+		name:     "invalid attempts",
+		reason:   "delivery failed",
+		attempts: "abc",
+	}, {
+		name:         "valid extensions",
+		reason:       "delivery failed",
+		attempts:     3,
+		wantReason:   "delivery failed",
+		wantAttempts: 3,
+		wantOK:       true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := event.New()
+			e.SetExtension(deadLetterReasonAttribute, tc.reason)
+			e.SetExtension(deadLetterAttemptsAttribute, tc.attempts)
+			gotReason, gotAttempts, gotOK := GetDeadLetterExtensions(&e)
+			if gotOK != tc.wantOK {
+				t.Errorf("Found dead letter extensions OK got=%v, want=%v", gotOK, tc.wantOK)
+			}
+			if gotReason != tc.wantReason {
+				t.Errorf("Reason got=%q, want=%q", gotReason, tc.wantReason)
+			}
+			if gotAttempts != tc.wantAttempts {
+				t.Errorf("Attempts got=%d, want=%d", gotAttempts, tc.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestSetDeadLetterExtensions(t *testing.T) {
+	e := event.New()
+	SetDeadLetterExtensions(&e, "delivery failed", 5)
+	reason, attempts, ok := GetDeadLetterExtensions(&e)
+	if !ok {
+		t.Error("Found dead letter extensions after SetDeadLetterExtensions got=false, want=true")
+	}
+	if reason != "delivery failed" {
+		t.Errorf("Reason got=%q, want=%q", reason, "delivery failed")
+	}
+	if attempts != 5 {
+		t.Errorf("Attempts got=%d, want=%d", attempts, 5)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+)
+
+const (
+	// visitedAttribute records the targets whose replies the event descends
+	// from. Each target is recorded as a short hash of its key to keep the
+	// Pubsub message small, and the chain is bounded by the hop limit.
+	visitedAttribute = "kgcpvisited"
+	visitedSeparator = "."
+)
+
+// Visited is the chain of targets whose replies an event descends from.
+type Visited string
+
+// GetVisited returns the chain of targets recorded in the event, or an empty
+// chain if there is none.
+func GetVisited(event *event.Event) Visited {
+	visitedRaw, ok := event.Extensions()[visitedAttribute]
+	if !ok {
+		return ""
+	}
+	visited, err := cetypes.ToString(visitedRaw)
+	if err != nil {
+		return ""
+	}
+	return Visited(visited)
+}
+
+// DeleteVisited deletes the chain of targets from the event extensions.
+func DeleteVisited(event *event.Event) {
+	event.SetExtension(visitedAttribute, nil)
+}
+
+// Contains returns true if the target with the given key is in the chain.
+func (v Visited) Contains(targetKey string) bool {
+	if v == "" {
+		return false
+	}
+	hash := visitedHash(targetKey)
+	for _, h := range strings.Split(string(v), visitedSeparator) {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Append returns the chain with the target with the given key added.
+func (v Visited) Append(targetKey string) Visited {
+	if v == "" {
+		return Visited(visitedHash(targetKey))
+	}
+	return v + visitedSeparator + Visited(visitedHash(targetKey))
+}
+
+// SetVisitedTransformer sets the chain of targets in a message.
+type SetVisitedTransformer Visited
+
+func (v SetVisitedTransformer) Transform(_ binding.MessageMetadataReader, out binding.MessageMetadataWriter) error {
+	out.SetExtension(visitedAttribute, string(v))
+	return nil
+}
+
+// visitedHash returns the short hash recording the target with the given key.
+func visitedHash(targetKey string) string {
+	h := fnv.New32a()
+	h.Write([]byte(targetKey))
+	return strconv.FormatUint(uint64(h.Sum32()), 36)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"testing"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+func TestVisited(t *testing.T) {
+	e := event.New()
+	if got := GetVisited(&e); got != "" {
+		t.Errorf("GetVisited got=%q, want empty chain", got)
+	}
+
+	visited := Visited("").Append("ns/broker/trigger1").Append("ns/broker/trigger2")
+	e.SetExtension(visitedAttribute, string(visited))
+	got := GetVisited(&e)
+	if got != visited {
+		t.Errorf("GetVisited got=%q, want=%q", got, visited)
+	}
+	for _, key := range []string{"ns/broker/trigger1", "ns/broker/trigger2"} {
+		if !got.Contains(key) {
+			t.Errorf("Visited %q doesn't contain %q", got, key)
+		}
+	}
+	if got.Contains("ns/broker/trigger3") {
+		t.Errorf("Visited %q contains ns/broker/trigger3", got)
+	}
+
+	DeleteVisited(&e)
+	if _, ok := e.Extensions()[visitedAttribute]; ok {
+		t.Error("After DeleteVisited visited found got=true, want=false")
+	}
+}
+
+func TestVisitedInvalid(t *testing.T) {
+	e := event.New()
+	e.SetExtension(visitedAttribute, 100)
+	if got := GetVisited(&e); got.Contains("ns/broker/trigger") {
+		t.Errorf("Visited %q contains ns/broker/trigger", got)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+)
+
+const (
+	// notBeforeAttribute records the earliest time the delivery of the event
+	// should be retried, as requested by the subscriber with Retry-After.
+	notBeforeAttribute = "kgcpnotbefore"
+)
+
+// SetNotBefore records the earliest time the delivery of the event should be retried.
+func SetNotBefore(event *event.Event, t time.Time) {
+	event.SetExtension(notBeforeAttribute, t.UTC())
+}
+
+// GetNotBefore returns the earliest time the delivery of the event should be
+// retried if it is present.
+func GetNotBefore(event *event.Event) (time.Time, bool) {
+	raw, ok := event.Extensions()[notBeforeAttribute]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := cetypes.ToTime(raw)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// DeleteNotBefore deletes the earliest retry time from the event.
+func DeleteNotBefore(event *event.Event) {
+	event.SetExtension(notBeforeAttribute, nil)
+}
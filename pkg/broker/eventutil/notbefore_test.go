@@ -0,0 +1,51 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+func TestNotBefore(t *testing.T) {
+	e := event.New()
+	if _, ok := GetNotBefore(&e); ok {
+		t.Error("Found not before in a new event got=true, want=false")
+	}
+
+	want := time.Date(2020, 6, 1, 12, 0, 30, 0, time.UTC)
+	SetNotBefore(&e, want)
+	got, ok := GetNotBefore(&e)
+	if !ok {
+		t.Error("Found not before after SetNotBefore got=false, want=true")
+	}
+	if !got.Equal(want) {
+		t.Errorf("Not before got=%v, want=%v", got, want)
+	}
+
+	DeleteNotBefore(&e)
+	if _, ok := GetNotBefore(&e); ok {
+		t.Error("Found not before after DeleteNotBefore got=true, want=false")
+	}
+
+	e.SetExtension(notBeforeAttribute, "soon")
+	if _, ok := GetNotBefore(&e); ok {
+		t.Error("Found invalid not before got=true, want=false")
+	}
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+)
+
+// OrderingKey returns the value of the ordering key extension of the event,
+// or an empty string if the extension is not set or the event is not ordered.
+func OrderingKey(event *event.Event, extension string) string {
+	if extension == "" {
+		return ""
+	}
+	v, ok := event.Extensions()[extension]
+	if !ok {
+		return ""
+	}
+	key, err := cetypes.Format(v)
+	if err != nil {
+		return ""
+	}
+	return key
+}
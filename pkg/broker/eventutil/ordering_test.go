@@ -0,0 +1,47 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"testing"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+func TestOrderingKey(t *testing.T) {
+	e := event.New()
+	e.SetExtension("partitionkey", "user-1")
+	e.SetExtension("shard", 3)
+
+	cases := []struct {
+		name      string
+		extension string
+		want      string
+	}{
+		{name: "string key", extension: "partitionkey", want: "user-1"},
+		{name: "integer key", extension: "shard", want: "3"},
+		{name: "missing key", extension: "other", want: ""},
+		{name: "not ordered", extension: "", want: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := OrderingKey(&e, tc.extension); got != tc.want {
+				t.Errorf("OrderingKey() got=%q, want=%q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+)
+
+// handlerInfo is the state of a handler listed by the admin API.
+type handlerInfo struct {
+	// Broker is the key of the broker of the handler.
+	Broker string `json:"broker"`
+	// Trigger is the name of the trigger of a retry handler.
+	Trigger string `json:"trigger,omitempty"`
+	// Generation is the generation of the targets config the handler was last synced with.
+	Generation int64 `json:"generation"`
+	Status
+	// Filters are the filter definitions of the triggers handled, keyed by trigger name.
+	Filters map[string]json.RawMessage `json:"filters,omitempty"`
+}
+
+// filterDefinition returns the filter attributes and filters of the target as JSON.
+func filterDefinition(t *config.Target) json.RawMessage {
+	b, err := protojson.Marshal(&config.Target{FilterAttributes: t.FilterAttributes, Filters: t.Filters})
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// sortHandlerInfos sorts the handlers by broker and trigger.
+func sortHandlerInfos(infos []handlerInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Broker != infos[j].Broker {
+			return infos[i].Broker < infos[j].Broker
+		}
+		return infos[i].Trigger < infos[j].Trigger
+	})
+}
+
+// adminHandler serves the admin API listing the handlers of a pool at
+// /debug/handlers, dumping the targets config at /debug/targets and the
+// status of the circuit breakers at /debug/breakers. Requests must be
+// authenticated with the token as a bearer token, the admin API is disabled
+// if the token is empty.
+func adminHandler(token string, targets config.ReadonlyTargets, handlers func() []handlerInfo, breakers *deliver.Breakers) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/breakers", breakers)
+	mux.HandleFunc("/debug/handlers", func(w http.ResponseWriter, req *http.Request) {
+		infos := handlers()
+		sortHandlerInfos(infos)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(infos)
+	})
+	mux.HandleFunc("/debug/targets", func(w http.ResponseWriter, req *http.Request) {
+		b, err := targetsJSON(targets)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if token == "" {
+			http.Error(w, "the admin API is disabled", http.StatusForbidden)
+			return
+		}
+		if !validAdminToken(token, req) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		mux.ServeHTTP(w, req)
+	})
+}
+
+// validAdminToken checks that the request has the token as a bearer token.
+func validAdminToken(token string, req *http.Request) bool {
+	auth := req.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, prefix)), []byte(token)) == 1
+}
+
+// targetsJSON returns the targets config as JSON.
+func targetsJSON(targets config.ReadonlyTargets) ([]byte, error) {
+	b, err := targets.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var tc config.TargetsConfig
+	if err := proto.Unmarshal(b, &tc); err != nil {
+		return nil, err
+	}
+	return protojson.Marshal(&tc)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+)
+
+func TestAdminHandler(t *testing.T) {
+	target := &config.Target{
+		Name:             "trigger",
+		Namespace:        "ns",
+		Broker:           "broker",
+		FilterAttributes: map[string]string{"type": "foo"},
+		Filters:          []*config.Filter{{Prefix: map[string]string{"source": "bar"}}},
+	}
+	targets := &config.TargetsConfig{
+		Brokers: map[string]*config.Broker{
+			"ns/broker": {Name: "broker", Namespace: "ns", Targets: map[string]*config.Target{"trigger": target}},
+		},
+		Generation: 3,
+	}
+	errTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	handlers := func() []handlerInfo {
+		return []handlerInfo{
+			{Broker: "ns/broker", Trigger: "trigger", Generation: 3, Status: Status{Alive: true, InFlight: 2}, Filters: map[string]json.RawMessage{"trigger": filterDefinition(target)}},
+			{Broker: "ns/a", Trigger: "b", Generation: 2, Status: Status{LastError: "boom", LastErrorTime: &errTime}},
+		}
+	}
+	breakers := deliver.NewBreakers(deliver.BreakerSettings{FailureThreshold: 1, OpenDuration: time.Minute})
+	breakers.Record("http://target", true)
+	h := adminHandler("secret", memory.NewTargets(targets), handlers, breakers)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		auth     string
+		wantCode int
+	}{{
+		name:     "no token",
+		method:   http.MethodGet,
+		path:     "/debug/handlers",
+		wantCode: http.StatusUnauthorized,
+	}, {
+		name:     "wrong token",
+		method:   http.MethodGet,
+		path:     "/debug/handlers",
+		auth:     "Bearer wrong",
+		wantCode: http.StatusUnauthorized,
+	}, {
+		name:     "not bearer",
+		method:   http.MethodGet,
+		path:     "/debug/handlers",
+		auth:     "secret",
+		wantCode: http.StatusUnauthorized,
+	}, {
+		name:     "wrong method",
+		method:   http.MethodPost,
+		path:     "/debug/handlers",
+		auth:     "Bearer secret",
+		wantCode: http.StatusMethodNotAllowed,
+	}, {
+		name:     "handlers",
+		method:   http.MethodGet,
+		path:     "/debug/handlers",
+		auth:     "Bearer secret",
+		wantCode: http.StatusOK,
+	}, {
+		name:     "targets",
+		method:   http.MethodGet,
+		path:     "/debug/targets",
+		auth:     "Bearer secret",
+		wantCode: http.StatusOK,
+	}, {
+		name:     "breakers without token",
+		method:   http.MethodGet,
+		path:     "/debug/breakers",
+		wantCode: http.StatusUnauthorized,
+	}, {
+		name:     "breakers",
+		method:   http.MethodGet,
+		path:     "/debug/breakers",
+		auth:     "Bearer secret",
+		wantCode: http.StatusOK,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tc.wantCode {
+				t.Errorf("status code got=%d, want=%d", w.Code, tc.wantCode)
+			}
+		})
+	}
+
+	t.Run("handlers are listed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/debug/handlers", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		var got []map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		want := []map[string]interface{}{
+			{"broker": "ns/a", "trigger": "b", "generation": 2.0, "alive": false, "inFlight": 0.0, "lastError": "boom", "lastErrorTime": "2020-01-01T00:00:00Z"},
+			{"broker": "ns/broker", "trigger": "trigger", "generation": 3.0, "alive": true, "inFlight": 2.0, "filters": map[string]interface{}{
+				"trigger": map[string]interface{}{
+					"filterAttributes": map[string]interface{}{"type": "foo"},
+					"filters":          []interface{}{map[string]interface{}{"prefix": map[string]interface{}{"source": "bar"}}},
+				},
+			}},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("handlers (-want,+got): %v", diff)
+		}
+	})
+
+	t.Run("targets config is dumped", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/debug/targets", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		var got config.TargetsConfig
+		if err := protojson.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !proto.Equal(targets, &got) {
+			t.Errorf("targets config got=%v, want=%v", &got, targets)
+		}
+	})
+}
+
+func TestAdminHandlerDisabled(t *testing.T) {
+	h := adminHandler("", memory.NewEmptyTargets(), func() []handlerInfo { return nil }, nil)
+	req := httptest.NewRequest(http.MethodGet, "/debug/handlers", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status code got=%d, want=%d", w.Code, http.StatusForbidden)
+	}
+}
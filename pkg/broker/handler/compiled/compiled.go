@@ -0,0 +1,128 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package compiled caches the filters and transforms compiled from the targets
+// config, so that events aren't held up by compiling them.
+package compiled
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
+	"github.com/google/knative-gcp/pkg/broker/eventtransform"
+)
+
+// Target holds the structured filters and transforms compiled from a target.
+type Target struct {
+	// Filter matches the events passing the structured filters of the target.
+	Filter eventfilter.Filter
+	// FilterErr is the error compiling the filters, if any.
+	FilterErr error
+	// Transformer applies the transforms of the target.
+	Transformer eventtransform.Transformer
+	// TransformErr is the error compiling the transforms, if any.
+	TransformErr error
+
+	filters    []*config.Filter
+	transforms []*config.Transform
+}
+
+func compile(t *config.Target) *Target {
+	c := &Target{filters: t.Filters, transforms: t.Transforms}
+	c.Filter, c.FilterErr = eventfilter.Compile(t.Filters)
+	c.Transformer, c.TransformErr = eventtransform.Compile(t.Transforms)
+	return c
+}
+
+// compiledFrom returns true if the filters and transforms of the target are
+// the ones c was compiled from.
+func (c *Target) compiledFrom(t *config.Target) bool {
+	if len(c.filters) != len(t.Filters) || len(c.transforms) != len(t.Transforms) {
+		return false
+	}
+	for i := range c.filters {
+		if !proto.Equal(c.filters[i], t.Filters[i]) {
+			return false
+		}
+	}
+	for i := range c.transforms {
+		if !proto.Equal(c.transforms[i], t.Transforms[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Cache holds the compiled targets by target key. It is shared by the handlers
+// of a pool and synced with the targets config by the pool.
+type Cache struct {
+	mu sync.RWMutex
+	// targets is never written once stored, so that it can be read without
+	// the lock once loaded. It is replaced by a copy instead.
+	targets map[string]*Target
+}
+
+// NewCache creates an empty Cache.
+func NewCache() *Cache {
+	return &Cache{targets: make(map[string]*Target)}
+}
+
+// Sync compiles the targets of the config and evicts the deleted targets. The
+// targets whose filters and transforms didn't change are not compiled again.
+func (c *Cache) Sync(targets config.ReadonlyTargets) {
+	c.mu.RLock()
+	old := c.targets
+	c.mu.RUnlock()
+
+	synced := make(map[string]*Target)
+	targets.RangeAllTargets(func(t *config.Target) bool {
+		ct, ok := old[t.Key()]
+		if !ok || !ct.compiledFrom(t) {
+			ct = compile(t)
+		}
+		synced[t.Key()] = ct
+		return true
+	})
+
+	c.mu.Lock()
+	c.targets = synced
+	c.mu.Unlock()
+}
+
+// Get returns the compiled target. A target updated since the last Sync is
+// compiled and kept until the next Sync, which evicts it if it was deleted.
+func (c *Cache) Get(t *config.Target) *Target {
+	c.mu.RLock()
+	ct, ok := c.targets[t.Key()]
+	c.mu.RUnlock()
+	if ok && ct.compiledFrom(t) {
+		return ct
+	}
+
+	ct = compile(t)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	targets := make(map[string]*Target, len(c.targets)+1)
+	for k, v := range c.targets {
+		targets[k] = v
+	}
+	targets[t.Key()] = ct
+	c.targets = targets
+	return ct
+}
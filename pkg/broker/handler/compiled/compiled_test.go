@@ -0,0 +1,123 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compiled
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+func newTarget(name, prefix string) *config.Target {
+	return &config.Target{
+		Name:      name,
+		Broker:    "broker",
+		Namespace: "ns",
+		Filters:   []*config.Filter{{Prefix: map[string]string{"type": prefix}}},
+	}
+}
+
+func TestCacheSync(t *testing.T) {
+	targets := memory.NewEmptyTargets()
+	targets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(newTarget("t1", "com.example."), newTarget("t2", "com.example."))
+	})
+	c := NewCache()
+	c.Sync(targets)
+	if got := len(c.targets); got != 2 {
+		t.Fatalf("compiled targets after sync got=%d, want=2", got)
+	}
+	t1, _ := targets.GetTarget("ns", "broker", "t1")
+	compiledT1 := c.Get(t1)
+	if compiledT1.FilterErr != nil {
+		t.Fatalf("unexpected error compiling filters: %v", compiledT1.FilterErr)
+	}
+
+	// t1 is unchanged, t2 is deleted and t3 is added.
+	targets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.DeleteTargets(newTarget("t2", ""))
+		bm.UpsertTargets(newTarget("t1", "com.example."), newTarget("t3", "org.example."))
+	})
+	c.Sync(targets)
+	if _, ok := c.targets["ns/broker/t2"]; ok {
+		t.Error("deleted target was not evicted")
+	}
+	if _, ok := c.targets["ns/broker/t3"]; !ok {
+		t.Error("added target was not compiled")
+	}
+	t1, _ = targets.GetTarget("ns", "broker", "t1")
+	if c.Get(t1) != compiledT1 {
+		t.Error("unchanged target was compiled again")
+	}
+}
+
+func TestCacheGet(t *testing.T) {
+	c := NewCache()
+
+	// A target added since the last sync is compiled on demand.
+	target := newTarget("t1", "com.example.")
+	got := c.Get(target)
+	if got.FilterErr != nil || got.TransformErr != nil {
+		t.Fatalf("unexpected errors compiling target: %v, %v", got.FilterErr, got.TransformErr)
+	}
+	if c.Get(target) != got {
+		t.Error("target was compiled again")
+	}
+
+	// A target updated since the last sync is compiled again.
+	updated := newTarget("t1", "org.example.")
+	if c.Get(updated) == got {
+		t.Error("updated target was not compiled again")
+	}
+
+	invalid := newTarget("t2", "")
+	invalid.Filters = []*config.Filter{{}}
+	if got := c.Get(invalid); got.FilterErr == nil {
+		t.Error("invalid filters compiled without error")
+	}
+
+	invalid = newTarget("t3", "com.example.")
+	invalid.Transforms = []*config.Transform{{}}
+	if got := c.Get(invalid); got.TransformErr == nil {
+		t.Error("invalid transforms compiled without error")
+	}
+}
+
+func TestCacheConcurrentSyncAndGet(t *testing.T) {
+	targets := memory.NewEmptyTargets()
+	targets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		for i := 0; i < 100; i++ {
+			bm.UpsertTargets(newTarget(fmt.Sprintf("t%d", i), "com.example."))
+		}
+	})
+	c := NewCache()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c.Sync(targets)
+		}
+	}()
+	// The targets are always updated since the last sync, so they're
+	// compiled and stored by Get while the cache is synced.
+	for i := 0; i < 1000; i++ {
+		c.Get(newTarget(fmt.Sprintf("t%d", i%100), "org.example."))
+	}
+	<-done
+}
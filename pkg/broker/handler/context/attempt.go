@@ -0,0 +1,37 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package context
+
+import (
+	"context"
+)
+
+type deliveryAttemptKey struct{}
+
+// WithDeliveryAttempt sets the delivery attempt of the message being processed in the context.
+func WithDeliveryAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, deliveryAttemptKey{}, attempt)
+}
+
+// GetDeliveryAttempt gets the delivery attempt of the message being processed from the context.
+func GetDeliveryAttempt(ctx context.Context) (int, error) {
+	untyped := ctx.Value(deliveryAttemptKey{})
+	if untyped == nil {
+		return 0, ErrDeliveryAttemptNotPresent
+	}
+	return untyped.(int), nil
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package context
+
+import (
+	"context"
+	"time"
+)
+
+type publishTimeKey struct{}
+
+// WithPublishTime sets the publish time of the message being processed in the context.
+func WithPublishTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, publishTimeKey{}, t)
+}
+
+// GetPublishTime gets the publish time of the message being processed from the context.
+func GetPublishTime(ctx context.Context) (time.Time, error) {
+	untyped := ctx.Value(publishTimeKey{})
+	if untyped == nil {
+		return time.Time{}, ErrPublishTimeNotPresent
+	}
+	return untyped.(time.Time), nil
+}
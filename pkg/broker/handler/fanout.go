@@ -18,22 +18,27 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"cloud.google.com/go/pubsub"
 	ceclient "github.com/cloudevents/sdk-go/v2/client"
 	"go.uber.org/zap"
 	"knative.dev/eventing/pkg/logging"
 
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/handler/compiled"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/dedup"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/fanout"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/transform"
+	"github.com/google/knative-gcp/pkg/broker/transport"
 	"github.com/google/knative-gcp/pkg/metrics"
 )
 
@@ -46,28 +51,47 @@ type FanoutPool struct {
 	targets config.ReadonlyTargets
 	pool    sync.Map
 
-	// Pubsub client used to pull events from decoupling topics.
-	pubsubClient *pubsub.Client
+	// Transport used to pull events from decoupling queues.
+	transport transport.Transport
 	// For sending retry events. We only need a shared client.
 	// And we can set retry topic dynamically.
 	deliverRetryClient ceclient.Client
+	// For sending retry events with an ordering key, which the retry client
+	// doesn't support.
+	orderedRetryPublisher *deliver.OrderedPublisher
 	// For initial events delivery. We only need a shared client.
 	// And we can set target address dynamically.
 	deliverClient *http.Client
+	// Circuit breakers of target addresses shared by all handlers.
+	breakers *deliver.Breakers
+	// The compiled filters and transforms of the targets shared by all handlers.
+	compiled *compiled.Cache
+	// The in-memory stores of the events processed by each broker. They
+	// outlive the broker handlers, which are renewed on config changes.
+	dedupStores   sync.Map
 	statsReporter *metrics.DeliveryReporter
+	// The handlers which haven't stopped yet.
+	running runningHandlers
+	// Backoff of the restarts of the failed handlers.
+	restarts *restartBackoff
 }
 
 type fanoutHandlerCache struct {
 	Handler
 	b *config.Broker
+	// generation is the generation of the targets config the handler was last synced with.
+	generation int64
 }
 
 // If somehow the existing handler's setting has deviated from the current broker config,
 // we need to renew the handler.
 func (hc *fanoutHandlerCache) shouldRenew(b *config.Broker) bool {
-	if !hc.IsAlive() {
-		return true
-	}
+	return !hc.IsAlive() || hc.configChanged(b)
+}
+
+// configChanged returns true if the handler's setting has deviated from the
+// current broker config.
+func (hc *fanoutHandlerCache) configChanged(b *config.Broker) bool {
 	// If this really happens, it means a data corruption.
 	// The handler creation will fail (which is expected).
 	if b == nil || b.DecoupleQueue == nil {
@@ -83,7 +107,7 @@ func (hc *fanoutHandlerCache) shouldRenew(b *config.Broker) bool {
 // NewFanoutPool creates a new fanout handler pool.
 func NewFanoutPool(
 	targets config.ReadonlyTargets,
-	pubsubClient *pubsub.Client,
+	transport transport.Transport,
 	deliverClient *http.Client,
 	retryClient RetryClient,
 	statsReporter *metrics.DeliveryReporter,
@@ -107,16 +131,84 @@ func NewFanoutPool(
 		options.DeliveryTimeout = options.TimeoutPerEvent - (5 * time.Second)
 	}
 	p := &FanoutPool{
-		targets:            targets,
-		options:            options,
-		pubsubClient:       pubsubClient,
-		deliverClient:      deliverClient,
-		deliverRetryClient: retryClient,
-		statsReporter:      statsReporter,
+		targets:               targets,
+		options:               options,
+		transport:             transport,
+		deliverClient:         deliverClient,
+		deliverRetryClient:    retryClient,
+		orderedRetryPublisher: deliver.NewOrderedPublisher(transport),
+		breakers:              deliver.NewBreakers(options.BreakerSettings),
+		compiled:              compiled.NewCache(),
+		statsReporter:         statsReporter,
+		restarts:              newRestartBackoff(),
 	}
 	return p, nil
 }
 
+// DebugHandler serves the admin API listing the handlers, dumping the targets
+// config and the status of the circuit breakers.
+func (p *FanoutPool) DebugHandler() http.Handler {
+	return adminHandler(p.options.AdminToken, p.targets, p.handlerInfos, p.breakers)
+}
+
+// Drain stops all the handlers and waits until their events in flight are
+// done, or the ctx is done.
+func (p *FanoutPool) Drain(ctx context.Context) error {
+	if err := p.running.drain(ctx); err != nil {
+		return err
+	}
+	p.orderedRetryPublisher.Retain(func(string) bool { return false })
+	return nil
+}
+
+// Health returns the health of the handler of each broker.
+func (p *FanoutPool) Health() PoolHealth {
+	handlers := 0
+	var failed []FailedHandler
+	p.pool.Range(func(key, value interface{}) bool {
+		handlers++
+		if hc := value.(*fanoutHandlerCache); !hc.IsAlive() {
+			failed = append(failed, FailedHandler{Key: key.(string), Status: hc.Status()})
+		}
+		return true
+	})
+	return poolHealth(handlers, failed)
+}
+
+// handlerInfos lists the state of the handler of each broker.
+func (p *FanoutPool) handlerInfos() []handlerInfo {
+	var infos []handlerInfo
+	p.pool.Range(func(key, value interface{}) bool {
+		hc := value.(*fanoutHandlerCache)
+		info := handlerInfo{
+			Broker:     key.(string),
+			Generation: atomic.LoadInt64(&hc.generation),
+			Status:     hc.Status(),
+		}
+		if b, ok := p.targets.GetBrokerByKey(key.(string)); ok && len(b.Targets) > 0 {
+			info.Filters = make(map[string]json.RawMessage, len(b.Targets))
+			for name, t := range b.Targets {
+				info.Filters[name] = filterDefinition(t)
+			}
+		}
+		infos = append(infos, info)
+		return true
+	})
+	return infos
+}
+
+// dedupStore returns the store of the events processed by the broker.
+func (p *FanoutPool) dedupStore(b *config.Broker) dedup.Store {
+	if p.options.DedupStore != nil {
+		return p.options.DedupStore
+	}
+	if s, ok := p.dedupStores.Load(b.Key()); ok {
+		return s.(dedup.Store)
+	}
+	s, _ := p.dedupStores.LoadOrStore(b.Key(), dedup.NewLRUStore(p.options.DedupCacheSize))
+	return s.(dedup.Store)
+}
+
 // SyncOnce syncs once the handler pool based on the targets config.
 func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 	ctx, err := p.statsReporter.AddTags(ctx)
@@ -128,18 +220,40 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 		if _, ok := p.targets.GetBrokerByKey(key.(string)); !ok {
 			value.(*fanoutHandlerCache).Stop()
 			p.pool.Delete(key)
+			p.dedupStores.Delete(key)
+			p.restarts.forget(key.(string))
 		}
 		return true
 	})
+	p.retainRetryTopics()
+	p.compiled.Sync(p.targets)
 
+	generation := p.targets.Generation()
 	p.targets.RangeBrokers(func(b *config.Broker) bool {
+		var restarts int32
 		if value, ok := p.pool.Load(b.Key()); ok {
+			hc := value.(*fanoutHandlerCache)
 			// Skip if we don't need to renew the handler.
-			if !value.(*fanoutHandlerCache).shouldRenew(b) {
+			if !hc.shouldRenew(b) {
+				atomic.StoreInt64(&hc.generation, generation)
+				// The handler is healthy again once it processed an event.
+				if hc.Status().LastAckTime != nil {
+					p.restarts.forget(b.Key())
+				}
 				return true
 			}
+			restarts = hc.restarts
+			if !hc.IsAlive() {
+				// Restart the failed handler after a backoff, unless its
+				// config changed.
+				if !hc.configChanged(b) && !p.restarts.ready(b.Key()) {
+					atomic.StoreInt64(&hc.generation, generation)
+					return true
+				}
+				restarts++
+			}
 			// Stop and clean up the old handler before we start a new one.
-			value.(*fanoutHandlerCache).Stop()
+			hc.Stop()
 			p.pool.Delete(b.Key())
 		}
 
@@ -149,14 +263,25 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 			return true
 		}
 
-		sub := p.pubsubClient.Subscription(b.DecoupleQueue.Subscription)
-		sub.ReceiveSettings = p.options.PubsubReceiveSettings
-
 		h := NewHandler(
-			sub,
+			p.transport.Subscription(b.DecoupleQueue.Subscription),
 			processors.ChainProcessors(
-				&fanout.Processor{MaxConcurrency: p.options.MaxConcurrencyPerEvent, Targets: p.targets},
-				&filter.Processor{Targets: p.targets},
+				&dedup.Processor{Targets: p.targets, Store: p.dedupStore(b), StatsReporter: p.statsReporter},
+				&fanout.Processor{
+					MaxConcurrency: p.options.MaxConcurrencyPerEvent,
+					Targets:        p.targets,
+					// Events for paused targets are kept in their retry queues if they pass the filters.
+					Divert: processors.ChainProcessors(
+						&filter.Processor{Targets: p.targets, Compiled: p.compiled},
+						&deliver.DivertProcessor{
+							Targets:               p.targets,
+							DeliverRetryClient:    p.deliverRetryClient,
+							OrderedRetryPublisher: p.orderedRetryPublisher,
+						},
+					),
+				},
+				&filter.Processor{Targets: p.targets, Compiled: p.compiled},
+				&transform.Processor{Targets: p.targets, Compiled: p.compiled},
 				&deliver.Processor{
 					DeliverClient:      p.deliverClient,
 					Targets:            p.targets,
@@ -164,18 +289,27 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 					DeliverRetryClient: p.deliverRetryClient,
 					DeliverTimeout:     p.options.DeliveryTimeout,
 					StatsReporter:      p.statsReporter,
+					ClaimCheckStore:    p.options.ClaimCheckStore,
+					Breakers:           p.breakers,
+					TokenSource:        p.options.TokenSource,
+					Classifier:         p.options.Classifier,
+					LoopDetected:       p.options.LoopDetected,
+					Backoff:            p.options.orderedBackoff,
 				},
 			),
 			p.options.TimeoutPerEvent,
 			p.options.RetryPolicy,
 		)
+		h.DrainTimeout = p.options.DrainTimeout
+		h.restarts = restarts
 		hc := &fanoutHandlerCache{
-			Handler: *h,
-			b:       b,
+			Handler:    *h,
+			b:          b,
+			generation: generation,
 		}
 
 		// Start the handler with broker key in context.
-		hc.Start(handlerctx.WithBrokerKey(ctx, b.Key()), func(err error) {
+		p.running.start(handlerctx.WithBrokerKey(ctx, b.Key()), &hc.Handler, func(err error) {
 			if err != nil {
 				logging.FromContext(ctx).Error("handler for broker has stopped with error", zap.String("broker", b.Key()), zap.Error(err))
 			} else {
@@ -187,5 +321,23 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 		return true
 	})
 
+	if p.options.ReportHealth != nil {
+		p.options.ReportHealth(p.Health())
+	}
+	if p.options.Synced != nil {
+		p.options.Synced(generation)
+	}
 	return nil
 }
+
+// retainRetryTopics stops the retry topics of the deleted targets.
+func (p *FanoutPool) retainRetryTopics() {
+	topics := make(map[string]bool)
+	p.targets.RangeAllTargets(func(t *config.Target) bool {
+		if t.RetryQueue != nil {
+			topics[t.RetryQueue.Topic] = true
+		}
+		return true
+	})
+	p.orderedRetryPublisher.Retain(func(topicID string) bool { return topics[topicID] })
+}
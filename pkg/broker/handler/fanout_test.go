@@ -25,10 +25,14 @@ import (
 	"github.com/cloudevents/sdk-go/v2/event"
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/sync/errgroup"
+	"k8s.io/client-go/util/workqueue"
 
 	"github.com/google/knative-gcp/pkg/broker/config"
+	configmemory "github.com/google/knative-gcp/pkg/broker/config/memory"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlertesting "github.com/google/knative-gcp/pkg/broker/handler/testing"
+	"github.com/google/knative-gcp/pkg/broker/transport/memory"
+	"github.com/google/knative-gcp/pkg/metrics"
 	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
 
 	_ "knative.dev/pkg/metrics/testing"
@@ -117,6 +121,81 @@ func TestFanoutWatchAndSync(t *testing.T) {
 	})
 }
 
+func TestFanoutRestartFailedHandlers(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reporter, err := metrics.NewDeliveryReporter(fanoutPod, fanoutContainer)
+	if err != nil {
+		t.Fatalf("failed to create delivery reporter: %v", err)
+	}
+
+	targets := configmemory.NewEmptyTargets()
+	targets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.SetState(config.State_READY)
+		bm.SetDecoupleQueue(&config.Queue{Topic: "topic", Subscription: "sub"})
+	})
+	// The handler fails right away as the subscription doesn't exist yet.
+	tr := memory.New()
+	var reported PoolHealth
+	p, err := NewFanoutPool(targets, tr, http.DefaultClient, nil, reporter,
+		WithReportHealth(func(h PoolHealth) { reported = h }))
+	if err != nil {
+		t.Fatalf("unexpected error from creating fanout pool: %v", err)
+	}
+	p.restarts.limiter = workqueue.NewItemExponentialFailureRateLimiter(200*time.Millisecond, time.Second)
+
+	if err := p.SyncOnce(ctx); err != nil {
+		t.Fatalf("unexpected error from syncing fanout pool: %v", err)
+	}
+	waitForHealth(t, p, HealthFailed)
+	if err := p.SyncOnce(ctx); err != nil {
+		t.Fatalf("unexpected error from syncing fanout pool: %v", err)
+	}
+	if reported.Status != HealthFailed || len(reported.Failed) != 1 {
+		t.Fatalf("reported health got=%+v, want one failed handler", reported)
+	}
+	if got := reported.Failed[0]; got.Key != "ns/broker" || got.LastError == "" || got.Restarts != 0 {
+		t.Errorf("failed handler got=%+v, want key ns/broker with an error and no restart", got)
+	}
+
+	// The handler isn't restarted before the backoff.
+	tr.CreateSubscription("sub", "topic")
+	if err := p.SyncOnce(ctx); err != nil {
+		t.Fatalf("unexpected error from syncing fanout pool: %v", err)
+	}
+	if reported.Status != HealthFailed {
+		t.Errorf("reported health status before backoff got=%v, want=%v", reported.Status, HealthFailed)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if err := p.SyncOnce(ctx); err != nil {
+		t.Fatalf("unexpected error from syncing fanout pool: %v", err)
+	}
+	if reported.Status != HealthOK || reported.Handlers != 1 {
+		t.Errorf("reported health after restart got=%+v, want one healthy handler", reported)
+	}
+	value, ok := p.pool.Load("ns/broker")
+	if !ok {
+		t.Fatal("handler not found after restart")
+	}
+	if got := value.(*fanoutHandlerCache).Status(); !got.Alive || got.Restarts != 1 {
+		t.Errorf("restarted handler status got=%+v, want alive with one restart", got)
+	}
+}
+
+func waitForHealth(t *testing.T, p HealthPool, status string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for p.Health().Status != status {
+		select {
+		case <-timeout:
+			t.Fatalf("timed out waiting for pool health %v, got %+v", status, p.Health())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
 func TestFanoutSyncPoolE2E(t *testing.T) {
 	reportertest.ResetDeliveryMetrics()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -413,6 +492,14 @@ func assertFanoutHandlers(t *testing.T, p *FanoutPool, targets config.Targets) {
 	if diff := cmp.Diff(wantHandlers, gotHandlers); diff != "" {
 		t.Errorf("handlers map (-want,+got): %v", diff)
 	}
+
+	gotInfos := make(map[string]bool)
+	for _, info := range p.handlerInfos() {
+		gotInfos[info.Broker] = info.Alive
+	}
+	if diff := cmp.Diff(wantHandlers, gotInfos); diff != "" {
+		t.Errorf("listed handlers (-want,+got): %v", diff)
+	}
 }
 
 func wantTags(target *config.Target) map[string]string {
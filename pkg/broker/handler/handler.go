@@ -22,22 +22,25 @@ import (
 	"sync/atomic"
 	"time"
 
-	"cloud.google.com/go/pubsub"
-	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
 	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/broker/transport"
 	"github.com/google/knative-gcp/pkg/metrics"
+	"github.com/google/knative-gcp/pkg/utils"
+	"github.com/google/knative-gcp/pkg/utils/delivery"
 	"go.uber.org/zap"
 	"k8s.io/client-go/util/workqueue"
 	"knative.dev/eventing/pkg/logging"
 )
 
-// Handler pulls Pubsub messages as events and processes them
+// Handler pulls messages from a subscription as events and processes them
 // with chain of processors.
 type Handler struct {
-	// PubsubEvents is the CloudEvents Pubsub protocol to pull
-	// messages as events.
-	Subscription *pubsub.Subscription
+	// Subscription is the subscription to pull messages from.
+	Subscription transport.Subscription
 
 	// Processor is the processor to process events.
 	Processor processors.Interface
@@ -45,18 +48,51 @@ type Handler struct {
 	// Timeout is the timeout for processing each individual event.
 	Timeout time.Duration
 
+	// DrainTimeout is how long the events in flight are given to finish once
+	// the handler stops pulling messages. They're aborted afterwards.
+	DrainTimeout time.Duration
+
 	// retryLimiter limits how fast to retry failed events.
 	retryLimiter workqueue.RateLimiter
 	// delayNack defaults to time.Sleep; could be overridden in test.
 	delayNack func(time.Duration)
 	// cancel is function to stop pulling messages.
 	cancel context.CancelFunc
-	alive  atomic.Value
+	// stopped is closed once the handler has stopped pulling messages and no
+	// event is in flight anymore.
+	stopped chan struct{}
+	// draining is set to 1 once the handler stops pulling messages.
+	draining int32
+	alive    atomic.Value
+	// inFlight is the number of events being processed.
+	inFlight int64
+	// lastError is the last *handlerError of the handler, if any.
+	lastError atomic.Value
+	// lastAck is the time.Time of the last event processed successfully, if any.
+	lastAck atomic.Value
+	// restarts is the number of times the handler was restarted after failing.
+	restarts int32
+}
+
+// handlerError is an error of the handler and when it happened.
+type handlerError struct {
+	err  string
+	time time.Time
+}
+
+// Status is the state of a handler reported by the admin API.
+type Status struct {
+	Alive         bool       `json:"alive"`
+	InFlight      int64      `json:"inFlight"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastErrorTime *time.Time `json:"lastErrorTime,omitempty"`
+	LastAckTime   *time.Time `json:"lastAckTime,omitempty"`
+	Restarts      int32      `json:"restarts,omitempty"`
 }
 
 // NewHandler creates a new Handler.
 func NewHandler(
-	sub *pubsub.Subscription,
+	sub transport.Subscription,
 	processor processors.Interface,
 	timeout time.Duration,
 	retryPolicy RetryPolicy,
@@ -65,41 +101,115 @@ func NewHandler(
 		Subscription: sub,
 		Processor:    processor,
 		Timeout:      timeout,
-		retryLimiter: workqueue.NewItemExponentialFailureRateLimiter(retryPolicy.MinBackoff, retryPolicy.MaxBackoff),
+		retryLimiter: newRetryLimiter(retryPolicy),
 		delayNack:    time.Sleep,
 	}
 }
 
+func newRetryLimiter(retryPolicy RetryPolicy) workqueue.RateLimiter {
+	if retryPolicy.BackoffPolicy == config.BackoffPolicy_LINEAR {
+		// With no fast attempts, every failure is delayed by the same backoff.
+		return workqueue.NewItemFastSlowRateLimiter(retryPolicy.MinBackoff, retryPolicy.MinBackoff, 0)
+	}
+	return workqueue.NewItemExponentialFailureRateLimiter(retryPolicy.MinBackoff, retryPolicy.MaxBackoff)
+}
+
 // Start starts the handler.
-// done func will be called if the pubsub inbound is closed.
+// done func will be called if the subscription inbound is closed.
+// The handler stops pulling messages when the ctx is done, and the events in
+// flight are given up to DrainTimeout to finish.
 func (h *Handler) Start(ctx context.Context, done func(error)) {
+	// Events are processed with a context which isn't canceled along with the
+	// receiving, so that they aren't aborted as soon as the handler stops.
+	processCtx, abort := context.WithCancel(utils.DetachContext(ctx))
 	ctx, h.cancel = context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	h.stopped = stopped
 	h.alive.Store(true)
 
 	go func() {
+		defer close(stopped)
+		defer abort()
 		// For any reason if inbound is closed, mark alive as false.
 		defer h.alive.Store(false)
-		done(h.Subscription.Receive(ctx, h.receive))
+		// Receive only returns once all the events in flight are done.
+		err := h.Subscription.Receive(ctx, func(_ context.Context, msg *transport.Message) {
+			h.receive(processCtx, msg)
+		})
+		if err != nil {
+			h.recordError(err)
+		}
+		done(err)
+	}()
+
+	go func() {
+		select {
+		case <-stopped:
+			return
+		case <-ctx.Done():
+		}
+		atomic.StoreInt32(&h.draining, 1)
+		timer := time.NewTimer(h.DrainTimeout)
+		defer timer.Stop()
+		select {
+		case <-stopped:
+		case <-timer.C:
+			logging.FromContext(ctx).Warn("events in flight didn't finish within the drain timeout; aborting them",
+				zap.Int64("inFlight", atomic.LoadInt64(&h.inFlight)), zap.Duration("drainTimeout", h.DrainTimeout))
+			abort()
+		}
 	}()
 }
 
-// Stop stops the handlers.
+// Stop stops the handler from pulling messages. It doesn't wait for the events
+// in flight, which are given up to DrainTimeout to finish; use Done to wait
+// for them.
 func (h *Handler) Stop() {
 	h.cancel()
 }
 
+// Done returns a channel which is closed once the handler has stopped and no
+// event is in flight anymore.
+func (h *Handler) Done() <-chan struct{} {
+	return h.stopped
+}
+
 // IsAlive indicates whether the handler is alive.
 func (h *Handler) IsAlive() bool {
 	return h.alive.Load().(bool)
 }
 
-func (h *Handler) receive(ctx context.Context, msg *pubsub.Message) {
+// Status returns the current state of the handler.
+func (h *Handler) Status() Status {
+	s := Status{
+		Alive:    h.IsAlive(),
+		InFlight: atomic.LoadInt64(&h.inFlight),
+		Restarts: h.restarts,
+	}
+	if e, ok := h.lastError.Load().(*handlerError); ok {
+		s.LastError = e.err
+		s.LastErrorTime = &e.time
+	}
+	if t, ok := h.lastAck.Load().(time.Time); ok {
+		s.LastAckTime = &t
+	}
+	return s
+}
+
+func (h *Handler) recordError(err error) {
+	h.lastError.Store(&handlerError{err: err.Error(), time: time.Now()})
+}
+
+func (h *Handler) receive(ctx context.Context, msg *transport.Message) {
+	atomic.AddInt64(&h.inFlight, 1)
+	defer atomic.AddInt64(&h.inFlight, -1)
 	ctx = metrics.StartEventProcessing(ctx)
-	event, err := binding.ToEvent(ctx, cepubsub.NewMessage(msg))
+	event, err := transport.ToEvent(ctx, msg)
 	if isNonRetryable(err) {
 		logEventConversionError(ctx, msg, err, "failed to convert received message to an event, check the msg format")
 		// Ack the message so it won't be retried.
-		// TODO Should this go to the DLQ once DLQ is implemented?
+		// The message is not sent to the dead letter sink because the sink
+		// only accepts events and the message cannot be converted to one.
 		msg.Ack()
 		return
 	}
@@ -109,13 +219,40 @@ func (h *Handler) receive(ctx context.Context, msg *pubsub.Message) {
 		return
 	}
 
-	if h.Timeout != 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
-		defer cancel()
+	ctx = handlerctx.WithDeliveryAttempt(ctx, h.deliveryAttempt(msg))
+	ctx = handlerctx.WithPublishTime(ctx, msg.PublishTime)
+
+	err = h.process(ctx, event)
+	// Pubsub counts every delivery of the message once it reports the delivery
+	// attempts, so the deliveries which didn't reach the target are retried in
+	// place once due instead of being nacked.
+	for err != nil && !delivery.Attempted(err) && msg.DeliveryAttempt != nil && ctx.Err() == nil && atomic.LoadInt32(&h.draining) == 0 {
+		delay := delivery.RetryDelay(err)
+		if delay <= 0 || delay > maxTimeout {
+			break
+		}
+		h.delayNack(delay)
+		err = h.process(ctx, event)
 	}
-	if err := h.Processor.Process(ctx, event); err != nil {
-		backoffPeriod := h.retryLimiter.When(msg.ID)
+	if err != nil {
+		h.recordError(err)
+		var backoffPeriod time.Duration
+		// Deliveries which didn't reach the target don't count as attempts.
+		if delivery.Attempted(err) {
+			backoffPeriod = h.retryLimiter.When(msg.ID)
+		}
+		// Wait at least as long as the target asked to with Retry-After.
+		if delay := delivery.RetryDelay(err); delay > backoffPeriod {
+			backoffPeriod = delay
+			if backoffPeriod > maxTimeout {
+				backoffPeriod = maxTimeout
+			}
+		}
+		// Nack right away while draining so that the shutdown isn't held up by
+		// the backoff.
+		if atomic.LoadInt32(&h.draining) == 1 {
+			backoffPeriod = 0
+		}
 		logging.FromContext(ctx).Error("failed to process event; backoff nack", zap.String("eventID", event.ID()), zap.Duration("backoffPeriod", backoffPeriod), zap.Error(err))
 		h.delayNack(backoffPeriod)
 		msg.Nack()
@@ -124,6 +261,30 @@ func (h *Handler) receive(ctx context.Context, msg *pubsub.Message) {
 
 	h.retryLimiter.Forget(msg.ID)
 	msg.Ack()
+	h.lastAck.Store(time.Now())
+}
+
+// process processes the event within the timeout of the handler.
+func (h *Handler) process(ctx context.Context, e *event.Event) error {
+	if h.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	return h.Processor.Process(ctx, e)
+}
+
+// deliveryAttempt returns the number of times the message has been delivered
+// including the current delivery. Pubsub only reports the delivery attempt if
+// the subscription has a dead letter policy, as the retry subscriptions do.
+// Otherwise it falls back to the number of failures this handler has observed
+// for the message, which restarts with the handler. Failures which didn't
+// reach the target, such as nacks before the retry is due, aren't counted.
+func (h *Handler) deliveryAttempt(msg *transport.Message) int {
+	if msg.DeliveryAttempt != nil {
+		return *msg.DeliveryAttempt
+	}
+	return h.retryLimiter.NumRequeues(msg.ID) + 1
 }
 
 func isNonRetryable(err error) bool {
@@ -133,7 +294,7 @@ func isNonRetryable(err error) bool {
 }
 
 // Log the full message in debug level and a truncated version as an error in case the message is too big (can be as big as 10MB),
-func logEventConversionError(ctx context.Context, pm *pubsub.Message, err error, msg string) {
+func logEventConversionError(ctx context.Context, pm *transport.Message, err error, msg string) {
 	maxLen := 2000
 	truncated := pm
 	if len(pm.Data) > maxLen {
@@ -19,6 +19,8 @@ package handler
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
@@ -31,7 +33,13 @@ import (
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
 
+	"github.com/google/knative-gcp/pkg/broker/config"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/broker/transport"
+	"github.com/google/knative-gcp/pkg/broker/transport/memory"
+	pubsubtransport "github.com/google/knative-gcp/pkg/broker/transport/pubsub"
+	"github.com/google/knative-gcp/pkg/utils/delivery"
 )
 
 const (
@@ -85,7 +93,7 @@ func TestHandler(t *testing.T) {
 
 	eventCh := make(chan *event.Event)
 	processor := &processors.FakeProcessor{PrevEventsCh: eventCh}
-	h := NewHandler(sub, processor, time.Second, RetryPolicy{})
+	h := NewHandler(pubsubtransport.New(c).Subscription(sub.ID()), processor, time.Second, RetryPolicy{})
 	h.Start(ctx, func(err error) {})
 	defer h.Stop()
 	if !h.IsAlive() {
@@ -123,6 +131,9 @@ func TestHandler(t *testing.T) {
 				t.Errorf("processed event (-want,+got): %v", diff)
 			}
 		}
+		if s := h.Status(); s.LastError != "process error" || s.LastErrorTime == nil {
+			t.Errorf("handler status got=%+v, want last error %q", s, "process error")
+		}
 	})
 
 	t.Run("message is not an event", func(t *testing.T) {
@@ -149,6 +160,9 @@ func TestHandler(t *testing.T) {
 		if diff := cmp.Diff(&testEvent, gotEvent); diff != "" {
 			t.Errorf("processed event (-want,+got): %v", diff)
 		}
+		if s := h.Status(); s.InFlight != 1 {
+			t.Errorf("handler in-flight events got=%d, want=1", s.InFlight)
+		}
 		unlock = processor.Lock()
 		if !processor.WasCancelled {
 			t.Error("processor was not cancelled on timeout")
@@ -161,11 +175,16 @@ type firstNErrProc struct {
 	processors.BaseProcessor
 	desiredErrCount, currErrCount int
 	successSignal                 chan struct{}
+	// err is the error returned, "always error" if nil.
+	err error
 }
 
 func (p *firstNErrProc) Process(_ context.Context, _ *event.Event) error {
 	if p.currErrCount < p.desiredErrCount {
 		p.currErrCount++
+		if p.err != nil {
+			return p.err
+		}
 		return errors.New("always error")
 	}
 	p.successSignal <- struct{}{}
@@ -204,7 +223,7 @@ func TestRetryBackoff(t *testing.T) {
 		desiredErrCount: desiredErrCount,
 		successSignal:   successSignal,
 	}
-	h := NewHandler(sub, processor, time.Second, RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 16 * time.Millisecond})
+	h := NewHandler(pubsubtransport.New(c).Subscription(sub.ID()), processor, time.Second, RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 16 * time.Millisecond})
 	// Mock sleep func to collect nack backoffs.
 	h.delayNack = func(d time.Duration) {
 		delays = append(delays, d)
@@ -248,6 +267,302 @@ func TestRetryBackoff(t *testing.T) {
 	}
 }
 
+func TestRetryAfterBackoff(t *testing.T) {
+	cases := []struct {
+		name       string
+		retryAfter time.Duration
+		// notAttempted makes the deliveries fail without reaching the subscriber.
+		notAttempted bool
+		wantDelays   []time.Duration
+	}{{
+		name:       "shorter than backoff",
+		retryAfter: time.Microsecond,
+		wantDelays: []time.Duration{time.Millisecond, 2 * time.Millisecond},
+	}, {
+		name:       "longer than backoff",
+		retryAfter: time.Minute,
+		wantDelays: []time.Duration{time.Minute, time.Minute},
+	}, {
+		name:       "longer than max timeout",
+		retryAfter: time.Hour,
+		wantDelays: []time.Duration{maxTimeout, maxTimeout},
+	}, {
+		// The backoff doesn't grow with deliveries which aren't attempts.
+		name:         "not attempted",
+		retryAfter:   time.Microsecond,
+		notAttempted: true,
+		wantDelays:   []time.Duration{time.Microsecond, time.Microsecond, time.Microsecond},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			c, close := testPubsubClient(ctx, t, "test-project")
+			defer close()
+
+			topic, err := c.CreateTopic(ctx, "test-topic")
+			if err != nil {
+				t.Fatalf("failed to create topic: %v", err)
+			}
+			sub, err := c.CreateSubscription(ctx, "test-sub", pubsub.SubscriptionConfig{
+				Topic: topic,
+			})
+			if err != nil {
+				t.Fatalf("failed to create subscription: %v", err)
+			}
+
+			p, err := cepubsub.New(context.Background(),
+				cepubsub.WithClient(c),
+				cepubsub.WithProjectID("test-project"),
+				cepubsub.WithTopicID("test-topic"),
+			)
+			if err != nil {
+				t.Fatalf("failed to create cloudevents pubsub protocol: %v", err)
+			}
+
+			delays := []time.Duration{}
+			successSignal := make(chan struct{})
+			var procErr error = &delivery.Error{StatusCode: http.StatusTooManyRequests, Delay: tc.retryAfter}
+			if tc.notAttempted {
+				procErr = &notAttemptedError{delay: tc.retryAfter}
+			}
+			processor := &firstNErrProc{
+				desiredErrCount: len(tc.wantDelays),
+				successSignal:   successSignal,
+				err:             fmt.Errorf("wrapped: %w", procErr),
+			}
+			h := NewHandler(pubsubtransport.New(c).Subscription(sub.ID()), processor, time.Second, RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 16 * time.Millisecond})
+			// Mock sleep func to collect nack backoffs.
+			h.delayNack = func(d time.Duration) {
+				delays = append(delays, d)
+			}
+			h.Start(ctx, func(err error) {})
+			defer h.Stop()
+
+			testEvent := event.New()
+			testEvent.SetID("id")
+			testEvent.SetSource("source")
+			testEvent.SetSubject("subject")
+			testEvent.SetType("type")
+
+			if err := p.Send(ctx, binding.ToMessage(&testEvent)); err != nil {
+				t.Fatalf("failed to seed event to pubsub: %v", err)
+			}
+
+			<-successSignal
+			cancel()
+
+			if diff := cmp.Diff(tc.wantDelays, delays); diff != "" {
+				t.Errorf("nack delays (-want,+got): %v", diff)
+			}
+		})
+	}
+}
+
+// attemptProc records the delivery attempt of each processing, and fails the
+// first ones with err.
+type attemptProc struct {
+	processors.BaseProcessor
+	failures int
+	err      error
+	attempts chan int
+}
+
+func (p *attemptProc) Process(ctx context.Context, _ *event.Event) error {
+	attempt, _ := handlerctx.GetDeliveryAttempt(ctx)
+	p.attempts <- attempt
+	if p.failures > 0 {
+		p.failures--
+		return p.err
+	}
+	return nil
+}
+
+func TestNotAttemptedRetriedInPlace(t *testing.T) {
+	ctx := context.Background()
+	tr := memory.New()
+	tr.CreateSubscription(testSub, testTopic)
+
+	// The memory transport counts the deliveries like a Pub/Sub subscription
+	// with a dead letter policy.
+	proc := &attemptProc{
+		failures: 2,
+		err:      fmt.Errorf("wrapped: %w", &notAttemptedError{delay: time.Millisecond}),
+		attempts: make(chan int, 10),
+	}
+	h := NewHandler(tr.Subscription(testSub), proc, time.Second, RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	var delays []time.Duration
+	h.delayNack = func(d time.Duration) {
+		delays = append(delays, d)
+	}
+	h.Start(ctx, func(err error) {})
+	defer h.Stop()
+
+	testEvent := event.New()
+	testEvent.SetID("id")
+	testEvent.SetSource("source")
+	testEvent.SetType("type")
+	msg := &transport.Message{}
+	if err := transport.WriteMessage(ctx, binding.ToMessage(&testEvent), msg); err != nil {
+		t.Fatalf("failed to write event to message: %v", err)
+	}
+	if _, err := tr.Topic(testTopic, false).Publish(ctx, msg).Get(ctx); err != nil {
+		t.Fatalf("failed to publish event: %v", err)
+	}
+
+	var got []int
+	for i := 0; i < 3; i++ {
+		select {
+		case a := <-proc.attempts:
+			got = append(got, a)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for processing %d", i)
+		}
+	}
+	// The message isn't delivered again, so the attempt doesn't grow.
+	if diff := cmp.Diff([]int{1, 1, 1}, got); diff != "" {
+		t.Errorf("delivery attempts (-want,+got): %v", diff)
+	}
+	if diff := cmp.Diff([]time.Duration{time.Millisecond, time.Millisecond}, delays); diff != "" {
+		t.Errorf("delays (-want,+got): %v", diff)
+	}
+}
+
+// notAttemptedError is a delivery which failed without reaching the subscriber.
+type notAttemptedError struct {
+	delay time.Duration
+}
+
+func (e *notAttemptedError) Error() string {
+	return "not attempted"
+}
+
+func (e *notAttemptedError) RetryAfter() time.Duration {
+	return e.delay
+}
+
+func (e *notAttemptedError) NotAttempted() bool {
+	return true
+}
+
+// blockingProc blocks processing the events until they're released or the
+// context is done.
+type blockingProc struct {
+	processors.BaseProcessor
+	started chan struct{}
+	release chan struct{}
+	// errs receives the error of the context when each event is done.
+	errs chan error
+}
+
+func (p *blockingProc) Process(ctx context.Context, _ *event.Event) error {
+	p.started <- struct{}{}
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+	}
+	p.errs <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestHandlerDrain(t *testing.T) {
+	tests := []struct {
+		name          string
+		drainTimeout  time.Duration
+		release       bool
+		wantErr       error
+		wantRedeliver bool
+	}{{
+		name:         "event in flight finishes after stop",
+		drainTimeout: time.Minute,
+		release:      true,
+	}, {
+		name:          "event in flight aborted after drain timeout",
+		drainTimeout:  100 * time.Millisecond,
+		wantErr:       context.Canceled,
+		wantRedeliver: true,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			tr := memory.New()
+			tr.CreateSubscription(testSub, testTopic)
+
+			proc := &blockingProc{
+				started: make(chan struct{}, 1),
+				release: make(chan struct{}),
+				errs:    make(chan error, 1),
+			}
+			h := NewHandler(tr.Subscription(testSub), proc, time.Minute, RetryPolicy{})
+			h.DrainTimeout = tc.drainTimeout
+			h.delayNack = func(time.Duration) {}
+			h.Start(ctx, func(err error) {})
+
+			testEvent := event.New()
+			testEvent.SetID("id")
+			testEvent.SetSource("source")
+			testEvent.SetType("type")
+			msg := &transport.Message{}
+			if err := transport.WriteMessage(ctx, binding.ToMessage(&testEvent), msg); err != nil {
+				t.Fatalf("failed to write event to message: %v", err)
+			}
+			if _, err := tr.Topic(testTopic, false).Publish(ctx, msg).Get(ctx); err != nil {
+				t.Fatalf("failed to publish event: %v", err)
+			}
+			<-proc.started
+
+			h.Stop()
+			select {
+			case <-h.Done():
+				t.Fatal("handler stopped with an event in flight")
+			case <-time.After(50 * time.Millisecond):
+			}
+			if tc.release {
+				close(proc.release)
+			}
+			select {
+			case err := <-proc.errs:
+				if err != tc.wantErr {
+					t.Errorf("processing context error got=%v, want=%v", err, tc.wantErr)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for the event in flight")
+			}
+			select {
+			case <-h.Done():
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for the handler to stop")
+			}
+
+			// The message is only redelivered if the event was aborted.
+			eventCh := make(chan *event.Event)
+			next := NewHandler(tr.Subscription(testSub), &processors.FakeProcessor{PrevEventsCh: eventCh}, time.Second, RetryPolicy{})
+			next.Start(ctx, func(err error) {})
+			defer next.Stop()
+			if got := nextEventWithTimeout(eventCh) != nil; got != tc.wantRedeliver {
+				t.Errorf("redelivered got=%v, want=%v", got, tc.wantRedeliver)
+			}
+		})
+	}
+}
+
+func TestLinearRetryBackoff(t *testing.T) {
+	limiter := newRetryLimiter(RetryPolicy{
+		MinBackoff:    time.Millisecond,
+		MaxBackoff:    16 * time.Millisecond,
+		BackoffPolicy: config.BackoffPolicy_LINEAR,
+	})
+	for i := 0; i < 8; i++ {
+		if got := limiter.When("id"); got != time.Millisecond {
+			t.Errorf("delays[%d] got=%v, want=%v", i, got, time.Millisecond)
+		}
+	}
+	if got := limiter.NumRequeues("id"); got != 8 {
+		t.Errorf("requeues got=%d, want=%d", got, 8)
+	}
+}
+
 func nextEventWithTimeout(eventCh <-chan *event.Event) *event.Event {
 	select {
 	case <-time.After(time.Second):
@@ -0,0 +1,124 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// Failed handlers are restarted with an exponential backoff between
+	// minRestartBackoff and maxRestartBackoff.
+	minRestartBackoff = time.Second
+	maxRestartBackoff = 5 * time.Minute
+)
+
+const (
+	// HealthOK is the status of a pool whose handlers are all alive.
+	HealthOK = "ok"
+	// HealthDegraded is the status of a pool with some failed handlers.
+	HealthDegraded = "degraded"
+	// HealthFailed is the status of a pool whose handlers all failed.
+	HealthFailed = "failed"
+)
+
+// HealthPool is implemented by sync pools which report the health of their
+// handlers.
+type HealthPool interface {
+	Health() PoolHealth
+}
+
+// PoolHealth is the health of the handlers of a pool.
+type PoolHealth struct {
+	// Status is one of HealthOK, HealthDegraded and HealthFailed.
+	Status string `json:"status"`
+	// Handlers is the number of handlers of the pool.
+	Handlers int `json:"handlers"`
+	// Failed lists the handlers which failed, sorted by key.
+	Failed []FailedHandler `json:"failed,omitempty"`
+}
+
+// FailedHandler is a handler which stopped with an error and is waiting to be
+// restarted.
+type FailedHandler struct {
+	// Key is the key of the broker of a fanout handler, or of the target of a
+	// retry handler.
+	Key string `json:"key"`
+	Status
+}
+
+// poolHealth returns the health of a pool with the given number of handlers,
+// of which the given ones failed.
+func poolHealth(handlers int, failed []FailedHandler) PoolHealth {
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].Key < failed[j].Key
+	})
+	h := PoolHealth{Status: HealthOK, Handlers: handlers, Failed: failed}
+	if len(failed) > 0 {
+		h.Status = HealthDegraded
+		if len(failed) == handlers {
+			h.Status = HealthFailed
+		}
+	}
+	return h
+}
+
+// restartBackoff spaces out the restarts of the handlers which keep failing.
+type restartBackoff struct {
+	limiter workqueue.RateLimiter
+
+	mux sync.Mutex
+	// next holds when each failed handler can be restarted.
+	next map[string]time.Time
+}
+
+func newRestartBackoff() *restartBackoff {
+	return &restartBackoff{
+		limiter: workqueue.NewItemExponentialFailureRateLimiter(minRestartBackoff, maxRestartBackoff),
+		next:    make(map[string]time.Time),
+	}
+}
+
+// ready returns true if the failed handler with the key can be restarted. The
+// first call after the handler failed schedules its restart.
+func (b *restartBackoff) ready(key string) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	next, ok := b.next[key]
+	if !ok {
+		next = time.Now().Add(b.limiter.When(key))
+		b.next[key] = next
+	}
+	if time.Now().Before(next) {
+		return false
+	}
+	delete(b.next, key)
+	return true
+}
+
+// forget resets the backoff of the handler with the key, once it's healthy
+// again or gone.
+func (b *restartBackoff) forget(key string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.limiter.Forget(key)
+	delete(b.next, key)
+}
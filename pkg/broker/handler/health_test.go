@@ -0,0 +1,97 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestPoolHealth(t *testing.T) {
+	cases := []struct {
+		name     string
+		handlers int
+		failed   []FailedHandler
+		want     PoolHealth
+	}{{
+		name: "no handler",
+		want: PoolHealth{Status: HealthOK},
+	}, {
+		name:     "all handlers alive",
+		handlers: 2,
+		want:     PoolHealth{Status: HealthOK, Handlers: 2},
+	}, {
+		name:     "some handlers failed",
+		handlers: 3,
+		failed:   []FailedHandler{{Key: "ns/b2"}, {Key: "ns/b1"}},
+		want: PoolHealth{
+			Status:   HealthDegraded,
+			Handlers: 3,
+			Failed:   []FailedHandler{{Key: "ns/b1"}, {Key: "ns/b2"}},
+		},
+	}, {
+		name:     "all handlers failed",
+		handlers: 1,
+		failed:   []FailedHandler{{Key: "ns/b1"}},
+		want: PoolHealth{
+			Status:   HealthFailed,
+			Handlers: 1,
+			Failed:   []FailedHandler{{Key: "ns/b1"}},
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, poolHealth(tc.handlers, tc.failed)); diff != "" {
+				t.Errorf("poolHealth (-want,+got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	b := newRestartBackoff()
+	b.limiter = workqueue.NewItemExponentialFailureRateLimiter(100*time.Millisecond, time.Second)
+
+	if b.ready("key") {
+		t.Error("handler ready to restart before the backoff")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if !b.ready("key") {
+		t.Error("handler not ready to restart after the backoff")
+	}
+
+	// The backoff doubles if the handler fails again.
+	if b.ready("key") {
+		t.Error("handler ready to restart before the second backoff")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if b.ready("key") {
+		t.Error("handler ready to restart before the second backoff")
+	}
+
+	// The backoff is reset once the handler is healthy again.
+	b.forget("key")
+	b.ready("key")
+	time.Sleep(150 * time.Millisecond)
+	if !b.ready("key") {
+		t.Error("handler not ready to restart after the backoff was reset")
+	}
+}
@@ -21,12 +21,22 @@ import (
 	"time"
 
 	"cloud.google.com/go/pubsub"
+
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/dedup"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+	"github.com/google/knative-gcp/pkg/utils/delivery"
+	"github.com/google/knative-gcp/pkg/utils/idtoken"
 )
 
 var (
 	defaultHandlerConcurrency     = runtime.NumCPU()
 	defaultMaxConcurrencyPerEvent = 1
 	defaultTimeout                = 10 * time.Minute
+	defaultDedupCacheSize         = 10000
+	defaultDrainTimeout           = 20 * time.Second
+	defaultReplayQuietPeriod      = time.Minute
 
 	// This is the pubsub default MaxExtension.
 	// It would not make sense for handler timeout per event be greater
@@ -40,6 +50,47 @@ var (
 // TODO: https://github.com/google/knative-gcp/issues/1100#issuecomment-638304147
 type RetryPolicy struct {
 	MinBackoff, MaxBackoff time.Duration
+	// BackoffPolicy defines how the backoff grows between retries.
+	// With the linear policy the backoff is always MinBackoff.
+	BackoffPolicy config.BackoffPolicy
+}
+
+// forTarget returns the retry policy for the given target. The backoff policy
+// and delay in the target's delivery spec override the defaults.
+func (rp RetryPolicy) forTarget(t *config.Target) RetryPolicy {
+	if t.DeliverySpec == nil {
+		return rp
+	}
+	rp.BackoffPolicy = t.DeliverySpec.BackoffPolicy
+	if t.DeliverySpec.BackoffDelay != nil {
+		rp.MinBackoff = t.DeliverySpec.BackoffDelay.AsDuration()
+		if rp.MaxBackoff < rp.MinBackoff {
+			rp.MaxBackoff = rp.MinBackoff
+		}
+	}
+	return rp
+}
+
+// backoff returns how long to wait after the given number of failed attempts,
+// the same as the handlers wait before nacking the event.
+func (rp RetryPolicy) backoff(attempts int) time.Duration {
+	if rp.BackoffPolicy == config.BackoffPolicy_LINEAR || attempts < 1 {
+		return rp.MinBackoff
+	}
+	backoff := rp.MinBackoff
+	for i := 1; i < attempts && backoff < rp.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > rp.MaxBackoff {
+		return rp.MaxBackoff
+	}
+	return backoff
+}
+
+// orderedBackoff returns how long to wait before retrying the delivery of an
+// ordered event to the target.
+func (o *Options) orderedBackoff(t *config.Target, attempts int) time.Duration {
+	return o.RetryPolicy.forTarget(t).backoff(attempts)
 }
 
 // Options holds all the options for create handler pool.
@@ -58,6 +109,42 @@ type Options struct {
 	PubsubReceiveSettings pubsub.ReceiveSettings
 	// RetryPolicy defines the retry policy for pubsub messages.
 	RetryPolicy RetryPolicy
+	// ClaimCheckStore is the store of event payloads offloaded by the ingress.
+	ClaimCheckStore claimcheck.Store
+	// BreakerSettings configures the circuit breakers of target addresses.
+	BreakerSettings deliver.BreakerSettings
+	// TokenSource mints the ID tokens authenticating the deliveries to targets.
+	TokenSource idtoken.Source
+	// Classifier decides which failed deliveries to targets are retried.
+	Classifier *delivery.Classifier
+	// DedupCacheSize is the number of events each broker remembers in memory
+	// to drop duplicates. Ignored if DedupStore is set.
+	DedupCacheSize int
+	// DedupStore is the store of processed events shared by all brokers.
+	// If nil, each broker remembers its events in memory.
+	DedupStore dedup.Store
+	// AdminToken is the bearer token authenticating the requests to the admin
+	// API. The admin API is disabled if it's empty.
+	AdminToken string
+	// ReplayCaughtUp is called with the key of a target and true when its
+	// replay caught up in the pod, and with false when an event is replayed
+	// again afterwards. If nil, the end of the replays is not reported.
+	ReplayCaughtUp func(targetKey string, caughtUp bool)
+	// ReplayQuietPeriod is how long no event must be replayed to a target for
+	// its replay to catch up in the pod.
+	ReplayQuietPeriod time.Duration
+	// DrainTimeout is how long the events in flight are given to finish once
+	// a handler stops pulling messages, before they're aborted.
+	DrainTimeout time.Duration
+	// ReportHealth is called with the health of the handlers after each sync.
+	// If nil, the health isn't reported.
+	ReportHealth func(PoolHealth)
+	// LoopDetected is called with the key of a target when an event looping
+	// back to it is dropped. If nil, the loops are not reported.
+	LoopDetected func(targetKey string)
+	// Synced is called with the generation of the targets config after each
+	// sync. If nil, the syncs are not reported.
+	Synced func(generation int64)
 }
 
 // NewOptions creates a Options.
@@ -67,6 +154,9 @@ func NewOptions(opts ...Option) (*Options, error) {
 		MaxConcurrencyPerEvent: defaultMaxConcurrencyPerEvent,
 		TimeoutPerEvent:        defaultTimeout,
 		PubsubReceiveSettings:  pubsub.DefaultReceiveSettings,
+		DedupCacheSize:         defaultDedupCacheSize,
+		DrainTimeout:           defaultDrainTimeout,
+		ReplayQuietPeriod:      defaultReplayQuietPeriod,
 	}
 	for _, o := range opts {
 		o(opt)
@@ -122,3 +212,94 @@ func WithRetryPolicy(r RetryPolicy) Option {
 		o.RetryPolicy = r
 	}
 }
+
+// WithClaimCheckStore sets the ClaimCheckStore.
+func WithClaimCheckStore(s claimcheck.Store) Option {
+	return func(o *Options) {
+		o.ClaimCheckStore = s
+	}
+}
+
+// WithBreakerSettings sets the BreakerSettings.
+func WithBreakerSettings(s deliver.BreakerSettings) Option {
+	return func(o *Options) {
+		o.BreakerSettings = s
+	}
+}
+
+// WithTokenSource sets the TokenSource.
+func WithTokenSource(s idtoken.Source) Option {
+	return func(o *Options) {
+		o.TokenSource = s
+	}
+}
+
+// WithClassifier sets the Classifier.
+func WithClassifier(c *delivery.Classifier) Option {
+	return func(o *Options) {
+		o.Classifier = c
+	}
+}
+
+// WithDedupCacheSize sets the DedupCacheSize.
+func WithDedupCacheSize(size int) Option {
+	return func(o *Options) {
+		o.DedupCacheSize = size
+	}
+}
+
+// WithDedupStore sets the DedupStore.
+func WithDedupStore(s dedup.Store) Option {
+	return func(o *Options) {
+		o.DedupStore = s
+	}
+}
+
+// WithAdminToken sets the AdminToken.
+func WithAdminToken(token string) Option {
+	return func(o *Options) {
+		o.AdminToken = token
+	}
+}
+
+// WithReplayCaughtUp sets the ReplayCaughtUp function.
+func WithReplayCaughtUp(f func(targetKey string, caughtUp bool)) Option {
+	return func(o *Options) {
+		o.ReplayCaughtUp = f
+	}
+}
+
+// WithReplayQuietPeriod sets the ReplayQuietPeriod.
+func WithReplayQuietPeriod(d time.Duration) Option {
+	return func(o *Options) {
+		o.ReplayQuietPeriod = d
+	}
+}
+
+// WithDrainTimeout sets the DrainTimeout.
+func WithDrainTimeout(t time.Duration) Option {
+	return func(o *Options) {
+		o.DrainTimeout = t
+	}
+}
+
+// WithReportHealth sets the ReportHealth function.
+func WithReportHealth(f func(PoolHealth)) Option {
+	return func(o *Options) {
+		o.ReportHealth = f
+	}
+}
+
+// WithSynced sets the Synced function.
+func WithSynced(f func(generation int64)) Option {
+	return func(o *Options) {
+		o.Synced = f
+	}
+}
+
+// WithLoopDetected sets the LoopDetected function.
+func WithLoopDetected(f func(targetKey string)) Option {
+	return func(o *Options) {
+		o.LoopDetected = f
+	}
+}
@@ -17,11 +17,18 @@ limitations under the License.
 package handler
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/dedup"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+	"github.com/google/knative-gcp/pkg/utils/delivery"
 )
 
 func TestWithHandlerConcurrency(t *testing.T) {
@@ -96,3 +103,170 @@ func TestWithDeliveryTimeout(t *testing.T) {
 		t.Errorf("options timeout per event got=%v, want=%v", opt.DeliveryTimeout, want)
 	}
 }
+
+func TestWithClaimCheckStore(t *testing.T) {
+	want, err := claimcheck.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt, err := NewOptions(WithClaimCheckStore(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.ClaimCheckStore != want {
+		t.Errorf("options claim check store got=%v, want=%v", opt.ClaimCheckStore, want)
+	}
+}
+
+func TestWithBreakerSettings(t *testing.T) {
+	want := deliver.BreakerSettings{FailureThreshold: 5, OpenDuration: 30 * time.Second}
+	opt, err := NewOptions(WithBreakerSettings(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, opt.BreakerSettings); diff != "" {
+		t.Errorf("options breaker settings (-want,+got): %v", diff)
+	}
+}
+
+func TestWithClassifier(t *testing.T) {
+	want := delivery.NewClassifier([]int{http.StatusBadRequest})
+	opt, err := NewOptions(WithClassifier(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.Classifier != want {
+		t.Errorf("options classifier got=%v, want=%v", opt.Classifier, want)
+	}
+}
+
+func TestWithDedupCacheSize(t *testing.T) {
+	want := 100
+	opt, err := NewOptions(WithDedupCacheSize(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.DedupCacheSize != want {
+		t.Errorf("options dedup cache size got=%v, want=%v", opt.DedupCacheSize, want)
+	}
+}
+
+func TestWithDedupStore(t *testing.T) {
+	want := dedup.NewLRUStore(10)
+	opt, err := NewOptions(WithDedupStore(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.DedupStore != want {
+		t.Errorf("options dedup store got=%v, want=%v", opt.DedupStore, want)
+	}
+}
+
+func TestWithAdminToken(t *testing.T) {
+	want := "token"
+	opt, err := NewOptions(WithAdminToken(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.AdminToken != want {
+		t.Errorf("options admin token got=%v, want=%v", opt.AdminToken, want)
+	}
+}
+
+func TestWithReplayCaughtUp(t *testing.T) {
+	var got string
+	opt, err := NewOptions(WithReplayCaughtUp(func(targetKey string, caughtUp bool) {
+		if caughtUp {
+			got = targetKey
+		}
+	}))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	opt.ReplayCaughtUp("ns/broker/trigger", true)
+	if got != "ns/broker/trigger" {
+		t.Errorf("options replay caught up called with %q, want %q", got, "ns/broker/trigger")
+	}
+}
+
+func TestWithReplayQuietPeriod(t *testing.T) {
+	want := 10 * time.Second
+	opt, err := NewOptions(WithReplayQuietPeriod(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.ReplayQuietPeriod != want {
+		t.Errorf("options replay quiet period got=%v, want=%v", opt.ReplayQuietPeriod, want)
+	}
+}
+
+func TestWithDrainTimeout(t *testing.T) {
+	want := 5 * time.Second
+	opt, err := NewOptions(WithDrainTimeout(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.DrainTimeout != want {
+		t.Errorf("options drain timeout got=%v, want=%v", opt.DrainTimeout, want)
+	}
+}
+
+func TestWithReportHealth(t *testing.T) {
+	var got PoolHealth
+	opt, err := NewOptions(WithReportHealth(func(h PoolHealth) { got = h }))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	want := PoolHealth{Status: HealthDegraded, Handlers: 2, Failed: []FailedHandler{{Key: "ns/broker"}}}
+	opt.ReportHealth(want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("options reported health (-want,+got): %v", diff)
+	}
+}
+
+func TestWithLoopDetected(t *testing.T) {
+	var got string
+	opt, err := NewOptions(WithLoopDetected(func(targetKey string) { got = targetKey }))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	opt.LoopDetected("ns/broker/trigger")
+	if got != "ns/broker/trigger" {
+		t.Errorf("options loop detected called with %q, want %q", got, "ns/broker/trigger")
+	}
+}
+
+func TestWithSynced(t *testing.T) {
+	var got int64
+	opt, err := NewOptions(WithSynced(func(generation int64) { got = generation }))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	opt.Synced(3)
+	if got != 3 {
+		t.Errorf("options synced called with %d, want 3", got)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	exponential := RetryPolicy{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	linear := RetryPolicy{MinBackoff: time.Second, MaxBackoff: 5 * time.Second, BackoffPolicy: config.BackoffPolicy_LINEAR}
+	cases := []struct {
+		policy   RetryPolicy
+		attempts int
+		want     time.Duration
+	}{
+		{exponential, 1, time.Second},
+		{exponential, 2, 2 * time.Second},
+		{exponential, 3, 4 * time.Second},
+		{exponential, 4, 5 * time.Second},
+		{exponential, 100, 5 * time.Second},
+		{linear, 1, time.Second},
+		{linear, 4, time.Second},
+	}
+	for _, tc := range cases {
+		if got := tc.policy.backoff(tc.attempts); got != tc.want {
+			t.Errorf("backoff(%d) with %v got=%v, want=%v", tc.attempts, tc.policy.BackoffPolicy, got, tc.want)
+		}
+	}
+}
@@ -18,8 +18,10 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,11 +38,28 @@ type SyncPool interface {
 	SyncOnce(ctx context.Context) error
 }
 
+// DrainPool is implemented by sync pools which can wait for the events in
+// flight to finish on shutdown.
+type DrainPool interface {
+	// Drain stops all the handlers from pulling messages and waits until
+	// their events in flight are done, or the ctx is done.
+	Drain(ctx context.Context) error
+}
+
+// DebugPool is implemented by sync pools which serve debug endpoints under
+// /debug/ on the health check port.
+type DebugPool interface {
+	DebugHandler() http.Handler
+}
+
 type healthChecker struct {
 	mux              sync.RWMutex
 	lastReportTime   time.Time
 	maxStaleDuration time.Duration
 	port             int
+	debug            http.Handler
+	// health returns the health of the handlers, if the pool reports it.
+	health func() PoolHealth
 }
 
 func (c *healthChecker) reportHealth() {
@@ -76,20 +95,33 @@ func (c *healthChecker) start(ctx context.Context) {
 }
 
 func (c *healthChecker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if req.URL.Path != "/healthz" {
+	if c.debug != nil && strings.HasPrefix(req.URL.Path, "/debug/") {
+		c.debug.ServeHTTP(w, req)
+		return
+	}
+	if req.URL.Path != "/healthz" && req.URL.Path != "/readyz" {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 	// Zero maxStaleDuration means infinite.
-	if c.maxStaleDuration == 0 {
+	if c.maxStaleDuration != 0 && time.Now().Sub(c.lastTime()) > c.maxStaleDuration {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if c.health == nil {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
-	if time.Now().Sub(c.lastTime()) > c.maxStaleDuration {
+	// The pool keeps running with some failed handlers, but isn't ready to
+	// process events once all of them failed.
+	health := c.health()
+	w.Header().Set("Content-Type", "application/json")
+	if req.URL.Path == "/readyz" && health.Status == HealthFailed {
 		w.WriteHeader(http.StatusServiceUnavailable)
-		return
+	} else {
+		w.WriteHeader(http.StatusOK)
 	}
-	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(health)
 }
 
 // StartSyncPool starts the sync pool.
@@ -108,18 +140,40 @@ func StartSyncPool(
 		maxStaleDuration: maxStaleDuration,
 		port:             healthCheckPort,
 	}
+	if dp, ok := syncPool.(DebugPool); ok {
+		c.debug = dp.DebugHandler()
+	}
+	hp, ok := syncPool.(HealthPool)
+	if ok {
+		c.health = hp.Health
+	}
 	go c.start(ctx)
-	if syncSignal != nil {
+	if syncSignal != nil || ok {
 		go watch(ctx, syncPool, syncSignal, c)
 	}
 	return syncPool, nil
 }
 
 func watch(ctx context.Context, syncPool SyncPool, syncSignal <-chan struct{}, c *healthChecker) {
+	// The failed handlers are restarted by syncing the pool, so the pool is
+	// also synced periodically while some of its handlers failed.
+	var restart <-chan time.Time
+	if c.health != nil {
+		ticker := time.NewTicker(minRestartBackoff)
+		defer ticker.Stop()
+		restart = ticker.C
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-restart:
+			if c.health().Status == HealthOK {
+				continue
+			}
+			if err := syncPool.SyncOnce(ctx); err != nil {
+				logging.FromContext(ctx).Error("failed to sync handlers pool to restart failed handlers", zap.Error(err))
+			}
 		case <-syncSignal:
 			if err := syncPool.SyncOnce(ctx); err != nil {
 				// Currently we don't really expect errors from SyncOnce.
@@ -131,3 +185,39 @@ func watch(ctx context.Context, syncPool SyncPool, syncSignal <-chan struct{}, c
 		}
 	}
 }
+
+// runningHandlers tracks the started handlers until they have stopped,
+// including the handlers removed from a pool which are still finishing their
+// events in flight.
+type runningHandlers struct {
+	handlers sync.Map
+}
+
+// start starts the handler and tracks it until it has stopped.
+func (r *runningHandlers) start(ctx context.Context, h *Handler, done func(error)) {
+	r.handlers.Store(h, struct{}{})
+	h.Start(ctx, func(err error) {
+		r.handlers.Delete(h)
+		done(err)
+	})
+}
+
+// drain stops all the running handlers and waits until they have stopped, or
+// the ctx is done.
+func (r *runningHandlers) drain(ctx context.Context) error {
+	var handlers []*Handler
+	r.handlers.Range(func(key, _ interface{}) bool {
+		h := key.(*Handler)
+		h.Stop()
+		handlers = append(handlers, h)
+		return true
+	})
+	for _, h := range handlers {
+		select {
+		case <-h.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -18,13 +18,18 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/broker/transport/memory"
 )
 
 func TestSyncPool(t *testing.T) {
@@ -80,6 +85,115 @@ func TestSyncPool(t *testing.T) {
 		time.Sleep(time.Second)
 		assertHealthCheckResult(t, p, false)
 	})
+
+	t.Run("Debug endpoints are served with the health check", func(t *testing.T) {
+		syncPool := &fakeDebugSyncPool{
+			fakeSyncPool: fakeSyncPool{syncCalled: make(chan struct{}, 1)},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		p, err := GetFreePort()
+		if err != nil {
+			t.Fatalf("failed to get random free port: %v", err)
+		}
+		if _, err := StartSyncPool(ctx, syncPool, nil, 0, p); err != nil {
+			t.Errorf("StartSyncPool got unexpected error: %v", err)
+		}
+		// Make sure the health checker is up.
+		time.Sleep(500 * time.Millisecond)
+
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/debug/breakers", p))
+		if err != nil {
+			t.Fatalf("failed to get debug endpoint: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusTeapot {
+			t.Errorf("debug endpoint status code got=%d, want=%d", resp.StatusCode, http.StatusTeapot)
+		}
+		assertHealthCheckResult(t, p, true)
+	})
+
+	t.Run("Readiness reports the pool health", func(t *testing.T) {
+		syncPool := &fakeHealthSyncPool{
+			fakeSyncPool: fakeSyncPool{syncCalled: make(chan struct{}, 1)},
+		}
+		syncPool.health.Store(PoolHealth{Status: HealthDegraded, Handlers: 2, Failed: []FailedHandler{{Key: "ns/b1"}}})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		p, err := GetFreePort()
+		if err != nil {
+			t.Fatalf("failed to get random free port: %v", err)
+		}
+		if _, err := StartSyncPool(ctx, syncPool, nil, 0, p); err != nil {
+			t.Errorf("StartSyncPool got unexpected error: %v", err)
+		}
+		syncPool.verifySyncOnceCalled(t)
+		// Make sure the health checker is up.
+		time.Sleep(500 * time.Millisecond)
+
+		// The pool is synced again to restart its failed handlers.
+		select {
+		case <-time.After(2 * minRestartBackoff):
+			t.Error("SyncOnce was not called to restart the failed handlers")
+		case <-syncPool.syncCalled:
+		}
+
+		got := assertReadinessResult(t, p, true)
+		if got.Status != HealthDegraded || len(got.Failed) != 1 || got.Failed[0].Key != "ns/b1" {
+			t.Errorf("readiness got health=%+v, want degraded with failed handler ns/b1", got)
+		}
+
+		// The pool isn't ready once all its handlers failed, but it's still
+		// alive.
+		syncPool.health.Store(PoolHealth{Status: HealthFailed, Handlers: 1, Failed: []FailedHandler{{Key: "ns/b1"}}})
+		assertReadinessResult(t, p, false)
+		assertHealthCheckResult(t, p, true)
+	})
+}
+
+func TestRunningHandlersDrain(t *testing.T) {
+	ctx := context.Background()
+	tr := memory.New()
+	tr.CreateSubscription("sub", "topic")
+
+	var r runningHandlers
+	stopped := make(chan struct{}, 2)
+	for i := 0; i < 2; i++ {
+		h := NewHandler(tr.Subscription("sub"), &processors.FakeProcessor{}, time.Second, RetryPolicy{})
+		r.start(ctx, h, func(error) { stopped <- struct{}{} })
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := r.drain(drainCtx); err != nil {
+		t.Fatalf("drain got unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		<-stopped
+	}
+	r.handlers.Range(func(key, _ interface{}) bool {
+		t.Errorf("handler %v still running after drain", key)
+		return true
+	})
+}
+
+func assertReadinessResult(t *testing.T, port int, ok bool) PoolHealth {
+	t.Helper()
+	var health PoolHealth
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/readyz", port))
+	if err != nil {
+		t.Fatalf("Failed to execute readiness check: %v", err)
+	}
+	defer resp.Body.Close()
+	if ok != (resp.StatusCode == http.StatusOK) {
+		t.Errorf("readiness check result ok got=%v, want=%v", !ok, ok)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Errorf("Failed to decode readiness check body: %v", err)
+	}
+	return health
 }
 
 func assertHealthCheckResult(t *testing.T, port int, ok bool) {
@@ -124,6 +238,25 @@ func (p *fakeSyncPool) SyncOnce(ctx context.Context) error {
 	return nil
 }
 
+type fakeDebugSyncPool struct {
+	fakeSyncPool
+}
+
+func (p *fakeDebugSyncPool) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+}
+
+type fakeHealthSyncPool struct {
+	fakeSyncPool
+	health atomic.Value
+}
+
+func (p *fakeHealthSyncPool) Health() PoolHealth {
+	return p.health.Load().(PoolHealth)
+}
+
 // GetFreePort asks a free open port.
 func GetFreePort() (int, error) {
 	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
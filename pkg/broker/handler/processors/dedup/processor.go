@@ -0,0 +1,95 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dedup
+
+import (
+	"context"
+	"fmt"
+
+	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/logging"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/metrics"
+)
+
+// Processor drops the events of a broker with the same source and id as an
+// event processed within the deduplication window of the broker. An event is
+// only remembered once the next processors succeeded, so that a failed event
+// can be redelivered. Duplicates processed concurrently are not dropped.
+type Processor struct {
+	processors.BaseProcessor
+
+	// Targets is the targets from config.
+	Targets config.ReadonlyTargets
+
+	// Store remembers the processed events.
+	Store Store
+
+	// StatsReporter is used to report the dropped duplicates.
+	StatsReporter *metrics.DeliveryReporter
+}
+
+var _ processors.Interface = (*Processor)(nil)
+
+// Process drops the event if it is a duplicate, otherwise it passes the event
+// to the next processor.
+func (p *Processor) Process(ctx context.Context, event *event.Event) error {
+	bk, err := handlerctx.GetBrokerKey(ctx)
+	if err != nil {
+		return err
+	}
+	broker, ok := p.Targets.GetBrokerByKey(bk)
+	if !ok || broker.DeduplicationWindow == nil || p.Store == nil {
+		return p.Next().Process(ctx, event)
+	}
+
+	key := Key(broker, event)
+	seen, err := p.Store.Seen(ctx, key)
+	if err != nil {
+		// Delivering a duplicate is better than losing the event.
+		logging.FromContext(ctx).Warn("failed to look up event in deduplication store", zap.String("broker", bk), zap.Error(err))
+	}
+	if seen {
+		logging.FromContext(ctx).Debug("dropping duplicate event", zap.String("broker", bk), zap.String("id", event.ID()), zap.String("source", event.Source()))
+		trace.FromContext(ctx).Annotate(ceclient.EventTraceAttributes(event), "event dropped: duplicate")
+		mctx, err := metrics.AddBrokerTags(ctx, broker)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to add broker tags to context", zap.String("broker", bk), zap.Error(err))
+		}
+		p.StatsReporter.ReportDuplicateEvent(mctx)
+		return nil
+	}
+
+	if err := p.Next().Process(ctx, event); err != nil {
+		return err
+	}
+	if err := p.Store.Mark(ctx, key, broker.DeduplicationWindow.AsDuration()); err != nil {
+		logging.FromContext(ctx).Warn("failed to record event in deduplication store", zap.String("broker", bk), zap.Error(err))
+	}
+	return nil
+}
+
+// Key returns the key identifying the event in the broker.
+func Key(broker *config.Broker, event *event.Event) string {
+	return fmt.Sprintf("%s %q %q", broker.Key(), event.Source(), event.ID())
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dedup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"knative.dev/pkg/metrics/metricskey"
+	"knative.dev/pkg/metrics/metricstest"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/metrics"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+
+	_ "knative.dev/pkg/metrics/testing"
+)
+
+func TestInvalidContext(t *testing.T) {
+	p := &Processor{}
+	e := event.New()
+	err := p.Process(context.Background(), &e)
+	if err != handlerctx.ErrBrokerKeyNotPresent {
+		t.Errorf("Process error got=%v, want=%v", err, handlerctx.ErrBrokerKeyNotPresent)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Seen(context.Context, string) (bool, error) {
+	return false, errors.New("store unavailable")
+}
+
+func (failingStore) Mark(context.Context, string, time.Duration) error {
+	return errors.New("store unavailable")
+}
+
+func TestDeduplication(t *testing.T) {
+	cases := []struct {
+		name      string
+		window    *durationpb.Duration
+		store     Store
+		failFirst bool
+		events    []string
+		wantIDs   []string
+		wantDups  int64
+	}{{
+		name:    "disabled",
+		store:   NewLRUStore(10),
+		events:  []string{"1", "1", "2"},
+		wantIDs: []string{"1", "1", "2"},
+	}, {
+		name:     "enabled",
+		window:   durationpb.New(time.Minute),
+		store:    NewLRUStore(10),
+		events:   []string{"1", "2", "1", "2", "3"},
+		wantIDs:  []string{"1", "2", "3"},
+		wantDups: 2,
+	}, {
+		name:      "failed event is not remembered",
+		window:    durationpb.New(time.Minute),
+		store:     NewLRUStore(10),
+		failFirst: true,
+		events:    []string{"1", "1", "1"},
+		wantIDs:   []string{"1", "1"},
+		wantDups:  1,
+	}, {
+		name:    "store failure",
+		window:  durationpb.New(time.Minute),
+		store:   failingStore{},
+		events:  []string{"1", "1"},
+		wantIDs: []string{"1", "1"},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+				bm.SetDeduplicationWindow(tc.window)
+			})
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, err := r.AddTags(handlerctx.WithBrokerKey(context.Background(), config.BrokerKey("ns", "broker")))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ch := make(chan *event.Event, len(tc.events))
+			next := &processors.FakeProcessor{PrevEventsCh: ch, OneTimeErr: tc.failFirst}
+			p := &Processor{Targets: testTargets, Store: tc.store, StatsReporter: r}
+			p.WithNext(next)
+
+			for i, id := range tc.events {
+				e := event.New()
+				e.SetID(id)
+				e.SetSource("source")
+				e.SetType("type")
+				err := p.Process(ctx, &e)
+				if wantErr := tc.failFirst && i == 0; (err != nil) != wantErr {
+					t.Errorf("processing event %d got error=%v, want=%v", i, err, wantErr)
+				}
+			}
+			close(ch)
+
+			var gotIDs []string
+			for e := range ch {
+				gotIDs = append(gotIDs, e.ID())
+			}
+			if diff := cmp.Diff(tc.wantIDs, gotIDs); diff != "" {
+				t.Errorf("processed event ids (-want,+got): %v", diff)
+			}
+			if tc.wantDups > 0 {
+				metricstest.CheckCountData(t, "duplicate_event_count", map[string]string{
+					metricskey.LabelNamespaceName: "ns",
+					metricskey.LabelBrokerName:    "broker",
+					metricskey.PodName:            "pod",
+					metricskey.ContainerName:      "container",
+				}, tc.wantDups)
+			} else {
+				metricstest.CheckStatsNotReported(t, "duplicate_event_count")
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	b := &config.Broker{Namespace: "ns", Name: "broker"}
+	e1 := event.New()
+	e1.SetSource("a b")
+	e1.SetID("c")
+	e2 := event.New()
+	e2.SetSource("a")
+	e2.SetID("b c")
+	if Key(b, &e1) == Key(b, &e2) {
+		t.Errorf("events with different source and id have the same key %q", Key(b, &e1))
+	}
+}
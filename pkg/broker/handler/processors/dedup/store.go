@@ -0,0 +1,63 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dedup
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+// Store remembers the events processed by brokers.
+type Store interface {
+	// Seen returns true if the key was marked within its window.
+	Seen(ctx context.Context, key string) (bool, error)
+	// Mark remembers the key for the window.
+	Mark(ctx context.Context, key string, window time.Duration) error
+}
+
+// LRUStore is an in-memory Store of a bounded number of keys. Once it is
+// full, the least recently used keys are forgotten first even if their
+// window hasn't passed.
+type LRUStore struct {
+	cache *cache.LRUExpireCache
+}
+
+var _ Store = (*LRUStore)(nil)
+
+// NewLRUStore creates a LRUStore remembering up to size keys.
+func NewLRUStore(size int) *LRUStore {
+	return &LRUStore{cache: cache.NewLRUExpireCache(size)}
+}
+
+// newLRUStoreWithClock creates a LRUStore with a fake clock for tests.
+func newLRUStoreWithClock(size int, clock cache.Clock) *LRUStore {
+	return &LRUStore{cache: cache.NewLRUExpireCacheWithClock(size, clock)}
+}
+
+// Seen implements Store.
+func (s *LRUStore) Seen(_ context.Context, key string) (bool, error) {
+	_, ok := s.cache.Get(key)
+	return ok, nil
+}
+
+// Mark implements Store.
+func (s *LRUStore) Mark(_ context.Context, key string, window time.Duration) error {
+	s.cache.Add(key, struct{}{}, window)
+	return nil
+}
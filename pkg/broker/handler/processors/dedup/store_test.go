@@ -0,0 +1,80 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dedup
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Now()}
+	s := newLRUStoreWithClock(2, clock)
+
+	seen := func(key string) bool {
+		t.Helper()
+		ok, err := s.Seen(ctx, key)
+		if err != nil {
+			t.Fatalf("Seen(%q) got unexpected error: %v", key, err)
+		}
+		return ok
+	}
+	mark := func(key string, window time.Duration) {
+		t.Helper()
+		if err := s.Mark(ctx, key, window); err != nil {
+			t.Fatalf("Mark(%q) got unexpected error: %v", key, err)
+		}
+	}
+
+	if seen("a") {
+		t.Error("unmarked key a was seen")
+	}
+	mark("a", time.Minute)
+	mark("b", time.Hour)
+	if !seen("a") || !seen("b") {
+		t.Error("marked keys were not seen")
+	}
+
+	// The window of a has passed.
+	clock.now = clock.now.Add(2 * time.Minute)
+	if seen("a") {
+		t.Error("key a was seen after its window")
+	}
+	if !seen("b") {
+		t.Error("key b was not seen within its window")
+	}
+
+	// The store is full, so the least recently used key is forgotten.
+	mark("c", time.Hour)
+	mark("d", time.Hour)
+	if seen("b") {
+		t.Error("key b was seen after it was evicted")
+	}
+	if !seen("c") || !seen("d") {
+		t.Error("keys c and d were not seen")
+	}
+}
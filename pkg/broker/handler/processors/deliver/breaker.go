@@ -0,0 +1,234 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/logging"
+)
+
+// BreakerState is the state of the circuit breaker of a target address.
+type BreakerState int
+
+const (
+	// BreakerClosed lets events be delivered to the target.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single probe event be delivered to the target
+	// to find out whether it has recovered.
+	BreakerHalfOpen
+	// BreakerOpen short-circuits events without delivering them to the target.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s BreakerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// halfOpenRetryDelay is how long events wait for the probe of a half-open
+// breaker.
+const halfOpenRetryDelay = time.Second
+
+// BreakerSettings configures the circuit breakers of target addresses.
+type BreakerSettings struct {
+	// FailureThreshold is the number of consecutive delivery failures that
+	// trips the breaker of a target address. Zero disables the breakers.
+	FailureThreshold int
+	// OpenDuration is how long a tripped breaker stays open before a probe
+	// event is delivered to the target.
+	OpenDuration time.Duration
+}
+
+// BreakerStatus is the status of the circuit breaker of a target address.
+type BreakerStatus struct {
+	Address             string       `json:"address"`
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	OpenedAt            *time.Time   `json:"openedAt,omitempty"`
+}
+
+type breaker struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+// Breakers holds a circuit breaker per target address, so that a target which
+// is down isn't sent every event only to time out. A nil *Breakers lets all
+// events through.
+type Breakers struct {
+	settings BreakerSettings
+	// now is overridden in tests.
+	now func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// NewBreakers creates the circuit breakers with the given settings. It returns
+// nil if the breakers are disabled.
+func NewBreakers(settings BreakerSettings) *Breakers {
+	if settings.FailureThreshold <= 0 {
+		return nil
+	}
+	return &Breakers{
+		settings: settings,
+		now:      time.Now,
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// Allow returns whether an event may be delivered to the address, along with
+// the state of its breaker. Once the breaker has been open for the open
+// duration, a single probe is allowed and the breaker becomes half-open until
+// the outcome of the probe is recorded.
+func (b *Breakers) Allow(address string) (bool, BreakerState) {
+	if b == nil {
+		return true, BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	br, ok := b.breakers[address]
+	if !ok {
+		return true, BreakerClosed
+	}
+	switch br.state {
+	case BreakerOpen:
+		if b.now().Sub(br.openedAt) < b.settings.OpenDuration {
+			return false, BreakerOpen
+		}
+		br.state = BreakerHalfOpen
+		return true, BreakerHalfOpen
+	case BreakerHalfOpen:
+		// The probe is still in flight.
+		return false, BreakerHalfOpen
+	default:
+		return true, BreakerClosed
+	}
+}
+
+// RetryAfter returns how long to wait before an event may be delivered to the
+// address again, or zero if its breaker isn't open. A half-open breaker is
+// checked again after a short delay, once the outcome of its probe is likely
+// known.
+func (b *Breakers) RetryAfter(address string) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	br, ok := b.breakers[address]
+	if !ok {
+		return 0
+	}
+	switch br.state {
+	case BreakerOpen:
+		if d := b.settings.OpenDuration - b.now().Sub(br.openedAt); d > 0 {
+			return d
+		}
+		return halfOpenRetryDelay
+	case BreakerHalfOpen:
+		return halfOpenRetryDelay
+	default:
+		return 0
+	}
+}
+
+// Record records the outcome of a delivery to the address and returns the
+// resulting state of its breaker. Every allowed delivery must be recorded,
+// otherwise a half-open breaker never lets another event through.
+func (b *Breakers) Record(address string, failed bool) BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		// Closed breakers without failures aren't kept around.
+		delete(b.breakers, address)
+		return BreakerClosed
+	}
+	br, ok := b.breakers[address]
+	if !ok {
+		br = &breaker{}
+		b.breakers[address] = br
+	}
+	br.failures++
+	if br.state == BreakerHalfOpen || br.failures >= b.settings.FailureThreshold {
+		br.state = BreakerOpen
+		br.openedAt = b.now()
+	}
+	return br.state
+}
+
+// Statuses returns the status of the breakers of the addresses with failures,
+// sorted by address.
+func (b *Breakers) Statuses() []BreakerStatus {
+	statuses := []BreakerStatus{}
+	if b == nil {
+		return statuses
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for address, br := range b.breakers {
+		s := BreakerStatus{
+			Address:             address,
+			State:               br.state,
+			ConsecutiveFailures: br.failures,
+		}
+		if br.state != BreakerClosed {
+			openedAt := br.openedAt
+			s.OpenedAt = &openedAt
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Address < statuses[j].Address
+	})
+	return statuses
+}
+
+// ServeHTTP serves the status of the breakers as JSON.
+func (b *Breakers) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(b.Statuses()); err != nil {
+		logging.FromContext(req.Context()).Warn("failed to write the breaker statuses", zap.Error(err))
+	}
+}
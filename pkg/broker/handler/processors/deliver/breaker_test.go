@@ -0,0 +1,137 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBreakers(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreakers(BreakerSettings{FailureThreshold: 2, OpenDuration: time.Minute})
+	b.now = func() time.Time { return now }
+
+	assertAllow := func(t *testing.T, address string, wantOK bool, wantState BreakerState) {
+		t.Helper()
+		ok, state := b.Allow(address)
+		if ok != wantOK || state != wantState {
+			t.Errorf("Allow(%q) got=(%v, %v), want=(%v, %v)", address, ok, state, wantOK, wantState)
+		}
+	}
+	assertRecord := func(t *testing.T, address string, failed bool, want BreakerState) {
+		t.Helper()
+		if got := b.Record(address, failed); got != want {
+			t.Errorf("Record(%q, %v) got=%v, want=%v", address, failed, got, want)
+		}
+	}
+
+	assertRetryAfter := func(t *testing.T, address string, want time.Duration) {
+		t.Helper()
+		if got := b.RetryAfter(address); got != want {
+			t.Errorf("RetryAfter(%q) got=%v, want=%v", address, got, want)
+		}
+	}
+
+	// A success resets the consecutive failures.
+	assertRecord(t, "a", true, BreakerClosed)
+	assertRecord(t, "a", false, BreakerClosed)
+	assertRecord(t, "a", true, BreakerClosed)
+	assertAllow(t, "a", true, BreakerClosed)
+
+	// The breaker trips after the threshold.
+	assertRecord(t, "a", true, BreakerOpen)
+	assertAllow(t, "a", false, BreakerOpen)
+	assertRetryAfter(t, "a", time.Minute)
+	// Other addresses are not affected.
+	assertAllow(t, "b", true, BreakerClosed)
+	assertRetryAfter(t, "b", 0)
+
+	now = now.Add(20 * time.Second)
+	assertRetryAfter(t, "a", 40*time.Second)
+
+	// A single probe is allowed after the open duration.
+	now = now.Add(40 * time.Second)
+	assertAllow(t, "a", true, BreakerHalfOpen)
+	assertAllow(t, "a", false, BreakerHalfOpen)
+	assertRetryAfter(t, "a", halfOpenRetryDelay)
+	// A failed probe opens the breaker again.
+	assertRecord(t, "a", true, BreakerOpen)
+	assertAllow(t, "a", false, BreakerOpen)
+
+	// A successful probe closes the breaker.
+	now = now.Add(time.Minute)
+	assertAllow(t, "a", true, BreakerHalfOpen)
+	assertRecord(t, "a", false, BreakerClosed)
+	assertAllow(t, "a", true, BreakerClosed)
+	assertRecord(t, "a", true, BreakerClosed)
+}
+
+func TestBreakersDisabled(t *testing.T) {
+	b := NewBreakers(BreakerSettings{})
+	if b != nil {
+		t.Fatalf("NewBreakers with zero threshold got=%v, want=nil", b)
+	}
+	for i := 0; i < 10; i++ {
+		if got := b.Record("a", true); got != BreakerClosed {
+			t.Errorf("Record got=%v, want=%v", got, BreakerClosed)
+		}
+	}
+	if ok, _ := b.Allow("a"); !ok {
+		t.Error("Allow got=false, want=true")
+	}
+	if got := b.Statuses(); len(got) != 0 {
+		t.Errorf("Statuses got=%v, want empty", got)
+	}
+}
+
+func TestBreakersServeHTTP(t *testing.T) {
+	openedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreakers(BreakerSettings{FailureThreshold: 2, OpenDuration: time.Minute})
+	b.now = func() time.Time { return openedAt }
+	b.Record("http://b", true)
+	b.Record("http://a", true)
+	b.Record("http://a", true)
+
+	w := httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/breakers", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code got=%d, want=%d", w.Code, http.StatusOK)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := []map[string]interface{}{
+		{"address": "http://a", "state": "open", "consecutiveFailures": 2.0, "openedAt": "2020-01-01T00:00:00Z"},
+		{"address": "http://b", "state": "closed", "consecutiveFailures": 1.0},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("breaker statuses (-want,+got): %v", diff)
+	}
+
+	w = httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/breakers", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status code got=%d, want=%d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+
+	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/logging"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+)
+
+// DivertProcessor sends events to the retry topic of the target in the context
+// instead of delivering them. It keeps the events of paused targets until they
+// are resumed and the retry handlers deliver them.
+type DivertProcessor struct {
+	processors.BaseProcessor
+
+	// Targets is the targets from config.
+	Targets config.ReadonlyTargets
+
+	// DeliverRetryClient is the cloudevents client to send events
+	// to the retry topic.
+	DeliverRetryClient ceclient.Client
+
+	// OrderedRetryPublisher publishes events with an ordering key to the retry
+	// topic. If nil, the ordering key of events is dropped.
+	OrderedRetryPublisher *OrderedPublisher
+}
+
+var _ processors.Interface = (*DivertProcessor)(nil)
+
+// Process sends the event to the retry topic of the target in the context.
+func (p *DivertProcessor) Process(ctx context.Context, event *event.Event) error {
+	bk, err := handlerctx.GetBrokerKey(ctx)
+	if err != nil {
+		return err
+	}
+	tk, err := handlerctx.GetTargetKey(ctx)
+	if err != nil {
+		return err
+	}
+	broker, ok := p.Targets.GetBrokerByKey(bk)
+	if !ok {
+		// If the broker no longer exists, then there is nothing to process.
+		logging.FromContext(ctx).Warn("broker no longer exist in the config", zap.String("broker", bk))
+		return nil
+	}
+	target, ok := p.Targets.GetTargetByKey(tk)
+	if !ok {
+		// If the target no longer exists, then there is nothing to process.
+		logging.FromContext(ctx).Warn("target no longer exist in the config", zap.String("target", tk))
+		return nil
+	}
+
+	logging.FromContext(ctx).Debug("target is paused, sending event to retry topic", zap.String("target", tk))
+	trace.FromContext(ctx).Annotate(ceclient.EventTraceAttributes(event), "target paused: enqueueing for retry")
+	if err := sendToRetryTopic(ctx, p.DeliverRetryClient, p.OrderedRetryPublisher, broker, target, event, 0); err != nil {
+		return err
+	}
+	return p.Next().Process(ctx, event)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"testing"
+
+	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	"github.com/google/go-cmp/cmp"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+)
+
+func TestDivert(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	srv, c, close := testPubsubClient(ctx, t, "test-project")
+	defer close()
+	if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+		t.Fatalf("failed to create test pubsub topc: %v", err)
+	}
+	ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+	if err != nil {
+		t.Fatalf("failed to create pubsub protocol: %v", err)
+	}
+	deliverRetryClient, err := ceclient.New(ps)
+	if err != nil {
+		t.Fatalf("failed to create cloudevents client: %v", err)
+	}
+
+	broker := &config.Broker{Namespace: "ns", Name: "broker"}
+	target := &config.Target{
+		Namespace:  "ns",
+		Name:       "target",
+		Broker:     "broker",
+		Address:    "http://target.example.com",
+		RetryQueue: &config.Queue{Topic: "test-retry-topic"},
+		State:      config.State_PAUSED,
+	}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(target)
+	})
+	ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+
+	p := &DivertProcessor{
+		Targets:            testTargets,
+		DeliverRetryClient: deliverRetryClient,
+	}
+
+	e := newSampleEvent()
+	// Events of targets which no longer exist are dropped.
+	if err := p.Process(handlerctx.WithTargetKey(ctx, config.TriggerKey("ns", "broker", "other")), e); err != nil {
+		t.Fatalf("unexpected error from processing: %v", err)
+	}
+	if err := p.Process(handlerctx.WithTargetKey(ctx, target.Key()), e); err != nil {
+		t.Fatalf("unexpected error from processing: %v", err)
+	}
+
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("retry messages got=%d, want=1", len(msgs))
+	}
+	retryEvent, err := binding.ToEvent(ctx, cepubsub.NewMessage(toFakePubsubMessage(msgs[0])))
+	if err != nil {
+		t.Fatalf("failed to convert retry message to event: %v", err)
+	}
+	if diff := cmp.Diff(e, retryEvent); diff != "" {
+		t.Errorf("retry event (-want,+got): %v", diff)
+	}
+}
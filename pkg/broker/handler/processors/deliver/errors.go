@@ -0,0 +1,93 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errCircuitOpen is wrapped by the errors returned instead of delivering an
+// event to a target whose circuit breaker is open.
+var errCircuitOpen = errors.New("event delivery short-circuited: circuit breaker is open")
+
+// circuitOpenError is returned instead of delivering an event to a target
+// whose circuit breaker is open. The event never reached the target, so it
+// isn't counted as a delivery attempt.
+type circuitOpenError struct {
+	delay time.Duration
+}
+
+func (e *circuitOpenError) Error() string {
+	return errCircuitOpen.Error()
+}
+
+func (e *circuitOpenError) Unwrap() error {
+	return errCircuitOpen
+}
+
+// RetryAfter implements delivery.RetryAfterError. It's the time left until
+// the breaker lets a probe through.
+func (e *circuitOpenError) RetryAfter() time.Duration {
+	return e.delay
+}
+
+// NotAttempted implements delivery.NotAttemptedError.
+func (e *circuitOpenError) NotAttempted() bool {
+	return true
+}
+
+// nonRetryableError is an error that retrying the delivery won't fix.
+type nonRetryableError struct {
+	err error
+}
+
+func (e *nonRetryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nonRetryableError) Unwrap() error {
+	return e.err
+}
+
+// isNonRetryable returns true if the delivery error can't be fixed by retrying.
+func isNonRetryable(err error) bool {
+	var nre *nonRetryableError
+	return errors.As(err, &nre)
+}
+
+// notDueError is returned instead of retrying the delivery of an event before
+// the time requested by the target with Retry-After. The event is nacked
+// without reaching the target, so it isn't counted as a delivery attempt.
+type notDueError struct {
+	delay time.Duration
+}
+
+func (e *notDueError) Error() string {
+	return fmt.Sprintf("event delivery is not due for another %v", e.delay)
+}
+
+// RetryAfter implements delivery.RetryAfterError.
+func (e *notDueError) RetryAfter() time.Duration {
+	return e.delay
+}
+
+// NotAttempted implements delivery.NotAttemptedError.
+func (e *notDueError) NotAttempted() bool {
+	return true
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/cloudevents/sdk-go/v2/extensions"
+	"go.opencensus.io/trace"
+
+	"github.com/google/knative-gcp/pkg/broker/transport"
+)
+
+// OrderedPublisher publishes events to topics with ordering keys, which
+// the cloudevents protocol doesn't support.
+type OrderedPublisher struct {
+	transport transport.Transport
+
+	mu     sync.Mutex
+	topics map[string]transport.Topic
+}
+
+// NewOrderedPublisher creates a new OrderedPublisher.
+func NewOrderedPublisher(t transport.Transport) *OrderedPublisher {
+	return &OrderedPublisher{
+		transport: t,
+		topics:    make(map[string]transport.Topic),
+	}
+}
+
+// Publish publishes the event to the topic with the ordering key.
+func (p *OrderedPublisher) Publish(ctx context.Context, topicID, key string, event *event.Event) error {
+	msg, err := toOrderedMessage(ctx, key, event)
+	if err != nil {
+		return err
+	}
+	topic := p.topic(topicID)
+	if _, err := topic.Publish(ctx, msg).Get(ctx); err != nil {
+		// The topic pauses publishing for the key after a failure. The event will be
+		// redelivered from the decouple queue, so resume publishing for later events.
+		topic.ResumePublish(key)
+		return err
+	}
+	return nil
+}
+
+func (p *OrderedPublisher) topic(id string) transport.Topic {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	topic, ok := p.topics[id]
+	if !ok {
+		topic = p.transport.Topic(id, true)
+		p.topics[id] = topic
+	}
+	return topic
+}
+
+// Retain stops and forgets the topics for which keep returns false, e.g. the
+// retry topics of deleted targets.
+func (p *OrderedPublisher) Retain(keep func(topicID string) bool) {
+	p.mu.Lock()
+	var stopped []transport.Topic
+	for id, topic := range p.topics {
+		if !keep(id) {
+			stopped = append(stopped, topic)
+			delete(p.topics, id)
+		}
+	}
+	p.mu.Unlock()
+	// Stopping waits for the pending messages, don't block the publishers meanwhile.
+	for _, topic := range stopped {
+		topic.Stop()
+	}
+}
+
+func toOrderedMessage(ctx context.Context, key string, event *event.Event) (*transport.Message, error) {
+	dt := extensions.FromSpanContext(trace.FromContext(ctx).SpanContext())
+	msg := &transport.Message{OrderingKey: key}
+	if err := transport.WriteMessage(ctx, binding.ToMessage(event), msg, dt.WriteTransformer()); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"testing"
+
+	logtest "knative.dev/pkg/logging/testing"
+
+	pubsubtransport "github.com/google/knative-gcp/pkg/broker/transport/pubsub"
+)
+
+func TestToOrderedMessage(t *testing.T) {
+	e := newSampleEvent()
+	msg, err := toOrderedMessage(context.Background(), "user-1", e)
+	if err != nil {
+		t.Fatalf("toOrderedMessage() failed: %v", err)
+	}
+	if msg.OrderingKey != "user-1" {
+		t.Errorf("message ordering key got=%q, want=%q", msg.OrderingKey, "user-1")
+	}
+	if got := msg.Attributes["ce-id"]; got != e.ID() {
+		t.Errorf("message event id got=%q, want=%q", got, e.ID())
+	}
+}
+
+func TestOrderedPublisher(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	srv, c, close := testPubsubClient(ctx, t, "test-project")
+	defer close()
+	if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+		t.Fatalf("failed to create test pubsub topic: %v", err)
+	}
+
+	p := NewOrderedPublisher(pubsubtransport.New(c))
+	for _, key := range []string{"user-1", "user-2", "user-1"} {
+		if err := p.Publish(ctx, "test-retry-topic", key, newSampleEvent()); err != nil {
+			t.Errorf("Publish() failed: %v", err)
+		}
+	}
+	if got := len(srv.Messages()); got != 3 {
+		t.Errorf("published messages got=%d, want=3", got)
+	}
+	if !p.topic("test-retry-topic").Ordered() {
+		t.Error("message ordering is not enabled on the topic")
+	}
+	if err := p.Publish(ctx, "missing-topic", "user-1", newSampleEvent()); err == nil {
+		t.Error("Publish() to a missing topic got=nil, want error")
+	}
+}
+
+func TestOrderedPublisherRetain(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	_, c, close := testPubsubClient(ctx, t, "test-project")
+	defer close()
+	for _, id := range []string{"kept-topic", "deleted-topic"} {
+		if _, err := c.CreateTopic(ctx, id); err != nil {
+			t.Fatalf("failed to create test pubsub topic: %v", err)
+		}
+	}
+
+	p := NewOrderedPublisher(pubsubtransport.New(c))
+	kept := p.topic("kept-topic")
+	p.topic("deleted-topic")
+	p.Retain(func(id string) bool { return id == "kept-topic" })
+	if got := p.topic("kept-topic"); got != kept {
+		t.Error("kept topic was recreated")
+	}
+	p.mu.Lock()
+	_, ok := p.topics["deleted-topic"]
+	p.mu.Unlock()
+	if ok {
+		t.Error("deleted topic is still cached")
+	}
+	// A topic is recreated when it's published to again.
+	if err := p.Publish(ctx, "deleted-topic", "user-1", newSampleEvent()); err != nil {
+		t.Errorf("Publish() after Retain failed: %v", err)
+	}
+}
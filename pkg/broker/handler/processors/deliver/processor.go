@@ -18,6 +18,7 @@ package deliver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -31,15 +32,16 @@ import (
 	"go.uber.org/zap"
 	"knative.dev/eventing/pkg/logging"
 
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 	"github.com/google/knative-gcp/pkg/metrics"
+	"github.com/google/knative-gcp/pkg/utils/delivery"
+	"github.com/google/knative-gcp/pkg/utils/idtoken"
 )
 
-const defaultEventHopsLimit int32 = 255
-
 // Processor delivers events based on the broker/target in the context.
 type Processor struct {
 	processors.BaseProcessor
@@ -64,6 +66,32 @@ type Processor struct {
 
 	// StatsReporter is used to report delivery metrics.
 	StatsReporter *metrics.DeliveryReporter
+
+	// Backoff returns how long to wait before retrying the delivery of an
+	// ordered event to the target after the given number of attempts. Ordered
+	// events are retried in place so that the later events with the same
+	// ordering key wait for them. If nil, they're retried right away.
+	Backoff func(target *config.Target, attempts int) time.Duration
+
+	// ClaimCheckStore is the store of event payloads offloaded by the ingress.
+	// If nil, events with offloaded payloads fail to be delivered.
+	ClaimCheckStore claimcheck.Store
+
+	// Breakers short-circuits the delivery to targets that keep failing.
+	// If nil, events are always delivered.
+	Breakers *Breakers
+
+	// TokenSource mints the ID tokens authenticating the deliveries to
+	// targets which require them. If nil, such deliveries fail.
+	TokenSource idtoken.Source
+
+	// Classifier decides which failed deliveries are retried.
+	// If nil, the delivery.DefaultClassifier is used.
+	Classifier *delivery.Classifier
+
+	// LoopDetected is called with the key of the target when an event looping
+	// back to it is dropped. If nil, the loops are only logged and counted.
+	LoopDetected func(targetKey string)
 }
 
 var _ processors.Interface = (*Processor)(nil)
@@ -99,27 +127,110 @@ func (p *Processor) Process(ctx context.Context, event *event.Event) error {
 		return nil
 	}
 
+	// Deliveries from the retry queue wait for the time requested by the target
+	// with Retry-After. The handler delays the nack until then.
+	if notBefore, ok := eventutil.GetNotBefore(event); ok && !p.RetryOnFailure {
+		if delay := time.Until(notBefore); delay > 0 {
+			return &notDueError{delay: delay}
+		}
+	}
+
 	// Hops is a broker local counter so remove any hops value before forwarding.
 	// Do not modify the original event as we need to send the original
 	// event to retry queue on failure.
 	copy := event.Clone()
 	// This will decrement the remaining hops if there is an existing value.
-	eventutil.UpdateRemainingHops(ctx, &copy, defaultEventHopsLimit)
+	eventutil.UpdateRemainingHops(ctx, &copy, hopLimit(broker))
 	hops, _ := eventutil.GetRemainingHops(ctx, &copy)
 	eventutil.DeleteRemainingHops(ctx, &copy)
+	eventutil.DeleteNotBefore(&copy)
+	// The targets an event visited are also broker local.
+	visited := eventutil.GetVisited(&copy)
+	eventutil.DeleteVisited(&copy)
+	if broker.LoopDetection && visited.Contains(tk) {
+		logging.FromContext(ctx).Warn("event looped back to the target, dropping event",
+			zap.String("target", tk), zap.String("visited", string(visited)))
+		trace.FromContext(ctx).Annotate(
+			ceclient.EventTraceAttributes(event),
+			"event dropped: loop detected",
+		)
+		p.StatsReporter.ReportDroppedEvent(ctx, metrics.DropReasonLoopDetected)
+		if p.LoopDetected != nil {
+			p.LoopDetected(tk)
+		}
+		return nil
+	}
+	replyTransformers := []binding.Transformer{eventutil.SetRemainingHopsTransformer(hops)}
+	if broker.LoopDetection {
+		replyTransformers = append(replyTransformers, eventutil.SetVisitedTransformer(visited.Append(tk)))
+	}
 
 	p.StatsReporter.FinishEventProcessing(ctx)
 
-	dctx := ctx
-	if p.DeliverTimeout > 0 {
-		var cancel context.CancelFunc
-		dctx, cancel = context.WithTimeout(dctx, p.DeliverTimeout)
-		defer cancel()
+	deliverOnce := func() error {
+		dctx := ctx
+		if p.DeliverTimeout > 0 {
+			var cancel context.CancelFunc
+			dctx, cancel = context.WithTimeout(dctx, p.DeliverTimeout)
+			defer cancel()
+		}
+		// Put the offloaded payload back into the copy, the retry queue still gets the reference.
+		if err := p.rehydrate(dctx, &copy); err != nil {
+			return err
+		}
+		// Forward the event copy that has hops removed.
+		return p.deliver(dctx, target, broker, (*binding.EventMessage)(&copy), hops, replyTransformers)
 	}
+	err = deliverOnce()
+	attempts := p.deliveryAttempts(ctx)
+	ordered := eventutil.OrderingKey(event, broker.OrderingKeyExtension) != ""
+	if err != nil && ordered {
+		// Sending the event to the retry topic or nacking it would let the
+		// later events with the same ordering key overtake it. Pub/Sub only
+		// hands out the next event with the key once this one is processed,
+		// so retrying here holds them back.
+		for err != nil && !shouldGiveUp(target, attempts, err) && p.waitRetry(ctx, target, attempts, err) {
+			logging.FromContext(ctx).Warn("ordered target delivery failed, retrying",
+				zap.String("target", tk), zap.Int("attempts", attempts), zap.Error(err))
+			if delivery.Attempted(err) {
+				attempts++
+			}
+			err = deliverOnce()
+		}
+	}
+	if err != nil {
+		if shouldGiveUp(target, attempts, err) {
+			if target.DeliverySpec == nil || target.DeliverySpec.DeadLetter == "" {
+				reason := metrics.DropReasonRetriesExhausted
+				if isNonRetryable(err) {
+					reason = metrics.DropReasonNonRetryable
+				}
+				logging.FromContext(ctx).Error("target delivery failed and can't be retried, dropping event",
+					zap.String("target", tk), zap.Int("attempts", attempts), zap.String("reason", reason), zap.Error(err))
+				p.StatsReporter.ReportDroppedEvent(ctx, reason)
+				return nil
+			}
+			logging.FromContext(ctx).Warn("target delivery failed, sending event to dead letter sink",
+				zap.String("target", tk), zap.Int("attempts", attempts), zap.Error(err))
+			trace.FromContext(ctx).Annotate(
+				[]trace.Attribute{
+					trace.StringAttribute("error_message", err.Error()),
+					trace.Int64Attribute("delivery_attempts", int64(attempts)),
+				},
+				"sending to dead letter sink",
+			)
+			dlErr := p.sendToDeadLetter(ctx, target, &copy, err, attempts)
+			if dlErr == nil {
+				return nil
+			}
+			logging.FromContext(ctx).Error("failed to send event to dead letter sink", zap.String("target", tk), zap.Error(dlErr))
+			err = dlErr
+		}
 
-	// Forward the event copy that has hops removed.
-	if err := p.deliver(dctx, target, broker, (*binding.EventMessage)(&copy), hops); err != nil {
-		if !p.RetryOnFailure {
+		if !p.RetryOnFailure || ordered {
+			// An ordered event still failing when the handler times out is
+			// nacked, and Pub/Sub redelivers it before the later events with
+			// its key.
 			return err
 		}
 
@@ -128,16 +239,54 @@ func (p *Processor) Process(ctx context.Context, event *event.Event) error {
 			[]trace.Attribute{trace.StringAttribute("error_message", err.Error())},
 			"enqueueing for retry",
 		)
-		return p.sendToRetryTopic(ctx, target, event)
+		// The retry handler filters and transforms the event again, so it needs
+		// the event as received rather than the one transformed for the target.
+		retryEvent := event
+		if original, _ := handlerctx.GetOriginalEvent(ctx); original != nil {
+			retryEvent = original
+		}
+		// Ordered events never get here, so they don't need the ordered publisher.
+		return sendToRetryTopic(ctx, p.DeliverRetryClient, nil, broker, target, retryEvent, delivery.RetryDelay(err))
 	}
 	// For post-delivery processing.
 	return p.Next().Process(ctx, event)
 }
 
+// hopLimit returns the number of times an event may be replied to the broker.
+func hopLimit(broker *config.Broker) int32 {
+	if broker.HopLimit > 0 {
+		return broker.HopLimit
+	}
+	return config.DefaultHopLimit
+}
+
+// rehydrate puts the payload offloaded by the ingress back into the event.
+func (p *Processor) rehydrate(ctx context.Context, event *event.Event) error {
+	if err := claimcheck.Rehydrate(ctx, p.ClaimCheckStore, event); err != nil {
+		if errors.Is(err, claimcheck.ErrNotFound) {
+			// Retrying won't bring back a missing payload.
+			return &nonRetryableError{err: err}
+		}
+		return err
+	}
+	return nil
+}
+
 // deliver delivers msg to target and sends the target's reply to the broker ingress.
-func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *config.Broker, msg binding.Message, hops int32) error {
+// The reply is sent with the given transformers, which attach the remaining hops
+// and the targets visited by the event.
+func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *config.Broker, msg binding.Message, hops int32, reply []binding.Transformer) error {
+	authorization, err := p.authorization(ctx, target)
+	if err != nil {
+		return err
+	}
+	if ok, state := p.Breakers.Allow(target.Address); !ok {
+		p.StatsReporter.ReportCircuitBreakerState(ctx, int(state))
+		return &circuitOpenError{delay: p.Breakers.RetryAfter(target.Address)}
+	}
 	startTime := time.Now()
-	resp, err := p.sendMsg(ctx, target.Address, msg)
+	resp, err := p.sendMsg(ctx, target.Address, authorization, msg)
+	p.recordBreaker(ctx, target.Address, resp, err)
 	if err != nil {
 		return err
 	}
@@ -149,7 +298,11 @@ func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *
 
 	p.StatsReporter.ReportEventDispatchTime(ctx, time.Since(startTime), resp.StatusCode)
 	if resp.StatusCode/100 != 2 {
-		return fmt.Errorf("event delivery failed: HTTP status code %d", resp.StatusCode)
+		derr := delivery.NewError(p.Classifier, resp)
+		if derr.Outcome == delivery.NonRetryable {
+			return &nonRetryableError{err: derr}
+		}
+		return derr
 	}
 
 	respMsg := cehttp.NewMessageFromHttpResponse(resp)
@@ -191,7 +344,7 @@ func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *
 	}
 
 	// Attach the previous hops for the reply.
-	replyResp, err := p.sendMsg(ctx, broker.Address, respMsg, eventutil.SetRemainingHopsTransformer(hops))
+	replyResp, err := p.sendMsg(ctx, broker.Address, "", respMsg, reply...)
 	if err != nil {
 		return err
 	}
@@ -201,20 +354,143 @@ func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *
 	return nil
 }
 
-func (p *Processor) sendMsg(ctx context.Context, address string, msg binding.Message, transformers ...binding.Transformer) (*http.Response, error) {
+// recordBreaker records the outcome of a delivery in the circuit breaker of the
+// target address. Requests that couldn't be built and client errors other
+// than throttling don't mean the target is down.
+func (p *Processor) recordBreaker(ctx context.Context, address string, resp *http.Response, err error) {
+	if p.Breakers == nil {
+		return
+	}
+	var failed bool
+	if err != nil {
+		failed = !isNonRetryable(err)
+	} else {
+		failed = resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests
+	}
+	state := p.Breakers.Record(address, failed)
+	p.StatsReporter.ReportCircuitBreakerState(ctx, int(state))
+}
+
+// authorization returns the value of the Authorization header of the
+// deliveries to the target, or an empty string if they are not authenticated.
+func (p *Processor) authorization(ctx context.Context, target *config.Target) (string, error) {
+	if target.DeliveryAuth == nil {
+		return "", nil
+	}
+	if p.TokenSource == nil {
+		return "", errors.New("no ID token source to authenticate the event delivery")
+	}
+	token, err := p.TokenSource.Token(ctx, target.DeliveryAuth.Audience, target.DeliveryAuth.ServiceAccount)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
+
+func (p *Processor) sendMsg(ctx context.Context, address, authorization string, msg binding.Message, transformers ...binding.Transformer) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, nil)
 	if err != nil {
-		return nil, err
+		// Retrying won't help if the address is invalid.
+		return nil, &nonRetryableError{err: err}
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
 	}
 	if err := cehttp.WriteRequest(ctx, msg, req, transformers...); err != nil {
-		return nil, err
+		return nil, &nonRetryableError{err: err}
 	}
 	return p.DeliverClient.Do(req)
 }
 
-func (p *Processor) sendToRetryTopic(ctx context.Context, target *config.Target, event *event.Event) error {
+// deliveryAttempts returns the number of attempts made to deliver the event
+// to the target including the current one. An event is delivered once from
+// the decouple queue before it is sent to the retry queue, so each delivery
+// from the retry queue is counted on top of that.
+func (p *Processor) deliveryAttempts(ctx context.Context) int {
+	if p.RetryOnFailure {
+		return 1
+	}
+	attempt, err := handlerctx.GetDeliveryAttempt(ctx)
+	if err != nil {
+		attempt = 1
+	}
+	return attempt + 1
+}
+
+// waitRetry waits before retrying the delivery of an ordered event to the
+// target, at least as long as the target asked to with Retry-After. It returns
+// false if the context is done first.
+func (p *Processor) waitRetry(ctx context.Context, target *config.Target, attempts int, err error) bool {
+	var delay time.Duration
+	if p.Backoff != nil {
+		delay = p.Backoff(target, attempts)
+	}
+	if d := delivery.RetryDelay(err); d > delay {
+		delay = d
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// shouldGiveUp returns true if the delivery to the target should not be
+// retried anymore, either because it can't succeed by retrying or because the
+// retries are exhausted. Targets without a delivery spec are retried forever
+// unless the delivery can't succeed. Deliveries which didn't reach the target,
+// e.g. because its circuit breaker is open, are always retried.
+func shouldGiveUp(target *config.Target, attempts int, err error) bool {
+	if !delivery.Attempted(err) {
+		return false
+	}
+	if isNonRetryable(err) {
+		return true
+	}
+	if target.DeliverySpec == nil {
+		return false
+	}
+	return attempts > int(target.DeliverySpec.Retry)
+}
+
+func (p *Processor) sendToDeadLetter(ctx context.Context, target *config.Target, event *event.Event, deliveryErr error, attempts int) error {
+	dlEvent := event.Clone()
+	eventutil.SetDeadLetterExtensions(&dlEvent, deliveryErr.Error(), int32(attempts))
+	resp, err := p.sendMsg(ctx, target.DeliverySpec.DeadLetter, "", (*binding.EventMessage)(&dlEvent))
+	if err != nil {
+		return fmt.Errorf("failed to send event to dead letter sink: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logging.FromContext(ctx).Warn("failed to close dead letter response body", zap.Error(err))
+		}
+	}()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to send event to dead letter sink: HTTP status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendToRetryTopic sends the event to the retry topic of the target. If the
+// target asked to delay the retry, the retry time is recorded in a copy of the
+// event since the original is shared with the other targets.
+func sendToRetryTopic(ctx context.Context, client ceclient.Client, orderedPublisher *OrderedPublisher, broker *config.Broker, target *config.Target, event *event.Event, delay time.Duration) error {
+	if delay > 0 {
+		retryEvent := event.Clone()
+		eventutil.SetNotBefore(&retryEvent, time.Now().Add(delay))
+		event = &retryEvent
+	}
+	if key := eventutil.OrderingKey(event, broker.OrderingKeyExtension); key != "" && orderedPublisher != nil {
+		if err := orderedPublisher.Publish(ctx, target.RetryQueue.Topic, key, event); err != nil {
+			return fmt.Errorf("failed to send event to retry topic: %w", err)
+		}
+		return nil
+	}
 	pctx := cecontext.WithTopic(ctx, target.RetryQueue.Topic)
-	if err := p.DeliverRetryClient.Send(pctx, *event); err != nil {
+	if err := client.Send(pctx, *event); err != nil {
 		return fmt.Errorf("failed to send event to retry topic: %w", err)
 	}
 	return nil
@@ -19,12 +19,14 @@ package deliver
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -44,13 +46,17 @@ import (
 	"google.golang.org/grpc"
 	"knative.dev/pkg/logging"
 	logtest "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/metrics/metricskey"
+	"knative.dev/pkg/metrics/metricstest"
 
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/metrics"
 	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+	"github.com/google/knative-gcp/pkg/utils/delivery"
 
 	_ "knative.dev/pkg/metrics/testing"
 )
@@ -81,11 +87,13 @@ func TestDeliverSuccess(t *testing.T) {
 	sampleReply.SetID("reply")
 
 	cases := []struct {
-		name       string
-		origin     *event.Event
-		wantOrigin *event.Event
-		reply      *event.Event
-		wantReply  *event.Event
+		name          string
+		hopLimit      int32
+		loopDetection bool
+		origin        *event.Event
+		wantOrigin    *event.Event
+		reply         *event.Event
+		wantReply     *event.Event
 	}{{
 		name:       "success",
 		origin:     sampleEvent,
@@ -93,9 +101,38 @@ func TestDeliverSuccess(t *testing.T) {
 		reply:      &sampleReply,
 		wantReply: func() *event.Event {
 			copy := sampleReply.Clone()
-			eventutil.UpdateRemainingHops(context.Background(), &copy, defaultEventHopsLimit)
+			eventutil.UpdateRemainingHops(context.Background(), &copy, config.DefaultHopLimit)
 			return &copy
 		}(),
+	}, {
+		name:       "success with broker hop limit",
+		hopLimit:   10,
+		origin:     sampleEvent,
+		wantOrigin: sampleEvent,
+		reply:      &sampleReply,
+		wantReply: func() *event.Event {
+			copy := sampleReply.Clone()
+			eventutil.UpdateRemainingHops(context.Background(), &copy, 10)
+			return &copy
+		}(),
+	}, {
+		name:          "success with loop detection",
+		loopDetection: true,
+		origin: func() *event.Event {
+			copy := sampleEvent.Clone()
+			e, _ := binding.ToEvent(context.Background(), binding.ToMessage(&copy),
+				eventutil.SetVisitedTransformer(eventutil.Visited("").Append("ns/broker/other")))
+			return e
+		}(),
+		wantOrigin: sampleEvent,
+		reply:      &sampleReply,
+		wantReply: func() *event.Event {
+			copy := sampleReply.Clone()
+			eventutil.UpdateRemainingHops(context.Background(), &copy, config.DefaultHopLimit)
+			e, _ := binding.ToEvent(context.Background(), binding.ToMessage(&copy),
+				eventutil.SetVisitedTransformer(eventutil.Visited("").Append("ns/broker/other").Append("ns/broker/target")))
+			return e
+		}(),
 	}, {
 		name: "success with dropped reply",
 		origin: func() *event.Event {
@@ -130,6 +167,8 @@ func TestDeliverSuccess(t *testing.T) {
 			testTargets := memory.NewEmptyTargets()
 			testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
 				bm.SetAddress(ingressSvr.URL)
+				bm.SetHopLimit(tc.hopLimit)
+				bm.SetLoopDetection(tc.loopDetection)
 				bm.UpsertTargets(target)
 			})
 			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
@@ -331,6 +370,767 @@ func TestDeliverFailure(t *testing.T) {
 	}
 }
 
+func TestDeliverDeadLetter(t *testing.T) {
+	cases := []struct {
+		name           string
+		withRetry      bool
+		attempt        int
+		deliverySpec   *config.DeliverySpec
+		withDeadLetter bool
+		targetAddress  string
+		targetStatus   int
+		failDeadLetter bool
+		wantDeadLetter bool
+		wantAttempts   int32
+		wantErr        bool
+	}{{
+		name:    "no delivery spec",
+		attempt: 5,
+		wantErr: true,
+	}, {
+		name:         "no dead letter sink",
+		attempt:      5,
+		deliverySpec: &config.DeliverySpec{Retry: 3},
+	}, {
+		name:           "retries remaining",
+		attempt:        2,
+		deliverySpec:   &config.DeliverySpec{Retry: 3},
+		withDeadLetter: true,
+		wantErr:        true,
+	}, {
+		name:           "retries exhausted",
+		attempt:        3,
+		deliverySpec:   &config.DeliverySpec{Retry: 3},
+		withDeadLetter: true,
+		wantDeadLetter: true,
+		wantAttempts:   4,
+	}, {
+		name:           "no retries",
+		withRetry:      true,
+		deliverySpec:   &config.DeliverySpec{},
+		withDeadLetter: true,
+		wantDeadLetter: true,
+		wantAttempts:   1,
+	}, {
+		name:           "non-retryable error",
+		attempt:        1,
+		deliverySpec:   &config.DeliverySpec{Retry: 3},
+		withDeadLetter: true,
+		targetAddress:  "http://invalid target",
+		wantDeadLetter: true,
+		wantAttempts:   2,
+	}, {
+		name:           "non-retryable status",
+		attempt:        1,
+		deliverySpec:   &config.DeliverySpec{Retry: 3},
+		withDeadLetter: true,
+		targetStatus:   http.StatusBadRequest,
+		wantDeadLetter: true,
+		wantAttempts:   2,
+	}, {
+		name:         "non-retryable status without dead letter sink",
+		attempt:      1,
+		targetStatus: http.StatusBadRequest,
+	}, {
+		name:           "retryable client error status",
+		attempt:        1,
+		deliverySpec:   &config.DeliverySpec{Retry: 3},
+		withDeadLetter: true,
+		targetStatus:   http.StatusTooManyRequests,
+		wantErr:        true,
+	}, {
+		name:           "dead letter sink failure",
+		attempt:        3,
+		deliverySpec:   &config.DeliverySpec{Retry: 3},
+		withDeadLetter: true,
+		failDeadLetter: true,
+		wantDeadLetter: true,
+		wantAttempts:   4,
+		wantErr:        true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			targetStatus := http.StatusInternalServerError
+			if tc.targetStatus != 0 {
+				targetStatus = tc.targetStatus
+			}
+			targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(targetStatus)
+			}))
+			defer targetSvr.Close()
+
+			deadLetterCh := make(chan *event.Event, 1)
+			deadLetterSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				e, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+				if err != nil {
+					t.Errorf("failed to convert dead letter request to event: %v", err)
+				}
+				deadLetterCh <- e
+				if tc.failDeadLetter {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer deadLetterSvr.Close()
+
+			_, c, close := testPubsubClient(ctx, t, "test-project")
+			defer close()
+			if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+				t.Fatalf("failed to create test pubsub topc: %v", err)
+			}
+			ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+			if err != nil {
+				t.Fatalf("failed to create pubsub protocol: %v", err)
+			}
+			deliverRetryClient, err := ceclient.New(ps)
+			if err != nil {
+				t.Fatalf("failed to create cloudevents client: %v", err)
+			}
+
+			if tc.withDeadLetter {
+				tc.deliverySpec.DeadLetter = deadLetterSvr.URL
+			}
+			targetAddress := targetSvr.URL
+			if tc.targetAddress != "" {
+				targetAddress = tc.targetAddress
+			}
+			broker := &config.Broker{Namespace: "ns", Name: "broker"}
+			target := &config.Target{
+				Namespace: "ns",
+				Name:      "target",
+				Broker:    "broker",
+				Address:   targetAddress,
+				RetryQueue: &config.Queue{
+					Topic: "test-retry-topic",
+				},
+				DeliverySpec: tc.deliverySpec,
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+			if tc.attempt > 0 {
+				ctx = handlerctx.WithDeliveryAttempt(ctx, tc.attempt)
+			}
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &Processor{
+				DeliverClient:      http.DefaultClient,
+				Targets:            testTargets,
+				RetryOnFailure:     tc.withRetry,
+				DeliverRetryClient: deliverRetryClient,
+				DeliverTimeout:     500 * time.Millisecond,
+				StatsReporter:      r,
+			}
+
+			origin := newSampleEvent()
+			err = p.Process(ctx, origin)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("processing got error=%v, want=%v", err, tc.wantErr)
+			}
+
+			select {
+			case got := <-deadLetterCh:
+				if !tc.wantDeadLetter {
+					t.Fatalf("unexpected event sent to dead letter sink: %v", got)
+				}
+				if got.ID() != origin.ID() {
+					t.Errorf("dead letter event id got=%s, want=%s", got.ID(), origin.ID())
+				}
+				reason, attempts, ok := eventutil.GetDeadLetterExtensions(got)
+				if !ok {
+					t.Fatalf("dead letter extensions not found in event: %v", got)
+				}
+				if reason == "" {
+					t.Error("dead letter reason is empty")
+				}
+				if attempts != tc.wantAttempts {
+					t.Errorf("dead letter attempts got=%d, want=%d", attempts, tc.wantAttempts)
+				}
+			default:
+				if tc.wantDeadLetter {
+					t.Error("event was not sent to dead letter sink")
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverClaimCheck(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	store, err := claimcheck.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receivedCh := make(chan *event.Event, 1)
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		e, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+		if err != nil {
+			t.Errorf("failed to convert request to event: %v", err)
+		}
+		receivedCh <- e
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer targetSvr.Close()
+
+	broker := &config.Broker{Namespace: "ns", Name: "broker"}
+	target := &config.Target{Namespace: "ns", Name: "target", Broker: "broker", Address: targetSvr.URL}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(target)
+	})
+	ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+	ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+	r, err := metrics.NewDeliveryReporter("pod", "container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Processor{
+		DeliverClient:   http.DefaultClient,
+		Targets:         testTargets,
+		StatsReporter:   r,
+		ClaimCheckStore: store,
+	}
+
+	origin := newSampleEvent()
+	want := []byte("offloaded payload")
+	if err := origin.SetData(event.TextPlain, want); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := claimcheck.Offload(ctx, store, origin, "ns/broker", 0); err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := claimcheck.Reference(origin)
+
+	if err := p.Process(ctx, origin); err != nil {
+		t.Fatalf("unexpected error from processing: %v", err)
+	}
+	got := <-receivedCh
+	if !bytes.Equal(got.Data(), want) {
+		t.Errorf("delivered event data got=%q, want=%q", got.Data(), want)
+	}
+	if _, ok := claimcheck.Reference(got); ok {
+		t.Error("delivered event has a claim check reference")
+	}
+	if got, _ := claimcheck.Reference(origin); got != ref {
+		t.Errorf("original event reference got=%q, want=%q", got, ref)
+	}
+
+	// A missing payload can't be fixed by retrying, so the event is dropped.
+	missing := newSampleEvent()
+	missing.SetExtension(claimcheck.Extension, ref+"-missing")
+	if err := p.Process(ctx, missing); err != nil {
+		t.Errorf("processing event with missing payload got error=%v, want dropped event", err)
+	}
+	metricstest.CheckCountData(t, "dropped_event_count", map[string]string{
+		"drop_reason": metrics.DropReasonNonRetryable,
+	}, 1)
+}
+
+func TestDeliverLoopDetected(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Error("event looping back to the target was delivered")
+	}))
+	defer targetSvr.Close()
+
+	broker := &config.Broker{Namespace: "ns", Name: "broker"}
+	target := &config.Target{Namespace: "ns", Name: "target", Broker: "broker", Address: targetSvr.URL}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.SetLoopDetection(true)
+		bm.UpsertTargets(target)
+	})
+	ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+	ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+	r, err := metrics.NewDeliveryReporter("pod", "container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var looped []string
+	p := &Processor{
+		DeliverClient: http.DefaultClient,
+		Targets:       testTargets,
+		StatsReporter: r,
+		LoopDetected:  func(targetKey string) { looped = append(looped, targetKey) },
+	}
+
+	// The event descends from a reply of the target.
+	origin, err := binding.ToEvent(ctx, binding.ToMessage(newSampleEvent()),
+		eventutil.SetVisitedTransformer(eventutil.Visited("").Append(target.Key()).Append("ns/broker/other")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Process(ctx, origin); err != nil {
+		t.Errorf("processing looping event got error=%v, want dropped event", err)
+	}
+	if diff := cmp.Diff([]string{target.Key()}, looped); diff != "" {
+		t.Errorf("loops detected (-want,+got): %v", diff)
+	}
+	metricstest.CheckCountData(t, "dropped_event_count", map[string]string{
+		"drop_reason": metrics.DropReasonLoopDetected,
+	}, 1)
+}
+
+func TestDeliverRetryOrdered(t *testing.T) {
+	cases := []struct {
+		name         string
+		failures     int32
+		deliverySpec *config.DeliverySpec
+		timeout      time.Duration
+		wantErr      bool
+		wantHits     int32
+		wantBackoffs []int
+	}{{
+		name:         "delivered after retries",
+		failures:     2,
+		wantHits:     3,
+		wantBackoffs: []int{1, 2},
+	}, {
+		name:         "retries exhausted",
+		failures:     10,
+		deliverySpec: &config.DeliverySpec{Retry: 2},
+		wantHits:     3,
+		wantBackoffs: []int{1, 2},
+	}, {
+		name:     "handler timed out",
+		failures: 1000,
+		timeout:  100 * time.Millisecond,
+		wantErr:  true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			var hits int32
+			targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if atomic.AddInt32(&hits, 1) <= tc.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer targetSvr.Close()
+
+			srv, c, close := testPubsubClient(ctx, t, "test-project")
+			defer close()
+			if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+				t.Fatalf("failed to create test pubsub topic: %v", err)
+			}
+
+			broker := &config.Broker{Namespace: "ns", Name: "broker"}
+			target := &config.Target{
+				Namespace:    "ns",
+				Name:         "target",
+				Broker:       "broker",
+				Address:      targetSvr.URL,
+				RetryQueue:   &config.Queue{Topic: "test-retry-topic"},
+				DeliverySpec: tc.deliverySpec,
+			}
+			testTargets := memory.NewEmptyTargets()
+			testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+				bm.SetOrderingKeyExtension("partitionkey")
+				bm.UpsertTargets(target)
+			})
+			ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+
+			r, err := metrics.NewDeliveryReporter("pod", "container")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ceps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			retryClient, err := ceclient.New(ceps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var backoffs []int
+			p := &Processor{
+				DeliverClient:      http.DefaultClient,
+				Targets:            testTargets,
+				RetryOnFailure:     true,
+				DeliverRetryClient: retryClient,
+				StatsReporter:      r,
+				Backoff: func(_ *config.Target, attempts int) time.Duration {
+					backoffs = append(backoffs, attempts)
+					return time.Millisecond
+				},
+			}
+
+			origin := newSampleEvent()
+			origin.SetExtension("partitionkey", "user-1")
+			err = p.Process(ctx, origin)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("processing error got=%v, wantErr=%v", err, tc.wantErr)
+			}
+			// The later events with the same key must not overtake the event in the retry queue.
+			if msgs := srv.Messages(); len(msgs) != 0 {
+				t.Errorf("retry messages got=%d, want=0", len(msgs))
+			}
+			if tc.wantHits > 0 {
+				if got := atomic.LoadInt32(&hits); got != tc.wantHits {
+					t.Errorf("target requests got=%d, want=%d", got, tc.wantHits)
+				}
+				if diff := cmp.Diff(tc.wantBackoffs, backoffs); diff != "" {
+					t.Errorf("backoffs (-want,+got): %v", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverCircuitBreaker(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	var hits int32
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer targetSvr.Close()
+
+	broker := &config.Broker{Namespace: "ns", Name: "broker"}
+	target := &config.Target{Namespace: "ns", Name: "target", Broker: "broker", Address: targetSvr.URL}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(target)
+	})
+	ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+	ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+	r, err := metrics.NewDeliveryReporter("pod", "container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = r.AddTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = metrics.AddTargetTags(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Processor{
+		DeliverClient: http.DefaultClient,
+		Targets:       testTargets,
+		StatsReporter: r,
+		Breakers:      NewBreakers(BreakerSettings{FailureThreshold: 2, OpenDuration: time.Hour}),
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := p.Process(ctx, newSampleEvent()); err == nil || errors.Is(err, errCircuitOpen) {
+			t.Errorf("processing event %d got error=%v, want delivery failure", i, err)
+		}
+	}
+	// The breaker is open, so the event must not reach the target.
+	if err := p.Process(ctx, newSampleEvent()); !errors.Is(err, errCircuitOpen) {
+		t.Errorf("processing event with open breaker got error=%v, want=%v", err, errCircuitOpen)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("target requests got=%d, want=2", got)
+	}
+
+	// Short-circuited events are not delivery attempts: they are retried once
+	// the breaker lets a probe through rather than dead-lettered.
+	var deadLetterHits int32
+	deadLetterSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&deadLetterHits, 1)
+	}))
+	defer deadLetterSvr.Close()
+	target.DeliverySpec = &config.DeliverySpec{Retry: 0, DeadLetter: deadLetterSvr.URL}
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(target)
+	})
+	err = p.Process(handlerctx.WithDeliveryAttempt(ctx, 5), newSampleEvent())
+	if !errors.Is(err, errCircuitOpen) {
+		t.Errorf("processing event with open breaker got error=%v, want=%v", err, errCircuitOpen)
+	}
+	if delivery.Attempted(err) {
+		t.Error("short-circuited delivery counted as an attempt")
+	}
+	if got := delivery.RetryDelay(err); got <= 59*time.Minute || got > time.Hour {
+		t.Errorf("retry delay with open breaker got=%v, want the remaining open duration", got)
+	}
+	if got := atomic.LoadInt32(&deadLetterHits); got != 0 {
+		t.Errorf("dead letter requests got=%d, want=0", got)
+	}
+
+	metricstest.CheckLastValueData(t, "circuit_breaker_state", map[string]string{
+		metricskey.LabelNamespaceName: "ns",
+		metricskey.LabelBrokerName:    "broker",
+		metricskey.LabelTriggerName:   "target",
+		metricskey.LabelFilterType:    "any",
+		metricskey.PodName:            "pod",
+		metricskey.ContainerName:      "container",
+	}, float64(BreakerOpen))
+}
+
+func TestDeliverRetryAfter(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	var hits int32
+	receivedCh := make(chan *event.Event, 1)
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		e, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+		if err != nil {
+			t.Errorf("failed to convert request to event: %v", err)
+		}
+		receivedCh <- e
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer targetSvr.Close()
+
+	srv, c, close := testPubsubClient(ctx, t, "test-project")
+	defer close()
+	if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+		t.Fatalf("failed to create test pubsub topc: %v", err)
+	}
+	ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+	if err != nil {
+		t.Fatalf("failed to create pubsub protocol: %v", err)
+	}
+	deliverRetryClient, err := ceclient.New(ps)
+	if err != nil {
+		t.Fatalf("failed to create cloudevents client: %v", err)
+	}
+
+	broker := &config.Broker{Namespace: "ns", Name: "broker"}
+	target := &config.Target{
+		Namespace:  "ns",
+		Name:       "target",
+		Broker:     "broker",
+		Address:    targetSvr.URL,
+		RetryQueue: &config.Queue{Topic: "test-retry-topic"},
+	}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(target)
+	})
+	ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+	ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+	r, err := metrics.NewDeliveryReporter("pod", "container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fanout := &Processor{
+		DeliverClient:      http.DefaultClient,
+		Targets:            testTargets,
+		RetryOnFailure:     true,
+		DeliverRetryClient: deliverRetryClient,
+		StatsReporter:      r,
+	}
+	retry := &Processor{
+		DeliverClient: http.DefaultClient,
+		Targets:       testTargets,
+		StatsReporter: r,
+	}
+
+	// The retry time requested by the target is recorded in the retry event,
+	// but not in the original event shared with the other targets.
+	origin := newSampleEvent()
+	if err := fanout.Process(ctx, origin); err != nil {
+		t.Fatalf("unexpected error from processing: %v", err)
+	}
+	if _, ok := eventutil.GetNotBefore(origin); ok {
+		t.Error("original event has a retry time")
+	}
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("retry messages got=%d, want=1", len(msgs))
+	}
+	retryEvent, err := binding.ToEvent(ctx, cepubsub.NewMessage(toFakePubsubMessage(msgs[0])))
+	if err != nil {
+		t.Fatalf("failed to convert retry message to event: %v", err)
+	}
+	notBefore, ok := eventutil.GetNotBefore(retryEvent)
+	if !ok {
+		t.Fatal("retry event has no retry time")
+	}
+	if delay := time.Until(notBefore); delay < 50*time.Second || delay > time.Minute {
+		t.Errorf("retry delay got=%v, want about 1m", delay)
+	}
+
+	// The retry is delayed until the retry time without delivering the event.
+	err = retry.Process(ctx, retryEvent)
+	if delay := delivery.RetryDelay(err); delay < 50*time.Second {
+		t.Errorf("processing retry event got error=%v, want retry delay of about 1m", err)
+	}
+	if delivery.Attempted(err) {
+		t.Error("not due retry counted as a delivery attempt")
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("target requests got=%d, want=1", got)
+	}
+
+	eventutil.SetNotBefore(retryEvent, time.Now().Add(-time.Second))
+	if err := retry.Process(ctx, retryEvent); err != nil {
+		t.Fatalf("unexpected error from processing: %v", err)
+	}
+	if got := <-receivedCh; len(got.Extensions()) != 0 {
+		t.Errorf("delivered event extensions got=%v, want none", got.Extensions())
+	}
+}
+
+func TestDeliverRetryOriginalEvent(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	receivedCh := make(chan string, 1)
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		receivedCh <- req.Header.Get("ce-type")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer targetSvr.Close()
+
+	srv, c, close := testPubsubClient(ctx, t, "test-project")
+	defer close()
+	if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+		t.Fatalf("failed to create test pubsub topc: %v", err)
+	}
+	ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+	if err != nil {
+		t.Fatalf("failed to create pubsub protocol: %v", err)
+	}
+	deliverRetryClient, err := ceclient.New(ps)
+	if err != nil {
+		t.Fatalf("failed to create cloudevents client: %v", err)
+	}
+
+	broker := &config.Broker{Namespace: "ns", Name: "broker"}
+	target := &config.Target{
+		Namespace:  "ns",
+		Name:       "target",
+		Broker:     "broker",
+		Address:    targetSvr.URL,
+		RetryQueue: &config.Queue{Topic: "test-retry-topic"},
+	}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(target)
+	})
+	ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+	ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+	r, err := metrics.NewDeliveryReporter("pod", "container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Processor{
+		DeliverClient:      http.DefaultClient,
+		Targets:            testTargets,
+		RetryOnFailure:     true,
+		DeliverRetryClient: deliverRetryClient,
+		StatsReporter:      r,
+	}
+
+	// The transformed event is delivered to the target, but the event as
+	// received is sent to the retry topic to be transformed again.
+	origin := newSampleEvent()
+	transformed := origin.Clone()
+	transformed.SetType("transformed")
+	if err := p.Process(handlerctx.WithOriginalEvent(ctx, origin), &transformed); err != nil {
+		t.Fatalf("unexpected error from processing: %v", err)
+	}
+	if got := <-receivedCh; got != "transformed" {
+		t.Errorf("delivered event type got=%q, want=%q", got, "transformed")
+	}
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("retry messages got=%d, want=1", len(msgs))
+	}
+	retryEvent, err := binding.ToEvent(ctx, cepubsub.NewMessage(toFakePubsubMessage(msgs[0])))
+	if err != nil {
+		t.Fatalf("failed to convert retry message to event: %v", err)
+	}
+	if retryEvent.Type() != origin.Type() {
+		t.Errorf("retry event type got=%q, want=%q", retryEvent.Type(), origin.Type())
+	}
+}
+
+type fakeTokenSource struct{}
+
+func (fakeTokenSource) Token(_ context.Context, audience, serviceAccount string) (string, error) {
+	return audience + "/" + serviceAccount, nil
+}
+
+func TestDeliverAuthenticated(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	authCh := make(chan string, 1)
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authCh <- req.Header.Get("Authorization")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer targetSvr.Close()
+
+	broker := &config.Broker{Namespace: "ns", Name: "broker"}
+	target := &config.Target{
+		Namespace: "ns",
+		Name:      "target",
+		Broker:    "broker",
+		Address:   targetSvr.URL,
+		DeliveryAuth: &config.DeliveryAuth{
+			Audience:       "https://subscriber",
+			ServiceAccount: "invoker@my-project.iam.gserviceaccount.com",
+		},
+	}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(target)
+	})
+	ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+	ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+	r, err := metrics.NewDeliveryReporter("pod", "container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Processor{
+		DeliverClient: http.DefaultClient,
+		Targets:       testTargets,
+		StatsReporter: r,
+	}
+
+	// Without a token source the event can't be delivered.
+	if err := p.Process(ctx, newSampleEvent()); err == nil {
+		t.Error("processing event without token source got nil error, want error")
+	}
+
+	p.TokenSource = fakeTokenSource{}
+	if err := p.Process(ctx, newSampleEvent()); err != nil {
+		t.Fatalf("unexpected error from processing: %v", err)
+	}
+	want := "Bearer https://subscriber/invoker@my-project.iam.gserviceaccount.com"
+	if got := <-authCh; got != want {
+		t.Errorf("Authorization header got=%q, want=%q", got, want)
+	}
+}
+
 type NoReplyHandler struct{}
 
 func (NoReplyHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
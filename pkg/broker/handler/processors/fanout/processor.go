@@ -47,6 +47,11 @@ type Processor struct {
 
 	// Targets is the targets from config.
 	Targets config.ReadonlyTargets
+
+	// Divert processes the event for the paused targets instead of the next
+	// processor, e.g. to keep it in their retry queues. If nil, the paused
+	// targets are skipped.
+	Divert processors.Interface
 }
 
 var _ processors.Interface = (*Processor)(nil)
@@ -64,15 +69,26 @@ func (p *Processor) Process(ctx context.Context, event *event.Event) error {
 		return nil
 	}
 
+	// Only dispatch the event to the targets that may accept it.
+	var targets []*config.Target
+	attrs := map[string]string{"type": event.Type(), "source": event.Source()}
+	p.Targets.RangeCandidateTargets(broker.Key(), attrs, func(t *config.Target) bool {
+		if t.State == config.State_PAUSED && p.Divert == nil {
+			return true
+		}
+		targets = append(targets, t)
+		return true
+	})
+
 	tc := make(chan *config.Target)
 	go func() {
 		defer close(tc)
-		for _, target := range broker.Targets {
+		for _, target := range targets {
 			tc <- target
 		}
 	}()
 
-	curr := len(broker.Targets)
+	curr := len(targets)
 	if curr > p.MaxConcurrency {
 		curr = p.MaxConcurrency
 	}
@@ -99,9 +115,13 @@ func (p *Processor) fanoutEvent(ctx context.Context, event *event.Event, tc <-ch
 				)
 			}
 			ctx = handlerctx.WithTargetKey(ctx, target.Key())
+			next := p.Next()
+			if target.State == config.State_PAUSED {
+				next = p.Divert
+			}
 			out <- &fanoutResult{
 				targetKey: target.Key(),
-				err:       p.Next().Process(ctx, event),
+				err:       next.Process(ctx, event),
 			}
 		}
 	}()
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"sync/atomic"
 	"testing"
 
 	"github.com/cloudevents/sdk-go/v2/event"
@@ -28,8 +29,10 @@ import (
 
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/handler/compiled"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
 )
 
 func TestInvalidContext(t *testing.T) {
@@ -142,6 +145,160 @@ func TestFanoutPartialFailure(t *testing.T) {
 	close(ch)
 }
 
+func TestFanoutCandidateTargets(t *testing.T) {
+	ns, broker := "ns", "broker"
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker(ns, broker, func(bm config.BrokerMutation) {
+		bm.UpsertTargets(
+			&config.Target{Name: "no-filter"},
+			&config.Target{Name: "matching-type", FilterAttributes: map[string]string{"type": "type"}},
+			&config.Target{Name: "other-type", FilterAttributes: map[string]string{"type": "other"}},
+			&config.Target{Name: "other-source", Filters: []*config.Filter{{Exact: map[string]string{"source": "other"}}}},
+		)
+	})
+	var gotTargets []string
+	next := &processors.FakeProcessor{
+		PrevEventsCh: make(chan *event.Event, 4),
+		InterceptFunc: func(ctx context.Context, e *event.Event) *event.Event {
+			t, _ := handlerctx.GetTargetKey(ctx)
+			gotTargets = append(gotTargets, t)
+			return e
+		},
+	}
+	p := &Processor{MaxConcurrency: 2, Targets: testTargets}
+	p.WithNext(next)
+
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("type")
+
+	ctx := handlerctx.WithBrokerKey(context.Background(), config.BrokerKey(ns, broker))
+	if err := p.Process(ctx, &e); err != nil {
+		t.Errorf("unexpected error from processing: %v", err)
+	}
+
+	sort.Strings(gotTargets)
+	wantTargets := []string{
+		config.TriggerKey(ns, broker, "matching-type"),
+		config.TriggerKey(ns, broker, "no-filter"),
+	}
+	if diff := cmp.Diff(wantTargets, gotTargets); diff != "" {
+		t.Errorf("got target keys (-want,+got): %v", diff)
+	}
+}
+
+func TestFanoutPausedTargets(t *testing.T) {
+	ns, broker := "ns", "broker"
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker(ns, broker, func(bm config.BrokerMutation) {
+		bm.UpsertTargets(
+			&config.Target{Name: "ready", State: config.State_READY},
+			&config.Target{Name: "paused", State: config.State_PAUSED},
+		)
+	})
+	targetsOf := func(got *[]string) func(ctx context.Context, e *event.Event) *event.Event {
+		return func(ctx context.Context, e *event.Event) *event.Event {
+			t, _ := handlerctx.GetTargetKey(ctx)
+			*got = append(*got, t)
+			return e
+		}
+	}
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("type")
+	ctx := handlerctx.WithBrokerKey(context.Background(), config.BrokerKey(ns, broker))
+
+	t.Run("diverted", func(t *testing.T) {
+		var gotNext, gotDivert []string
+		next := &processors.FakeProcessor{PrevEventsCh: make(chan *event.Event, 2), InterceptFunc: targetsOf(&gotNext)}
+		divert := &processors.FakeProcessor{PrevEventsCh: make(chan *event.Event, 2), InterceptFunc: targetsOf(&gotDivert)}
+		p := &Processor{MaxConcurrency: 1, Targets: testTargets, Divert: divert}
+		p.WithNext(next)
+		if err := p.Process(ctx, &e); err != nil {
+			t.Errorf("unexpected error from processing: %v", err)
+		}
+		if diff := cmp.Diff([]string{config.TriggerKey(ns, broker, "ready")}, gotNext); diff != "" {
+			t.Errorf("got next target keys (-want,+got): %v", diff)
+		}
+		if diff := cmp.Diff([]string{config.TriggerKey(ns, broker, "paused")}, gotDivert); diff != "" {
+			t.Errorf("got diverted target keys (-want,+got): %v", diff)
+		}
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		var gotNext []string
+		next := &processors.FakeProcessor{PrevEventsCh: make(chan *event.Event, 2), InterceptFunc: targetsOf(&gotNext)}
+		p := &Processor{MaxConcurrency: 1, Targets: testTargets}
+		p.WithNext(next)
+		if err := p.Process(ctx, &e); err != nil {
+			t.Errorf("unexpected error from processing: %v", err)
+		}
+		if diff := cmp.Diff([]string{config.TriggerKey(ns, broker, "ready")}, gotNext); diff != "" {
+			t.Errorf("got next target keys (-want,+got): %v", diff)
+		}
+	})
+}
+
+type countProcessor struct {
+	processors.BaseProcessor
+	count int32
+}
+
+func (p *countProcessor) Process(context.Context, *event.Event) error {
+	atomic.AddInt32(&p.count, 1)
+	return nil
+}
+
+// BenchmarkFanoutLargeBroker compares the fanout to a broker with many
+// triggers when the triggers can be indexed by the event type and when each
+// of them has to be filtered.
+func BenchmarkFanoutLargeBroker(b *testing.B) {
+	for _, numTargets := range []int{10, 100, 1000, 10000} {
+		for _, indexed := range []bool{true, false} {
+			b.Run(fmt.Sprintf("%d targets indexed=%t", numTargets, indexed), func(b *testing.B) {
+				ns, broker := "ns", "broker"
+				testTargets := memory.NewEmptyTargets()
+				testTargets.MutateBroker(ns, broker, func(bm config.BrokerMutation) {
+					for i := 0; i < numTargets; i++ {
+						t := &config.Target{Name: fmt.Sprintf("target-%d", i)}
+						if indexed {
+							t.FilterAttributes = map[string]string{"type": fmt.Sprintf("type-%d", i)}
+						} else {
+							t.Filters = []*config.Filter{{Sql: fmt.Sprintf("type = 'type-%d'", i)}}
+						}
+						bm.UpsertTargets(t)
+					}
+				})
+				next := &countProcessor{}
+				p := processors.ChainProcessors(
+					&Processor{MaxConcurrency: 10, Targets: testTargets},
+					&filter.Processor{Targets: testTargets, Compiled: compiled.NewCache()},
+					next,
+				)
+
+				e := event.New()
+				e.SetID("id")
+				e.SetSource("source")
+				e.SetType("type-0")
+				ctx := handlerctx.WithBrokerKey(context.Background(), config.BrokerKey(ns, broker))
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := p.Process(ctx, &e); err != nil {
+						b.Errorf("unexpected error from processing: %v", err)
+					}
+				}
+				b.StopTimer()
+				if got := atomic.LoadInt32(&next.count); got != int32(b.N) {
+					b.Errorf("delivered events got=%d, want=%d", got, b.N)
+				}
+			})
+		}
+	}
+}
+
 func newTestTargets(ns, broker string, num int) config.ReadonlyTargets {
 	targets := memory.NewEmptyTargets()
 	targets.MutateBroker(ns, broker, func(bm config.BrokerMutation) {
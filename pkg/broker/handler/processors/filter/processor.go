@@ -28,6 +28,8 @@ import (
 	kntracing "knative.dev/eventing/pkg/tracing"
 
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
+	"github.com/google/knative-gcp/pkg/broker/handler/compiled"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 	"github.com/google/knative-gcp/pkg/tracing"
@@ -39,6 +41,9 @@ type Processor struct {
 
 	// Targets is the targets from config.
 	Targets config.ReadonlyTargets
+
+	// Compiled holds the compiled structured filters of the targets.
+	Compiled *compiled.Cache
 }
 
 var _ processors.Interface = (*Processor)(nil)
@@ -64,15 +69,15 @@ func (p *Processor) Process(ctx context.Context, event *event.Event) error {
 	ctx, span := startSpan(ctx, trigger, event)
 	defer span.End()
 
-	if target.FilterAttributes == nil {
-		return p.Next().Process(ctx, event)
+	if target.FilterAttributes != nil && !p.passFilter(ctx, target.FilterAttributes, event) {
+		logging.FromContext(ctx).Debug("event does not pass filter for target", zap.Any("target", target))
+		return nil
 	}
-
-	if p.passFilter(ctx, target.FilterAttributes, event) {
-		return p.Next().Process(ctx, event)
+	if len(target.Filters) != 0 && !p.passStructuredFilters(ctx, target, event) {
+		logging.FromContext(ctx).Debug("event does not pass structured filters for target", zap.Any("target", target))
+		return nil
 	}
-	logging.FromContext(ctx).Debug("event does not pass filter for target", zap.Any("target", target))
-	return nil
+	return p.Next().Process(ctx, event)
 }
 
 func startSpan(ctx context.Context, trigger types.NamespacedName, event *event.Event) (context.Context, *trace.Span) {
@@ -133,3 +138,19 @@ func (p *Processor) passFilter(ctx context.Context, attrs map[string]string, eve
 	}
 	return true
 }
+
+func (p *Processor) passStructuredFilters(ctx context.Context, target *config.Target, event *event.Event) bool {
+	c := p.Compiled.Get(target)
+	if c.FilterErr != nil {
+		// The trigger webhook and the brokercell reconciler reject invalid filters,
+		// so this should not happen.
+		logging.FromContext(ctx).Error("failed to compile filters for target", zap.String("target", target.Key()), zap.Error(c.FilterErr))
+		trace.FromContext(ctx).Annotatef(nil, "invalid filters: %v", c.FilterErr)
+		return false
+	}
+	if !c.Filter.Matches(eventfilter.Attributes(event)) {
+		trace.FromContext(ctx).Annotate(nil, "event does not match structured filters")
+		return false
+	}
+	return true
+}
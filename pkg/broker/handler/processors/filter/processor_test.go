@@ -29,6 +29,7 @@ import (
 
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/handler/compiled"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 )
@@ -39,7 +40,7 @@ const (
 )
 
 func TestInvalidContext(t *testing.T) {
-	p := &Processor{}
+	p := &Processor{Compiled: compiled.NewCache()}
 	e := event.New()
 	err := p.Process(context.Background(), &e)
 	if err != handlerctx.ErrTargetKeyNotPresent {
@@ -70,7 +71,7 @@ func TestTriggerSpanCreatedFromTraceparent(t *testing.T) {
 
 	ctx, testTargets := newTestTargets(nil)
 
-	p := &Processor{Targets: testTargets}
+	p := &Processor{Targets: testTargets, Compiled: compiled.NewCache()}
 	p.WithNext(&VerifyTraceID{wantTraceID: traceID})
 
 	if err := p.Process(ctx, &e); err != nil {
@@ -284,7 +285,7 @@ func TestFilterProcessor(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx, testTargets := newTestTargets(tc.filter)
 			next := &processors.FakeProcessor{}
-			p := &Processor{Targets: testTargets}
+			p := &Processor{Targets: testTargets, Compiled: compiled.NewCache()}
 			p.WithNext(next)
 			ch := make(chan *event.Event, 1)
 			next.PrevEventsCh = ch
@@ -311,6 +312,120 @@ func TestFilterProcessor(t *testing.T) {
 	}
 }
 
+func TestStructuredFilterProcessor(t *testing.T) {
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("com.example.created")
+	e.SetSubject("image.png")
+
+	cases := []struct {
+		name       string
+		attributes map[string]string
+		filters    []*config.Filter
+		shouldPass bool
+	}{{
+		name:       "prefix pass",
+		filters:    []*config.Filter{{Prefix: map[string]string{"type": "com.example."}}},
+		shouldPass: true,
+	}, {
+		name:       "suffix not pass",
+		filters:    []*config.Filter{{Suffix: map[string]string{"subject": ".jpg"}}},
+		shouldPass: false,
+	}, {
+		name: "any pass",
+		filters: []*config.Filter{{Any: []*config.Filter{
+			{Exact: map[string]string{"source": "other"}},
+			{Sql: "subject LIKE '%.png'"},
+		}}},
+		shouldPass: true,
+	}, {
+		name:       "not not pass",
+		filters:    []*config.Filter{{Not: &config.Filter{Exact: map[string]string{"source": "source"}}}},
+		shouldPass: false,
+	}, {
+		name:       "filter attributes and filters pass",
+		attributes: map[string]string{"source": "source"},
+		filters:    []*config.Filter{{Sql: "type = 'com.example.created'"}},
+		shouldPass: true,
+	}, {
+		name:       "filter attributes not pass",
+		attributes: map[string]string{"source": "other"},
+		filters:    []*config.Filter{{Sql: "type = 'com.example.created'"}},
+		shouldPass: false,
+	}, {
+		name:       "invalid filter not pass",
+		filters:    []*config.Filter{{Sql: "type ="}},
+		shouldPass: false,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, testTargets := newTestTargets(tc.attributes)
+			testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+				bm.UpsertTargets(&config.Target{
+					Name:             "target",
+					Broker:           "broker",
+					Namespace:        "ns",
+					FilterAttributes: tc.attributes,
+					Filters:          tc.filters,
+				})
+			})
+			next := &processors.FakeProcessor{}
+			p := &Processor{Targets: testTargets, Compiled: compiled.NewCache()}
+			p.WithNext(next)
+			ch := make(chan *event.Event, 1)
+			next.PrevEventsCh = ch
+
+			if err := p.Process(ctx, &e); err != nil {
+				t.Errorf("unexpected error from processing: %v", err)
+			}
+			close(ch)
+			if gotEvent := <-ch; (gotEvent != nil) != tc.shouldPass {
+				t.Errorf("event passed filters got=%v, want=%v", gotEvent != nil, tc.shouldPass)
+			}
+		})
+	}
+}
+
+func TestStructuredFilterRecompiledOnUpdate(t *testing.T) {
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("com.example.created")
+
+	ctx, testTargets := newTestTargets(nil)
+	p := &Processor{Targets: testTargets, Compiled: compiled.NewCache()}
+	next := &processors.FakeProcessor{}
+	p.WithNext(next)
+
+	for _, tc := range []struct {
+		filter     string
+		shouldPass bool
+	}{
+		{filter: "com.example.", shouldPass: true},
+		{filter: "org.example.", shouldPass: false},
+	} {
+		testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+			bm.UpsertTargets(&config.Target{
+				Name:      "target",
+				Broker:    "broker",
+				Namespace: "ns",
+				Filters:   []*config.Filter{{Prefix: map[string]string{"type": tc.filter}}},
+			})
+		})
+		ch := make(chan *event.Event, 1)
+		next.PrevEventsCh = ch
+		if err := p.Process(ctx, &e); err != nil {
+			t.Errorf("unexpected error from processing: %v", err)
+		}
+		close(ch)
+		if gotEvent := <-ch; (gotEvent != nil) != tc.shouldPass {
+			t.Errorf("event passed prefix %q got=%v, want=%v", tc.filter, gotEvent != nil, tc.shouldPass)
+		}
+	}
+}
+
 func newTestTargets(filter map[string]string) (context.Context, config.Targets) {
 	testTarget := &config.Target{
 		Name:             "target",
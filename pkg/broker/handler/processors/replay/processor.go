@@ -0,0 +1,123 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/logging"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+)
+
+// Processor passes the events replayed to the target in the context to the
+// next processor until the replay caught up. The events published after the
+// replay started were delivered to the target already and are dropped.
+//
+// The order in which Pub/Sub delivers the events isn't the order they were
+// published in, and other pods may still hold some of them. So the replay
+// only caught up in the pod once no event was replayed for a quiet period,
+// see CheckCaughtUp.
+type Processor struct {
+	processors.BaseProcessor
+
+	// Targets is the targets from config.
+	Targets config.ReadonlyTargets
+
+	// OnProgress is called with the key of the target and true when the
+	// replay caught up in the pod, and with false when an event is replayed
+	// again afterwards. If nil, nobody is told.
+	OnProgress func(targetKey string, caughtUp bool)
+
+	// mux guards the fields below, and the calls to OnProgress.
+	mux sync.Mutex
+	// inFlight is the number of events being replayed.
+	inFlight int
+	// lastActive is when an event was last replayed, or when the replay was
+	// first checked.
+	lastActive time.Time
+	caughtUp   bool
+}
+
+var _ processors.Interface = (*Processor)(nil)
+
+// Process passes the event to the next processor if it was published before
+// the replay started. Otherwise it simply returns.
+func (p *Processor) Process(ctx context.Context, event *event.Event) error {
+	tk, err := handlerctx.GetTargetKey(ctx)
+	if err != nil {
+		return err
+	}
+	p.active(tk, 1)
+	defer p.active(tk, -1)
+
+	target, ok := p.Targets.GetTargetByKey(tk)
+	if !ok || target.Replay == nil {
+		// If the replay no longer exists, then there is nothing to process.
+		logging.FromContext(ctx).Warn("replay no longer exist in the config", zap.String("target", tk))
+		return nil
+	}
+	publishTime, err := handlerctx.GetPublishTime(ctx)
+	if err != nil {
+		return err
+	}
+	if target.Replay.Until != nil && !publishTime.Before(target.Replay.Until.AsTime()) {
+		logging.FromContext(ctx).Debug("event published after the replay started", zap.String("target", tk))
+		return nil
+	}
+	return p.Next().Process(ctx, event)
+}
+
+// active records an event of the replay starting or ending. The replay is no
+// longer caught up once an event starts.
+func (p *Processor) active(targetKey string, delta int) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.inFlight += delta
+	p.lastActive = time.Now()
+	if p.caughtUp {
+		p.caughtUp = false
+		if p.OnProgress != nil {
+			p.OnProgress(targetKey, false)
+		}
+	}
+}
+
+// CheckCaughtUp reports the replay of the target as caught up once no event
+// was replayed for the quiet period, and none is in flight. The quiet period
+// starts with the first check at the latest. It's meant to be called
+// periodically while the replay handler is running.
+func (p *Processor) CheckCaughtUp(targetKey string, quietPeriod time.Duration) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.lastActive.IsZero() {
+		p.lastActive = time.Now()
+	}
+	if p.caughtUp || p.inFlight > 0 || time.Since(p.lastActive) < quietPeriod {
+		return
+	}
+	p.caughtUp = true
+	if p.OnProgress != nil {
+		p.OnProgress(targetKey, true)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+)
+
+func TestInvalidContext(t *testing.T) {
+	p := &Processor{Targets: memory.NewEmptyTargets()}
+	e := event.New()
+	err := p.Process(context.Background(), &e)
+	if err != handlerctx.ErrTargetKeyNotPresent {
+		t.Errorf("Process error got=%v, want=%v", err, handlerctx.ErrTargetKeyNotPresent)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	until := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	target := &config.Target{
+		Namespace: "ns",
+		Broker:    "broker",
+		Name:      "replaying",
+		Replay: &config.Replay{
+			Queue: &config.Queue{Topic: "decouple", Subscription: "replay"},
+			Until: timestamppb.New(until),
+		},
+	}
+	done := &config.Target{Namespace: "ns", Broker: "broker", Name: "done"}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(target, done)
+	})
+
+	cases := []struct {
+		name        string
+		target      *config.Target
+		publishTime time.Time
+		wantNext    bool
+	}{{
+		name:        "published before the replay",
+		target:      target,
+		publishTime: until.Add(-time.Hour),
+		wantNext:    true,
+	}, {
+		name:        "published after the replay",
+		target:      target,
+		publishTime: until.Add(time.Second),
+	}, {
+		name:        "replay over",
+		target:      done,
+		publishTime: until.Add(-time.Hour),
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ch := make(chan *event.Event, 1)
+			p := &Processor{Targets: testTargets}
+			p.WithNext(&processors.FakeProcessor{PrevEventsCh: ch})
+
+			e := event.New()
+			e.SetID("id")
+			e.SetSource("source")
+			e.SetType("type")
+			ctx := handlerctx.WithTargetKey(context.Background(), tc.target.Key())
+			ctx = handlerctx.WithPublishTime(ctx, tc.publishTime)
+			if err := p.Process(ctx, &e); err != nil {
+				t.Fatalf("unexpected error from processing: %v", err)
+			}
+			close(ch)
+			if gotNext := len(ch) > 0; gotNext != tc.wantNext {
+				t.Errorf("event passed to next processor got=%v, want=%v", gotNext, tc.wantNext)
+			}
+		})
+	}
+}
+
+func TestCheckCaughtUp(t *testing.T) {
+	target := &config.Target{
+		Namespace: "ns",
+		Broker:    "broker",
+		Name:      "replaying",
+		Replay: &config.Replay{
+			Queue: &config.Queue{Topic: "decouple", Subscription: "replay"},
+			Until: timestamppb.Now(),
+		},
+	}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(target)
+	})
+	const quietPeriod = 50 * time.Millisecond
+
+	var progress []bool
+	next := make(chan *event.Event)
+	p := &Processor{
+		Targets: testTargets,
+		OnProgress: func(tk string, caughtUp bool) {
+			if tk != target.Key() {
+				t.Errorf("progress reported for target %q, want %q", tk, target.Key())
+			}
+			progress = append(progress, caughtUp)
+		},
+	}
+	p.WithNext(&processors.FakeProcessor{PrevEventsCh: next})
+	replayEvent := func() <-chan error {
+		e := event.New()
+		e.SetID("id")
+		e.SetSource("source")
+		e.SetType("type")
+		ctx := handlerctx.WithTargetKey(context.Background(), target.Key())
+		ctx = handlerctx.WithPublishTime(ctx, target.Replay.Until.AsTime().Add(-time.Hour))
+		errCh := make(chan error, 1)
+		go func() { errCh <- p.Process(ctx, &e) }()
+		<-next
+		return errCh
+	}
+
+	// The quiet period starts with the first check.
+	p.CheckCaughtUp(target.Key(), quietPeriod)
+	time.Sleep(quietPeriod)
+
+	// Not caught up while an event is in flight.
+	errCh := replayEvent()
+	p.CheckCaughtUp(target.Key(), quietPeriod)
+	if len(progress) != 0 {
+		t.Errorf("replay caught up with an event in flight: %v", progress)
+	}
+	close(next)
+	if err := <-errCh; err != nil {
+		t.Fatalf("unexpected error from processing: %v", err)
+	}
+
+	// Not caught up until the quiet period after the last event.
+	p.CheckCaughtUp(target.Key(), quietPeriod)
+	if len(progress) != 0 {
+		t.Errorf("replay caught up within the quiet period: %v", progress)
+	}
+	time.Sleep(quietPeriod)
+	p.CheckCaughtUp(target.Key(), quietPeriod)
+	p.CheckCaughtUp(target.Key(), quietPeriod)
+	if diff := cmp.Diff([]bool{true}, progress); diff != "" {
+		t.Errorf("replay progress (-want,+got): %v", diff)
+	}
+
+	// An event replayed again withdraws the report.
+	next = make(chan *event.Event)
+	p.WithNext(&processors.FakeProcessor{PrevEventsCh: next})
+	errCh = replayEvent()
+	close(next)
+	<-errCh
+	if diff := cmp.Diff([]bool{true, false}, progress); diff != "" {
+		t.Errorf("replay progress (-want,+got): %v", diff)
+	}
+}
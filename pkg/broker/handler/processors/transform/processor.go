@@ -0,0 +1,83 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"context"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/logging"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/handler/compiled"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+)
+
+// Processor is the processor to transform events based on trigger transforms.
+type Processor struct {
+	processors.BaseProcessor
+
+	// Targets is the targets from config.
+	Targets config.ReadonlyTargets
+
+	// Compiled holds the compiled transforms of the targets.
+	Compiled *compiled.Cache
+}
+
+var _ processors.Interface = (*Processor)(nil)
+
+// Process passes a transformed copy of the event to the next processor. The
+// event as received is kept in the context, so that it is the one sent to the
+// retry topic and transformed again when retried. Events that fail to be
+// transformed are dropped.
+func (p *Processor) Process(ctx context.Context, event *event.Event) error {
+	tk, err := handlerctx.GetTargetKey(ctx)
+	if err != nil {
+		return err
+	}
+	target, ok := p.Targets.GetTargetByKey(tk)
+	if !ok {
+		// If the target no longer exists, then there is nothing to process.
+		logging.FromContext(ctx).Warn("target no longer exist in the config", zap.String("target", tk))
+		return nil
+	}
+	if len(target.Transforms) == 0 {
+		return p.Next().Process(ctx, event)
+	}
+
+	c := p.Compiled.Get(target)
+	if c.TransformErr != nil {
+		// The trigger webhook and the brokercell reconciler reject invalid transforms,
+		// so this should not happen.
+		logging.FromContext(ctx).Error("failed to compile transforms for target", zap.String("target", tk), zap.Error(c.TransformErr))
+		trace.FromContext(ctx).Annotatef(nil, "invalid transforms: %v", c.TransformErr)
+		return nil
+	}
+	// The same event is passed to the processors of every target, so it
+	// must not be modified.
+	transformed := event.Clone()
+	if err := c.Transformer.Apply(&transformed); err != nil {
+		logging.FromContext(ctx).Error("failed to transform event for target, dropping event",
+			zap.String("target", tk), zap.String("event", event.ID()), zap.Error(err))
+		trace.FromContext(ctx).Annotatef(nil, "event failed to be transformed: %v", err)
+		return nil
+	}
+	return p.Next().Process(handlerctx.WithOriginalEvent(ctx, event), &transformed)
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/handler/compiled"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+)
+
+// recordingProcessor records the event and the original event in the context.
+type recordingProcessor struct {
+	processors.BaseProcessor
+	event    *event.Event
+	original *event.Event
+}
+
+func (p *recordingProcessor) Process(ctx context.Context, e *event.Event) error {
+	p.event = e
+	p.original, _ = handlerctx.GetOriginalEvent(ctx)
+	return nil
+}
+
+func TestInvalidContext(t *testing.T) {
+	p := &Processor{Compiled: compiled.NewCache()}
+	e := event.New()
+	err := p.Process(context.Background(), &e)
+	if err != handlerctx.ErrTargetKeyNotPresent {
+		t.Errorf("Process error got=%v, want=%v", err, handlerctx.ErrTargetKeyNotPresent)
+	}
+}
+
+func TestTransformProcessor(t *testing.T) {
+	newEvent := func() *event.Event {
+		e := event.New()
+		e.SetID("id")
+		e.SetSource("source")
+		e.SetType("com.example.created")
+		e.SetSubject("image.png")
+		return &e
+	}
+
+	cases := []struct {
+		name       string
+		transforms []*config.Transform
+		// wantType is the type of the event passed to the next processor,
+		// empty if the event should be dropped.
+		wantType     string
+		wantOriginal bool
+	}{{
+		name:     "no transforms",
+		wantType: "com.example.created",
+	}, {
+		name:         "set type",
+		transforms:   []*config.Transform{{Set: map[string]string{"type": "${type}.v2"}}},
+		wantType:     "com.example.created.v2",
+		wantOriginal: true,
+	}, {
+		name: "transforms in order",
+		transforms: []*config.Transform{
+			{Rename: map[string]string{"subject": "name"}},
+			{Set: map[string]string{"type": "${name}"}},
+		},
+		wantType:     "image.png",
+		wantOriginal: true,
+	}, {
+		name:       "invalid transforms dropped",
+		transforms: []*config.Transform{{Remove: []string{"type"}}},
+	}, {
+		name:       "failed transform dropped",
+		transforms: []*config.Transform{{Set: map[string]string{"time": "${subject}"}}},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, testTargets := newTestTargets(tc.transforms)
+			next := &recordingProcessor{}
+			p := &Processor{Targets: testTargets, Compiled: compiled.NewCache()}
+			p.WithNext(next)
+
+			e := newEvent()
+			if err := p.Process(ctx, e); err != nil {
+				t.Errorf("unexpected error from processing: %v", err)
+			}
+			if diff := cmp.Diff(newEvent(), e); diff != "" {
+				t.Errorf("processed event was modified (-want,+got): %v", diff)
+			}
+			if tc.wantType == "" {
+				if next.event != nil {
+					t.Errorf("event passed to next processor got=%v, want dropped", next.event)
+				}
+				return
+			}
+			if next.event == nil {
+				t.Fatal("event was not passed to next processor")
+			}
+			if got := next.event.Type(); got != tc.wantType {
+				t.Errorf("transformed event type got=%q, want=%q", got, tc.wantType)
+			}
+			if gotOriginal := next.original == e; gotOriginal != tc.wantOriginal {
+				t.Errorf("original event in context got=%v, want=%v", gotOriginal, tc.wantOriginal)
+			}
+		})
+	}
+}
+
+func TestTransformRecompiledOnUpdate(t *testing.T) {
+	ctx, testTargets := newTestTargets(nil)
+	next := &recordingProcessor{}
+	p := &Processor{Targets: testTargets, Compiled: compiled.NewCache()}
+	p.WithNext(next)
+
+	for _, wantType := range []string{"v1", "v2"} {
+		testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+			bm.UpsertTargets(&config.Target{
+				Name:       "target",
+				Broker:     "broker",
+				Namespace:  "ns",
+				Transforms: []*config.Transform{{Set: map[string]string{"type": wantType}}},
+			})
+		})
+		e := event.New()
+		e.SetID("id")
+		e.SetSource("source")
+		e.SetType("type")
+		if err := p.Process(ctx, &e); err != nil {
+			t.Errorf("unexpected error from processing: %v", err)
+		}
+		if got := next.event.Type(); got != wantType {
+			t.Errorf("transformed event type got=%q, want=%q", got, wantType)
+		}
+	}
+}
+
+func newTestTargets(transforms []*config.Transform) (context.Context, config.Targets) {
+	testTarget := &config.Target{
+		Name:       "target",
+		Broker:     "broker",
+		Namespace:  "ns",
+		Transforms: transforms,
+	}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.UpsertTargets(testTarget)
+	})
+	ctx := handlerctx.WithTargetKey(context.Background(), testTarget.Key())
+	return ctx, testTargets
+}
@@ -22,8 +22,9 @@ import (
 	"time"
 
 	"cloud.google.com/go/pubsub"
-	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
 	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	"github.com/google/knative-gcp/pkg/broker/transport"
+	pubsubtransport "github.com/google/knative-gcp/pkg/broker/transport/pubsub"
 	"github.com/google/knative-gcp/pkg/utils/clients"
 	"github.com/google/wire"
 	"go.opencensus.io/plugin/ochttp"
@@ -56,6 +57,7 @@ var (
 		NewFanoutPool,
 		NewRetryPool,
 		clients.NewPubsubClient,
+		NewPubsubTransport,
 		NewRetryClient,
 		wire.Value(DefaultHTTPClient),
 		wire.Value(DefaultCEClientOpts),
@@ -64,12 +66,19 @@ var (
 
 type RetryClient ceclient.Client
 
-// NewRetryClient provides a retry CE client from a PubSub client and list of CE client options.
-func NewRetryClient(ctx context.Context, client *pubsub.Client, opts ...ceclient.Option) (RetryClient, error) {
-	rps, err := cepubsub.New(ctx, cepubsub.WithClient(client))
+// NewPubsubTransport provides the Pub/Sub transport receiving with the Pub/Sub receive settings
+// of the options.
+func NewPubsubTransport(client *pubsub.Client, opts ...Option) (transport.Transport, error) {
+	options, err := NewOptions(opts...)
 	if err != nil {
 		return nil, err
 	}
+	t := pubsubtransport.New(client)
+	t.ReceiveSettings = options.PubsubReceiveSettings
+	return t, nil
+}
 
-	return ceclient.NewObserved(rps, opts...)
+// NewRetryClient provides a retry CE client from a transport and list of CE client options.
+func NewRetryClient(ctx context.Context, t transport.Transport, opts ...ceclient.Option) (RetryClient, error) {
+	return ceclient.NewObserved(transport.NewSender(t), opts...)
 }
@@ -18,19 +18,24 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 	"knative.dev/eventing/pkg/logging"
 
-	"cloud.google.com/go/pubsub"
-
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/handler/compiled"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/replay"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/transform"
+	"github.com/google/knative-gcp/pkg/broker/transport"
 	"github.com/google/knative-gcp/pkg/metrics"
 )
 
@@ -42,41 +47,87 @@ type RetryPool struct {
 	options *Options
 	targets config.ReadonlyTargets
 	pool    sync.Map
-	// Pubsub client used to pull events from decoupling topics.
-	pubsubClient *pubsub.Client
+	// replays holds the handlers of the replays in progress by target key.
+	replays sync.Map
+	// Transport used to pull events from retry queues.
+	transport transport.Transport
 	// For initial events delivery. We only need a shared client.
 	// And we can set target address dynamically.
 	deliverClient *http.Client
+	// Circuit breakers of target addresses shared by all handlers.
+	breakers *deliver.Breakers
+	// The compiled filters and transforms of the targets shared by all handlers.
+	compiled      *compiled.Cache
 	statsReporter *metrics.DeliveryReporter
+	// The handlers which haven't stopped yet.
+	running runningHandlers
+	// Backoff of the restarts of the failed handlers.
+	restarts *restartBackoff
 }
 
 type retryHandlerCache struct {
 	Handler
 	t *config.Target
+	// generation is the generation of the targets config the handler was last synced with.
+	generation int64
 }
 
 // If somehow the existing handler's setting has deviated from the current target config,
 // we need to renew the handler.
 func (hc *retryHandlerCache) shouldRenew(t *config.Target) bool {
-	if !hc.IsAlive() {
-		return true
-	}
+	return !hc.IsAlive() || hc.configChanged(t)
+}
+
+// configChanged returns true if the handler's setting has deviated from the
+// current target config.
+func (hc *retryHandlerCache) configChanged(t *config.Target) bool {
 	// If this really happens, it means a data corruption.
 	// The handler creation will fail (which is expected).
 	if t == nil || t.RetryQueue == nil {
 		return true
 	}
+	// The handler of a paused target is stopped. A new one is started once
+	// the target is ready again.
+	if t.State == config.State_PAUSED {
+		return true
+	}
 	if t.RetryQueue.Topic != hc.t.RetryQueue.Topic ||
 		t.RetryQueue.Subscription != hc.t.RetryQueue.Subscription {
 		return true
 	}
+	// The retry policy of the handler is built from the delivery spec.
+	if !proto.Equal(t.DeliverySpec, hc.t.DeliverySpec) {
+		return true
+	}
 	return false
 }
 
+type replayHandlerCache struct {
+	Handler
+	t *config.Target
+	// replay tracks the progress of the replay.
+	replay *replay.Processor
+}
+
+// shouldRenew returns true if the replay handler no longer matches the target
+// config. The handler is stopped once the target has no replay in progress.
+func (hc *replayHandlerCache) shouldRenew(t *config.Target) bool {
+	if !hc.IsAlive() {
+		return true
+	}
+	if t == nil || t.Replay == nil || t.State != config.State_READY {
+		return true
+	}
+	if !proto.Equal(t.Replay, hc.t.Replay) {
+		return true
+	}
+	return !proto.Equal(t.DeliverySpec, hc.t.DeliverySpec)
+}
+
 // NewRetryPool creates a new retry handler pool.
 func NewRetryPool(
 	targets config.ReadonlyTargets,
-	pubsubClient *pubsub.Client,
+	transport transport.Transport,
 	deliverClient *http.Client,
 	statsReporter *metrics.DeliveryReporter,
 	opts ...Option) (*RetryPool, error) {
@@ -88,13 +139,62 @@ func NewRetryPool(
 	p := &RetryPool{
 		targets:       targets,
 		options:       options,
-		pubsubClient:  pubsubClient,
+		transport:     transport,
 		deliverClient: deliverClient,
+		breakers:      deliver.NewBreakers(options.BreakerSettings),
+		compiled:      compiled.NewCache(),
 		statsReporter: statsReporter,
+		restarts:      newRestartBackoff(),
 	}
 	return p, nil
 }
 
+// DebugHandler serves the admin API listing the handlers, dumping the targets
+// config and the status of the circuit breakers.
+func (p *RetryPool) DebugHandler() http.Handler {
+	return adminHandler(p.options.AdminToken, p.targets, p.handlerInfos, p.breakers)
+}
+
+// Drain stops all the handlers, including the replay ones, and waits until
+// their events in flight are done, or the ctx is done.
+func (p *RetryPool) Drain(ctx context.Context) error {
+	return p.running.drain(ctx)
+}
+
+// Health returns the health of the retry handler of each trigger.
+func (p *RetryPool) Health() PoolHealth {
+	handlers := 0
+	var failed []FailedHandler
+	p.pool.Range(func(key, value interface{}) bool {
+		handlers++
+		if hc := value.(*retryHandlerCache); !hc.IsAlive() {
+			failed = append(failed, FailedHandler{Key: key.(string), Status: hc.Status()})
+		}
+		return true
+	})
+	return poolHealth(handlers, failed)
+}
+
+// handlerInfos lists the state of the handler of each trigger.
+func (p *RetryPool) handlerInfos() []handlerInfo {
+	var infos []handlerInfo
+	p.pool.Range(func(key, value interface{}) bool {
+		hc := value.(*retryHandlerCache)
+		info := handlerInfo{
+			Broker:     config.BrokerKey(hc.t.Namespace, hc.t.Broker),
+			Trigger:    hc.t.Name,
+			Generation: atomic.LoadInt64(&hc.generation),
+			Status:     hc.Status(),
+		}
+		if t, ok := p.targets.GetTargetByKey(key.(string)); ok {
+			info.Filters = map[string]json.RawMessage{t.Name: filterDefinition(t)}
+		}
+		infos = append(infos, info)
+		return true
+	})
+	return infos
+}
+
 // SyncOnce syncs once the handler pool based on the targets config.
 func (p *RetryPool) SyncOnce(ctx context.Context) error {
 	ctx, err := p.statsReporter.AddTags(ctx)
@@ -107,18 +207,38 @@ func (p *RetryPool) SyncOnce(ctx context.Context) error {
 		if _, ok := p.targets.GetTargetByKey(key.(string)); !ok {
 			value.(*retryHandlerCache).Stop()
 			p.pool.Delete(key)
+			p.restarts.forget(key.(string))
 		}
 		return true
 	})
+	p.compiled.Sync(p.targets)
 
+	generation := p.targets.Generation()
 	p.targets.RangeAllTargets(func(t *config.Target) bool {
+		var restarts int32
 		if value, ok := p.pool.Load(t.Key()); ok {
+			hc := value.(*retryHandlerCache)
 			// Skip if we don't need to renew the handler.
-			if !value.(*retryHandlerCache).shouldRenew(t) {
+			if !hc.shouldRenew(t) {
+				atomic.StoreInt64(&hc.generation, generation)
+				// The handler is healthy again once it processed an event.
+				if hc.Status().LastAckTime != nil {
+					p.restarts.forget(t.Key())
+				}
 				return true
 			}
+			restarts = hc.restarts
+			if !hc.IsAlive() {
+				// Restart the failed handler after a backoff, unless its
+				// config changed.
+				if !hc.configChanged(t) && !p.restarts.ready(t.Key()) {
+					atomic.StoreInt64(&hc.generation, generation)
+					return true
+				}
+				restarts++
+			}
 			// Stop and clean up the old handler before we start a new one.
-			value.(*retryHandlerCache).Stop()
+			hc.Stop()
 			p.pool.Delete(t.Key())
 		}
 
@@ -128,48 +248,114 @@ func (p *RetryPool) SyncOnce(ctx context.Context) error {
 			return true
 		}
 
-		sub := p.pubsubClient.Subscription(t.RetryQueue.Subscription)
-		sub.ReceiveSettings = p.options.PubsubReceiveSettings
-
-		h := NewHandler(
-			sub,
-			processors.ChainProcessors(
-				&filter.Processor{Targets: p.targets},
-				&deliver.Processor{
-					DeliverClient: p.deliverClient,
-					Targets:       p.targets,
-					StatsReporter: p.statsReporter,
-				},
-			),
-			p.options.TimeoutPerEvent,
-			p.options.RetryPolicy,
-		)
+		h := p.newHandler(t, t.RetryQueue.Subscription)
+		h.restarts = restarts
 		hc := &retryHandlerCache{
-			Handler: *h,
-			t:       t,
-		}
-
-		ctx, err := metrics.AddTargetTags(ctx, t)
-		if err != nil {
-			logging.FromContext(ctx).Error("failed to add target tags to context", zap.Error(err))
+			Handler:    *h,
+			t:          t,
+			generation: generation,
 		}
-
-		// Deliver processor needs the broker in the context for reply.
-		ctx = handlerctx.WithBrokerKey(ctx, config.BrokerKey(t.Namespace, t.Broker))
-		ctx = handlerctx.WithTargetKey(ctx, t.Key())
-		// Start the handler with target in context.
-		hc.Start(ctx, func(err error) {
-			// We will anyway get an error because of https://github.com/cloudevents/sdk-go/issues/470
-			if err != nil {
-				logging.FromContext(ctx).Error("handler for trigger has stopped with error", zap.String("trigger", t.Key()), zap.Error(err))
-			} else {
-				logging.FromContext(ctx).Info("handler for trigger has stopped", zap.String("trigger", t.Key()))
-			}
-		})
+		p.startHandler(ctx, t, &hc.Handler, "retry")
 
 		p.pool.Store(t.Key(), hc)
 		return true
 	})
 
+	p.syncReplays(ctx)
+	if p.options.ReportHealth != nil {
+		p.options.ReportHealth(p.Health())
+	}
+	if p.options.Synced != nil {
+		p.options.Synced(generation)
+	}
 	return nil
 }
+
+// syncReplays starts a handler for each replay in progress, and stops the
+// handlers of the replays which are over. It also checks whether the running
+// replays caught up.
+func (p *RetryPool) syncReplays(ctx context.Context) {
+	p.replays.Range(func(key, value interface{}) bool {
+		hc := value.(*replayHandlerCache)
+		t, _ := p.targets.GetTargetByKey(key.(string))
+		if hc.shouldRenew(t) {
+			hc.Stop()
+			p.replays.Delete(key)
+			return true
+		}
+		hc.replay.CheckCaughtUp(key.(string), p.options.ReplayQuietPeriod)
+		return true
+	})
+
+	p.targets.RangeAllTargets(func(t *config.Target) bool {
+		// Paused and not ready targets don't get the events replayed either.
+		if t.Replay == nil || t.Replay.Queue == nil || t.State != config.State_READY {
+			return true
+		}
+		if _, ok := p.replays.Load(t.Key()); ok {
+			return true
+		}
+		rp := &replay.Processor{Targets: p.targets, OnProgress: p.options.ReplayCaughtUp}
+		h := p.newHandler(t, t.Replay.Queue.Subscription, rp)
+		hc := &replayHandlerCache{Handler: *h, t: t, replay: rp}
+		// The quiet period starts with the handler.
+		rp.CheckCaughtUp(t.Key(), p.options.ReplayQuietPeriod)
+		p.startHandler(ctx, t, &hc.Handler, "replay")
+		p.replays.Store(t.Key(), hc)
+		return true
+	})
+}
+
+// newHandler creates a handler delivering the events of the subscription to the
+// target, after the given processors.
+func (p *RetryPool) newHandler(t *config.Target, subscription string, first ...processors.ChainableProcessor) *Handler {
+	chain := append(first,
+		&filter.Processor{Targets: p.targets, Compiled: p.compiled},
+		&transform.Processor{Targets: p.targets, Compiled: p.compiled},
+		&deliver.Processor{
+			DeliverClient:   p.deliverClient,
+			Targets:         p.targets,
+			StatsReporter:   p.statsReporter,
+			ClaimCheckStore: p.options.ClaimCheckStore,
+			Breakers:        p.breakers,
+			TokenSource:     p.options.TokenSource,
+			Classifier:      p.options.Classifier,
+			LoopDetected:    p.options.LoopDetected,
+			Backoff:         p.options.orderedBackoff,
+		},
+	)
+	h := NewHandler(
+		p.transport.Subscription(subscription),
+		processors.ChainProcessors(chain[0], chain[1:]...),
+		p.options.TimeoutPerEvent,
+		p.retryPolicy(t),
+	)
+	h.DrainTimeout = p.options.DrainTimeout
+	return h
+}
+
+// startHandler starts the handler with the target in the context.
+func (p *RetryPool) startHandler(ctx context.Context, t *config.Target, h *Handler, kind string) {
+	ctx, err := metrics.AddTargetTags(ctx, t)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to add target tags to context", zap.Error(err))
+	}
+
+	// Deliver processor needs the broker in the context for reply.
+	ctx = handlerctx.WithBrokerKey(ctx, config.BrokerKey(t.Namespace, t.Broker))
+	ctx = handlerctx.WithTargetKey(ctx, t.Key())
+	p.running.start(ctx, h, func(err error) {
+		// We will anyway get an error because of https://github.com/cloudevents/sdk-go/issues/470
+		if err != nil {
+			logging.FromContext(ctx).Error(kind+" handler for trigger has stopped with error", zap.String("trigger", t.Key()), zap.Error(err))
+		} else {
+			logging.FromContext(ctx).Info(kind+" handler for trigger has stopped", zap.String("trigger", t.Key()))
+		}
+	})
+}
+
+// retryPolicy returns the retry policy for the given target. The backoff
+// policy and delay in the target's delivery spec override the pool's default.
+func (p *RetryPool) retryPolicy(t *config.Target) RetryPolicy {
+	return p.options.RetryPolicy.forTarget(t)
+}
@@ -24,6 +24,9 @@ import (
 	"github.com/cloudevents/sdk-go/v2/event"
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
@@ -96,6 +99,72 @@ func TestRetryWatchAndSync(t *testing.T) {
 		assertRetryHandlers(t, syncPool, helper.Targets)
 	})
 
+	setState := func(t *testing.T, b *config.Broker, state config.State) {
+		b, _ = helper.Targets.GetBrokerByKey(b.Key())
+		if len(b.Targets) == 0 {
+			t.Fatalf("broker %s has no targets", b.Key())
+		}
+		helper.Targets.MutateBroker(b.Namespace, b.Name, func(bm config.BrokerMutation) {
+			for _, bt := range b.Targets {
+				target := proto.Clone(bt).(*config.Target)
+				target.State = state
+				bm.UpsertTargets(target)
+			}
+		})
+	}
+
+	t.Run("pausing targets stops their handlers", func(t *testing.T) {
+		setState(t, bs[3], config.State_PAUSED)
+		signal <- struct{}{}
+		// Wait a short period for the handlers to be updated.
+		<-time.After(time.Second)
+		assertRetryHandlers(t, syncPool, helper.Targets)
+	})
+
+	t.Run("resuming targets restarts their handlers", func(t *testing.T) {
+		setState(t, bs[3], config.State_READY)
+		signal <- struct{}{}
+		// Wait a short period for the handlers to be updated.
+		<-time.After(time.Second)
+		assertRetryHandlers(t, syncPool, helper.Targets)
+	})
+
+	setReplay := func(t *testing.T, b *config.Broker, replay bool) {
+		b, _ = helper.Targets.GetBrokerByKey(b.Key())
+		if len(b.Targets) == 0 {
+			t.Fatalf("broker %s has no targets", b.Key())
+		}
+		helper.Targets.MutateBroker(b.Namespace, b.Name, func(bm config.BrokerMutation) {
+			for _, bt := range b.Targets {
+				target := proto.Clone(bt).(*config.Target)
+				target.Replay = nil
+				if replay {
+					// Replay from the retry subscription which already exists.
+					target.Replay = &config.Replay{Queue: bt.RetryQueue, Until: timestamppb.Now()}
+				}
+				bm.UpsertTargets(target)
+			}
+		})
+	}
+
+	t.Run("replaying targets starts their replay handlers", func(t *testing.T) {
+		setReplay(t, bs[3], true)
+		signal <- struct{}{}
+		// Wait a short period for the handlers to be updated.
+		<-time.After(time.Second)
+		assertRetryHandlers(t, syncPool, helper.Targets)
+		assertReplayHandlers(t, syncPool, helper.Targets)
+	})
+
+	t.Run("ending replays stops their replay handlers", func(t *testing.T) {
+		setReplay(t, bs[3], false)
+		signal <- struct{}{}
+		// Wait a short period for the handlers to be updated.
+		<-time.After(time.Second)
+		assertRetryHandlers(t, syncPool, helper.Targets)
+		assertReplayHandlers(t, syncPool, helper.Targets)
+	})
+
 	t.Run("delete and adding targets in brokers", func(t *testing.T) {
 		for i := 0; i < 2; i++ {
 			for _, bt := range bs[i].Targets {
@@ -320,6 +389,40 @@ func TestRetrySyncPoolE2E(t *testing.T) {
 	})
 }
 
+func TestRetryPolicy(t *testing.T) {
+	defaultPolicy := RetryPolicy{MinBackoff: time.Second, MaxBackoff: time.Minute}
+	cases := []struct {
+		name         string
+		deliverySpec *config.DeliverySpec
+		want         RetryPolicy
+	}{{
+		name: "no delivery spec",
+		want: defaultPolicy,
+	}, {
+		name:         "no backoff delay",
+		deliverySpec: &config.DeliverySpec{Retry: 3, BackoffPolicy: config.BackoffPolicy_LINEAR},
+		want:         RetryPolicy{MinBackoff: time.Second, MaxBackoff: time.Minute, BackoffPolicy: config.BackoffPolicy_LINEAR},
+	}, {
+		name:         "backoff delay",
+		deliverySpec: &config.DeliverySpec{BackoffDelay: durationpb.New(5 * time.Second)},
+		want:         RetryPolicy{MinBackoff: 5 * time.Second, MaxBackoff: time.Minute},
+	}, {
+		name:         "backoff delay greater than max backoff",
+		deliverySpec: &config.DeliverySpec{BackoffDelay: durationpb.New(5 * time.Minute)},
+		want:         RetryPolicy{MinBackoff: 5 * time.Minute, MaxBackoff: 5 * time.Minute},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &RetryPool{options: &Options{RetryPolicy: defaultPolicy}}
+			got := p.retryPolicy(&config.Target{DeliverySpec: tc.deliverySpec})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("retryPolicy (-want,+got): %v", diff)
+			}
+		})
+	}
+}
+
 func assertRetryHandlers(t *testing.T, p *RetryPool, targets config.Targets) {
 	t.Helper()
 	gotHandlers := make(map[string]bool)
@@ -340,6 +443,36 @@ func assertRetryHandlers(t *testing.T, p *RetryPool, targets config.Targets) {
 	if diff := cmp.Diff(wantHandlers, gotHandlers); diff != "" {
 		t.Errorf("handlers map (-want,+got): %v", diff)
 	}
+
+	gotInfos := make(map[string]bool)
+	for _, info := range p.handlerInfos() {
+		gotInfos[info.Broker+"/"+info.Trigger] = info.Alive
+	}
+	if diff := cmp.Diff(wantHandlers, gotInfos); diff != "" {
+		t.Errorf("listed handlers (-want,+got): %v", diff)
+	}
+}
+
+func assertReplayHandlers(t *testing.T, p *RetryPool, targets config.Targets) {
+	t.Helper()
+	gotHandlers := make(map[string]bool)
+	wantHandlers := make(map[string]bool)
+
+	p.replays.Range(func(key, value interface{}) bool {
+		gotHandlers[key.(string)] = true
+		return true
+	})
+
+	targets.RangeAllTargets(func(t *config.Target) bool {
+		if t.State == config.State_READY && t.Replay != nil {
+			wantHandlers[t.Key()] = true
+		}
+		return true
+	})
+
+	if diff := cmp.Diff(wantHandlers, gotHandlers); diff != "" {
+		t.Errorf("replay handlers map (-want,+got): %v", diff)
+	}
 }
 
 func genTestEvent(subject, t, id, source string) event.Event {
@@ -37,6 +37,7 @@ func InitializeTestFanoutPool(
 ) (*FanoutPool, error) {
 	panic(wire.Build(
 		NewFanoutPool,
+		NewPubsubTransport,
 		NewRetryClient,
 		metrics.NewDeliveryReporter,
 		wire.Value(DefaultHTTPClient),
@@ -53,6 +54,7 @@ func InitializeTestRetryPool(
 ) (*RetryPool, error) {
 	panic(wire.Build(
 		NewRetryPool,
+		NewPubsubTransport,
 		metrics.NewDeliveryReporter,
 		wire.Value(DefaultHTTPClient),
 	))
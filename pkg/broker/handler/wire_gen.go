@@ -15,9 +15,13 @@ import (
 // Injectors from wire.go:
 
 func InitializeTestFanoutPool(ctx context.Context, podName metrics.PodName, containerName metrics.ContainerName, targets config.ReadonlyTargets, pubsubClient *pubsub.Client, opts ...Option) (*FanoutPool, error) {
+	transport, err := NewPubsubTransport(pubsubClient, opts...)
+	if err != nil {
+		return nil, err
+	}
 	client := _wireClientValue
 	v := _wireValue
-	retryClient, err := NewRetryClient(ctx, pubsubClient, v...)
+	retryClient, err := NewRetryClient(ctx, transport, v...)
 	if err != nil {
 		return nil, err
 	}
@@ -25,7 +29,7 @@ func InitializeTestFanoutPool(ctx context.Context, podName metrics.PodName, cont
 	if err != nil {
 		return nil, err
 	}
-	fanoutPool, err := NewFanoutPool(targets, pubsubClient, client, retryClient, deliveryReporter, opts...)
+	fanoutPool, err := NewFanoutPool(targets, transport, client, retryClient, deliveryReporter, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -38,12 +42,16 @@ var (
 )
 
 func InitializeTestRetryPool(targets config.ReadonlyTargets, podName metrics.PodName, containerName metrics.ContainerName, pubsubClient *pubsub.Client, opts ...Option) (*RetryPool, error) {
+	transport, err := NewPubsubTransport(pubsubClient, opts...)
+	if err != nil {
+		return nil, err
+	}
 	client := _wireHttpClientValue
 	deliveryReporter, err := metrics.NewDeliveryReporter(podName, containerName)
 	if err != nil {
 		return nil, err
 	}
-	retryPool, err := NewRetryPool(targets, pubsubClient, client, deliveryReporter, opts...)
+	retryPool, err := NewRetryPool(targets, transport, client, deliveryReporter, opts...)
 	if err != nil {
 		return nil, err
 	}
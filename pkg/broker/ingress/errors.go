@@ -28,3 +28,6 @@ var ErrIncomplete = errors.New("incomplete config")
 
 // ErrNotReady is the error when a broker is not ready.
 var ErrNotReady = errors.New("not ready")
+
+// ErrEventTooLarge is the error when the pubsub message of an event is larger than the limit.
+var ErrEventTooLarge = errors.New("event too large")
@@ -17,21 +17,34 @@ limitations under the License.
 package ingress
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	nethttp "net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"cloud.google.com/go/pubsub"
 	cev2 "github.com/cloudevents/sdk-go/v2"
 	"github.com/cloudevents/sdk-go/v2/binding"
 	"github.com/cloudevents/sdk-go/v2/binding/transformer"
 	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	"github.com/cloudevents/sdk-go/v2/event"
 	"github.com/cloudevents/sdk-go/v2/protocol"
 	"github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/google/knative-gcp/pkg/broker/auth"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/transport"
+	pubsubtransport "github.com/google/knative-gcp/pkg/broker/transport/pubsub"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/tracing"
+	"github.com/google/knative-gcp/pkg/utils"
 	"github.com/google/knative-gcp/pkg/utils/clients"
 	"github.com/google/wire"
 	"go.opencensus.io/trace"
@@ -54,8 +67,13 @@ const (
 
 	// For probes.
 	heathCheckPath = "/healthz"
+	readinessPath  = "/readyz"
 )
 
+// DrainPeriod is how long the ingress fails the readiness probe before it
+// stops accepting requests on shutdown.
+type DrainPeriod time.Duration
+
 // HandlerSet provides a handler with a real HTTPMessageReceiver and pubsub MultiTopicDecoupleSink.
 var HandlerSet wire.ProviderSet = wire.NewSet(
 	NewHandler,
@@ -64,6 +82,8 @@ var HandlerSet wire.ProviderSet = wire.NewSet(
 	NewMultiTopicDecoupleSink,
 	wire.Bind(new(DecoupleSink), new(*multiTopicDecoupleSink)),
 	clients.NewPubsubClient,
+	pubsubtransport.New,
+	wire.Bind(new(transport.Transport), new(*pubsubtransport.Transport)),
 	metrics.NewIngressReporter,
 )
 
@@ -71,6 +91,9 @@ var HandlerSet wire.ProviderSet = wire.NewSet(
 type DecoupleSink interface {
 	// Send sends the event from a broker to the corresponding decoupling sink.
 	Send(ctx context.Context, broker types.NamespacedName, event cev2.Event) protocol.Result
+	// SendBatch sends a batch of events from a broker to the corresponding decoupling sink. It
+	// returns one result per event, in the same order as the events.
+	SendBatch(ctx context.Context, broker types.NamespacedName, events []cev2.Event) []protocol.Result
 }
 
 // HttpMessageReceiver is an interface to listen on http requests.
@@ -84,35 +107,94 @@ type Handler struct {
 	httpReceiver HttpMessageReceiver
 	// decouple is the client to send events to a decouple sink.
 	decouple DecoupleSink
+	// brokerConfig holds configurations for all brokers. The rate limits and auth policies of the
+	// brokers are read from it.
+	brokerConfig config.ReadonlyTargets
+	// authenticator validates the bearer tokens of requests. Requests are not authenticated if it
+	// is nil.
+	authenticator auth.Authenticator
+	// limiter limits the rate of events accepted per broker and per namespace.
+	limiter *rateLimiter
+	// maxRequestBytes is the maximum size of a request body.
+	maxRequestBytes int64
+	// drainPeriod is how long the readiness probe fails on shutdown.
+	drainPeriod time.Duration
+	// draining is set to 1 once the ingress is shutting down.
+	draining int32
 	logger   *zap.Logger
 	reporter *metrics.IngressReporter
 }
 
 // NewHandler creates a new ingress handler.
-func NewHandler(ctx context.Context, httpReceiver HttpMessageReceiver, decouple DecoupleSink, reporter *metrics.IngressReporter) *Handler {
+// A nil authenticator disables authentication.
+func NewHandler(ctx context.Context, httpReceiver HttpMessageReceiver, decouple DecoupleSink, brokerConfig config.ReadonlyTargets, authenticator auth.Authenticator, limits SizeLimits, drainPeriod DrainPeriod, reporter *metrics.IngressReporter) *Handler {
 	return &Handler{
-		httpReceiver: httpReceiver,
-		decouple:     decouple,
-		reporter:     reporter,
-		logger:       logging.FromContext(ctx),
+		httpReceiver:    httpReceiver,
+		decouple:        decouple,
+		brokerConfig:    brokerConfig,
+		authenticator:   authenticator,
+		limiter:         newRateLimiter(brokerConfig),
+		maxRequestBytes: limits.MaxRequestBytes,
+		drainPeriod:     time.Duration(drainPeriod),
+		reporter:        reporter,
+		logger:          logging.FromContext(ctx),
 	}
 }
 
 // Start blocks to receive events over HTTP.
+// Once the ctx is done, the ingress fails the readiness probe for the drain
+// period so that no new request is routed to it, and then stops accepting
+// requests and waits for the requests in flight.
 func (h *Handler) Start(ctx context.Context) error {
-	return h.httpReceiver.StartListen(ctx, h)
+	listenCtx, cancel := context.WithCancel(utils.DetachContext(ctx))
+	drained := make(chan struct{})
+	defer func() {
+		// Stop draining if the ingress stopped listening on its own, and wait for
+		// it so that nothing is logged once Start returned.
+		cancel()
+		<-drained
+	}()
+	go func() {
+		defer close(drained)
+		select {
+		case <-listenCtx.Done():
+			return
+		case <-ctx.Done():
+		}
+		h.logger.Info("Draining the ingress", zap.Duration("drainPeriod", h.drainPeriod))
+		atomic.StoreInt32(&h.draining, 1)
+		timer := time.NewTimer(h.drainPeriod)
+		defer timer.Stop()
+		select {
+		case <-listenCtx.Done():
+		case <-timer.C:
+		}
+		cancel()
+	}()
+	return h.httpReceiver.StartListen(listenCtx, h)
 }
 
 // ServeHTTP implements net/http Handler interface method.
 // 1. Performs basic validation of the request.
 // 2. Parse request URL to get namespace and broker.
-// 3. Convert request to event.
-// 4. Send event to decouple sink.
+// 3. Authenticate the sender if authentication is enabled.
+// 4. Check the rate limits of the broker before the request body is read.
+// 5. Read the request body up to the size limit.
+// 6. Convert request to event, or to a batch of events if the request is in batched mode.
+// 7. Send event(s) to decouple sink.
 func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Request) {
 	if request.URL.Path == heathCheckPath {
 		response.WriteHeader(nethttp.StatusOK)
 		return
 	}
+	if request.URL.Path == readinessPath {
+		if atomic.LoadInt32(&h.draining) == 1 {
+			response.WriteHeader(nethttp.StatusServiceUnavailable)
+			return
+		}
+		response.WriteHeader(nethttp.StatusOK)
+		return
+	}
 
 	ctx := request.Context()
 	h.logger.Debug("Serving http", zap.Any("headers", request.Header))
@@ -134,6 +216,34 @@ func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Re
 		Name:      pieces[2],
 	}
 
+	if statusCode, err := h.authorize(ctx, request, broker); err != nil {
+		if statusCode == nethttp.StatusUnauthorized {
+			response.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		nethttp.Error(response, err.Error(), statusCode)
+		return
+	}
+
+	// A single event takes its token right away. The events of a batch are only counted once
+	// decoded, but the batch is rejected early if the buckets are already exhausted.
+	batch := isBatchRequest(request)
+	if ok, retryAfter := h.allowRequest(ctx, broker, batch); !ok {
+		response.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+		nethttp.Error(response, fmt.Sprintf("Rate limit exceeded for broker %s.", broker), nethttp.StatusTooManyRequests)
+		return
+	}
+
+	if err := h.limitBody(request); err != nil {
+		h.reportTooLarge(ctx, broker, requestSizeLimit)
+		nethttp.Error(response, err.Error(), nethttp.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if batch {
+		h.serveBatch(ctx, response, request, broker)
+		return
+	}
+
 	event, err := h.toEvent(request)
 	if err != nil {
 		nethttp.Error(response, err.Error(), nethttp.StatusBadRequest)
@@ -166,11 +276,10 @@ func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Re
 	if res := h.decouple.Send(ctx, broker, *event); !cev2.IsACK(res) {
 		msg := fmt.Sprintf("Error publishing to PubSub for broker %s. event: %+v, err: %v.", broker, event, res)
 		h.logger.Error(msg)
-		statusCode = nethttp.StatusInternalServerError
-		if errors.Is(res, ErrNotFound) {
-			statusCode = nethttp.StatusNotFound
-		} else if errors.Is(res, ErrNotReady) {
-			statusCode = nethttp.StatusServiceUnavailable
+		statusCode = statusCodeForResult(res)
+		if statusCode == nethttp.StatusRequestEntityTooLarge {
+			h.reportTooLarge(request.Context(), broker, messageSizeLimit)
+			msg = fmt.Sprintf("Event is too large for broker %s: %v.", broker, res)
 		}
 		nethttp.Error(response, msg, statusCode)
 		return
@@ -179,6 +288,232 @@ func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Re
 	response.WriteHeader(statusCode)
 }
 
+// batchResult is the result for a single event of a batched request.
+type batchResult struct {
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// serveBatch handles a request in the batched content mode. Valid events of the batch are sent to
+// the decouple sink together and the response body contains a JSON array of per-event results in
+// the order of the request. The response status code is the status shared by all the events, or
+// 207 Multi-Status if the events have different results.
+func (h *Handler) serveBatch(ctx context.Context, response nethttp.ResponseWriter, request *nethttp.Request, broker types.NamespacedName) {
+	events, err := h.toEvents(request)
+	if err != nil {
+		nethttp.Error(response, err.Error(), nethttp.StatusBadRequest)
+		return
+	}
+
+	results := make([]batchResult, len(events))
+	valid := make([]cev2.Event, 0, len(events))
+	// validIdx maps the index of a valid event to its index in the batch.
+	validIdx := make([]int, 0, len(events))
+	now := time.Now()
+	for i := range events {
+		e := &events[i]
+		results[i].ID = e.ID()
+		if e.Time().IsZero() {
+			e.SetTime(now)
+		}
+		if err := e.Validate(); err != nil {
+			results[i].Status = nethttp.StatusBadRequest
+			results[i].Error = err.Error()
+			continue
+		}
+		e.SetExtension(EventArrivalTime, cev2.Timestamp{Time: now})
+		valid = append(valid, *e)
+		validIdx = append(validIdx, i)
+	}
+
+	ctx, span := trace.StartSpan(ctx, kntracing.BrokerMessagingDestination(broker))
+	defer span.End()
+	if span.IsRecordingEvents() {
+		span.AddAttributes(
+			kntracing.MessagingSystemAttribute,
+			tracing.PubSubProtocolAttribute,
+			kntracing.BrokerMessagingDestinationAttribute(broker),
+			trace.Int64Attribute("messaging.batch_size", int64(len(events))),
+		)
+	}
+
+	if ok, retryAfter := h.allow(request.Context(), broker, len(valid)); !ok {
+		response.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+		for _, i := range validIdx {
+			results[i].Status = nethttp.StatusTooManyRequests
+			results[i].Error = "rate limit exceeded"
+		}
+		valid = nil
+	}
+
+	if len(valid) > 0 {
+		sendCtx, cancel := context.WithTimeout(ctx, decoupleSinkTimeout)
+		defer cancel()
+		for i, res := range h.decouple.SendBatch(sendCtx, broker, valid) {
+			r := &results[validIdx[i]]
+			if cev2.IsACK(res) {
+				r.Status = nethttp.StatusAccepted
+				continue
+			}
+			h.logger.Error("Error publishing to PubSub", zap.Stringer("broker", broker), zap.String("id", r.ID), zap.Error(res))
+			r.Status = statusCodeForResult(res)
+			r.Error = res.Error()
+			if r.Status == nethttp.StatusRequestEntityTooLarge {
+				h.reportTooLarge(request.Context(), broker, messageSizeLimit)
+			}
+		}
+	}
+
+	statusCode := results[0].Status
+	for i := range events {
+		if results[i].Status != statusCode {
+			statusCode = nethttp.StatusMultiStatus
+		}
+		h.reportMetrics(request.Context(), broker, &events[i], results[i].Status)
+	}
+
+	body, err := json.Marshal(results)
+	if err != nil {
+		nethttp.Error(response, err.Error(), nethttp.StatusInternalServerError)
+		return
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(statusCode)
+	if _, err := response.Write(body); err != nil {
+		h.logger.Warn("Failed to write batch response", zap.Error(err))
+	}
+}
+
+// authorize authenticates the sender of the request and checks that the auth policy of the broker
+// allows it. On failure, it returns the status code to respond with.
+func (h *Handler) authorize(ctx context.Context, request *nethttp.Request, broker types.NamespacedName) (int, error) {
+	if h.authenticator == nil {
+		return nethttp.StatusOK, nil
+	}
+	token, err := auth.BearerToken(request)
+	if err != nil {
+		return nethttp.StatusUnauthorized, err
+	}
+	identity, err := h.authenticator.Authenticate(ctx, token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			h.logger.Debug("Invalid token", zap.Stringer("broker", broker), zap.Error(err))
+			return nethttp.StatusUnauthorized, auth.ErrInvalidToken
+		}
+		h.logger.Error("Failed to authenticate request", zap.Stringer("broker", broker), zap.Error(err))
+		return nethttp.StatusInternalServerError, errors.New("failed to authenticate request")
+	}
+	if b, ok := h.brokerConfig.GetBroker(broker.Namespace, broker.Name); ok && !auth.Allowed(b.AuthPolicy, identity) {
+		h.logger.Debug("Sender not allowed", zap.Stringer("broker", broker), zap.String("identity", identity))
+		return nethttp.StatusForbidden, fmt.Errorf("%q is not allowed to send events to broker %s", identity, broker)
+	}
+	return nethttp.StatusOK, nil
+}
+
+// allowRequest checks the rate limits of the broker and its namespace for a request before its body
+// is read. A single event takes its token, while a batch is only checked for an exhausted bucket and
+// counts as one rejected event. If the request is rejected, it returns false and how long the
+// client should wait before retrying.
+func (h *Handler) allowRequest(ctx context.Context, broker types.NamespacedName, batch bool) (bool, time.Duration) {
+	if !batch {
+		return h.allow(ctx, broker, 1)
+	}
+	b, ok := h.brokerConfig.GetBroker(broker.Namespace, broker.Name)
+	if !ok {
+		return true, 0
+	}
+	exhausted, scope, retryAfter := h.limiter.exhausted(b)
+	if !exhausted {
+		return true, 0
+	}
+	h.reportRateLimited(ctx, broker, scope, 1)
+	return false, retryAfter
+}
+
+// allow checks the rate limits of the broker and its namespace for n events. If the events are
+// rejected, it returns false and how long the client should wait before retrying.
+func (h *Handler) allow(ctx context.Context, broker types.NamespacedName, n int) (bool, time.Duration) {
+	if n == 0 {
+		return true, 0
+	}
+	b, ok := h.brokerConfig.GetBroker(broker.Namespace, broker.Name)
+	if !ok {
+		// Let the decouple sink report the missing broker.
+		return true, 0
+	}
+	ok, scope, retryAfter := h.limiter.allow(b, n)
+	if ok {
+		return true, 0
+	}
+	h.reportRateLimited(ctx, broker, scope, n)
+	return false, retryAfter
+}
+
+// reportRateLimited records n events rejected by the rate limit of the given scope.
+func (h *Handler) reportRateLimited(ctx context.Context, broker types.NamespacedName, scope string, n int) {
+	h.logger.Debug("Rate limit exceeded", zap.Stringer("broker", broker), zap.String("scope", scope), zap.Int("count", n))
+	args := metrics.IngressRateLimitReportArgs{
+		Namespace: broker.Namespace,
+		Broker:    broker.Name,
+		Scope:     scope,
+		Count:     n,
+	}
+	if err := h.reporter.ReportRateLimitedEventCount(ctx, args); err != nil {
+		h.logger.Warn("Failed to record metrics.", zap.Any("namespace", broker.Namespace), zap.Any("broker", broker.Name), zap.Error(err))
+	}
+}
+
+// limitBody reads the request body and fails if it's larger than the limit, so that large requests
+// are rejected before they are decoded. On success, the body is replaced with the bytes read.
+func (h *Handler) limitBody(request *nethttp.Request) error {
+	if request.ContentLength > h.maxRequestBytes {
+		return fmt.Errorf("request body of %d bytes exceeds the limit of %d bytes", request.ContentLength, h.maxRequestBytes)
+	}
+	// Read one more byte than the limit to detect larger bodies without a content length.
+	body, err := ioutil.ReadAll(io.LimitReader(request.Body, h.maxRequestBytes+1))
+	request.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	if int64(len(body)) > h.maxRequestBytes {
+		return fmt.Errorf("request body exceeds the limit of %d bytes", h.maxRequestBytes)
+	}
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+// reportTooLarge records requests or events rejected because they exceed the size limit.
+func (h *Handler) reportTooLarge(ctx context.Context, broker types.NamespacedName, limit string) {
+	args := metrics.IngressSizeLimitReportArgs{
+		Namespace: broker.Namespace,
+		Broker:    broker.Name,
+		Limit:     limit,
+	}
+	if err := h.reporter.ReportTooLargeCount(ctx, args); err != nil {
+		h.logger.Warn("Failed to record metrics.", zap.Any("namespace", broker.Namespace), zap.Any("broker", broker.Name), zap.Error(err))
+	}
+}
+
+// isBatchRequest returns true if the request is in the batched content mode.
+func isBatchRequest(request *nethttp.Request) bool {
+	return strings.HasPrefix(request.Header.Get("Content-Type"), event.ApplicationCloudEventsBatchJSON)
+}
+
+// statusCodeForResult returns the status code to respond with for a failed send to the decouple sink.
+func statusCodeForResult(res protocol.Result) int {
+	switch {
+	case errors.Is(res, ErrNotFound):
+		return nethttp.StatusNotFound
+	case errors.Is(res, ErrNotReady):
+		return nethttp.StatusServiceUnavailable
+	case errors.Is(res, ErrEventTooLarge), errors.Is(res, pubsub.ErrOversizedMessage):
+		return nethttp.StatusRequestEntityTooLarge
+	default:
+		return nethttp.StatusInternalServerError
+	}
+}
+
 // toEvent converts an http request to an event.
 func (h *Handler) toEvent(request *nethttp.Request) (*cev2.Event, error) {
 	message := http.NewMessageFromHttpRequest(request)
@@ -202,6 +537,24 @@ func (h *Handler) toEvent(request *nethttp.Request) (*cev2.Event, error) {
 	return event, nil
 }
 
+// toEvents converts a batched http request to events.
+func (h *Handler) toEvents(request *nethttp.Request) ([]cev2.Event, error) {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	var events []cev2.Event
+	if err := json.Unmarshal(body, &events); err != nil {
+		msg := fmt.Sprintf("Failed to convert request to event batch: %v", err)
+		h.logger.Debug(msg)
+		return nil, errors.New(msg)
+	}
+	if len(events) == 0 {
+		return nil, errors.New("event batch is empty")
+	}
+	return events, nil
+}
+
 func (h *Handler) reportMetrics(ctx context.Context, broker types.NamespacedName, event *cev2.Event, statusCode int) {
 	args := metrics.IngressReportArgs{
 		Namespace:    broker.Namespace,
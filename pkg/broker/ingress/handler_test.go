@@ -23,6 +23,7 @@ import (
 	"fmt"
 	nethttp "net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,9 +34,13 @@ import (
 	"github.com/cloudevents/sdk-go/v2/binding"
 	cecontext "github.com/cloudevents/sdk-go/v2/context"
 	"github.com/cloudevents/sdk-go/v2/extensions"
+	"github.com/cloudevents/sdk-go/v2/protocol"
 	"github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/knative-gcp/pkg/broker/auth"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	pubsubtransport "github.com/google/knative-gcp/pkg/broker/transport/pubsub"
 	"github.com/google/knative-gcp/pkg/metrics"
 	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
 	kgcptesting "github.com/google/knative-gcp/pkg/testing"
@@ -43,6 +48,8 @@ import (
 	"go.uber.org/zap/zaptest"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"knative.dev/eventing/pkg/kncloudevents"
 	"knative.dev/pkg/logging"
 	logtest "knative.dev/pkg/logging/testing"
@@ -124,6 +131,12 @@ func TestHandler(t *testing.T) {
 			method:   nethttp.MethodGet,
 			wantCode: nethttp.StatusOK,
 		},
+		{
+			name:     "readiness check",
+			path:     "/readyz",
+			method:   nethttp.MethodGet,
+			wantCode: nethttp.StatusOK,
+		},
 		{
 			name:           "happy case",
 			path:           "/ns1/broker1",
@@ -317,6 +330,425 @@ func TestHandler(t *testing.T) {
 	}
 }
 
+func TestHandlerBatch(t *testing.T) {
+	valid := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"specversion": "1.0",
+			"id":          id,
+			"source":      "test-source",
+			"type":        eventType,
+		}
+	}
+	invalid := map[string]interface{}{
+		"specversion": "1.0",
+		"id":          "invalid",
+		"type":        eventType,
+	}
+	cases := []struct {
+		name        string
+		body        interface{}
+		sinkResults map[string]protocol.Result
+		wantCode    int
+		wantResults []batchResult
+	}{{
+		name:     "all accepted",
+		body:     []interface{}{valid("1"), valid("2")},
+		wantCode: nethttp.StatusAccepted,
+		wantResults: []batchResult{
+			{ID: "1", Status: nethttp.StatusAccepted},
+			{ID: "2", Status: nethttp.StatusAccepted},
+		},
+	}, {
+		name: "all rejected",
+		body: []interface{}{valid("1"), valid("2")},
+		sinkResults: map[string]protocol.Result{
+			"1": ErrNotFound,
+			"2": ErrNotFound,
+		},
+		wantCode: nethttp.StatusNotFound,
+		wantResults: []batchResult{
+			{ID: "1", Status: nethttp.StatusNotFound, Error: ErrNotFound.Error()},
+			{ID: "2", Status: nethttp.StatusNotFound, Error: ErrNotFound.Error()},
+		},
+	}, {
+		name: "partial failure",
+		body: []interface{}{valid("1"), valid("2"), invalid},
+		sinkResults: map[string]protocol.Result{
+			"2": ErrNotReady,
+		},
+		wantCode: nethttp.StatusMultiStatus,
+		wantResults: []batchResult{
+			{ID: "1", Status: nethttp.StatusAccepted},
+			{ID: "2", Status: nethttp.StatusServiceUnavailable, Error: ErrNotReady.Error()},
+			{ID: "invalid", Status: nethttp.StatusBadRequest, Error: "source: REQUIRED\n"},
+		},
+	}, {
+		name:     "malformed batch",
+		body:     map[string]string{"id": "1"},
+		wantCode: nethttp.StatusBadRequest,
+	}, {
+		name:     "empty batch",
+		body:     []interface{}{},
+		wantCode: nethttp.StatusBadRequest,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetIngressMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			sink := &fakeDecoupleSink{results: tc.sinkResults}
+			statsReporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := NewHandler(ctx, nil, sink, memory.NewTargets(brokerConfig), nil, DefaultSizeLimits(), 0, statsReporter)
+
+			body, err := json.Marshal(tc.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("POST", "/ns1/broker1", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", cloudevents.ApplicationCloudEventsBatchJSON)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if got := w.Result().StatusCode; got != tc.wantCode {
+				t.Errorf("StatusCode mismatch, got=%v, want=%v", got, tc.wantCode)
+			}
+			if tc.wantResults == nil {
+				return
+			}
+			var gotResults []batchResult
+			if err := json.Unmarshal(w.Body.Bytes(), &gotResults); err != nil {
+				t.Fatalf("Failed to parse response body %q: %v", w.Body.String(), err)
+			}
+			if diff := cmp.Diff(tc.wantResults, gotResults); diff != "" {
+				t.Errorf("Unexpected results (-want +got): %s", diff)
+			}
+			metricstest.CheckStatsReported(t, "event_count")
+		})
+	}
+}
+
+func TestHandlerRateLimit(t *testing.T) {
+	brokerConfig := &config.TargetsConfig{
+		Brokers: map[string]*config.Broker{
+			"ns1/broker1": {
+				Name:               "broker1",
+				Namespace:          "ns1",
+				DecoupleQueue:      &config.Queue{Topic: topicID},
+				State:              config.State_READY,
+				RateLimit:          &config.RateLimit{EventsPerSecond: 0.1, Burst: 1},
+				NamespaceRateLimit: &config.RateLimit{EventsPerSecond: 0.1, Burst: 3},
+			},
+			"ns1/broker2": {
+				Name:               "broker2",
+				Namespace:          "ns1",
+				DecoupleQueue:      &config.Queue{Topic: topicID},
+				State:              config.State_READY,
+				NamespaceRateLimit: &config.RateLimit{EventsPerSecond: 0.1, Burst: 3},
+			},
+		},
+	}
+	cases := []struct {
+		name string
+		// requests are sent in order, each with the given number of events. A single event is
+		// sent in binary mode, more events are sent in batched mode.
+		requests       []int
+		path           string
+		wantCodes      []int
+		wantRetryAfter string
+		wantScope      string
+		wantRejected   float64
+	}{{
+		name:           "broker limit",
+		path:           "/ns1/broker1",
+		requests:       []int{1, 1},
+		wantCodes:      []int{nethttp.StatusAccepted, nethttp.StatusTooManyRequests},
+		wantRetryAfter: "10",
+		wantScope:      brokerScope,
+		wantRejected:   1,
+	}, {
+		// The batch is rejected before it's read, as a single event.
+		name:         "batch over exhausted broker limit",
+		path:         "/ns1/broker1",
+		requests:     []int{1, 2},
+		wantCodes:    []int{nethttp.StatusAccepted, nethttp.StatusTooManyRequests},
+		wantScope:    brokerScope,
+		wantRejected: 1,
+	}, {
+		name:         "namespace limit",
+		path:         "/ns1/broker2",
+		requests:     []int{1, 3, 2},
+		wantCodes:    []int{nethttp.StatusAccepted, nethttp.StatusTooManyRequests, nethttp.StatusAccepted},
+		wantScope:    namespaceScope,
+		wantRejected: 3,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetIngressMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			statsReporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := NewHandler(ctx, nil, &fakeDecoupleSink{}, memory.NewTargets(brokerConfig), nil, DefaultSizeLimits(), 0, statsReporter)
+
+			for i, n := range tc.requests {
+				req := httptest.NewRequest("POST", tc.path, nil)
+				if n == 1 {
+					http.WriteRequest(ctx, binding.ToMessage(createTestEvent("test-event")), req)
+				} else {
+					events := make([]*cloudevents.Event, n)
+					for j := range events {
+						events[j] = createTestEvent(fmt.Sprintf("test-event-%d", j))
+					}
+					body, err := json.Marshal(events)
+					if err != nil {
+						t.Fatal(err)
+					}
+					req = httptest.NewRequest("POST", tc.path, bytes.NewBuffer(body))
+					req.Header.Set("Content-Type", cloudevents.ApplicationCloudEventsBatchJSON)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+				res := w.Result()
+				if res.StatusCode != tc.wantCodes[i] {
+					t.Errorf("Request %d StatusCode mismatch, got=%v, want=%v", i, res.StatusCode, tc.wantCodes[i])
+				}
+				if res.StatusCode == nethttp.StatusTooManyRequests {
+					if got := res.Header.Get("Retry-After"); got == "" || (tc.wantRetryAfter != "" && got != tc.wantRetryAfter) {
+						t.Errorf("Request %d Retry-After mismatch, got=%q, want=%q", i, got, tc.wantRetryAfter)
+					}
+				}
+			}
+
+			metricstest.CheckSumData(t, "rate_limited_event_count", map[string]string{
+				metricskey.LabelNamespaceName: "ns1",
+				metricskey.LabelBrokerName:    strings.TrimPrefix(tc.path, "/ns1/"),
+				"rate_limit_scope":            tc.wantScope,
+				metricskey.PodName:            pod,
+				metricskey.ContainerName:      container,
+			}, tc.wantRejected)
+		})
+	}
+}
+
+func TestHandlerAuth(t *testing.T) {
+	brokerConfig := &config.TargetsConfig{
+		Brokers: map[string]*config.Broker{
+			"ns1/unlisted": {
+				Name:          "unlisted",
+				Namespace:     "ns1",
+				DecoupleQueue: &config.Queue{Topic: topicID},
+				State:         config.State_READY,
+			},
+			"ns1/restricted": {
+				Name:          "restricted",
+				Namespace:     "ns1",
+				DecoupleQueue: &config.Queue{Topic: topicID},
+				State:         config.State_READY,
+				AuthPolicy: &config.AuthPolicy{
+					AllowedIdentities: []string{"system:serviceaccount:ns1:*"},
+				},
+			},
+		},
+	}
+	authenticator := fakeAuthenticator{
+		"producer-token": "system:serviceaccount:ns1:producer",
+		"other-token":    "system:serviceaccount:ns2:producer",
+	}
+	cases := []struct {
+		name           string
+		path           string
+		authorization  string
+		wantCode       int
+		wantChallenged bool
+	}{{
+		name:           "no token",
+		path:           "/ns1/unlisted",
+		wantCode:       nethttp.StatusUnauthorized,
+		wantChallenged: true,
+	}, {
+		name:           "invalid token",
+		path:           "/ns1/unlisted",
+		authorization:  "Bearer invalid",
+		wantCode:       nethttp.StatusUnauthorized,
+		wantChallenged: true,
+	}, {
+		name:          "no policy",
+		path:          "/ns1/unlisted",
+		authorization: "Bearer producer-token",
+		wantCode:      nethttp.StatusForbidden,
+	}, {
+		name:          "allowed by policy",
+		path:          "/ns1/restricted",
+		authorization: "Bearer producer-token",
+		wantCode:      nethttp.StatusAccepted,
+	}, {
+		name:          "denied by policy",
+		path:          "/ns1/restricted",
+		authorization: "Bearer other-token",
+		wantCode:      nethttp.StatusForbidden,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetIngressMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			statsReporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := NewHandler(ctx, nil, &fakeDecoupleSink{}, memory.NewTargets(brokerConfig), authenticator, DefaultSizeLimits(), 0, statsReporter)
+
+			req := httptest.NewRequest("POST", tc.path, nil)
+			http.WriteRequest(ctx, binding.ToMessage(createTestEvent("test-event")), req)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			res := w.Result()
+			if res.StatusCode != tc.wantCode {
+				t.Errorf("StatusCode mismatch, got=%v, want=%v", res.StatusCode, tc.wantCode)
+			}
+			if got := res.Header.Get("WWW-Authenticate") != ""; got != tc.wantChallenged {
+				t.Errorf("WWW-Authenticate header present got=%v, want=%v", got, tc.wantChallenged)
+			}
+		})
+	}
+}
+
+func TestHandlerSizeLimits(t *testing.T) {
+	largeEvent := createTestEvent("large")
+	largeEvent.SetData(cloudevents.ApplicationJSON, map[string]string{"data": strings.Repeat("x", 1000)})
+	cases := []struct {
+		name        string
+		event       *cloudevents.Event
+		chunked     bool
+		sinkResults map[string]protocol.Result
+		wantCode    int
+		wantLimit   string
+	}{{
+		name:     "within limits",
+		event:    createTestEvent("small"),
+		wantCode: nethttp.StatusAccepted,
+	}, {
+		name:      "request too large",
+		event:     largeEvent,
+		wantCode:  nethttp.StatusRequestEntityTooLarge,
+		wantLimit: requestSizeLimit,
+	}, {
+		name:      "request too large without content length",
+		event:     largeEvent,
+		chunked:   true,
+		wantCode:  nethttp.StatusRequestEntityTooLarge,
+		wantLimit: requestSizeLimit,
+	}, {
+		name:        "message too large",
+		event:       createTestEvent("small"),
+		sinkResults: map[string]protocol.Result{"small": ErrEventTooLarge},
+		wantCode:    nethttp.StatusRequestEntityTooLarge,
+		wantLimit:   messageSizeLimit,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetIngressMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			statsReporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
+			if err != nil {
+				t.Fatal(err)
+			}
+			limits := SizeLimits{MaxRequestBytes: 500, MaxMessageBytes: 500}
+			h := NewHandler(ctx, nil, &fakeDecoupleSink{results: tc.sinkResults}, memory.NewTargets(brokerConfig), nil, limits, 0, statsReporter)
+
+			req := httptest.NewRequest("POST", "/ns1/broker1", nil)
+			http.WriteRequest(ctx, binding.ToMessage(tc.event), req)
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if got := w.Result().StatusCode; got != tc.wantCode {
+				t.Errorf("StatusCode mismatch, got=%v, want=%v", got, tc.wantCode)
+			}
+			if tc.wantLimit == "" {
+				metricstest.CheckStatsNotReported(t, "too_large_count")
+				return
+			}
+			metricstest.CheckCountData(t, "too_large_count", map[string]string{
+				metricskey.LabelNamespaceName: "ns1",
+				metricskey.LabelBrokerName:    "broker1",
+				"size_limit":                  tc.wantLimit,
+				metricskey.PodName:            pod,
+				metricskey.ContainerName:      container,
+			}, 1)
+		})
+	}
+}
+
+func TestHandlerDrain(t *testing.T) {
+	reportertest.ResetIngressMetrics()
+	ctx, cancel := context.WithCancel(logtest.TestContextWithLogger(t))
+	defer cancel()
+	statsReporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := &testHttpMessageReceiver{urlCh: make(chan string)}
+	h := NewHandler(ctx, receiver, &fakeDecoupleSink{}, memory.NewTargets(brokerConfig), nil, DefaultSizeLimits(), DrainPeriod(time.Second), statsReporter)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- h.Start(ctx)
+	}()
+	url := <-receiver.urlCh
+
+	readiness := func() int {
+		resp, err := nethttp.Get(url + "/readyz")
+		if err != nil {
+			t.Fatalf("failed to check readiness: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := readiness(); got != nethttp.StatusOK {
+		t.Errorf("readiness before shutdown got=%v, want=%v", got, nethttp.StatusOK)
+	}
+
+	cancel()
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return readiness() == nethttp.StatusServiceUnavailable, nil
+	}); err != nil {
+		t.Fatalf("readiness didn't fail on shutdown: %v", err)
+	}
+
+	// Requests are still accepted during the drain period.
+	req, err := nethttp.NewRequest(nethttp.MethodPost, url+"/ns1/broker1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	http.WriteRequest(ctx, binding.ToMessage(createTestEvent("drain")), req)
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send event while draining: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != nethttp.StatusAccepted {
+		t.Errorf("StatusCode while draining got=%v, want=%v", resp.StatusCode, nethttp.StatusAccepted)
+	}
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("Start got unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the ingress to stop after the drain period")
+	}
+}
+
 func BenchmarkIngressHandler(b *testing.B) {
 	for _, eventSize := range kgcptesting.BenchmarkEventSizes {
 		b.Run(fmt.Sprintf("%d bytes", eventSize), func(b *testing.B) {
@@ -338,12 +770,12 @@ func runIngressHandlerBenchmark(b *testing.B, eventSize int) {
 	defer psSrv.Close()
 
 	psClient := createPubsubClient(ctx, b, psSrv)
-	decouple := NewMultiTopicDecoupleSink(ctx, memory.NewTargets(brokerConfig), psClient)
+	decouple := NewMultiTopicDecoupleSink(ctx, memory.NewTargets(brokerConfig), pubsubtransport.New(psClient), DefaultSizeLimits(), nil)
 	statsReporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
 	if err != nil {
 		b.Fatal(err)
 	}
-	h := NewHandler(ctx, nil, decouple, statsReporter)
+	h := NewHandler(ctx, nil, decouple, memory.NewTargets(brokerConfig), nil, DefaultSizeLimits(), 0, statsReporter)
 
 	if _, err := psClient.CreateTopic(ctx, topicID); err != nil {
 		b.Fatal(err)
@@ -407,16 +839,18 @@ func setupTestReceiver(ctx context.Context, t testing.TB, psSrv *pstest.Server)
 	return p
 }
 
-// createAndStartIngress creates an ingress and calls its Start() method in a goroutine.
+// createAndStartIngress creates an ingress and calls its Start() method in a goroutine. The test
+// waits for Start to return once the ctx is done.
 func createAndStartIngress(ctx context.Context, t testing.TB, psSrv *pstest.Server) string {
-	decouple := NewMultiTopicDecoupleSink(ctx, memory.NewTargets(brokerConfig), createPubsubClient(ctx, t, psSrv))
+	targets := memory.NewTargets(brokerConfig)
+	decouple := NewMultiTopicDecoupleSink(ctx, targets, pubsubtransport.New(createPubsubClient(ctx, t, psSrv)), DefaultSizeLimits(), nil)
 
 	receiver := &testHttpMessageReceiver{urlCh: make(chan string)}
 	statsReporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
 	if err != nil {
 		t.Fatal(err)
 	}
-	h := NewHandler(ctx, receiver, decouple, statsReporter)
+	h := NewHandler(ctx, receiver, decouple, targets, nil, DefaultSizeLimits(), 0, statsReporter)
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -426,6 +860,11 @@ func createAndStartIngress(ctx context.Context, t testing.TB, psSrv *pstest.Serv
 	case err := <-errCh:
 		t.Fatalf("Failed to start ingress: %v", err)
 	case url := <-h.httpReceiver.(*testHttpMessageReceiver).urlCh:
+		t.Cleanup(func() {
+			if err := <-errCh; err != nil {
+				t.Errorf("Ingress failed: %v", err)
+			}
+		})
 		return url
 	}
 	return ""
@@ -497,6 +936,36 @@ func assertExtensionsExist(extensions ...string) eventAssertion {
 	}
 }
 
+// fakeDecoupleSink implements DecoupleSink. It acknowledges all events except those with an
+// injected result.
+type fakeDecoupleSink struct {
+	// results maps event IDs to the injected result for the event.
+	results map[string]protocol.Result
+}
+
+func (s *fakeDecoupleSink) Send(ctx context.Context, broker types.NamespacedName, event cloudevents.Event) protocol.Result {
+	return s.results[event.ID()]
+}
+
+func (s *fakeDecoupleSink) SendBatch(ctx context.Context, broker types.NamespacedName, events []cloudevents.Event) []protocol.Result {
+	results := make([]protocol.Result, len(events))
+	for i, e := range events {
+		results[i] = s.Send(ctx, broker, e)
+	}
+	return results
+}
+
+// fakeAuthenticator implements auth.Authenticator. It maps valid tokens to their identities.
+type fakeAuthenticator map[string]string
+
+func (a fakeAuthenticator) Authenticate(ctx context.Context, token string) (string, error) {
+	identity, ok := a[token]
+	if !ok {
+		return "", auth.ErrInvalidToken
+	}
+	return identity, nil
+}
+
 // testHttpMessageReceiver implements HttpMessageReceiver. When created, it creates an httptest.Server,
 // which starts a server with any available port.
 type testHttpMessageReceiver struct {
@@ -0,0 +1,43 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import "cloud.google.com/go/pubsub"
+
+const (
+	// requestSizeLimit is the size limit label of requests with a body larger than the limit.
+	requestSizeLimit = "request"
+	// messageSizeLimit is the size limit label of events with a message larger than the limit.
+	messageSizeLimit = "message"
+)
+
+// SizeLimits are the limits on the size of the requests and events accepted by the ingress.
+type SizeLimits struct {
+	// MaxRequestBytes is the maximum size of a request body. Larger requests are rejected before
+	// they are decoded.
+	MaxRequestBytes int64
+	// MaxMessageBytes is the maximum size of the pubsub message of an event.
+	MaxMessageBytes int
+}
+
+// DefaultSizeLimits returns the limits matching the maximum size of a pubsub publish request.
+func DefaultSizeLimits() SizeLimits {
+	return SizeLimits{
+		MaxRequestBytes: pubsub.MaxPublishRequestBytes,
+		MaxMessageBytes: pubsub.MaxPublishRequestBytes,
+	}
+}
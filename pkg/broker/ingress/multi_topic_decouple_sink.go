@@ -19,52 +19,63 @@ package ingress
 import (
 	"context"
 	"fmt"
+	"path"
 	"sync"
 
-	"cloud.google.com/go/pubsub"
 	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/types"
 
-	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
 	cev2 "github.com/cloudevents/sdk-go/v2"
 	"github.com/cloudevents/sdk-go/v2/binding"
 	"github.com/cloudevents/sdk-go/v2/extensions"
 	"github.com/cloudevents/sdk-go/v2/protocol"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	"github.com/google/knative-gcp/pkg/broker/transport"
 	"knative.dev/eventing/pkg/logging"
 )
 
 const projectEnvKey = "PROJECT_ID"
 
-// NewMultiTopicDecoupleSink creates a new multiTopicDecoupleSink.
-func NewMultiTopicDecoupleSink(ctx context.Context, brokerConfig config.ReadonlyTargets, client *pubsub.Client) *multiTopicDecoupleSink {
+// NewMultiTopicDecoupleSink creates a new multiTopicDecoupleSink. The store is used to offload
+// large event payloads of brokers with a claim check threshold, and may be nil.
+func NewMultiTopicDecoupleSink(ctx context.Context, brokerConfig config.ReadonlyTargets, t transport.Transport, limits SizeLimits, store claimcheck.Store) *multiTopicDecoupleSink {
 	return &multiTopicDecoupleSink{
-		logger:       logging.FromContext(ctx),
-		pubsub:       client,
-		brokerConfig: brokerConfig,
+		logger:          logging.FromContext(ctx),
+		transport:       t,
+		brokerConfig:    brokerConfig,
+		maxMessageBytes: limits.MaxMessageBytes,
+		claimCheckStore: store,
 		// TODO(#1118): remove Topic when broker config is removed
-		topics: make(map[types.NamespacedName]*pubsub.Topic),
+		topics: make(map[types.NamespacedName]transport.Topic),
 	}
 }
 
-// multiTopicDecoupleSink implements DecoupleSink and routes events to pubsub topics corresponding
-// to the broker to which the events are sent.
+// multiTopicDecoupleSink implements DecoupleSink and routes events to the decouple topics
+// corresponding to the broker to which the events are sent.
 type multiTopicDecoupleSink struct {
-	// pubsub talks to pubsub.
-	pubsub *pubsub.Client
+	// transport publishes to the decouple topics.
+	transport transport.Transport
 	// map from brokers to topics
-	topics    map[types.NamespacedName]*pubsub.Topic
+	topics    map[types.NamespacedName]transport.Topic
 	topicsMut sync.RWMutex
 	// brokerConfig holds configurations for all brokers. It's a view of a configmap populated by
 	// the broker controller.
 	brokerConfig config.ReadonlyTargets
-	logger       *zap.Logger
+	// maxMessageBytes is the maximum size of a pubsub message.
+	maxMessageBytes int
+	// claimCheckStore stores the offloaded event payloads.
+	claimCheckStore claimcheck.Store
+	// noClaimCheckStore warns once that payloads aren't offloaded without a store.
+	noClaimCheckStore sync.Once
+	logger            *zap.Logger
 }
 
 // Send sends incoming event to its corresponding pubsub topic based on which broker it belongs to.
 func (m *multiTopicDecoupleSink) Send(ctx context.Context, broker types.NamespacedName, event cev2.Event) protocol.Result {
-	topic, err := m.getTopicForBroker(broker)
+	topic, b, err := m.getTopicForBroker(broker)
 	if err != nil {
 		trace.FromContext(ctx).Annotate(
 			[]trace.Attribute{
@@ -76,76 +87,183 @@ func (m *multiTopicDecoupleSink) Send(ctx context.Context, broker types.Namespac
 	}
 
 	dt := extensions.FromSpanContext(trace.FromContext(ctx).SpanContext())
-	msg := new(pubsub.Message)
-	if err := cepubsub.WritePubSubMessage(ctx, binding.ToMessage(&event), msg, dt.WriteTransformer()); err != nil {
+	msg, err := m.toMessage(ctx, broker, b, event, dt)
+	if err != nil {
 		return err
 	}
 
 	_, err = topic.Publish(ctx, msg).Get(ctx)
+	if err != nil {
+		resumePublish(topic, msg)
+	}
 	return err
 }
 
-// getTopicForBroker finds the corresponding decouple topic for the broker from the mounted broker configmap volume.
-func (m *multiTopicDecoupleSink) getTopicForBroker(broker types.NamespacedName) (*pubsub.Topic, error) {
-	topicID, err := m.getTopicIDForBroker(broker)
+// SendBatch sends a batch of incoming events to the pubsub topic of the broker. All events are
+// published before waiting on any of the results, so the pubsub client is free to bundle and send
+// them concurrently. The returned results are in the same order as the events.
+func (m *multiTopicDecoupleSink) SendBatch(ctx context.Context, broker types.NamespacedName, events []cev2.Event) []protocol.Result {
+	results := make([]protocol.Result, len(events))
+	topic, b, err := m.getTopicForBroker(broker)
 	if err != nil {
+		trace.FromContext(ctx).Annotate(
+			[]trace.Attribute{
+				trace.StringAttribute("error_message", err.Error()),
+			},
+			"unable to accept event batch",
+		)
+		for i := range results {
+			results[i] = err
+		}
+		return results
+	}
+
+	dt := extensions.FromSpanContext(trace.FromContext(ctx).SpanContext())
+	msgs := make([]*transport.Message, len(events))
+	published := make([]transport.PublishResult, len(events))
+	for i := range events {
+		msg, err := m.toMessage(ctx, broker, b, events[i], dt)
+		if err != nil {
+			results[i] = err
+			continue
+		}
+		msgs[i] = msg
+		published[i] = topic.Publish(ctx, msg)
+	}
+
+	for i, res := range published {
+		if res == nil {
+			continue
+		}
+		if _, results[i] = res.Get(ctx); results[i] != nil {
+			resumePublish(topic, msgs[i])
+		}
+	}
+	return results
+}
+
+// resumePublish resumes publishing messages with the ordering key of a message that failed to be
+// published. Topics pause publishing for an ordering key after a failure so that later messages
+// are not published out of order, but each event is acknowledged to its sender on its own.
+func resumePublish(topic transport.Topic, msg *transport.Message) {
+	if msg.OrderingKey != "" {
+		topic.ResumePublish(msg.OrderingKey)
+	}
+}
+
+// toMessage converts an event to a message, offloading its payload to the claim check store
+// if the broker asks for it. It fails with ErrEventTooLarge if the message is larger than the
+// limit.
+func (m *multiTopicDecoupleSink) toMessage(ctx context.Context, broker types.NamespacedName, b *config.Broker, event cev2.Event, dt extensions.DistributedTracingExtension) (*transport.Message, error) {
+	if err := m.claimCheck(ctx, broker, b, &event); err != nil {
+		return nil, err
+	}
+	msg := &transport.Message{OrderingKey: eventutil.OrderingKey(&event, b.OrderingKeyExtension)}
+	if err := transport.WriteMessage(ctx, binding.ToMessage(&event), msg, dt.WriteTransformer()); err != nil {
 		return nil, err
 	}
+	if size := msg.Size(); size > m.maxMessageBytes {
+		return nil, fmt.Errorf("%w: message of %d bytes exceeds the limit of %d bytes", ErrEventTooLarge, size, m.maxMessageBytes)
+	}
+	return msg, nil
+}
+
+// claimCheck strips any claim check reference set by the sender, so that senders can't make the
+// broker deliver arbitrary payloads from the store, and offloads the event payload if it's larger
+// than the claim check threshold of the broker.
+func (m *multiTopicDecoupleSink) claimCheck(ctx context.Context, broker types.NamespacedName, b *config.Broker, event *cev2.Event) error {
+	// The event context is shared with the caller, so copy it before changing the extensions.
+	if _, ok := event.Extensions()[claimcheck.Extension]; ok {
+		event.Context = event.Context.Clone()
+		claimcheck.Strip(event)
+	}
+
+	if b.ClaimCheckThreshold <= 0 {
+		return nil
+	}
+	if m.claimCheckStore == nil {
+		m.noClaimCheckStore.Do(func() {
+			m.logger.Warn("No claim check store is configured, event payloads of brokers with a claim check threshold are not offloaded",
+				zap.String("broker", broker.String()))
+		})
+		return nil
+	}
+	event.Context = event.Context.Clone()
+	_, err := claimcheck.Offload(ctx, m.claimCheckStore, event, path.Join(broker.Namespace, broker.Name), b.ClaimCheckThreshold)
+	return err
+}
+
+// getTopicForBroker finds the corresponding decouple topic for the broker from the mounted broker configmap volume.
+func (m *multiTopicDecoupleSink) getTopicForBroker(broker types.NamespacedName) (transport.Topic, *config.Broker, error) {
+	b, err := m.getBrokerConfig(broker)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	if topic, ok := m.getExistingTopic(broker); ok {
-		// Check that the broker's topic ID hasn't changed.
-		if topic.ID() == topicID {
-			return topic, nil
+		// Check that the broker's topic hasn't changed.
+		if topicMatches(topic, b) {
+			return topic, b, nil
 		}
 	}
 
 	// Topic needs to be created or updated.
-	return m.updateTopicForBroker(broker)
+	topic, err := m.updateTopicForBroker(broker)
+	if err != nil {
+		return nil, nil, err
+	}
+	return topic, b, nil
 }
 
-func (m *multiTopicDecoupleSink) updateTopicForBroker(broker types.NamespacedName) (*pubsub.Topic, error) {
+func (m *multiTopicDecoupleSink) updateTopicForBroker(broker types.NamespacedName) (transport.Topic, error) {
 	m.topicsMut.Lock()
 	defer m.topicsMut.Unlock()
-	// Fetch latest decouple topic ID under lock.
-	topicID, err := m.getTopicIDForBroker(broker)
+	// Fetch latest broker config under lock.
+	b, err := m.getBrokerConfig(broker)
 	if err != nil {
 		return nil, err
 	}
 
 	if topic, ok := m.topics[broker]; ok {
-		if topic.ID() == topicID {
+		if topicMatches(topic, b) {
 			// Topic already updated.
 			return topic, nil
 		}
 		// Stop old topic.
 		m.topics[broker].Stop()
 	}
-	topic := m.pubsub.Topic(topicID)
+	topic := m.transport.Topic(b.DecoupleQueue.Topic, b.OrderingKeyExtension != "")
 	m.topics[broker] = topic
 	return topic, nil
 }
 
-func (m *multiTopicDecoupleSink) getTopicIDForBroker(broker types.NamespacedName) (string, error) {
+// topicMatches returns true if the topic publishes to the decouple topic of the broker with the
+// broker's ordering setting.
+func topicMatches(topic transport.Topic, b *config.Broker) bool {
+	return topic.ID() == b.DecoupleQueue.Topic && topic.Ordered() == (b.OrderingKeyExtension != "")
+}
+
+func (m *multiTopicDecoupleSink) getBrokerConfig(broker types.NamespacedName) (*config.Broker, error) {
 	brokerConfig, ok := m.brokerConfig.GetBroker(broker.Namespace, broker.Name)
 	if !ok {
 		// There is an propagation delay between the controller reconciles the broker config and
 		// the config being pushed to the configmap volume in the ingress pod. So sometimes we return
 		// an error even if the request is valid.
 		m.logger.Warn("config is not found for", zap.String("broker", broker.String()))
-		return "", fmt.Errorf("%q: %w", broker, ErrNotFound)
+		return nil, fmt.Errorf("%q: %w", broker, ErrNotFound)
 	}
 	if brokerConfig.State != config.State_READY {
 		m.logger.Debug("broker is not ready", zap.Any("ns", broker.Namespace), zap.Any("broker", broker))
-		return "", fmt.Errorf("%q: %w", broker, ErrNotReady)
+		return nil, fmt.Errorf("%q: %w", broker, ErrNotReady)
 	}
 	if brokerConfig.DecoupleQueue == nil || brokerConfig.DecoupleQueue.Topic == "" {
 		m.logger.Error("DecoupleQueue or topic missing for broker, this should NOT happen.", zap.Any("brokerConfig", brokerConfig))
-		return "", fmt.Errorf("decouple queue of %q: %w", broker, ErrIncomplete)
+		return nil, fmt.Errorf("decouple queue of %q: %w", broker, ErrIncomplete)
 	}
-	return brokerConfig.DecoupleQueue.Topic, nil
+	return brokerConfig, nil
 }
 
-func (m *multiTopicDecoupleSink) getExistingTopic(broker types.NamespacedName) (*pubsub.Topic, bool) {
+func (m *multiTopicDecoupleSink) getExistingTopic(broker types.NamespacedName) (transport.Topic, bool) {
 	m.topicsMut.RLock()
 	defer m.topicsMut.RUnlock()
 	topic, ok := m.topics[broker]
@@ -18,7 +18,9 @@ package ingress
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"cloud.google.com/go/pubsub"
@@ -32,10 +34,13 @@ import (
 	"github.com/cloudevents/sdk-go/v2/client/test"
 	cecontext "github.com/cloudevents/sdk-go/v2/context"
 	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/cloudevents/sdk-go/v2/extensions"
 	"github.com/cloudevents/sdk-go/v2/protocol"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	pubsubtransport "github.com/google/knative-gcp/pkg/broker/transport/pubsub"
 	logtest "knative.dev/pkg/logging/testing"
 )
 
@@ -183,7 +188,7 @@ func TestMultiTopicDecoupleSink(t *testing.T) {
 					t.Fatal(err)
 				}
 
-				sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient)
+				sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, pubsubtransport.New(psClient), DefaultSizeLimits(), nil)
 				// Send events
 				event := createTestEvent(uuid.New().String())
 				err = sink.Send(context.Background(), testCase.broker, *event)
@@ -220,6 +225,209 @@ func TestMultiTopicDecoupleSink(t *testing.T) {
 	}
 }
 
+func TestMultiTopicDecoupleSinkBatch(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	psSrv := pstest.NewServer()
+	defer psSrv.Close()
+	psClient := createPubsubClient(ctx, t, psSrv)
+	brokerConfig := memory.NewTargets(&config.TargetsConfig{
+		Brokers: map[string]*config.Broker{
+			"test_ns_1/test_broker_1": {State: config.State_READY, DecoupleQueue: &config.Queue{Topic: "test_topic_1"}},
+			"test_ns_1/not_ready":     {State: config.State_UNKNOWN, DecoupleQueue: &config.Queue{Topic: "test_topic_1"}},
+		},
+	})
+	if _, err := psClient.CreateTopic(ctx, "test_topic_1"); err != nil {
+		t.Fatal(err)
+	}
+	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, pubsubtransport.New(psClient), DefaultSizeLimits(), nil)
+
+	events := make([]cloudevents.Event, 5)
+	for i := range events {
+		events[i] = *createTestEvent(fmt.Sprintf("event-%d", i))
+	}
+
+	t.Run("ready broker", func(t *testing.T) {
+		results := sink.SendBatch(ctx, types.NamespacedName{Namespace: "test_ns_1", Name: "test_broker_1"}, events)
+		if len(results) != len(events) {
+			t.Fatalf("Unexpected number of results, got=%d, want=%d", len(results), len(events))
+		}
+		for i, res := range results {
+			if !cloudevents.IsACK(res) {
+				t.Errorf("Unexpected result for event %d: %v", i, res)
+			}
+		}
+		if got := len(psSrv.Messages()); got != len(events) {
+			t.Errorf("Unexpected number of published messages, got=%d, want=%d", got, len(events))
+		}
+	})
+
+	t.Run("broker not ready", func(t *testing.T) {
+		results := sink.SendBatch(ctx, types.NamespacedName{Namespace: "test_ns_1", Name: "not_ready"}, events)
+		if len(results) != len(events) {
+			t.Fatalf("Unexpected number of results, got=%d, want=%d", len(results), len(events))
+		}
+		for i, res := range results {
+			if !errors.Is(res, ErrNotReady) {
+				t.Errorf("Unexpected result for event %d, got=%v, want=%v", i, res, ErrNotReady)
+			}
+		}
+	})
+}
+
+func TestMultiTopicDecoupleSinkMessageTooLarge(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	psSrv := pstest.NewServer()
+	defer psSrv.Close()
+	psClient := createPubsubClient(ctx, t, psSrv)
+	brokerConfig := memory.NewTargets(&config.TargetsConfig{
+		Brokers: map[string]*config.Broker{
+			"test_ns_1/test_broker_1": {State: config.State_READY, DecoupleQueue: &config.Queue{Topic: "test_topic_1"}},
+		},
+	})
+	if _, err := psClient.CreateTopic(ctx, "test_topic_1"); err != nil {
+		t.Fatal(err)
+	}
+	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, pubsubtransport.New(psClient), SizeLimits{MaxMessageBytes: 500}, nil)
+	broker := types.NamespacedName{Namespace: "test_ns_1", Name: "test_broker_1"}
+
+	small := createTestEvent("small")
+	large := createTestEvent("large")
+	large.SetData(cloudevents.TextPlain, strings.Repeat("x", 1000))
+
+	if err := sink.Send(ctx, broker, *small); err != nil {
+		t.Errorf("Unexpected error sending small event: %v", err)
+	}
+	if err := sink.Send(ctx, broker, *large); !errors.Is(err, ErrEventTooLarge) {
+		t.Errorf("Unexpected error sending large event, got=%v, want=%v", err, ErrEventTooLarge)
+	}
+	results := sink.SendBatch(ctx, broker, []cloudevents.Event{*small, *large})
+	if results[0] != nil {
+		t.Errorf("Unexpected error sending small event in batch: %v", results[0])
+	}
+	if !errors.Is(results[1], ErrEventTooLarge) {
+		t.Errorf("Unexpected error sending large event in batch, got=%v, want=%v", results[1], ErrEventTooLarge)
+	}
+	if got := len(psSrv.Messages()); got != 2 {
+		t.Errorf("Unexpected number of published messages, got=%d, want=2", got)
+	}
+}
+
+func TestMultiTopicDecoupleSinkClaimCheck(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	psSrv := pstest.NewServer()
+	defer psSrv.Close()
+	psClient := createPubsubClient(ctx, t, psSrv)
+	brokerConfig := memory.NewTargets(&config.TargetsConfig{
+		Brokers: map[string]*config.Broker{
+			"test_ns_1/test_broker_1": {State: config.State_READY, DecoupleQueue: &config.Queue{Topic: "test_topic_1"}, ClaimCheckThreshold: 100},
+		},
+	})
+	if _, err := psClient.CreateTopic(ctx, "test_topic_1"); err != nil {
+		t.Fatal(err)
+	}
+	store, err := claimcheck.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, pubsubtransport.New(psClient), SizeLimits{MaxMessageBytes: 500}, store)
+	broker := types.NamespacedName{Namespace: "test_ns_1", Name: "test_broker_1"}
+
+	data := strings.Repeat("x", 1000)
+	large := createTestEvent("large")
+	large.SetData(cloudevents.TextPlain, data)
+	if err := sink.Send(ctx, broker, *large); err != nil {
+		t.Fatalf("Unexpected error sending large event: %v", err)
+	}
+	if _, ok := claimcheck.Reference(large); ok {
+		t.Error("Sent event was modified")
+	}
+
+	// Senders must not be able to set a reference themselves.
+	spoofed := createTestEvent("spoofed")
+	spoofed.SetExtension(claimcheck.Extension, "file:///etc/passwd")
+	if err := sink.Send(ctx, broker, *spoofed); err != nil {
+		t.Fatalf("Unexpected error sending spoofed event: %v", err)
+	}
+
+	msgs := psSrv.Messages()
+	if len(msgs) != 2 {
+		t.Fatalf("Unexpected number of published messages, got=%d, want=2", len(msgs))
+	}
+	ref, ok := msgs[0].Attributes["ce-"+claimcheck.Extension]
+	if !ok {
+		t.Fatal("Published message of large event has no claim check reference")
+	}
+	if len(msgs[0].Data) != 0 {
+		t.Errorf("Published message of large event has %d bytes of data", len(msgs[0].Data))
+	}
+	got, err := store.Get(ctx, ref)
+	if err != nil {
+		t.Fatalf("Failed to get offloaded payload: %v", err)
+	}
+	if string(got) != data {
+		t.Errorf("Unexpected offloaded payload, got=%q, want=%q", got, data)
+	}
+	if _, ok := msgs[1].Attributes["ce-"+claimcheck.Extension]; ok {
+		t.Error("Published message of spoofed event has a claim check reference")
+	}
+}
+
+func TestMultiTopicDecoupleSinkOrdering(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	psSrv := pstest.NewServer()
+	defer psSrv.Close()
+	psClient := createPubsubClient(ctx, t, psSrv)
+	targets := memory.NewEmptyTargets()
+	targets.MutateBroker("test_ns_1", "test_broker_1", func(m config.BrokerMutation) {
+		m.SetState(config.State_READY)
+		m.SetDecoupleQueue(&config.Queue{Topic: "test_topic_1"})
+		m.SetOrderingKeyExtension("partitionkey")
+	})
+	if _, err := psClient.CreateTopic(ctx, "test_topic_1"); err != nil {
+		t.Fatal(err)
+	}
+	sink := NewMultiTopicDecoupleSink(ctx, targets, pubsubtransport.New(psClient), DefaultSizeLimits(), nil)
+	broker := types.NamespacedName{Namespace: "test_ns_1", Name: "test_broker_1"}
+
+	event := createTestEvent("ordered")
+	event.SetExtension("partitionkey", "user-1")
+	if err := sink.Send(ctx, broker, *event); err != nil {
+		t.Fatalf("Unexpected error sending ordered event: %v", err)
+	}
+	if results := sink.SendBatch(ctx, broker, []cloudevents.Event{*event, *createTestEvent("unordered")}); results[0] != nil || results[1] != nil {
+		t.Fatalf("Unexpected errors sending ordered batch: %v", results)
+	}
+	if got := len(psSrv.Messages()); got != 3 {
+		t.Errorf("Unexpected number of published messages, got=%d, want=3", got)
+	}
+
+	topic, b, err := sink.getTopicForBroker(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !topic.Ordered() {
+		t.Error("Message ordering is not enabled on the decouple topic")
+	}
+	msg, err := sink.toMessage(ctx, broker, b, *event, extensions.DistributedTracingExtension{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.OrderingKey != "user-1" {
+		t.Errorf("Unexpected ordering key, got=%q, want=%q", msg.OrderingKey, "user-1")
+	}
+
+	// Disabling ordering on the broker replaces the topic.
+	targets.MutateBroker("test_ns_1", "test_broker_1", func(m config.BrokerMutation) {
+		m.SetOrderingKeyExtension("")
+	})
+	if topic, _, err = sink.getTopicForBroker(broker); err != nil {
+		t.Fatal(err)
+	}
+	if topic.Ordered() {
+		t.Error("Message ordering is still enabled on the decouple topic")
+	}
+}
+
 type fakePubsubClient struct {
 	t *testing.T
 	// topics is the mapping from topic name to corresponding channel which contains the event.
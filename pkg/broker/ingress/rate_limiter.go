@@ -0,0 +1,176 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+const (
+	// brokerScope is the scope of a rate limit applied to a single broker.
+	brokerScope = "broker"
+	// namespaceScope is the scope of a rate limit shared by all brokers in a namespace.
+	namespaceScope = "namespace"
+
+	// pruneInterval is how often the buckets of the brokers and namespaces no longer limited in
+	// the broker config are removed.
+	pruneInterval = time.Minute
+)
+
+// rateLimiter limits the rate of events accepted by the ingress with token buckets per broker and
+// per namespace. The buckets are created on first use and follow the limits in the broker config.
+// The buckets of the brokers and namespaces no longer limited are pruned on use, at most once per
+// pruneInterval. They are local to the ingress pod, so the limits apply to each replica of the ingress: the
+// events accepted by the brokercell add up to the limit times the number of ingress replicas.
+type rateLimiter struct {
+	targets config.ReadonlyTargets
+
+	mu sync.Mutex
+	// brokers is keyed by the broker key.
+	brokers map[string]*rate.Limiter
+	// namespaces is keyed by the namespace name.
+	namespaces map[string]*rate.Limiter
+	// lastPrune is when the buckets were last pruned.
+	lastPrune time.Time
+}
+
+func newRateLimiter(targets config.ReadonlyTargets) *rateLimiter {
+	return &rateLimiter{
+		targets:    targets,
+		brokers:    make(map[string]*rate.Limiter),
+		namespaces: make(map[string]*rate.Limiter),
+	}
+}
+
+// allow takes n tokens from the buckets of the broker and its namespace. If either bucket doesn't
+// have enough tokens, no token is taken and allow returns false with the scope of the exhausted
+// bucket and how long to wait before retrying.
+func (l *rateLimiter) allow(b *config.Broker, n int) (ok bool, scope string, retryAfter time.Duration) {
+	return l.reserve(b, n, true)
+}
+
+// exhausted returns true if the buckets of the broker or its namespace don't have a single token
+// left, with the scope of the exhausted bucket and how long to wait before retrying. No token is
+// taken.
+func (l *rateLimiter) exhausted(b *config.Broker) (ok bool, scope string, retryAfter time.Duration) {
+	ok, scope, retryAfter = l.reserve(b, 1, false)
+	return !ok, scope, retryAfter
+}
+
+// reserve takes n tokens from the buckets of the broker and its namespace, and gives them back
+// unless keep is true.
+func (l *rateLimiter) reserve(b *config.Broker, n int, keep bool) (ok bool, scope string, retryAfter time.Duration) {
+	now := time.Now()
+	l.mu.Lock()
+	if now.Sub(l.lastPrune) >= pruneInterval {
+		l.prune()
+		l.lastPrune = now
+	}
+	brokerLimiter := updateLimiter(l.brokers, b.Key(), b.RateLimit, now)
+	namespaceLimiter := updateLimiter(l.namespaces, b.Namespace, b.NamespaceRateLimit, now)
+	l.mu.Unlock()
+
+	var reserved []*rate.Reservation
+	for _, c := range []struct {
+		scope   string
+		limiter *rate.Limiter
+	}{{brokerScope, brokerLimiter}, {namespaceScope, namespaceLimiter}} {
+		if c.limiter == nil {
+			continue
+		}
+		r := c.limiter.ReserveN(now, n)
+		if !r.OK() || r.DelayFrom(now) > 0 {
+			retryAfter = r.DelayFrom(now)
+			if !r.OK() {
+				// The request is larger than the bucket and can never be accepted. Suggest the
+				// time it takes to refill the tokens anyway.
+				retryAfter = time.Duration(float64(n) / float64(c.limiter.Limit()) * float64(time.Second))
+			}
+			r.CancelAt(now)
+			for _, r := range reserved {
+				r.CancelAt(now)
+			}
+			return false, c.scope, retryAfter
+		}
+		reserved = append(reserved, r)
+	}
+	if !keep {
+		for _, r := range reserved {
+			r.CancelAt(now)
+		}
+	}
+	return true, "", 0
+}
+
+// prune removes the buckets of the brokers and namespaces which are no longer in the broker config
+// or no longer have a limit. It must be called with the lock held.
+func (l *rateLimiter) prune() {
+	for key := range l.brokers {
+		if b, ok := l.targets.GetBrokerByKey(key); !ok || b.RateLimit == nil {
+			delete(l.brokers, key)
+		}
+	}
+	if len(l.namespaces) == 0 {
+		return
+	}
+	limited := make(map[string]bool)
+	l.targets.RangeBrokers(func(b *config.Broker) bool {
+		if b.NamespaceRateLimit != nil {
+			limited[b.Namespace] = true
+		}
+		return true
+	})
+	for namespace := range l.namespaces {
+		if !limited[namespace] {
+			delete(l.namespaces, namespace)
+		}
+	}
+}
+
+// updateLimiter returns the limiter for key, updated to the given limit. The limiter is created if
+// needed, and removed if the limit is nil.
+func updateLimiter(limiters map[string]*rate.Limiter, key string, limit *config.RateLimit, now time.Time) *rate.Limiter {
+	if limit == nil {
+		delete(limiters, key)
+		return nil
+	}
+	lim, ok := limiters[key]
+	if !ok {
+		lim = rate.NewLimiter(rate.Limit(limit.EventsPerSecond), int(limit.Burst))
+		limiters[key] = lim
+		return lim
+	}
+	if lim.Limit() != rate.Limit(limit.EventsPerSecond) {
+		lim.SetLimitAt(now, rate.Limit(limit.EventsPerSecond))
+	}
+	if lim.Burst() != int(limit.Burst) {
+		lim.SetBurstAt(now, int(limit.Burst))
+	}
+	return lim
+}
+
+// retryAfterSeconds converts a delay to the value of a Retry-After header, rounded up to whole
+// seconds.
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(memory.NewEmptyTargets())
+	b := &config.Broker{
+		Name:      "broker",
+		Namespace: "ns",
+		RateLimit: &config.RateLimit{EventsPerSecond: 1, Burst: 2},
+	}
+
+	// Checking for an exhausted bucket doesn't take any token.
+	for i := 0; i < 3; i++ {
+		if exhausted, _, _ := l.exhausted(b); exhausted {
+			t.Fatal("exhausted() got=true, want=false")
+		}
+	}
+	if ok, _, _ := l.allow(b, 2); !ok {
+		t.Fatal("allow() got=false, want=true")
+	}
+	if exhausted, scope, _ := l.exhausted(b); !exhausted || scope != brokerScope {
+		t.Errorf("exhausted() got=(%v, %v), want=(true, %v)", exhausted, scope, brokerScope)
+	}
+	ok, scope, retryAfter := l.allow(b, 1)
+	if ok {
+		t.Fatal("allow() got=true, want=false")
+	}
+	if scope != brokerScope {
+		t.Errorf("scope got=%v, want=%v", scope, brokerScope)
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("retryAfter got=%v, want in (0, 1s]", retryAfter)
+	}
+
+	// A request larger than the bucket is never accepted.
+	if ok, _, retryAfter := l.allow(b, 3); ok || retryAfter != 3*time.Second {
+		t.Errorf("allow() got=(%v, %v), want=(false, 3s)", ok, retryAfter)
+	}
+
+	// Removing the limit removes the bucket.
+	b.RateLimit = nil
+	if ok, _, _ := l.allow(b, 100); !ok {
+		t.Error("allow() got=false, want=true")
+	}
+	if len(l.brokers) != 0 {
+		t.Errorf("len(brokers) got=%d, want=0", len(l.brokers))
+	}
+
+	// A namespace rejection doesn't consume the tokens of the broker bucket.
+	b.RateLimit = &config.RateLimit{EventsPerSecond: 1, Burst: 1}
+	b.NamespaceRateLimit = &config.RateLimit{EventsPerSecond: 1, Burst: 1}
+	other := &config.Broker{Name: "other", Namespace: "ns", NamespaceRateLimit: b.NamespaceRateLimit}
+	if ok, _, _ := l.allow(other, 1); !ok {
+		t.Fatal("allow() got=false, want=true")
+	}
+	if ok, scope, _ := l.allow(b, 1); ok || scope != namespaceScope {
+		t.Fatalf("allow() got=(%v, %v), want=(false, %v)", ok, scope, namespaceScope)
+	}
+	if !l.brokers[b.Key()].Allow() {
+		t.Error("broker bucket is empty after a namespace rejection")
+	}
+}
+
+func TestRateLimiterPrune(t *testing.T) {
+	limit := &config.RateLimit{EventsPerSecond: 1, Burst: 1}
+	targets := memory.NewEmptyTargets()
+	for _, b := range []struct{ namespace, name string }{{"ns", "broker"}, {"ns", "unlimited"}, {"other", "gone"}} {
+		targets.MutateBroker(b.namespace, b.name, func(m config.BrokerMutation) {
+			m.SetRateLimit(limit).SetNamespaceRateLimit(limit)
+		})
+	}
+	l := newRateLimiter(targets)
+	for _, key := range []string{"ns/broker", "ns/unlimited", "other/gone"} {
+		b, _ := targets.GetBrokerByKey(key)
+		l.allow(b, 1)
+	}
+	if len(l.brokers) != 3 || len(l.namespaces) != 2 {
+		t.Fatalf("buckets got=(%d, %d), want=(3, 2)", len(l.brokers), len(l.namespaces))
+	}
+
+	targets.MutateBroker("ns", "unlimited", func(m config.BrokerMutation) {
+		m.SetRateLimit(nil)
+	})
+	targets.MutateBroker("other", "gone", func(m config.BrokerMutation) {
+		m.Delete()
+	})
+	// The buckets are only pruned once per interval.
+	b, _ := targets.GetBrokerByKey("ns/broker")
+	l.allow(b, 1)
+	if len(l.brokers) != 3 || len(l.namespaces) != 2 {
+		t.Fatalf("buckets got=(%d, %d), want=(3, 2)", len(l.brokers), len(l.namespaces))
+	}
+	l.lastPrune = l.lastPrune.Add(-pruneInterval)
+	l.allow(b, 1)
+	if _, ok := l.brokers["ns/broker"]; !ok || len(l.brokers) != 1 {
+		t.Errorf("broker buckets got=%v, want=[ns/broker]", l.brokers)
+	}
+	if _, ok := l.namespaces["ns"]; !ok || len(l.namespaces) != 1 {
+		t.Errorf("namespace buckets got=%v, want=[ns]", l.namespaces)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	cases := []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 1},
+		{100 * time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+	}
+	for _, tc := range cases {
+		if got := retryAfterSeconds(tc.d); got != tc.want {
+			t.Errorf("retryAfterSeconds(%v) got=%v, want=%v", tc.d, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transport
+
+import (
+	"context"
+
+	"cloud.google.com/go/pubsub"
+	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+// Events are encoded in messages with the Pub/Sub protocol binding of
+// CloudEvents, whatever the transport, so that the format of the queues
+// doesn't depend on the transport.
+
+// ToEvent converts the message to an event.
+func ToEvent(ctx context.Context, msg *Message) (*event.Event, error) {
+	return binding.ToEvent(ctx, cepubsub.NewMessage(&pubsub.Message{Data: msg.Data, Attributes: msg.Attributes}))
+}
+
+// WriteMessage writes the binding message to the message.
+func WriteMessage(ctx context.Context, m binding.Message, msg *Message, transformers ...binding.Transformer) error {
+	pm := &pubsub.Message{}
+	if err := cepubsub.WritePubSubMessage(ctx, m, pm, transformers...); err != nil {
+		return err
+	}
+	msg.Data = pm.Data
+	msg.Attributes = pm.Attributes
+	return nil
+}
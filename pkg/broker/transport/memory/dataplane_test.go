@@ -0,0 +1,136 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memory_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	memtargets "github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/handler"
+	"github.com/google/knative-gcp/pkg/broker/ingress"
+	"github.com/google/knative-gcp/pkg/broker/transport/memory"
+	"github.com/google/knative-gcp/pkg/metrics"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+
+	_ "knative.dev/pkg/metrics/testing"
+)
+
+// TestDataPlane runs the ingress, fanout and retry in-process on the memory
+// transport and checks that an event failing the first delivery reaches the
+// subscriber through the retry queue.
+func TestDataPlane(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	attempts := 0
+	received := make(chan *cev2.Event, 1)
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		first := attempts == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		e, err := binding.ToEvent(r.Context(), cehttp.NewMessageFromHttpRequest(r))
+		if err != nil {
+			t.Errorf("failed to read delivered event: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- e
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer subscriber.Close()
+
+	targets := memtargets.NewTargets(&config.TargetsConfig{})
+	targets.MutateBroker("ns", "broker", func(bm config.BrokerMutation) {
+		bm.SetDecoupleQueue(&config.Queue{Topic: "decouple", Subscription: "decouple-sub"})
+		bm.SetState(config.State_READY)
+		bm.UpsertTargets(&config.Target{
+			Name:       "trigger",
+			Namespace:  "ns",
+			Broker:     "broker",
+			Address:    subscriber.URL,
+			RetryQueue: &config.Queue{Topic: "retry", Subscription: "retry-sub"},
+			State:      config.State_READY,
+		})
+	})
+
+	tr := memory.New()
+	tr.SyncSubscriptions(targets)
+
+	reporter, err := metrics.NewDeliveryReporter("pod", "container")
+	if err != nil {
+		t.Fatalf("failed to create delivery reporter: %v", err)
+	}
+	retryClient, err := handler.NewRetryClient(ctx, tr, handler.DefaultCEClientOpts...)
+	if err != nil {
+		t.Fatalf("failed to create retry client: %v", err)
+	}
+	fanout, err := handler.NewFanoutPool(targets, tr, http.DefaultClient, retryClient, reporter)
+	if err != nil {
+		t.Fatalf("failed to create fanout pool: %v", err)
+	}
+	retry, err := handler.NewRetryPool(targets, tr, http.DefaultClient, reporter)
+	if err != nil {
+		t.Fatalf("failed to create retry pool: %v", err)
+	}
+	if err := fanout.SyncOnce(ctx); err != nil {
+		t.Fatalf("failed to sync fanout pool: %v", err)
+	}
+	if err := retry.SyncOnce(ctx); err != nil {
+		t.Fatalf("failed to sync retry pool: %v", err)
+	}
+
+	sink := ingress.NewMultiTopicDecoupleSink(ctx, targets, tr, ingress.DefaultSizeLimits(), nil)
+	e := cev2.NewEvent()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("type")
+	if res := sink.Send(ctx, types.NamespacedName{Namespace: "ns", Name: "broker"}, e); !cev2.IsACK(res) {
+		t.Fatalf("failed to send event to the decouple topic: %v", res)
+	}
+
+	select {
+	case got := <-received:
+		if got.ID() != e.ID() {
+			t.Errorf("delivered event ID got=%q, want=%q", got.ID(), e.ID())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the event to be retried")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 {
+		t.Errorf("delivery attempts got=%d, want=2", attempts)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package memory implements an in-process transport for local development and
+// tests. The messages are lost when the process exits.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/transport"
+)
+
+// defaultMaxOutstanding is the default number of messages a subscription
+// delivers concurrently.
+const defaultMaxOutstanding = 100
+
+// Transport is the in-memory transport. Like with Pub/Sub, the messages
+// published to a topic are copied to the subscriptions of the topic which
+// exist at that time, and a nacked message is delivered again.
+type Transport struct {
+	// MaxOutstanding is the number of messages a subscription delivers concurrently.
+	MaxOutstanding int
+
+	mu   sync.Mutex
+	subs map[string]*subscription
+	// nextID is the ID of the next message published.
+	nextID int64
+}
+
+var _ transport.Transport = (*Transport)(nil)
+
+// New creates an in-memory transport.
+func New() *Transport {
+	return &Transport{
+		MaxOutstanding: defaultMaxOutstanding,
+		subs:           make(map[string]*subscription),
+	}
+}
+
+// CreateSubscription creates a subscription to the topic if it doesn't exist
+// yet. It receives the messages published from then on.
+func (t *Transport) CreateSubscription(id, topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.subs[id]; !ok {
+		t.subs[id] = newSubscription(topic)
+	}
+}
+
+// DeleteSubscription deletes the subscription and its messages. Receiving from
+// the subscription stops.
+func (t *Transport) DeleteSubscription(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.subs[id]; ok {
+		s.delete()
+		delete(t.subs, id)
+	}
+}
+
+// SyncSubscriptions creates the subscriptions of the queues of the targets
+// config, and deletes the others. The data plane can then run on the targets
+// config without the controller creating the Pub/Sub resources.
+func (t *Transport) SyncSubscriptions(targets config.ReadonlyTargets) {
+	want := make(map[string]string)
+	add := func(q *config.Queue) {
+		if q != nil && q.Topic != "" && q.Subscription != "" {
+			want[q.Subscription] = q.Topic
+		}
+	}
+	targets.RangeBrokers(func(b *config.Broker) bool {
+		add(b.DecoupleQueue)
+		for _, t := range b.Targets {
+			add(t.RetryQueue)
+			if t.Replay != nil {
+				add(t.Replay.Queue)
+			}
+		}
+		return true
+	})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, s := range t.subs {
+		if topic, ok := want[id]; !ok || topic != s.topic {
+			s.delete()
+			delete(t.subs, id)
+		}
+	}
+	for id, topic := range want {
+		if _, ok := t.subs[id]; !ok {
+			t.subs[id] = newSubscription(topic)
+		}
+	}
+}
+
+// Topic implements transport.Transport. Messages with the same ordering key
+// are always delivered in order.
+func (t *Transport) Topic(id string, ordered bool) transport.Topic {
+	return &topic{transport: t, id: id, ordered: ordered}
+}
+
+// Subscription implements transport.Transport.
+func (t *Transport) Subscription(id string) transport.Subscription {
+	return &subscriptionRef{transport: t, id: id}
+}
+
+// publish copies the message to the subscriptions of the topic.
+func (t *Transport) publish(topic string, msg *transport.Message) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	id := strconv.FormatInt(t.nextID, 10)
+	now := time.Now()
+	for _, s := range t.subs {
+		if s.topic != topic {
+			continue
+		}
+		s.push(&transport.Message{
+			ID:          id,
+			Data:        msg.Data,
+			Attributes:  msg.Attributes,
+			OrderingKey: msg.OrderingKey,
+			PublishTime: now,
+		})
+	}
+	return id
+}
+
+func (t *Transport) subscription(id string) (*subscription, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.subs[id]
+	return s, ok
+}
+
+type topic struct {
+	transport *Transport
+	id        string
+	ordered   bool
+}
+
+func (t *topic) ID() string {
+	return t.id
+}
+
+func (t *topic) Ordered() bool {
+	return t.ordered
+}
+
+func (t *topic) Publish(ctx context.Context, msg *transport.Message) transport.PublishResult {
+	if err := ctx.Err(); err != nil {
+		return publishResult{err: err}
+	}
+	return publishResult{id: t.transport.publish(t.id, msg)}
+}
+
+// ResumePublish does nothing, publishing never fails.
+func (t *topic) ResumePublish(string) {}
+
+func (t *topic) Stop() {}
+
+type publishResult struct {
+	id  string
+	err error
+}
+
+func (r publishResult) Get(context.Context) (string, error) {
+	return r.id, r.err
+}
+
+// subscriptionRef refers to a subscription by ID, like a Pub/Sub subscription.
+type subscriptionRef struct {
+	transport *Transport
+	id        string
+}
+
+func (r *subscriptionRef) ID() string {
+	return r.id
+}
+
+func (r *subscriptionRef) Receive(ctx context.Context, f func(context.Context, *transport.Message)) error {
+	s, ok := r.transport.subscription(r.id)
+	if !ok {
+		return fmt.Errorf("subscription %q not found", r.id)
+	}
+	return s.receive(ctx, r.transport.MaxOutstanding, f)
+}
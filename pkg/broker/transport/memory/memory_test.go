@@ -0,0 +1,219 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/transport"
+)
+
+func TestPublishReceive(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tr := New()
+	tr.CreateSubscription("sub1", "topic")
+	tr.CreateSubscription("sub2", "topic")
+	tr.CreateSubscription("other", "other-topic")
+
+	topic := tr.Topic("topic", false)
+	id, err := topic.Publish(ctx, &transport.Message{Data: []byte("foo"), Attributes: map[string]string{"k": "v"}}).Get(ctx)
+	if err != nil {
+		t.Fatalf("Publish got unexpected error: %v", err)
+	}
+
+	for _, sub := range []string{"sub1", "sub2"} {
+		msg := receiveOne(ctx, t, tr.Subscription(sub))
+		if msg.ID != id {
+			t.Errorf("%s: message ID got=%q, want=%q", sub, msg.ID, id)
+		}
+		if string(msg.Data) != "foo" || msg.Attributes["k"] != "v" {
+			t.Errorf("%s: unexpected message %+v", sub, msg)
+		}
+		if msg.PublishTime.IsZero() {
+			t.Errorf("%s: message has no publish time", sub)
+		}
+	}
+	if got := len(tr.subs["other"].pending); got != 0 {
+		t.Errorf("messages of the other topic got=%d, want=0", got)
+	}
+}
+
+func TestNackRedelivers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tr := New()
+	tr.CreateSubscription("sub", "topic")
+	if _, err := tr.Topic("topic", false).Publish(ctx, &transport.Message{Data: []byte("foo")}).Get(ctx); err != nil {
+		t.Fatalf("Publish got unexpected error: %v", err)
+	}
+
+	var attempts []int
+	rctx, rcancel := context.WithCancel(ctx)
+	err := tr.Subscription("sub").Receive(rctx, func(_ context.Context, msg *transport.Message) {
+		attempts = append(attempts, *msg.DeliveryAttempt)
+		if len(attempts) < 3 {
+			msg.Nack()
+			return
+		}
+		msg.Ack()
+		rcancel()
+	})
+	if err != nil {
+		t.Errorf("Receive got unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]int{1, 2, 3}, attempts); diff != "" {
+		t.Errorf("delivery attempts (-want,+got): %v", diff)
+	}
+	if got := len(tr.subs["sub"].pending); got != 0 {
+		t.Errorf("pending messages got=%d, want=0", got)
+	}
+}
+
+func TestOrderingKey(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tr := New()
+	tr.CreateSubscription("sub", "topic")
+	topic := tr.Topic("topic", true)
+	for _, data := range []string{"a1", "b1", "a2", "a3", "b2"} {
+		msg := &transport.Message{Data: []byte(data), OrderingKey: data[:1]}
+		if _, err := topic.Publish(ctx, msg).Get(ctx); err != nil {
+			t.Fatalf("Publish got unexpected error: %v", err)
+		}
+	}
+
+	var mu sync.Mutex
+	got := make(map[string][]string)
+	nacked := false
+	rctx, rcancel := context.WithCancel(ctx)
+	err := tr.Subscription("sub").Receive(rctx, func(_ context.Context, msg *transport.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		// The messages published after a nacked message with the same key wait for its redelivery.
+		if string(msg.Data) == "a2" && !nacked {
+			nacked = true
+			msg.Nack()
+			return
+		}
+		got[msg.OrderingKey] = append(got[msg.OrderingKey], string(msg.Data))
+		msg.Ack()
+		if len(got["a"])+len(got["b"]) == 5 {
+			rcancel()
+		}
+	})
+	if err != nil {
+		t.Errorf("Receive got unexpected error: %v", err)
+	}
+	want := map[string][]string{
+		"a": {"a1", "a2", "a3"},
+		"b": {"b1", "b2"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("received messages (-want,+got): %v", diff)
+	}
+}
+
+func TestReceiveMissingSubscription(t *testing.T) {
+	tr := New()
+	if err := tr.Subscription("missing").Receive(context.Background(), func(context.Context, *transport.Message) {}); err == nil {
+		t.Error("Receive from a missing subscription got nil error")
+	}
+}
+
+func TestDeleteSubscriptionStopsReceive(t *testing.T) {
+	tr := New()
+	tr.CreateSubscription("sub", "topic")
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- tr.Subscription("sub").Receive(context.Background(), func(context.Context, *transport.Message) {})
+	}()
+	tr.DeleteSubscription("sub")
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("Receive from a deleted subscription got nil error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Receive didn't return after the subscription was deleted")
+	}
+}
+
+func TestSyncSubscriptions(t *testing.T) {
+	targets := memory.NewTargets(&config.TargetsConfig{
+		Brokers: map[string]*config.Broker{
+			"ns/broker": {
+				Namespace:     "ns",
+				Name:          "broker",
+				DecoupleQueue: &config.Queue{Topic: "decouple", Subscription: "decouple-sub"},
+				Targets: map[string]*config.Target{
+					"trigger": {
+						Namespace:  "ns",
+						Broker:     "broker",
+						Name:       "trigger",
+						RetryQueue: &config.Queue{Topic: "retry", Subscription: "retry-sub"},
+						Replay: &config.Replay{
+							Queue: &config.Queue{Topic: "decouple", Subscription: "replay-sub"},
+						},
+					},
+				},
+			},
+		},
+	})
+	tr := New()
+	tr.CreateSubscription("stale", "topic")
+	tr.SyncSubscriptions(targets)
+
+	got := make(map[string]string)
+	for id, s := range tr.subs {
+		got[id] = s.topic
+	}
+	want := map[string]string{
+		"decouple-sub": "decouple",
+		"retry-sub":    "retry",
+		"replay-sub":   "decouple",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("subscriptions (-want,+got): %v", diff)
+	}
+}
+
+// receiveOne receives a message from the subscription and acks it.
+func receiveOne(ctx context.Context, t *testing.T, sub transport.Subscription) *transport.Message {
+	t.Helper()
+	ctx, cancel := context.WithCancel(ctx)
+	var got *transport.Message
+	err := sub.Receive(ctx, func(_ context.Context, msg *transport.Message) {
+		msg.Ack()
+		got = msg
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("Receive got unexpected error: %v", err)
+	}
+	if got == nil {
+		t.Fatal("no message received")
+	}
+	return got
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/knative-gcp/pkg/broker/transport"
+)
+
+var errDeleted = errors.New("subscription deleted")
+
+// subscription holds the messages not acked yet. Messages are delivered in
+// order of publication, one at a time for each ordering key.
+type subscription struct {
+	topic string
+
+	mu      sync.Mutex
+	pending []*transport.Message
+	// attempts is the number of deliveries of each pending message.
+	attempts map[*transport.Message]int
+	// keys holds the ordering keys of the messages being delivered.
+	keys map[string]bool
+	// notify is signaled when a message may be ready to deliver.
+	notify chan struct{}
+	// deleted is closed when the subscription is deleted.
+	deleted chan struct{}
+}
+
+func newSubscription(topic string) *subscription {
+	return &subscription{
+		topic:    topic,
+		attempts: make(map[*transport.Message]int),
+		keys:     make(map[string]bool),
+		notify:   make(chan struct{}, 1),
+		deleted:  make(chan struct{}),
+	}
+}
+
+func (s *subscription) push(msg *transport.Message) {
+	s.mu.Lock()
+	s.pending = append(s.pending, msg)
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *subscription) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscription) delete() {
+	close(s.deleted)
+}
+
+// next removes and returns the first message ready to deliver, or nil if
+// there is none. A message is not ready while a message with the same
+// ordering key is being delivered.
+func (s *subscription) next() *transport.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blocked := make(map[string]bool)
+	for i, msg := range s.pending {
+		if key := msg.OrderingKey; key != "" {
+			if s.keys[key] || blocked[key] {
+				blocked[key] = true
+				continue
+			}
+			s.keys[key] = true
+		}
+		s.pending = append(s.pending[:i], s.pending[i+1:]...)
+		return msg
+	}
+	return nil
+}
+
+// done releases the ordering key of the message, and puts it back ahead of
+// the other messages if it was nacked.
+func (s *subscription) done(msg *transport.Message, ack bool) {
+	s.mu.Lock()
+	delete(s.keys, msg.OrderingKey)
+	if ack {
+		delete(s.attempts, msg)
+	} else {
+		s.pending = append([]*transport.Message{msg}, s.pending...)
+	}
+	s.mu.Unlock()
+	s.signal()
+}
+
+// deliver returns a copy of the message to deliver, acked or nacked once.
+func (s *subscription) deliver(msg *transport.Message) *transport.Message {
+	s.mu.Lock()
+	s.attempts[msg]++
+	attempt := s.attempts[msg]
+	s.mu.Unlock()
+
+	received := *msg
+	received.DeliveryAttempt = &attempt
+	var once sync.Once
+	return transport.WithDone(&received, func(ack bool) {
+		once.Do(func() { s.done(msg, ack) })
+	})
+}
+
+// receive delivers the messages to f, at most maxOutstanding at a time, until
+// the context is done or the subscription is deleted. It waits for f to return
+// for the messages being delivered.
+func (s *subscription) receive(ctx context.Context, maxOutstanding int, f func(context.Context, *transport.Message)) error {
+	if maxOutstanding <= 0 {
+		maxOutstanding = 1
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	outstanding := make(chan struct{}, maxOutstanding)
+	for {
+		select {
+		case outstanding <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		case <-s.deleted:
+			return errDeleted
+		}
+		msg := s.next()
+		for msg == nil {
+			select {
+			case <-s.notify:
+				msg = s.next()
+			case <-ctx.Done():
+				return nil
+			case <-s.deleted:
+				return errDeleted
+			}
+		}
+		wg.Add(1)
+		go func(msg *transport.Message) {
+			defer wg.Done()
+			defer func() { <-outstanding }()
+			f(ctx, s.deliver(msg))
+		}(msg)
+	}
+}
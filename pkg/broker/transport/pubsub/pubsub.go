@@ -0,0 +1,122 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pubsub implements the transport with Pub/Sub.
+package pubsub
+
+import (
+	"context"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/google/knative-gcp/pkg/broker/transport"
+)
+
+// Transport is the Pub/Sub transport.
+type Transport struct {
+	client *pubsub.Client
+	// ReceiveSettings are the settings of the subscriptions.
+	ReceiveSettings pubsub.ReceiveSettings
+}
+
+var _ transport.Transport = (*Transport)(nil)
+
+// New creates a Pub/Sub transport with the default receive settings.
+func New(client *pubsub.Client) *Transport {
+	return &Transport{
+		client:          client,
+		ReceiveSettings: pubsub.DefaultReceiveSettings,
+	}
+}
+
+// Topic implements transport.Transport.
+func (t *Transport) Topic(id string, ordered bool) transport.Topic {
+	topic := t.client.Topic(id)
+	topic.EnableMessageOrdering = ordered
+	return &Topic{topic}
+}
+
+// Subscription implements transport.Transport.
+func (t *Transport) Subscription(id string) transport.Subscription {
+	sub := t.client.Subscription(id)
+	sub.ReceiveSettings = t.ReceiveSettings
+	return &Subscription{sub}
+}
+
+// Topic is a Pub/Sub topic.
+type Topic struct {
+	topic *pubsub.Topic
+}
+
+// ID implements transport.Topic.
+func (t *Topic) ID() string {
+	return t.topic.ID()
+}
+
+// Ordered implements transport.Topic.
+func (t *Topic) Ordered() bool {
+	return t.topic.EnableMessageOrdering
+}
+
+// Publish implements transport.Topic.
+func (t *Topic) Publish(ctx context.Context, msg *transport.Message) transport.PublishResult {
+	return t.topic.Publish(ctx, &pubsub.Message{
+		Data:        msg.Data,
+		Attributes:  msg.Attributes,
+		OrderingKey: msg.OrderingKey,
+	})
+}
+
+// ResumePublish implements transport.Topic.
+func (t *Topic) ResumePublish(orderingKey string) {
+	t.topic.ResumePublish(orderingKey)
+}
+
+// Stop implements transport.Topic.
+func (t *Topic) Stop() {
+	t.topic.Stop()
+}
+
+// Subscription is a Pub/Sub subscription.
+type Subscription struct {
+	sub *pubsub.Subscription
+}
+
+// ID implements transport.Subscription.
+func (s *Subscription) ID() string {
+	return s.sub.ID()
+}
+
+// Receive implements transport.Subscription.
+func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *transport.Message)) error {
+	return s.sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		msg := &transport.Message{
+			ID:              m.ID,
+			Data:            m.Data,
+			Attributes:      m.Attributes,
+			OrderingKey:     m.OrderingKey,
+			PublishTime:     m.PublishTime,
+			DeliveryAttempt: m.DeliveryAttempt,
+		}
+		f(ctx, transport.WithDone(msg, func(ack bool) {
+			if ack {
+				m.Ack()
+			} else {
+				m.Nack()
+			}
+		}))
+	})
+}
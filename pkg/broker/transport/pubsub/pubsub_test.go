@@ -0,0 +1,88 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+
+	"github.com/google/knative-gcp/pkg/broker/transport"
+)
+
+func TestPublishReceive(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	srv := pstest.NewServer()
+	defer srv.Close()
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial test pubsub connection: %v", err)
+	}
+	defer conn.Close()
+	c, err := pubsub.NewClient(ctx, "test-project", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("failed to create test pubsub client: %v", err)
+	}
+	topic, err := c.CreateTopic(ctx, "topic")
+	if err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	if _, err := c.CreateSubscription(ctx, "sub", pubsub.SubscriptionConfig{Topic: topic}); err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	tr := New(c)
+	pt := tr.Topic("topic", true)
+	defer pt.Stop()
+	if !pt.Ordered() {
+		t.Error("ordered topic is not ordered")
+	}
+	id, err := pt.Publish(ctx, &transport.Message{
+		Data:        []byte("foo"),
+		Attributes:  map[string]string{"k": "v"},
+		OrderingKey: "key",
+	}).Get(ctx)
+	if err != nil {
+		t.Fatalf("Publish got unexpected error: %v", err)
+	}
+
+	rctx, rcancel := context.WithCancel(ctx)
+	var got *transport.Message
+	err = tr.Subscription("sub").Receive(rctx, func(_ context.Context, msg *transport.Message) {
+		msg.Ack()
+		got = msg
+		rcancel()
+	})
+	if err != nil {
+		t.Fatalf("Receive got unexpected error: %v", err)
+	}
+	if got == nil {
+		t.Fatal("no message received")
+	}
+	if got.ID != id || string(got.Data) != "foo" || got.Attributes["k"] != "v" || got.OrderingKey != "key" || got.PublishTime.IsZero() {
+		t.Errorf("unexpected message %+v", got)
+	}
+	if srv.Messages()[0].Acks != 1 {
+		t.Errorf("message acks got=%d, want=1", srv.Messages()[0].Acks)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+)
+
+// Sender is a CloudEvents protocol sender publishing the events to the topic
+// in the context, see cecontext.WithTopic.
+type Sender struct {
+	transport Transport
+
+	mu     sync.Mutex
+	topics map[string]Topic
+}
+
+var _ protocol.Sender = (*Sender)(nil)
+
+// NewSender creates a Sender publishing with the transport.
+func NewSender(t Transport) *Sender {
+	return &Sender{
+		transport: t,
+		topics:    make(map[string]Topic),
+	}
+}
+
+// Send implements protocol.Sender.
+func (s *Sender) Send(ctx context.Context, m binding.Message, transformers ...binding.Transformer) (err error) {
+	defer func() {
+		if err2 := m.Finish(err); err == nil {
+			err = err2
+		}
+	}()
+	topicID := cecontext.TopicFrom(ctx)
+	if topicID == "" {
+		return errors.New("no topic in the context")
+	}
+	msg := &Message{}
+	if err := WriteMessage(ctx, m, msg, transformers...); err != nil {
+		return err
+	}
+	_, err = s.topic(topicID).Publish(ctx, msg).Get(ctx)
+	return err
+}
+
+func (s *Sender) topic(id string) Topic {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	topic, ok := s.topics[id]
+	if !ok {
+		topic = s.transport.Topic(id, false)
+		s.topics[id] = topic
+	}
+	return topic
+}
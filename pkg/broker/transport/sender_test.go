@@ -0,0 +1,78 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transport_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/transport"
+	"github.com/google/knative-gcp/pkg/broker/transport/memory"
+)
+
+func TestSender(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tr := memory.New()
+	tr.CreateSubscription("sub", "topic")
+	client, err := ceclient.New(transport.NewSender(tr))
+	if err != nil {
+		t.Fatalf("failed to create cloudevents client: %v", err)
+	}
+
+	want := event.New()
+	want.SetID("id")
+	want.SetSource("source")
+	want.SetType("type")
+	want.SetExtension("ext", "value")
+	if err := want.SetData("application/json", map[string]string{"foo": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Events can't be sent without a topic in the context.
+	if res := client.Send(ctx, want); protocol.IsACK(res) {
+		t.Error("Send without a topic got ACK")
+	}
+	if res := client.Send(cecontext.WithTopic(ctx, "topic"), want); !protocol.IsACK(res) {
+		t.Fatalf("Send got unexpected result: %v", res)
+	}
+
+	rctx, rcancel := context.WithCancel(ctx)
+	var got *event.Event
+	var convErr error
+	err = tr.Subscription("sub").Receive(rctx, func(ctx context.Context, msg *transport.Message) {
+		msg.Ack()
+		got, convErr = transport.ToEvent(ctx, msg)
+		rcancel()
+	})
+	if err != nil {
+		t.Fatalf("failed to receive the event: %v", err)
+	}
+	if convErr != nil {
+		t.Fatalf("failed to convert the message to an event: %v", convErr)
+	}
+	if diff := cmp.Diff(&want, got); diff != "" {
+		t.Errorf("received event (-want,+got): %v", diff)
+	}
+}
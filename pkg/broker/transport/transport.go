@@ -0,0 +1,117 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package transport abstracts the queues the broker data plane decouples the
+// events with. A queue of config.Queue is a topic the events are published
+// to, and a subscription they are received from.
+package transport
+
+import (
+	"context"
+	"time"
+)
+
+// Transport opens the topics and the subscriptions of the queues.
+type Transport interface {
+	// Topic returns the topic with the given ID. Messages with the same
+	// ordering key are delivered in order if ordered is true.
+	Topic(id string, ordered bool) Topic
+	// Subscription returns the subscription with the given ID.
+	Subscription(id string) Subscription
+}
+
+// Topic publishes messages to a queue.
+type Topic interface {
+	// ID returns the ID of the topic.
+	ID() string
+	// Ordered returns true if the messages are published in order of their
+	// ordering key.
+	Ordered() bool
+	// Publish publishes the message asynchronously.
+	Publish(ctx context.Context, msg *Message) PublishResult
+	// ResumePublish resumes publishing the messages with the ordering key
+	// after a failure paused it.
+	ResumePublish(orderingKey string)
+	// Stop sends the pending messages and stops the topic.
+	Stop()
+}
+
+// PublishResult is the result of publishing a message.
+type PublishResult interface {
+	// Get blocks until the message is published and returns its ID.
+	Get(ctx context.Context) (string, error)
+}
+
+// Subscription receives the messages of a queue.
+type Subscription interface {
+	// ID returns the ID of the subscription.
+	ID() string
+	// Receive calls f with the messages of the subscription, possibly
+	// concurrently, until the context is done or a non-retryable error
+	// occurs. Each message must be acked or nacked.
+	Receive(ctx context.Context, f func(context.Context, *Message)) error
+}
+
+// Message is a message of a queue.
+type Message struct {
+	// ID is set by the transport when the message is published.
+	ID string
+	// Data is the payload of the message.
+	Data []byte
+	// Attributes are the key-value pairs of the message.
+	Attributes map[string]string
+	// OrderingKey orders the messages of an ordered topic.
+	OrderingKey string
+	// PublishTime is set by the transport when the message is published.
+	PublishTime time.Time
+	// DeliveryAttempt is the number of times the message has been
+	// delivered, if the transport reports it.
+	DeliveryAttempt *int
+
+	// done acks the message if called with true, and nacks it otherwise.
+	done func(ack bool)
+}
+
+// WithDone sets the function called when the received message is acked or
+// nacked, and returns the message.
+func WithDone(msg *Message, done func(ack bool)) *Message {
+	msg.done = done
+	return msg
+}
+
+// Ack acknowledges the message so that it's not delivered again.
+func (m *Message) Ack() {
+	if m.done != nil {
+		m.done(true)
+	}
+}
+
+// Nack asks for the message to be delivered again.
+func (m *Message) Nack() {
+	if m.done != nil {
+		m.done(false)
+	}
+}
+
+// Size returns the size of the message, counting its data, attributes and
+// ordering key.
+func (m *Message) Size() int {
+	size := len(m.Data) + len(m.OrderingKey)
+	for k, v := range m.Attributes {
+		size += len(k) + len(v)
+	}
+	return size
+}
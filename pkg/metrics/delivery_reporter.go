@@ -40,8 +40,23 @@ type DeliveryReporter struct {
 	containerName         ContainerName
 	dispatchTimeInMsecM   *stats.Float64Measure
 	processingTimeInMsecM *stats.Float64Measure
+	breakerStateM         *stats.Int64Measure
+	droppedEventsM        *stats.Int64Measure
+	duplicateEventsM      *stats.Int64Measure
 }
 
+const (
+	// DropReasonNonRetryable is the drop reason of events rejected by the
+	// subscriber with a response that isn't worth retrying.
+	DropReasonNonRetryable = "non_retryable"
+	// DropReasonRetriesExhausted is the drop reason of events that couldn't be
+	// delivered within the retries allowed by the delivery spec.
+	DropReasonRetriesExhausted = "retries_exhausted"
+	// DropReasonLoopDetected is the drop reason of events looping back to a
+	// target whose reply they descend from.
+	DropReasonLoopDetected = "loop_detected"
+)
+
 func (r *DeliveryReporter) register() error {
 	return metrics.RegisterResourceView(
 		&view.View{
@@ -90,6 +105,47 @@ func (r *DeliveryReporter) register() error {
 				ContainerNameKey,
 			},
 		},
+		&view.View{
+			Name:        r.breakerStateM.Name(),
+			Description: r.breakerStateM.Description(),
+			Measure:     r.breakerStateM,
+			Aggregation: view.LastValue(),
+			TagKeys: []tag.Key{
+				NamespaceNameKey,
+				BrokerNameKey,
+				TriggerNameKey,
+				TriggerFilterTypeKey,
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
+		&view.View{
+			Name:        r.droppedEventsM.Name(),
+			Description: r.droppedEventsM.Description(),
+			Measure:     r.droppedEventsM,
+			Aggregation: view.Count(),
+			TagKeys: []tag.Key{
+				NamespaceNameKey,
+				BrokerNameKey,
+				TriggerNameKey,
+				TriggerFilterTypeKey,
+				DropReasonKey,
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
+		&view.View{
+			Name:        r.duplicateEventsM.Name(),
+			Description: r.duplicateEventsM.Description(),
+			Measure:     r.duplicateEventsM,
+			Aggregation: view.Count(),
+			TagKeys: []tag.Key{
+				NamespaceNameKey,
+				BrokerNameKey,
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
 	)
 }
 
@@ -112,6 +168,27 @@ func NewDeliveryReporter(podName PodName, containerName ContainerName) (*Deliver
 			"The time spent processing an event before it is dispatched to a Trigger subscriber",
 			stats.UnitMilliseconds,
 		),
+		// breakerStateM records the state of the circuit breaker of a Trigger
+		// subscriber: 0 is closed, 1 is half-open and 2 is open.
+		breakerStateM: stats.Int64(
+			"circuit_breaker_state",
+			"The state of the circuit breaker of a Trigger subscriber: 0 closed, 1 half-open, 2 open",
+			stats.UnitDimensionless,
+		),
+		// droppedEventsM records the events dropped without being delivered to
+		// a Trigger subscriber nor its dead letter sink.
+		droppedEventsM: stats.Int64(
+			"dropped_event_count",
+			"Number of events dropped without being delivered to a Trigger subscriber",
+			stats.UnitDimensionless,
+		),
+		// duplicateEventsM records the events dropped by a Broker because an
+		// event with the same source and id was processed recently.
+		duplicateEventsM: stats.Int64(
+			"duplicate_event_count",
+			"Number of duplicate events dropped by a Broker",
+			stats.UnitDimensionless,
+		),
 	}
 
 	if err := r.register(); err != nil {
@@ -131,6 +208,21 @@ func (r *DeliveryReporter) ReportEventDispatchTime(ctx context.Context, d time.D
 	)
 }
 
+// ReportCircuitBreakerState captures the state of the circuit breaker of a Trigger subscriber.
+func (r *DeliveryReporter) ReportCircuitBreakerState(ctx context.Context, state int) {
+	metrics.Record(ctx, r.breakerStateM.M(int64(state)))
+}
+
+// ReportDroppedEvent captures an event dropped for the given reason.
+func (r *DeliveryReporter) ReportDroppedEvent(ctx context.Context, reason string) {
+	metrics.Record(ctx, r.droppedEventsM.M(1), stats.WithTags(tag.Insert(DropReasonKey, reason)))
+}
+
+// ReportDuplicateEvent captures a duplicate event dropped by a Broker.
+func (r *DeliveryReporter) ReportDuplicateEvent(ctx context.Context) {
+	metrics.Record(ctx, r.duplicateEventsM.M(1))
+}
+
 // StartEventProcessing records the start of event processing for delivery within the given context.
 func StartEventProcessing(ctx context.Context) context.Context {
 	return context.WithValue(ctx, startDeliveryProcessingTime, time.Now())
@@ -169,6 +261,14 @@ func (r *DeliveryReporter) AddTags(ctx context.Context) (context.Context, error)
 	)
 }
 
+// AddBrokerTags adds the tags of the broker to the context.
+func AddBrokerTags(ctx context.Context, broker *config.Broker) (context.Context, error) {
+	return tag.New(ctx,
+		tag.Insert(NamespaceNameKey, broker.Namespace),
+		tag.Insert(BrokerNameKey, broker.Name),
+	)
+}
+
 func AddTargetTags(ctx context.Context, target *config.Target) (context.Context, error) {
 	return tag.New(ctx,
 		tag.Insert(NamespaceNameKey, target.Namespace),
@@ -161,3 +161,100 @@ func TestMetricsWithEmptySourceAndTypeFilter(t *testing.T) {
 	})
 	metricstest.CheckCountData(t, "event_count", wantTags, 1)
 }
+
+func TestReportCircuitBreakerState(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+
+	wantTags := map[string]string{
+		metricskey.LabelNamespaceName: "testns",
+		metricskey.LabelBrokerName:    "testbroker",
+		metricskey.LabelTriggerName:   "testtrigger",
+		metricskey.LabelFilterType:    "any",
+		metricskey.PodName:            "testpod",
+		metricskey.ContainerName:      "testcontainer",
+	}
+
+	r, err := NewDeliveryReporter("testpod", "testcontainer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := r.AddTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = AddTargetTags(ctx, &config.Target{
+		Namespace: "testns",
+		Broker:    "testbroker",
+		Name:      "testtrigger",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.ReportCircuitBreakerState(ctx, 2)
+	r.ReportCircuitBreakerState(ctx, 1)
+	metricstest.CheckLastValueData(t, "circuit_breaker_state", wantTags, 1)
+}
+
+func TestReportDroppedEvent(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+
+	wantTags := map[string]string{
+		metricskey.LabelNamespaceName: "testns",
+		metricskey.LabelBrokerName:    "testbroker",
+		metricskey.LabelTriggerName:   "testtrigger",
+		metricskey.LabelFilterType:    "any",
+		"drop_reason":                 DropReasonNonRetryable,
+		metricskey.PodName:            "testpod",
+		metricskey.ContainerName:      "testcontainer",
+	}
+
+	r, err := NewDeliveryReporter("testpod", "testcontainer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := r.AddTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = AddTargetTags(ctx, &config.Target{
+		Namespace: "testns",
+		Broker:    "testbroker",
+		Name:      "testtrigger",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.ReportDroppedEvent(ctx, DropReasonNonRetryable)
+	r.ReportDroppedEvent(ctx, DropReasonNonRetryable)
+	metricstest.CheckCountData(t, "dropped_event_count", wantTags, 2)
+}
+
+func TestReportDuplicateEvent(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+
+	wantTags := map[string]string{
+		metricskey.LabelNamespaceName: "testns",
+		metricskey.LabelBrokerName:    "testbroker",
+		metricskey.PodName:            "testpod",
+		metricskey.ContainerName:      "testcontainer",
+	}
+
+	r, err := NewDeliveryReporter("testpod", "testcontainer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := r.AddTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = AddBrokerTags(ctx, &config.Broker{Namespace: "testns", Name: "testbroker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.ReportDuplicateEvent(ctx)
+	r.ReportDuplicateEvent(ctx)
+	metricstest.CheckCountData(t, "duplicate_event_count", wantTags, 2)
+}
@@ -39,6 +39,22 @@ type IngressReportArgs struct {
 	ResponseCode int
 }
 
+type IngressRateLimitReportArgs struct {
+	Namespace string
+	Broker    string
+	// Scope is the scope of the limit that rejected the events, either "broker" or "namespace".
+	Scope string
+	// Count is the number of rejected events.
+	Count int
+}
+
+type IngressSizeLimitReportArgs struct {
+	Namespace string
+	Broker    string
+	// Limit is the size limit that was exceeded, either "request" or "message".
+	Limit string
+}
+
 func (r *IngressReporter) register() error {
 	tagKeys := []tag.Key{
 		NamespaceNameKey,
@@ -59,6 +75,32 @@ func (r *IngressReporter) register() error {
 			Aggregation: view.Count(),
 			TagKeys:     tagKeys,
 		},
+		&view.View{
+			Name:        r.rateLimitedCountM.Name(),
+			Description: r.rateLimitedCountM.Description(),
+			Measure:     r.rateLimitedCountM,
+			Aggregation: view.Sum(),
+			TagKeys: []tag.Key{
+				NamespaceNameKey,
+				BrokerNameKey,
+				RateLimitScopeKey,
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
+		&view.View{
+			Name:        r.tooLargeCountM.Name(),
+			Description: r.tooLargeCountM.Description(),
+			Measure:     r.tooLargeCountM,
+			Aggregation: view.Count(),
+			TagKeys: []tag.Key{
+				NamespaceNameKey,
+				BrokerNameKey,
+				SizeLimitKey,
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
 	)
 }
 
@@ -72,6 +114,16 @@ func NewIngressReporter(podName PodName, containerName ContainerName) (*IngressR
 			"Number of events received by a Broker",
 			stats.UnitDimensionless,
 		),
+		rateLimitedCountM: stats.Int64(
+			"rate_limited_event_count",
+			"Number of events rejected by a Broker because of a rate limit",
+			stats.UnitDimensionless,
+		),
+		tooLargeCountM: stats.Int64(
+			"too_large_count",
+			"Number of requests or events rejected by a Broker because they exceed a size limit",
+			stats.UnitDimensionless,
+		),
 	}
 	if err := r.register(); err != nil {
 		return nil, fmt.Errorf("failed to register ingress stats: %w", err)
@@ -84,6 +136,10 @@ type IngressReporter struct {
 	podName       PodName
 	containerName ContainerName
 	eventCountM   *stats.Int64Measure
+	// rateLimitedCountM counts the events rejected by rate limits.
+	rateLimitedCountM *stats.Int64Measure
+	// tooLargeCountM counts the requests and events rejected by size limits.
+	tooLargeCountM *stats.Int64Measure
 }
 
 func (r *IngressReporter) ReportEventCount(ctx context.Context, args IngressReportArgs) error {
@@ -103,3 +159,37 @@ func (r *IngressReporter) ReportEventCount(ctx context.Context, args IngressRepo
 	metrics.Record(tag, r.eventCountM.M(1))
 	return nil
 }
+
+// ReportRateLimitedEventCount records events rejected because of a rate limit.
+func (r *IngressReporter) ReportRateLimitedEventCount(ctx context.Context, args IngressRateLimitReportArgs) error {
+	tag, err := tag.New(
+		ctx,
+		tag.Insert(PodNameKey, string(r.podName)),
+		tag.Insert(ContainerNameKey, string(r.containerName)),
+		tag.Insert(NamespaceNameKey, args.Namespace),
+		tag.Insert(BrokerNameKey, args.Broker),
+		tag.Insert(RateLimitScopeKey, args.Scope),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics tag: %v", err)
+	}
+	metrics.Record(tag, r.rateLimitedCountM.M(int64(args.Count)))
+	return nil
+}
+
+// ReportTooLargeCount records a request or event rejected because it exceeds a size limit.
+func (r *IngressReporter) ReportTooLargeCount(ctx context.Context, args IngressSizeLimitReportArgs) error {
+	tag, err := tag.New(
+		ctx,
+		tag.Insert(PodNameKey, string(r.podName)),
+		tag.Insert(ContainerNameKey, string(r.containerName)),
+		tag.Insert(NamespaceNameKey, args.Namespace),
+		tag.Insert(BrokerNameKey, args.Broker),
+		tag.Insert(SizeLimitKey, args.Limit),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics tag: %v", err)
+	}
+	metrics.Record(tag, r.tooLargeCountM.M(1))
+	return nil
+}
@@ -94,3 +94,66 @@ func TestStatsReporterWithCustomEventType(t *testing.T) {
 	})
 	metricstest.CheckCountData(t, "event_count", wantTags, 2)
 }
+
+func TestStatsReporterRateLimited(t *testing.T) {
+	reportertest.ResetIngressMetrics()
+
+	args := IngressRateLimitReportArgs{
+		Namespace: "testns",
+		Broker:    "testbroker",
+		Scope:     "namespace",
+		Count:     3,
+	}
+	wantTags := map[string]string{
+		metricskey.LabelNamespaceName: "testns",
+		metricskey.LabelBrokerName:    "testbroker",
+		"rate_limit_scope":            "namespace",
+		metricskey.ContainerName:      "testcontainer",
+		metricskey.PodName:            "testpod",
+	}
+
+	r, err := NewIngressReporter(PodName("testpod"), ContainerName("testcontainer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// test ReportRateLimitedEventCount
+	reportertest.ExpectMetrics(t, func() error {
+		return r.ReportRateLimitedEventCount(context.Background(), args)
+	})
+	reportertest.ExpectMetrics(t, func() error {
+		return r.ReportRateLimitedEventCount(context.Background(), args)
+	})
+	metricstest.CheckSumData(t, "rate_limited_event_count", wantTags, 6)
+}
+
+func TestStatsReporterTooLarge(t *testing.T) {
+	reportertest.ResetIngressMetrics()
+
+	args := IngressSizeLimitReportArgs{
+		Namespace: "testns",
+		Broker:    "testbroker",
+		Limit:     "request",
+	}
+	wantTags := map[string]string{
+		metricskey.LabelNamespaceName: "testns",
+		metricskey.LabelBrokerName:    "testbroker",
+		"size_limit":                  "request",
+		metricskey.ContainerName:      "testcontainer",
+		metricskey.PodName:            "testpod",
+	}
+
+	r, err := NewIngressReporter(PodName("testpod"), ContainerName("testcontainer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// test ReportTooLargeCount
+	reportertest.ExpectMetrics(t, func() error {
+		return r.ReportTooLargeCount(context.Background(), args)
+	})
+	reportertest.ExpectMetrics(t, func() error {
+		return r.ReportTooLargeCount(context.Background(), args)
+	})
+	metricstest.CheckCountData(t, "too_large_count", wantTags, 2)
+}
@@ -44,6 +44,13 @@ var (
 	ResponseCodeKey      = tag.MustNewKey(metricskey.LabelResponseCode)
 	ResponseCodeClassKey = tag.MustNewKey(metricskey.LabelResponseCodeClass)
 
+	// RateLimitScopeKey is the scope of the rate limit that rejected events.
+	RateLimitScopeKey = tag.MustNewKey("rate_limit_scope")
+	// SizeLimitKey is the size limit exceeded by rejected requests or events.
+	SizeLimitKey = tag.MustNewKey("size_limit")
+	// DropReasonKey is the reason why events were dropped instead of delivered.
+	DropReasonKey = tag.MustNewKey("drop_reason")
+
 	PodNameKey       = tag.MustNewKey(metricskey.PodName)
 	ContainerNameKey = tag.MustNewKey(metricskey.ContainerName)
 )
@@ -8,12 +8,12 @@ import (
 
 func ResetIngressMetrics() {
 	// OpenCensus metrics carry global state that need to be reset between unit tests.
-	metricstest.Unregister("event_count", "event_dispatch_latencies")
+	metricstest.Unregister("event_count", "event_dispatch_latencies", "rate_limited_event_count", "too_large_count")
 }
 
 func ResetDeliveryMetrics() {
 	// OpenCensus metrics carry global state that need to be reset between unit tests.
-	metricstest.Unregister("event_count", "event_dispatch_latencies", "event_processing_latencies")
+	metricstest.Unregister("event_count", "event_dispatch_latencies", "event_processing_latencies", "circuit_breaker_state", "dropped_event_count", "duplicate_event_count")
 }
 
 func ExpectMetrics(t *testing.T, f func() error) {
@@ -19,6 +19,7 @@ package adapter
 import (
 	"context"
 	nethttp "net/http"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -32,6 +33,8 @@ import (
 	"github.com/google/knative-gcp/pkg/pubsub/adapter/converters"
 	"github.com/google/knative-gcp/pkg/tracing"
 	"github.com/google/knative-gcp/pkg/utils/clients"
+	"github.com/google/knative-gcp/pkg/utils/delivery"
+	"github.com/google/knative-gcp/pkg/utils/idtoken"
 	"go.opencensus.io/trace"
 	"k8s.io/apimachinery/pkg/types"
 	"knative.dev/eventing/pkg/logging"
@@ -55,8 +58,31 @@ type AdapterArgs struct {
 
 	// ConverterType use to select which converter to use.
 	ConverterType converters.ConverterType
+
+	// SinkAudience is the audience of the ID tokens authenticating the
+	// deliveries to the sink. If empty, the deliveries are not authenticated.
+	SinkAudience string
+
+	// SinkServiceAccount is the Google service account the ID tokens are
+	// minted for. If empty, the tokens are minted for the adapter's own
+	// service account.
+	SinkServiceAccount string
+
+	// Classifier decides which failed deliveries are retried.
+	// If nil, the delivery.DefaultClassifier is used.
+	Classifier *delivery.Classifier
+
+	// DropNonRetryable acks and drops the events the sink rejects with a
+	// non-retryable status code. Otherwise they are nacked like any other
+	// failed delivery, leaving them to the retry and dead letter policies of
+	// the subscription.
+	DropNonRetryable bool
 }
 
+// maxRetryAfter caps how long a nack is delayed for the Retry-After of a
+// subscriber, as the message lease can't be extended forever.
+const maxRetryAfter = 10 * time.Minute
+
 // Adapter implements the Pub/Sub adapter to deliver Pub/Sub messages from a
 // pre-existing topic/subscription to a Sink.
 type Adapter struct {
@@ -84,9 +110,15 @@ type Adapter struct {
 	// args holds a set of arguments used to configure the Adapter.
 	args *AdapterArgs
 
+	// tokens mints the ID tokens authenticating the deliveries to the sink.
+	tokens idtoken.Source
+
 	// cancel is function to stop pulling messages.
 	cancel context.CancelFunc
 
+	// delayNack defaults to time.Sleep; could be overridden in test.
+	delayNack func(time.Duration)
+
 	logger *zap.Logger
 }
 
@@ -102,7 +134,7 @@ func NewAdapter(
 	converter converters.Converter,
 	reporter StatsReporter,
 	args *AdapterArgs) *Adapter {
-	return &Adapter{
+	a := &Adapter{
 		subscription:   subscription,
 		projectID:      string(projectID),
 		namespacedName: types.NamespacedName{Namespace: string(namespace), Name: string(name)},
@@ -111,8 +143,13 @@ func NewAdapter(
 		converter:      converter,
 		reporter:       reporter,
 		args:           args,
+		delayNack:      time.Sleep,
 		logger:         logging.FromContext(ctx),
 	}
+	if args.SinkAudience != "" {
+		a.tokens = idtoken.NewSource(ctx)
+	}
+	return a
 }
 
 func (a *Adapter) Start(ctx context.Context) error {
@@ -174,8 +211,7 @@ func (a *Adapter) receive(ctx context.Context, msg *pubsub.Message) {
 		a.reporter.ReportEventCount(args, resp.StatusCode)
 
 		if resp.StatusCode/100 != 2 {
-			a.logger.Error("Event delivery failed", zap.Int("StatusCode", resp.StatusCode))
-			msg.Nack()
+			a.settleFailedDelivery(msg, args, resp)
 			return
 		}
 
@@ -212,7 +248,7 @@ func (a *Adapter) receive(ctx context.Context, msg *pubsub.Message) {
 		}
 	}
 
-	response, err := a.sendMsg(ctx, a.args.SinkURI, (*binding.EventMessage)(event))
+	response, err := a.sendToSink(ctx, (*binding.EventMessage)(event))
 	if err != nil {
 		a.logger.Error("Failed to send message to sink", zap.String("address", a.args.SinkURI), zap.Error(err))
 		msg.Nack()
@@ -228,15 +264,62 @@ func (a *Adapter) receive(ctx context.Context, msg *pubsub.Message) {
 	a.reporter.ReportEventCount(args, response.StatusCode)
 
 	if response.StatusCode/100 != 2 {
-		a.logger.Error("Event delivery failed", zap.Int("StatusCode", response.StatusCode))
-		msg.Nack()
+		a.settleFailedDelivery(msg, args, response)
 		return
 	}
 
 	msg.Ack()
 }
 
+// settleFailedDelivery settles a message whose delivery failed with resp.
+// The message is acked and dropped if retrying won't help and dropping was
+// opted in, otherwise it is nacked once the Retry-After requested by the
+// subscriber has passed.
+func (a *Adapter) settleFailedDelivery(msg *pubsub.Message, args *ReportArgs, resp *nethttp.Response) {
+	derr := delivery.NewError(a.args.Classifier, resp)
+	if derr.Outcome == delivery.NonRetryable && a.args.DropNonRetryable {
+		a.logger.Error("Event delivery failed with a non-retryable status code, dropping event", zap.Int("StatusCode", resp.StatusCode))
+		if err := a.reporter.ReportEventDropped(args, resp.StatusCode); err != nil {
+			a.logger.Warn("Failed to report dropped event", zap.Error(err))
+		}
+		msg.Ack()
+		return
+	}
+	a.logger.Error("Event delivery failed", zap.Int("StatusCode", resp.StatusCode), zap.Duration("RetryAfter", derr.Delay))
+	if derr.Delay > 0 {
+		delay := derr.Delay
+		if delay > maxRetryAfter {
+			delay = maxRetryAfter
+		}
+		a.delayNack(delay)
+	}
+	msg.Nack()
+}
+
 func (a *Adapter) sendMsg(ctx context.Context, address string, msg binding.Message) (*nethttp.Response, error) {
+	req, err := newRequest(ctx, address, msg)
+	if err != nil {
+		return nil, err
+	}
+	return a.outbound.Do(req)
+}
+
+// sendToSink sends the message to the sink, authenticated with an ID token if
+// the sink requires it.
+func (a *Adapter) sendToSink(ctx context.Context, msg binding.Message) (*nethttp.Response, error) {
+	req, err := newRequest(ctx, a.args.SinkURI, msg)
+	if err != nil {
+		return nil, err
+	}
+	if a.args.SinkAudience != "" {
+		if err := idtoken.SetAuthorization(ctx, a.tokens, req, a.args.SinkAudience, a.args.SinkServiceAccount); err != nil {
+			return nil, err
+		}
+	}
+	return a.outbound.Do(req)
+}
+
+func newRequest(ctx context.Context, address string, msg binding.Message) (*nethttp.Request, error) {
 	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodPost, address, nil)
 	if err != nil {
 		return nil, err
@@ -244,7 +327,7 @@ func (a *Adapter) sendMsg(ctx context.Context, address string, msg binding.Messa
 	if err := cehttp.WriteRequest(ctx, msg, req); err != nil {
 		return nil, err
 	}
-	return a.outbound.Do(req)
+	return req, nil
 }
 
 func (a *Adapter) startSpan(ctx context.Context, event *cev2.Event) (context.Context, *trace.Span) {
@@ -23,6 +23,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -78,14 +79,31 @@ type metricLabels struct {
 }
 
 type statsReporterRecorder struct {
-	labels []metricLabels
+	mu      sync.Mutex
+	labels  []metricLabels
+	dropped []metricLabels
 }
 
 func (r *statsReporterRecorder) ReportEventCount(args *ReportArgs, responseCode int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.labels = append(r.labels, metricLabels{CeType: args.EventType, CeSource: args.EventSource, StatusCode: responseCode})
 	return nil
 }
 
+func (r *statsReporterRecorder) ReportEventDropped(args *ReportArgs, responseCode int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dropped = append(r.dropped, metricLabels{CeType: args.EventType, CeSource: args.EventSource, StatusCode: responseCode})
+	return nil
+}
+
+func (r *statsReporterRecorder) droppedCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.dropped)
+}
+
 type mockConverter struct {
 	converted *cev2.Event
 }
@@ -287,6 +305,132 @@ func TestAdapter(t *testing.T) {
 	}
 }
 
+func TestAdapterDeliveryFailure(t *testing.T) {
+	cases := []struct {
+		name             string
+		status           int
+		retryAfter       string
+		dropNonRetryable bool
+		wantRetried      bool
+		wantNackDelay    time.Duration
+		wantDropped      int
+	}{{
+		name:        "non-retryable",
+		status:      http.StatusBadRequest,
+		wantRetried: true,
+	}, {
+		name:             "non-retryable dropped",
+		status:           http.StatusBadRequest,
+		dropNonRetryable: true,
+		wantDropped:      1,
+	}, {
+		name:        "retryable",
+		status:      http.StatusInternalServerError,
+		wantRetried: true,
+	}, {
+		name:          "retry after",
+		status:        http.StatusTooManyRequests,
+		retryAfter:    "30",
+		wantRetried:   true,
+		wantNackDelay: 30 * time.Second,
+	}, {
+		name:          "retry after longer than max",
+		status:        http.StatusServiceUnavailable,
+		retryAfter:    "3600",
+		wantRetried:   true,
+		wantNackDelay: maxRetryAfter,
+	}}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := logtest.TestContextWithLogger(t)
+
+			hits := make(chan struct{}, 10)
+			sinkSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				hits <- struct{}{}
+				if tc.retryAfter != "" {
+					w.Header().Set("Retry-After", tc.retryAfter)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer sinkSvr.Close()
+
+			c, close := testPubsubClient(ctx, t, testProjectID)
+			defer close()
+
+			topic, err := c.CreateTopic(ctx, testTopic)
+			if err != nil {
+				t.Fatalf("failed to create topic: %v", err)
+			}
+			sub, err := c.CreateSubscription(ctx, testSub, pubsub.SubscriptionConfig{
+				Topic: topic,
+			})
+			if err != nil {
+				t.Fatalf("failed to create subscription: %v", err)
+			}
+
+			p, err := cepubsub.New(context.Background(),
+				cepubsub.WithClient(c),
+				cepubsub.WithProjectID(testProjectID),
+				cepubsub.WithTopicID(testTopic),
+			)
+			if err != nil {
+				t.Fatalf("failed to create cloudevents pubsub protocol: %v", err)
+			}
+
+			sampleEvent := newSampleEvent()
+			reporter := &statsReporterRecorder{}
+			adapter := NewAdapter(ctx,
+				clients.ProjectID(testProjectID),
+				Namespace(testNamespace),
+				Name(testName),
+				ResourceGroup(testResourceGroup),
+				sub,
+				http.DefaultClient,
+				&mockConverter{converted: sampleEvent},
+				reporter,
+				&AdapterArgs{
+					TopicID:          testTopic,
+					SinkURI:          sinkSvr.URL,
+					ConverterType:    converters.ConverterType(testConverterType),
+					DropNonRetryable: tc.dropNonRetryable,
+				})
+			nackDelays := make(chan time.Duration, 10)
+			adapter.delayNack = func(d time.Duration) {
+				nackDelays <- d
+			}
+
+			go adapter.Start(ctx)
+			defer adapter.Stop()
+
+			if err := p.Send(ctx, binding.ToMessage(sampleEvent)); err != nil {
+				t.Fatalf("failed to seed event to pubsub: %v", err)
+			}
+
+			<-hits
+			select {
+			case <-hits:
+				if !tc.wantRetried {
+					t.Error("non-retryable delivery was retried")
+				}
+			case <-time.After(2 * time.Second):
+				if tc.wantRetried {
+					t.Error("retryable delivery was not retried")
+				}
+			}
+			if tc.wantNackDelay > 0 {
+				if got := <-nackDelays; got != tc.wantNackDelay {
+					t.Errorf("nack delay got=%v, want=%v", got, tc.wantNackDelay)
+				}
+			}
+			if got := reporter.droppedCount(); got != tc.wantDropped {
+				t.Errorf("dropped events got=%d, want=%d", got, tc.wantDropped)
+			}
+		})
+	}
+}
+
 func newSampleEvent() *event.Event {
 	sampleEvent := event.New()
 	sampleEvent.SetID("id")
@@ -296,3 +440,41 @@ func newSampleEvent() *event.Event {
 	sampleEvent.SetTime(time.Now())
 	return &sampleEvent
 }
+
+type fakeTokenSource struct{}
+
+func (fakeTokenSource) Token(_ context.Context, audience, serviceAccount string) (string, error) {
+	return audience + "/" + serviceAccount, nil
+}
+
+func TestSendToSinkAuthenticated(t *testing.T) {
+	authCh := make(chan string, 1)
+	sinkSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authCh <- req.Header.Get("Authorization")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer sinkSvr.Close()
+
+	a := &Adapter{
+		outbound: http.DefaultClient,
+		args: &AdapterArgs{
+			SinkURI:            sinkSvr.URL,
+			SinkAudience:       "https://sink",
+			SinkServiceAccount: "invoker@my-project.iam.gserviceaccount.com",
+		},
+		tokens: fakeTokenSource{},
+	}
+	e := cev2.NewEvent()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("type")
+	resp, err := a.sendToSink(context.Background(), binding.ToMessage(&e))
+	if err != nil {
+		t.Fatalf("sendToSink got unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	want := "Bearer https://sink/invoker@my-project.iam.gserviceaccount.com"
+	if got := <-authCh; got != want {
+		t.Errorf("Authorization header got=%q, want=%q", got, want)
+	}
+}
@@ -37,6 +37,14 @@ var (
 		stats.UnitDimensionless,
 	)
 
+	// eventDropCountM is a counter which records the number of events
+	// dropped after the sink rejected them with a non-retryable status code.
+	eventDropCountM = stats.Int64(
+		"event_drop_count",
+		"Number of events dropped after a non-retryable response of the sink",
+		stats.UnitDimensionless,
+	)
+
 	// Create the tag keys that will be used to add tags to our measurements.
 	// Tag keys must conform to the restrictions described in
 	// go.opencensus.io/tag/validate.go. Currently those restrictions are:
@@ -60,6 +68,9 @@ type ReportArgs struct {
 type StatsReporter interface {
 	// ReportEventCount captures the event count. It records one per call.
 	ReportEventCount(args *ReportArgs, responseCode int) error
+	// ReportEventDropped captures an event dropped after the sink responded
+	// with responseCode. It records one per call.
+	ReportEventDropped(args *ReportArgs, responseCode int) error
 }
 
 var _ StatsReporter = (*reporter)(nil)
@@ -94,6 +105,15 @@ func (r *reporter) ReportEventCount(args *ReportArgs, responseCode int) error {
 	return nil
 }
 
+func (r *reporter) ReportEventDropped(args *ReportArgs, responseCode int) error {
+	ctx, err := r.generateTag(args, responseCode)
+	if err != nil {
+		return err
+	}
+	metrics.Record(ctx, eventDropCountM.M(1))
+	return nil
+}
+
 func (r *reporter) generateTag(args *ReportArgs, responseCode int) (context.Context, error) {
 	return tag.New(
 		emptyContext,
@@ -124,5 +144,11 @@ func (r *reporter) register() error {
 			Aggregation: view.Count(),
 			TagKeys:     tagKeys,
 		},
+		&view.View{
+			Description: eventDropCountM.Description(),
+			Measure:     eventDropCountM,
+			Aggregation: view.Count(),
+			TagKeys:     tagKeys,
+		},
 	)
 }
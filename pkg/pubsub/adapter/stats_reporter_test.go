@@ -55,6 +55,14 @@ func TestStatsReporter(t *testing.T) {
 		return r.ReportEventCount(args, http.StatusAccepted)
 	})
 	metricstest.CheckCountData(t, "event_count", wantTags, 2)
+
+	// test ReportEventDropped
+	wantTags[metricskey.LabelResponseCode] = "400"
+	wantTags[metricskey.LabelResponseCodeClass] = "4xx"
+	expectSuccess(t, func() error {
+		return r.ReportEventDropped(args, http.StatusBadRequest)
+	})
+	metricstest.CheckCountData(t, "event_drop_count", wantTags, 1)
 }
 
 func expectSuccess(t *testing.T, f func() error) {
@@ -28,13 +28,16 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"knative.dev/eventing/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/broker/config"
 	brokerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/broker"
 	inteventslisters "github.com/google/knative-gcp/pkg/client/listers/intevents/v1alpha1"
 	metadataClient "github.com/google/knative-gcp/pkg/gclient/metadata"
 	"github.com/google/knative-gcp/pkg/reconciler"
 	"github.com/google/knative-gcp/pkg/reconciler/broker/resources"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/configserver"
 	reconcilerutilspubsub "github.com/google/knative-gcp/pkg/reconciler/utils/pubsub"
 	"github.com/google/knative-gcp/pkg/utils"
 )
@@ -56,6 +59,9 @@ type Reconciler struct {
 
 	// pubsubClient is used as the Pubsub client when present.
 	pubsubClient *pubsub.Client
+
+	// configServer tracks the targets config applied by the data plane, nil if disabled.
+	configServer *configserver.Server
 }
 
 // Check that Reconciler implements Interface
@@ -102,9 +108,27 @@ func (r *Reconciler) reconcileBroker(ctx context.Context, b *brokerv1beta1.Broke
 		return fmt.Errorf("decoupling topic reconcile failed: %v", err)
 	}
 
+	r.reconcileDataPlane(b)
+
 	return nil
 }
 
+// reconcileDataPlane updates the broker status based on whether the data plane pods
+// applied the latest targets config of the broker.
+func (r *Reconciler) reconcileDataPlane(b *brokerv1beta1.Broker) {
+	if r.configServer == nil {
+		// Without the targets config server there is no way to tell, keep the old behavior.
+		b.Status.MarkDataPlaneReady()
+		return
+	}
+	// TODO(#866) Get brokercell based on the label (or annotation) on the broker.
+	if err := r.configServer.BrokerApplied(system.Namespace(), resources.DefaultBrokerCellName, config.BrokerKey(b.Namespace, b.Name)); err != nil {
+		b.Status.MarkDataPlaneUnknown("TargetsConfigNotApplied", "%v", err)
+		return
+	}
+	b.Status.MarkDataPlaneReady()
+}
+
 func (r *Reconciler) reconcileDecouplingTopicAndSubscription(ctx context.Context, b *brokerv1beta1.Broker) error {
 	logger := logging.FromContext(ctx)
 	logger.Debug("Reconciling decoupling topic", zap.Any("broker", b))
@@ -155,14 +179,20 @@ func (r *Reconciler) reconcileDecouplingTopicAndSubscription(ctx context.Context
 
 	// Check if PullSub exists, and if not, create it.
 	subID := resources.GenerateDecouplingSubscriptionName(b)
+	_, ordered := b.Annotations[brokerv1beta1.OrderingKeyExtensionAnnotation]
 	subConfig := pubsub.SubscriptionConfig{
-		Topic:  topic,
-		Labels: labels,
+		Topic:                 topic,
+		Labels:                reconcilerutilspubsub.OrderingLabels(labels, ordered),
+		EnableMessageOrdering: ordered,
 		//TODO(grantr): configure these settings?
 		// AckDeadline
 		// RetentionDuration
 	}
-	if _, err := pubsubReconciler.ReconcileSubscription(ctx, subID, subConfig, b, &b.Status); err != nil {
+	sub, err := pubsubReconciler.ReconcileSubscription(ctx, subID, subConfig, b, &b.Status)
+	if err != nil {
+		return err
+	}
+	if err := reconcilerutilspubsub.ReconcileOrdering(ctx, sub, ordered, &b.Status); err != nil {
 		return err
 	}
 
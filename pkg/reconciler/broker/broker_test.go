@@ -164,6 +164,80 @@ func TestAllCases(t *testing.T) {
 			TopicExists("cre-bkr_testnamespace_test-broker_abc123"),
 			SubscriptionExists("cre-bkr_testnamespace_test-broker_abc123"),
 		},
+	}, {
+		Name: "Create ordered broker, subscription is ordered",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.OrderingKeyExtensionAnnotation, "partitionkey"),
+				WithBrokerUID(testUID),
+				WithBrokerSetDefaults),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellReady,
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.OrderingKeyExtensionAnnotation, "partitionkey"),
+				WithBrokerUID(testUID),
+				WithBrokerReadyURI(brokerAddress),
+				WithBrokerOrderingEnabled,
+				WithBrokerSetDefaults,
+			),
+		}},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeNormal, "TopicCreated", `Created PubSub topic "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr_testnamespace_test-broker_abc123"`),
+			brokerReconciledEvent,
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{},
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			TopicExists("cre-bkr_testnamespace_test-broker_abc123"),
+			SubscriptionExists("cre-bkr_testnamespace_test-broker_abc123"),
+		},
+	}, {
+		Name: "Ordering requested for existing unordered subscription",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.OrderingKeyExtensionAnnotation, "partitionkey"),
+				WithBrokerUID(testUID),
+				WithBrokerSetDefaults),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellReady,
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1beta1.BrokerClass),
+				WithBrokerAnnotation(brokerv1beta1.OrderingKeyExtensionAnnotation, "partitionkey"),
+				WithBrokerUID(testUID),
+				WithBrokerReadyURI(brokerAddress),
+				WithBrokerOrderingDisabled("SubscriptionNotOrdered", `Pub/Sub subscription "cre-bkr_testnamespace_test-broker_abc123" was created without message ordering, it must be recreated to deliver events in order`),
+				WithBrokerSetDefaults,
+			),
+		}},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			brokerReconciledEvent,
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{
+				TopicAndSub("cre-bkr_testnamespace_test-broker_abc123", "cre-bkr_testnamespace_test-broker_abc123"),
+			},
+		},
 	}, {
 		Name: "Create broker with unready brokercell, broker is created",
 		Key:  testKey,
@@ -29,6 +29,7 @@ import (
 	"knative.dev/eventing/pkg/logging"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
 	pkgreconciler "knative.dev/pkg/reconciler"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
@@ -38,6 +39,7 @@ import (
 	brokerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/broker"
 	metadataClient "github.com/google/knative-gcp/pkg/gclient/metadata"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/configserver"
 	"github.com/google/knative-gcp/pkg/utils"
 )
 
@@ -47,7 +49,19 @@ const (
 	controllerAgentName = "broker-controller"
 )
 
-func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+// filterBroker is the function to filter brokers with proper brokerclass.
+var filterBroker = pkgreconciler.AnnotationFilterFunc(eventingv1beta1.BrokerClassAnnotationKey, brokerv1beta1.BrokerClass, false /*allowUnset*/)
+
+type Constructor injection.ControllerConstructor
+
+// NewConstructor creates a constructor to make a Broker controller.
+func NewConstructor(css *configserver.Singleton) Constructor {
+	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+		return newController(ctx, cmw, css.Server(ctx))
+	}
+}
+
+func newController(ctx context.Context, cmw configmap.Watcher, configServer *configserver.Server) *controller.Impl {
 	brokerInformer := brokerinformer.Get(ctx)
 	bcInformer := brokercellinformer.Get(ctx)
 
@@ -76,6 +90,7 @@ func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl
 		Base:             reconciler.NewBase(ctx, controllerAgentName, cmw),
 		brokerCellLister: bcInformer.Lister(),
 		pubsubClient:     client,
+		configServer:     configServer,
 	}
 
 	impl := brokerreconciler.NewImpl(ctx, r, brokerv1beta1.BrokerClass)
@@ -85,7 +100,7 @@ func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl
 	brokerInformer.Informer().AddEventHandlerWithResyncPeriod(
 		cache.FilteringResourceEventHandler{
 			// Only reconcile brokers with the proper class annotation
-			FilterFunc: pkgreconciler.AnnotationFilterFunc(eventingv1beta1.BrokerClassAnnotationKey, brokerv1beta1.BrokerClass, false /*allowUnset*/),
+			FilterFunc: filterBroker,
 			Handler:    controller.HandleAll(impl.Enqueue),
 		},
 		reconciler.DefaultResyncPeriod,
@@ -109,6 +124,17 @@ func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl
 		},
 	))
 
+	if configServer != nil {
+		// Recheck the brokers whose data plane isn't ready yet once the data plane pods applied a
+		// newer targets config.
+		configServer.OnApplied(func(string) {
+			impl.FilteredGlobalResync(func(obj interface{}) bool {
+				b, ok := obj.(*brokerv1beta1.Broker)
+				return ok && filterBroker(b) && !b.Status.GetCondition(brokerv1beta1.BrokerConditionDataPlane).IsTrue()
+			}, brokerInformer.Informer())
+		})
+	}
+
 	return impl
 }
 
@@ -60,3 +60,18 @@ func GenerateRetryTopicName(t *brokerv1beta1.Trigger) string {
 func GenerateRetrySubscriptionName(t *brokerv1beta1.Trigger) string {
 	return naming.TruncatedPubsubResourceName("cre-tgr", t.Namespace, t.Name, t.UID)
 }
+
+// RetryMaxDeliveryAttempts is the maximum number of delivery attempts of the
+// dead letter policy of the retry subscriptions, the highest one Pub/Sub allows.
+// The policy only makes Pub/Sub count the delivery attempts: its dead letter
+// topic is the retry topic itself, so the events still retried after that many
+// attempts are delivered again with the count restarted.
+const RetryMaxDeliveryAttempts = 100
+
+// GenerateReplaySubscriptionName generates a deterministic name for the
+// subscription to the decouple topic the events of a Trigger are replayed
+// from. If the subscription name would be longer than allowed by PubSub, the
+// Trigger name is truncated to fit.
+func GenerateReplaySubscriptionName(t *brokerv1beta1.Trigger) string {
+	return naming.TruncatedPubsubResourceName("cre-rpl", t.Namespace, t.Name, t.UID)
+}
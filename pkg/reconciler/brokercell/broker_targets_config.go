@@ -21,8 +21,14 @@ import (
 	"fmt"
 
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
+	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
 	"knative.dev/eventing/pkg/apis/eventing"
 	"knative.dev/eventing/pkg/logging"
 
@@ -30,9 +36,12 @@ import (
 	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
+	"github.com/google/knative-gcp/pkg/broker/eventtransform"
 	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
 	"github.com/google/knative-gcp/pkg/reconciler/utils/volume"
+	"github.com/google/knative-gcp/pkg/utils"
 )
 
 const (
@@ -40,6 +49,14 @@ const (
 )
 
 func (r *Reconciler) reconcileConfig(ctx context.Context, bc *intv1alpha1.BrokerCell) error {
+	// Only the shards of the config with brokers or triggers that changed since they were last
+	// written are rebuilt. Each of them is rebuilt from scratch from all of its brokers/triggers.
+	bcKey := bc.Namespace + "/" + bc.Name
+	versions := r.shards.dirty(bcKey)
+	if len(versions) == 0 {
+		bc.Status.MarkTargetsConfigReady()
+		return nil
+	}
 	// TODO(#866) Only select brokers that point to this brokercell by label selector once the
 	// webhook assigns the brokercell label, i.e.,
 	// r.brokerLister.List(labels.SelectorFromSet(map[string]string{"brokercell":bc.Name, "brokercellns":bc.Namespace}))
@@ -49,11 +66,16 @@ func (r *Reconciler) reconcileConfig(ctx context.Context, bc *intv1alpha1.Broker
 		bc.Status.MarkTargetsConfigFailed(configFailed, "failed to list brokers: %v", err)
 		return err
 	}
-	// Start with a fresh config and add brokers/triggers into it. This approach is straightforward and reliable,
-	// however not efficient if there are too many triggers. If performance becomes an issue, we can consider
-	// maintaining 2 queues for updated brokers and triggers, and only update the config for updated brokers/triggers.
-	brokerTargets := memory.NewEmptyTargets()
+	shardTargets := make(map[int]config.Targets, len(versions))
+	for shard := range versions {
+		shardTargets[shard] = memory.NewEmptyTargets()
+	}
+	namespaceRateLimits := namespaceRateLimits(ctx, brokers)
 	for _, broker := range brokers {
+		brokerTargets, ok := shardTargets[config.ShardIndex(broker.Namespace, resources.TargetsConfigShards)]
+		if !ok {
+			continue
+		}
 		// Filter by `eventing.knative.dev/broker: <name>` here
 		// to get only the triggers for this broker. The trigger webhook will
 		// ensure that triggers are always labeled with their broker name.
@@ -63,19 +85,49 @@ func (r *Reconciler) reconcileConfig(ctx context.Context, bc *intv1alpha1.Broker
 			bc.Status.MarkTargetsConfigFailed(configFailed, "failed to list triggers for broker %v: %v", broker.Name, err)
 			return err
 		}
-		r.addToConfig(ctx, broker, triggers, brokerTargets)
+		r.addToConfig(ctx, broker, triggers, namespaceRateLimits[broker.Namespace], brokerTargets)
 	}
-	if err := r.updateTargetsConfig(ctx, bc, brokerTargets); err != nil {
-		logging.FromContext(ctx).Error("Failed to update broker targets configmap", zap.Error(err))
-		bc.Status.MarkTargetsConfigFailed(configFailed, "failed to update configmap: %v", err)
-		return err
+	for shard := 0; shard < resources.TargetsConfigShards; shard++ {
+		brokerTargets, ok := shardTargets[shard]
+		if !ok {
+			continue
+		}
+		if err := r.updateTargetsConfig(ctx, bc, shard, brokerTargets); err != nil {
+			logging.FromContext(ctx).Error("Failed to update broker targets configmap", zap.Int("shard", shard), zap.Error(err))
+			bc.Status.MarkTargetsConfigFailed(configFailed, "failed to update configmap: %v", err)
+			return err
+		}
+		r.shards.synced(bcKey, shard, versions[shard])
+		r.publishTargetsConfig(bcKey, shard, brokerTargets)
 	}
 	bc.Status.MarkTargetsConfigReady()
 	return nil
 }
 
+// namespaceRateLimits returns the ingress rate limit of each namespace set by the brokers. If
+// several brokers in a namespace set a limit, the one with the lowest rate is used.
+func namespaceRateLimits(ctx context.Context, brokers []*brokerv1beta1.Broker) map[string]*config.RateLimit {
+	limits := make(map[string]*config.RateLimit)
+	for _, b := range brokers {
+		v, ok := b.Annotations[brokerv1beta1.NamespaceIngressRateLimitAnnotation]
+		if !ok {
+			continue
+		}
+		l, err := config.ParseRateLimit(v)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to parse namespace rate limit", zap.String("Broker", b.Name), zap.Error(err))
+			continue
+		}
+		if cur, ok := limits[b.Namespace]; !ok || l.EventsPerSecond < cur.EventsPerSecond ||
+			(l.EventsPerSecond == cur.EventsPerSecond && l.Burst < cur.Burst) {
+			limits[b.Namespace] = l
+		}
+	}
+	return limits
+}
+
 // addToConfig reconstructs the data entry for the given broker and add it to targets-config.
-func (r *Reconciler) addToConfig(ctx context.Context, b *brokerv1beta1.Broker, triggers []*brokerv1beta1.Trigger, brokerTargets config.Targets) {
+func (r *Reconciler) addToConfig(ctx context.Context, b *brokerv1beta1.Broker, triggers []*brokerv1beta1.Trigger, namespaceRateLimit *config.RateLimit, brokerTargets config.Targets) {
 	// TODO Maybe get rid of BrokerMutation and add Delete() and Upsert(broker) methods to TargetsConfig. Now we always
 	//  delete or update the entire broker entry and we don't need partial updates per trigger.
 	// The code can be simplified to r.targetsConfig.Upsert(brokerConfigEntry)
@@ -96,6 +148,64 @@ func (r *Reconciler) addToConfig(ctx context.Context, b *brokerv1beta1.Broker, t
 			m.SetState(config.State_UNKNOWN)
 		}
 
+		if v, ok := b.Annotations[brokerv1beta1.IngressRateLimitAnnotation]; ok {
+			if l, err := config.ParseRateLimit(v); err != nil {
+				logging.FromContext(ctx).Error("Failed to parse broker rate limit", zap.String("Broker", b.Name), zap.Error(err))
+			} else {
+				m.SetRateLimit(l)
+			}
+		}
+		if v, ok := b.Annotations[brokerv1beta1.AllowedIdentitiesAnnotation]; ok {
+			if p, err := config.ParseAuthPolicy(v); err != nil {
+				// The broker webhook rejects invalid identities. Deny all senders rather than
+				// allowing any authenticated sender.
+				logging.FromContext(ctx).Error("Failed to parse broker allowed identities", zap.String("Broker", b.Name), zap.Error(err))
+				m.SetAuthPolicy(&config.AuthPolicy{})
+			} else {
+				m.SetAuthPolicy(p)
+			}
+		}
+		if v, ok := b.Annotations[brokerv1beta1.ClaimCheckThresholdAnnotation]; ok {
+			if n, err := config.ParseClaimCheckThreshold(v); err != nil {
+				logging.FromContext(ctx).Error("Failed to parse broker claim check threshold", zap.String("Broker", b.Name), zap.Error(err))
+			} else {
+				m.SetClaimCheckThreshold(n)
+			}
+		}
+		if v, ok := b.Annotations[brokerv1beta1.OrderingKeyExtensionAnnotation]; ok {
+			if e, err := config.ParseOrderingKeyExtension(v); err != nil {
+				logging.FromContext(ctx).Error("Failed to parse broker ordering key extension", zap.String("Broker", b.Name), zap.Error(err))
+			} else {
+				m.SetOrderingKeyExtension(e)
+			}
+		}
+		if v, ok := b.Annotations[brokerv1beta1.DeduplicationWindowAnnotation]; ok {
+			if w, err := config.ParseDeduplicationWindow(v); err != nil {
+				logging.FromContext(ctx).Error("Failed to parse broker deduplication window", zap.String("Broker", b.Name), zap.Error(err))
+			} else {
+				m.SetDeduplicationWindow(w)
+			}
+		}
+		if v, ok := b.Annotations[brokerv1beta1.HopLimitAnnotation]; ok {
+			if n, err := config.ParseHopLimit(v); err != nil {
+				logging.FromContext(ctx).Error("Failed to parse broker hop limit", zap.String("Broker", b.Name), zap.Error(err))
+			} else {
+				m.SetHopLimit(n)
+			}
+		}
+		if v, ok := b.Annotations[brokerv1beta1.LoopDetectionAnnotation]; ok {
+			if enabled, err := config.ParseLoopDetection(v); err != nil {
+				logging.FromContext(ctx).Error("Failed to parse broker loop detection", zap.String("Broker", b.Name), zap.Error(err))
+			} else {
+				m.SetLoopDetection(enabled)
+			}
+		}
+		if namespaceRateLimit != nil {
+			m.SetNamespaceRateLimit(proto.Clone(namespaceRateLimit).(*config.RateLimit))
+		}
+
+		deliverySpec := r.resolveDeliverySpec(ctx, b)
+
 		// Insert each Trigger to the config.
 		for _, t := range triggers {
 			if t.Spec.Broker == b.Name {
@@ -110,12 +220,63 @@ func (r *Reconciler) addToConfig(ctx context.Context, b *brokerv1beta1.Broker, t
 						Subscription: brokerresources.GenerateRetrySubscriptionName(t),
 					},
 				}
+				target.DeliverySpec = resolveTriggerDeliverySpec(ctx, t, deliverySpec)
+				if v, ok := t.Annotations[brokerv1beta1.FiltersAnnotation]; ok {
+					filters, err := eventfilter.Parse(v)
+					if err == nil {
+						// Compile the filters too, so that the data plane never gets filters that
+						// fail to compile.
+						_, err = eventfilter.Compile(filters)
+					}
+					if err != nil {
+						// The trigger webhook rejects invalid filters. Leave the trigger out of the
+						// config rather than delivering events to it without its filters.
+						logging.FromContext(ctx).Error("Invalid trigger filters", zap.String("Trigger", t.Name), zap.Error(err))
+						continue
+					}
+					target.Filters = filters
+				}
+				if v, ok := t.Annotations[brokerv1beta1.TransformsAnnotation]; ok {
+					transforms, err := eventtransform.Parse(v)
+					if err == nil {
+						_, err = eventtransform.Compile(transforms)
+					}
+					if err != nil {
+						// The trigger webhook rejects invalid transforms. Leave the trigger out of the
+						// config rather than delivering untransformed events to it.
+						logging.FromContext(ctx).Error("Invalid trigger transforms", zap.String("Trigger", t.Name), zap.Error(err))
+						continue
+					}
+					target.Transforms = transforms
+				}
+				auth, err := t.DeliveryAuth(ctx)
+				if err != nil {
+					// Leave the trigger out of the config rather than delivering events to it
+					// without the credentials it expects, or minting tokens that the namespace
+					// isn't allowed to use. The trigger reconciler reports the error.
+					logging.FromContext(ctx).Error("Invalid trigger delivery authentication", zap.String("Trigger", t.Name), zap.Error(err))
+					continue
+				}
+				target.DeliveryAuth = auth
 				if t.Spec.Filter != nil && t.Spec.Filter.Attributes != nil {
 					target.FilterAttributes = t.Spec.Filter.Attributes
 				}
+				if start, ok := t.Status.ReplayStartTime(); ok {
+					target.Replay = &config.Replay{
+						Queue: &config.Queue{
+							Topic:        brokerresources.GenerateDecouplingTopicName(b),
+							Subscription: brokerresources.GenerateReplaySubscriptionName(t),
+						},
+						Until: timestamppb.New(start),
+					}
+				}
 				// TODO(#939) May need to use "data plane readiness" for trigger in stead of the
 				//  overall status, see https://github.com/google/knative-gcp/issues/939#issuecomment-644337937
-				if t.Status.IsReady() {
+				if t.IsPaused() {
+					// Paused triggers stay in the config so their events are kept in their
+					// retry queues until they are resumed.
+					target.State = config.State_PAUSED
+				} else if t.Status.IsReady() {
 					target.State = config.State_READY
 				} else {
 					target.State = config.State_UNKNOWN
@@ -126,14 +287,96 @@ func (r *Reconciler) addToConfig(ctx context.Context, b *brokerv1beta1.Broker, t
 	})
 }
 
+// resolveDeliverySpec resolves the delivery options for the triggers of the given broker.
+// Triggers may override them with their DeliveryAnnotation, see resolveTriggerDeliverySpec.
+func (r *Reconciler) resolveDeliverySpec(ctx context.Context, b *brokerv1beta1.Broker) *config.DeliverySpec {
+	if b.Spec.Delivery == nil {
+		return nil
+	}
+	deliverySpec := &config.DeliverySpec{}
+	if err := setDeliveryOptions(deliverySpec, b.Spec.Delivery); err != nil {
+		// The default backoff delay of the retry pods is used instead.
+		logging.FromContext(ctx).Error("Failed to parse backoff delay", zap.String("Broker", b.Name), zap.Error(err))
+	}
+	if b.Spec.Delivery.DeadLetterSink != nil {
+		deadLetterURI, err := r.uriResolver.URIFromDestinationV1(*b.Spec.Delivery.DeadLetterSink, b)
+		if err != nil {
+			// Don't fail the whole config because of a single broker. The events of its triggers
+			// are still retried, they are just not sent to the dead letter sink.
+			logging.FromContext(ctx).Error("Failed to resolve dead letter sink", zap.String("Broker", b.Name), zap.Error(err))
+		} else {
+			deliverySpec.DeadLetter = deadLetterURI.String()
+		}
+	}
+	return deliverySpec
+}
+
+// resolveTriggerDeliverySpec resolves the delivery options of the given trigger: those set
+// with its DeliveryAnnotation override the given ones of its broker.
+func resolveTriggerDeliverySpec(ctx context.Context, t *brokerv1beta1.Trigger, brokerDeliverySpec *config.DeliverySpec) *config.DeliverySpec {
+	var deliverySpec *config.DeliverySpec
+	if brokerDeliverySpec != nil {
+		deliverySpec = proto.Clone(brokerDeliverySpec).(*config.DeliverySpec)
+	}
+	v, ok := t.Annotations[brokerv1beta1.DeliveryAnnotation]
+	if !ok {
+		return deliverySpec
+	}
+	delivery, err := brokerv1beta1.ParseDelivery(v)
+	if err != nil {
+		// The trigger webhook rejects invalid delivery options, the broker's are used instead.
+		logging.FromContext(ctx).Error("Invalid trigger delivery", zap.String("Trigger", t.Name), zap.Error(err))
+		return deliverySpec
+	}
+	if deliverySpec == nil {
+		deliverySpec = &config.DeliverySpec{}
+	}
+	// ParseDelivery already checked the backoff delay.
+	_ = setDeliveryOptions(deliverySpec, delivery)
+	return deliverySpec
+}
+
+// setDeliveryOptions sets the retry, backoff policy and backoff delay of the given target
+// delivery spec to those set in the given delivery spec. It returns an error if the backoff
+// delay is invalid, the other options are set anyway.
+func setDeliveryOptions(deliverySpec *config.DeliverySpec, delivery *eventingduckv1beta1.DeliverySpec) error {
+	if delivery.Retry != nil {
+		deliverySpec.Retry = *delivery.Retry
+		// Pub/Sub counts at most this many deliveries from the retry queue
+		// before the count restarts, so more retries would never end.
+		if deliverySpec.Retry > brokerresources.RetryMaxDeliveryAttempts {
+			deliverySpec.Retry = brokerresources.RetryMaxDeliveryAttempts
+		}
+	}
+	if delivery.BackoffPolicy != nil {
+		if *delivery.BackoffPolicy == eventingduckv1beta1.BackoffPolicyLinear {
+			deliverySpec.BackoffPolicy = config.BackoffPolicy_LINEAR
+		} else {
+			deliverySpec.BackoffPolicy = config.BackoffPolicy_EXPONENTIAL
+		}
+	}
+	if delivery.BackoffDelay != nil {
+		delay, err := utils.ParseISO8601Duration(*delivery.BackoffDelay)
+		if err != nil {
+			return err
+		}
+		deliverySpec.BackoffDelay = durationpb.New(delay)
+	}
+	return nil
+}
+
 //TODO all this stuff should be in a configmap variant of the config object
-func (r *Reconciler) updateTargetsConfig(ctx context.Context, bc *intv1alpha1.BrokerCell, brokerTargets config.Targets) error {
-	desired, err := resources.MakeTargetsConfig(bc, brokerTargets)
+func (r *Reconciler) updateTargetsConfig(ctx context.Context, bc *intv1alpha1.BrokerCell, shard int, brokerTargets config.Targets) error {
+	if shard != 0 && isEmpty(brokerTargets) {
+		// Only the first shard is required by the data plane, empty shards are deleted.
+		return r.deleteTargetsConfig(ctx, bc, shard)
+	}
+	desired, err := resources.MakeTargetsConfig(bc, shard, brokerTargets)
 	if err != nil {
 		return fmt.Errorf("error creating targets config: %w", err)
 	}
 
-	logging.FromContext(ctx).Debug("Current targets config", zap.Any("targetsConfig", brokerTargets.String()))
+	logging.FromContext(ctx).Debug("Current targets config", zap.Int("shard", shard), zap.Any("targetsConfig", brokerTargets.String()))
 
 	handlerFuncs := cache.ResourceEventHandlerFuncs{
 		AddFunc:    func(obj interface{}) { r.refreshPodVolume(ctx, bc) },
@@ -145,6 +388,28 @@ func (r *Reconciler) updateTargetsConfig(ctx context.Context, bc *intv1alpha1.Br
 	return err
 }
 
+func (r *Reconciler) deleteTargetsConfig(ctx context.Context, bc *intv1alpha1.BrokerCell, shard int) error {
+	name := resources.TargetsConfigName(bc.Name, shard)
+	if _, err := r.configMapLister.ConfigMaps(bc.Namespace).Get(name); apierrs.IsNotFound(err) {
+		return nil
+	}
+	err := r.KubeClientSet.CoreV1().ConfigMaps(bc.Namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !apierrs.IsNotFound(err) {
+		return err
+	}
+	r.refreshPodVolume(ctx, bc)
+	return nil
+}
+
+func isEmpty(brokerTargets config.Targets) bool {
+	empty := true
+	brokerTargets.RangeBrokers(func(*config.Broker) bool {
+		empty = false
+		return false
+	})
+	return empty
+}
+
 func (r *Reconciler) refreshPodVolume(ctx context.Context, bc *intv1alpha1.BrokerCell) {
 	if err := volume.UpdateVolumeGeneration(r.KubeClientSet, r.podLister, bc.Namespace, resources.CommonLabels(bc.Name)); err != nil {
 		// Failing to update the annotation on the data plane pods means there
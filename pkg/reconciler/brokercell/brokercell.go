@@ -19,6 +19,7 @@ package brokercell
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"go.uber.org/zap"
@@ -33,11 +34,13 @@ import (
 	"knative.dev/eventing/pkg/logging"
 	"knative.dev/eventing/pkg/reconciler/names"
 	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/resolver"
 
 	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	bcreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/intevents/v1alpha1/brokercell"
 	brokerlisters "github.com/google/knative-gcp/pkg/client/listers/broker/v1beta1"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/configserver"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
 )
 
@@ -48,6 +51,19 @@ type envConfig struct {
 	ServiceAccountName string `envconfig:"SERVICE_ACCOUNT" default:"broker"`
 	IngressPort        int    `envconfig:"INGRESS_PORT" default:"8080"`
 	MetricsPort        int    `envconfig:"METRICS_PORT" default:"9090"`
+
+	// IngressAuthMode is how the ingress authenticates requests: "none", "oidc" or "tokenreview".
+	IngressAuthMode string `envconfig:"INGRESS_AUTH_MODE"`
+	// IngressAuthAudience is the audience the bearer tokens sent to the ingress must have.
+	IngressAuthAudience string `envconfig:"INGRESS_AUTH_AUDIENCE"`
+	// IngressAuthOIDCIssuer is the issuer of the OIDC tokens sent to the ingress.
+	IngressAuthOIDCIssuer string `envconfig:"INGRESS_AUTH_OIDC_ISSUER"`
+
+	// ClaimCheckStore is the URL of the store for the event payloads offloaded by the ingress,
+	// either "gs://<bucket>/<prefix>" or "file:///<dir>".
+	ClaimCheckStore string `envconfig:"CLAIM_CHECK_STORE"`
+	// ClaimCheckTTL is how long the offloaded payloads are kept before the ingress deletes them.
+	ClaimCheckTTL time.Duration `envconfig:"CLAIM_CHECK_TTL"`
 }
 
 type listers struct {
@@ -62,7 +78,7 @@ type listers struct {
 }
 
 // NewReconciler creates a new BrokerCell reconciler.
-func NewReconciler(base *reconciler.Base, ls listers) (*Reconciler, error) {
+func NewReconciler(base *reconciler.Base, ls listers, configServer *configserver.Server) (*Reconciler, error) {
 	var env envConfig
 	if err := envconfig.Process("BROKER_CELL", &env); err != nil {
 		return nil, err
@@ -90,6 +106,8 @@ func NewReconciler(base *reconciler.Base, ls listers) (*Reconciler, error) {
 		svcRec:        svcRec,
 		deploymentRec: deploymentRec,
 		cmRec:         cmRec,
+		shards:        newShardTracker(),
+		configServer:  configServer,
 	}
 	return r, nil
 }
@@ -104,6 +122,14 @@ type Reconciler struct {
 	deploymentRec *reconciler.DeploymentReconciler
 	cmRec         *reconciler.ConfigMapReconciler
 
+	uriResolver *resolver.URIResolver
+
+	// shards tracks the shards of the targets config to rebuild.
+	shards *shardTracker
+
+	// configServer streams the targets config to the data plane pods, nil if disabled.
+	configServer *configserver.Server
+
 	env envConfig
 }
 
@@ -190,10 +216,27 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 	}
 	bc.Status.PropagateRetryAvailability(rd)
 
+	r.reconcileHandlersHealth(bc)
+
 	bc.Status.ObservedGeneration = bc.Generation
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, "BrokerCellReconciled", "BrokerCell reconciled: \"%s/%s\"", bc.Namespace, bc.Name)
 }
 
+// reconcileHandlersHealth reports in the brokercell status the handlers which
+// failed in the data plane pods. The health of the handlers is only reported
+// over the targets config stream.
+func (r *Reconciler) reconcileHandlersHealth(bc *intv1alpha1.BrokerCell) {
+	if r.configServer == nil {
+		bc.Status.ClearHandlersHealth()
+		return
+	}
+	if err := r.configServer.HandlersHealthy(bc.Namespace, bc.Name); err != nil {
+		bc.Status.MarkHandlersDegraded("HandlersFailed", "%v", err)
+		return
+	}
+	bc.Status.MarkHandlersHealthy()
+}
+
 // shouldGC returns true if
 // 1. the brokercell was automatically created by GCP broker controller (with annotation
 // internal.events.cloud.google.com/creator: googlecloud), and
@@ -227,13 +270,20 @@ func (r *Reconciler) delete(ctx context.Context, bc *intv1alpha1.BrokerCell) pkg
 func (r *Reconciler) makeIngressArgs(bc *intv1alpha1.BrokerCell) resources.IngressArgs {
 	return resources.IngressArgs{
 		Args: resources.Args{
-			ComponentName:      resources.IngressName,
-			BrokerCell:         bc,
-			Image:              r.env.IngressImage,
-			ServiceAccountName: r.env.ServiceAccountName,
-			MetricsPort:        r.env.MetricsPort,
+			ComponentName:         resources.IngressName,
+			BrokerCell:            bc,
+			Image:                 r.env.IngressImage,
+			ServiceAccountName:    r.env.ServiceAccountName,
+			MetricsPort:           r.env.MetricsPort,
+			TargetsConfigServer:   r.targetsConfigServerAddress(),
+			TargetsConfigServerCA: r.targetsConfigServerCA(),
+			ClaimCheckStore:       r.env.ClaimCheckStore,
 		},
-		Port: r.env.IngressPort,
+		Port:           r.env.IngressPort,
+		AuthMode:       r.env.IngressAuthMode,
+		AuthAudience:   r.env.IngressAuthAudience,
+		AuthOIDCIssuer: r.env.IngressAuthOIDCIssuer,
+		ClaimCheckTTL:  r.env.ClaimCheckTTL,
 	}
 }
 
@@ -250,11 +300,14 @@ func (r *Reconciler) makeIngressHPAArgs(bc *intv1alpha1.BrokerCell) resources.Au
 func (r *Reconciler) makeFanoutArgs(bc *intv1alpha1.BrokerCell) resources.FanoutArgs {
 	return resources.FanoutArgs{
 		Args: resources.Args{
-			ComponentName:      resources.FanoutName,
-			BrokerCell:         bc,
-			Image:              r.env.FanoutImage,
-			ServiceAccountName: r.env.ServiceAccountName,
-			MetricsPort:        r.env.MetricsPort,
+			ComponentName:         resources.FanoutName,
+			BrokerCell:            bc,
+			Image:                 r.env.FanoutImage,
+			ServiceAccountName:    r.env.ServiceAccountName,
+			MetricsPort:           r.env.MetricsPort,
+			TargetsConfigServer:   r.targetsConfigServerAddress(),
+			TargetsConfigServerCA: r.targetsConfigServerCA(),
+			ClaimCheckStore:       r.env.ClaimCheckStore,
 		},
 	}
 }
@@ -277,11 +330,14 @@ func (r *Reconciler) makeFanoutHPAArgs(bc *intv1alpha1.BrokerCell) resources.Aut
 func (r *Reconciler) makeRetryArgs(bc *intv1alpha1.BrokerCell) resources.RetryArgs {
 	return resources.RetryArgs{
 		Args: resources.Args{
-			ComponentName:      resources.RetryName,
-			BrokerCell:         bc,
-			Image:              r.env.RetryImage,
-			ServiceAccountName: r.env.ServiceAccountName,
-			MetricsPort:        r.env.MetricsPort,
+			ComponentName:         resources.RetryName,
+			BrokerCell:            bc,
+			Image:                 r.env.RetryImage,
+			ServiceAccountName:    r.env.ServiceAccountName,
+			MetricsPort:           r.env.MetricsPort,
+			TargetsConfigServer:   r.targetsConfigServerAddress(),
+			TargetsConfigServerCA: r.targetsConfigServerCA(),
+			ClaimCheckStore:       r.env.ClaimCheckStore,
 		},
 	}
 }
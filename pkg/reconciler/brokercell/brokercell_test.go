@@ -19,32 +19,52 @@ package brokercell
 import (
 	"context"
 	"fmt"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	authv1 "k8s.io/api/authentication/v1"
 	hpav2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	clientgotesting "k8s.io/client-go/testing"
 
+	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
 	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/ptr"
 	. "knative.dev/pkg/reconciler/testing"
+	"knative.dev/pkg/resolver"
 
 	"github.com/google/go-cmp/cmp"
+	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
 	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	bcreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/intevents/v1alpha1/brokercell"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/configserver"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/testingdata"
 	. "github.com/google/knative-gcp/pkg/reconciler/testing"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
 )
 
 const (
@@ -897,10 +917,12 @@ func TestAllCases(t *testing.T) {
 			podLister:        testingListers.GetPodLister(),
 		}
 
-		r, err := NewReconciler(base, ls)
+		r, err := NewReconciler(base, ls, nil)
 		if err != nil {
 			t.Fatalf("Failed to created BrokerCell reconciler: %v", err)
 		}
+		ctx = addressable.WithDuck(ctx)
+		r.uriResolver = resolver.NewURIResolver(ctx, func(types.NamespacedName) {})
 		return bcreconciler.NewReconciler(ctx, r.Logger, r.RunClientSet, testingListers.GetBrokerCellLister(), r.Recorder, r)
 	}))
 }
@@ -916,13 +938,59 @@ func emptyHPASpec(template *hpav2beta2.HorizontalPodAutoscaler) *hpav2beta2.Hori
 func TestBrokerTargetsReconcileConfig(t *testing.T) {
 	setReconcilerEnv()
 	bc := NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)
+	deadLetterURI, _ := apis.ParseURL("http://dead-letter.example.com")
+	linear := eventingduckv1beta1.BackoffPolicyLinear
+	filters := `[{"prefix": {"type": "com.example."}}, {"sql": "subject LIKE '%.png'"}]`
+	transforms := `[{"set": {"type": "${type}.v2"}}, {"remove": ["subject"]}]`
+	delivery := `{"retry": 5, "backoffPolicy": "exponential"}`
+	deliverySpec := &eventingduckv1beta1.DeliverySpec{
+		DeadLetterSink: &duckv1.Destination{URI: deadLetterURI},
+		Retry:          ptr.Int32(3),
+		BackoffPolicy:  &linear,
+		BackoffDelay:   ptr.String("PT0.5S"),
+	}
+	// The replay start time is the transition time of the condition.
+	replaying := NewTrigger("trigger4", testNS, "broker", WithTriggerSetDefaults, WithReplayAnnotation("pre-upgrade"),
+		WithTriggerReplaying("pre-upgrade"))
 	objects := []runtime.Object{
 		bc,
-		NewBroker("broker", testNS, WithBrokerSetDefaults),
-		NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults),
-		NewTrigger("trigger2", testNS, "broker", WithTriggerSetDefaults),
+		NewBroker("broker", testNS, WithBrokerSetDefaults, WithBrokerDeliverySpec(deliverySpec),
+			WithBrokerAnnotation(brokerv1beta1.IngressRateLimitAnnotation, "100"),
+			WithBrokerAnnotation(brokerv1beta1.NamespaceIngressRateLimitAnnotation, "1000,2000"),
+			WithBrokerAnnotation(brokerv1beta1.AllowedIdentitiesAnnotation, "system:serviceaccount:testnamespace:*"),
+			WithBrokerAnnotation(brokerv1beta1.ClaimCheckThresholdAnnotation, "1048576"),
+			WithBrokerAnnotation(brokerv1beta1.OrderingKeyExtensionAnnotation, "partitionkey"),
+			WithBrokerAnnotation(brokerv1beta1.DeduplicationWindowAnnotation, "10m"),
+			WithBrokerAnnotation(brokerv1beta1.HopLimitAnnotation, "10"),
+			WithBrokerAnnotation(brokerv1beta1.LoopDetectionAnnotation, "true")),
+		NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults, WithFiltersAnnotation(filters),
+			WithTransformsAnnotation(transforms)),
+		NewTrigger("trigger2", testNS, "broker", WithTriggerSetDefaults,
+			WithDeliveryAuthAnnotations("https://subscriber-abc-uc.a.run.app", "invoker@my-project.iam.gserviceaccount.com")),
+		NewTrigger("trigger3", testNS, "broker", WithTriggerSetDefaults, WithPausedAnnotation),
+		replaying,
+		// The trigger overrides the retry and backoff policy of the broker.
+		NewTrigger("trigger8", testNS, "broker", WithTriggerSetDefaults, WithDeliveryAnnotation(delivery)),
+		// The delivery service account isn't allowed in the namespace, the trigger is left out.
+		NewTrigger("trigger5", testNS, "broker", WithTriggerSetDefaults,
+			WithDeliveryAuthAnnotations("https://subscriber-abc-uc.a.run.app", "admin@my-project.iam.gserviceaccount.com")),
+		// The filters parse but don't compile, the trigger is left out.
+		NewTrigger("trigger6", testNS, "broker", WithTriggerSetDefaults, WithFiltersAnnotation(`[{"sql": "subject LIKE"}]`)),
+		// The transforms parse but don't compile, the trigger is left out.
+		NewTrigger("trigger7", testNS, "broker", WithTriggerSetDefaults, WithTransformsAnnotation(`[{"remove": ["id"]}]`)),
 	}
 	ctx, _ := SetupFakeContext(t)
+	gcpAuthDefaults, err := gcpauth.NewDefaultsConfigFromMap(map[string]string{
+		"default-auth-config": `
+  clusterDefaults:
+    deliveryServiceAccounts:
+    - invoker@my-project.iam.gserviceaccount.com
+`,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create GCP auth defaults: %v", err)
+	}
+	ctx = gcpauth.ToContext(ctx, &gcpauth.Config{GCPAuthDefaults: gcpAuthDefaults})
 	cmw := configmap.NewStaticWatcher()
 	ctx, client := fakekubeclient.With(ctx)
 	base := reconciler.NewBase(ctx, controllerAgentName, cmw)
@@ -937,16 +1005,31 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 		deploymentLister: testingListers.GetDeploymentLister(),
 		podLister:        testingListers.GetPodLister(),
 	}
-	r, err := NewReconciler(base, ls)
+	r, err := NewReconciler(base, ls, nil)
 	if err != nil {
 		t.Fatalf("Failed to create BrokerCell reconciler: %v", err)
 	}
+	ctx = addressable.WithDuck(ctx)
+	r.uriResolver = resolver.NewURIResolver(ctx, func(types.NamespacedName) {})
 	// here we only want to test the functionality of the reconcileConfig that it should create a brokerTargets config successfully
 	r.reconcileConfig(ctx, bc)
 	wantMap := testingdata.Config(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
-		NewBroker("broker", testNS, WithBrokerSetDefaults),
-		NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults),
-		NewTrigger("trigger2", testNS, "broker", WithTriggerSetDefaults))
+		NewBroker("broker", testNS, WithBrokerSetDefaults, WithBrokerDeliverySpec(deliverySpec),
+			WithBrokerAnnotation(brokerv1beta1.IngressRateLimitAnnotation, "100"),
+			WithBrokerAnnotation(brokerv1beta1.NamespaceIngressRateLimitAnnotation, "1000,2000"),
+			WithBrokerAnnotation(brokerv1beta1.AllowedIdentitiesAnnotation, "system:serviceaccount:testnamespace:*"),
+			WithBrokerAnnotation(brokerv1beta1.ClaimCheckThresholdAnnotation, "1048576"),
+			WithBrokerAnnotation(brokerv1beta1.OrderingKeyExtensionAnnotation, "partitionkey"),
+			WithBrokerAnnotation(brokerv1beta1.DeduplicationWindowAnnotation, "10m"),
+			WithBrokerAnnotation(brokerv1beta1.HopLimitAnnotation, "10"),
+			WithBrokerAnnotation(brokerv1beta1.LoopDetectionAnnotation, "true")),
+		NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults, WithFiltersAnnotation(filters),
+			WithTransformsAnnotation(transforms)),
+		NewTrigger("trigger2", testNS, "broker", WithTriggerSetDefaults,
+			WithDeliveryAuthAnnotations("https://subscriber-abc-uc.a.run.app", "invoker@my-project.iam.gserviceaccount.com")),
+		NewTrigger("trigger3", testNS, "broker", WithTriggerSetDefaults, WithPausedAnnotation),
+		replaying,
+		NewTrigger("trigger8", testNS, "broker", WithTriggerSetDefaults, WithDeliveryAnnotation(delivery)))
 	gotMap, err := client.CoreV1().ConfigMaps(testNS).Get(resources.Name(bc.Name, targetsCMName), metav1.GetOptions{})
 	if err != nil {
 		t.Fatalf("Failed to get ConfigMap from client: %v", err)
@@ -969,3 +1052,294 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 		t.Fatalf("Unexpected brokerTargets in ConfigMap(-want, +got): %s", diff)
 	}
 }
+
+func TestBrokerTargetsReconcileShardedConfig(t *testing.T) {
+	setReconcilerEnv()
+	bc := NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)
+	// The brokers of the test namespace are in the first shard, those of "ns" in another one.
+	otherNS := "ns"
+	otherShard := config.ShardIndex(otherNS, resources.TargetsConfigShards)
+	if otherShard == config.ShardIndex(testNS, resources.TargetsConfigShards) {
+		t.Fatalf("namespaces %q and %q are in the same shard", testNS, otherNS)
+	}
+	// A shard without brokers left is deleted.
+	staleShard := (otherShard + 1) % resources.TargetsConfigShards
+	staleTargets := memory.NewEmptyTargets()
+	staleTargets.MutateBroker("deleted", "broker", func(m config.BrokerMutation) { m.SetID("deleted") })
+	staleConfig, err := resources.MakeTargetsConfig(bc, staleShard, staleTargets)
+	if err != nil {
+		t.Fatalf("Failed to make targets config: %v", err)
+	}
+	broker := NewBroker("broker", testNS, WithBrokerSetDefaults)
+	otherBroker := NewBroker("broker", otherNS, WithBrokerSetDefaults)
+	objects := []runtime.Object{
+		bc,
+		broker,
+		otherBroker,
+		NewTrigger("trigger", otherNS, "broker", WithTriggerSetDefaults),
+		staleConfig,
+	}
+	ctx, _ := SetupFakeContext(t)
+	ctx, client := fakekubeclient.With(ctx, staleConfig)
+	base := reconciler.NewBase(ctx, controllerAgentName, configmap.NewStaticWatcher())
+	testingListers := NewListers(objects)
+	ls := listers{
+		brokerLister:    testingListers.GetBrokerLister(),
+		triggerLister:   testingListers.GetTriggerLister(),
+		configMapLister: testingListers.GetConfigMapLister(),
+		podLister:       testingListers.GetPodLister(),
+	}
+	r, err := NewReconciler(base, ls, nil)
+	if err != nil {
+		t.Fatalf("Failed to create BrokerCell reconciler: %v", err)
+	}
+	ctx = addressable.WithDuck(ctx)
+	r.uriResolver = resolver.NewURIResolver(ctx, func(types.NamespacedName) {})
+
+	// configMapActions returns the names of the ConfigMaps written since the last call.
+	configMapActions := func() []string {
+		var names []string
+		for _, a := range client.Actions() {
+			if a.GetResource().Resource != "configmaps" {
+				continue
+			}
+			switch a.GetVerb() {
+			case "create", "update":
+				names = append(names, a.GetVerb()+" "+a.(clientgotesting.CreateAction).GetObject().(*corev1.ConfigMap).Name)
+			case "delete":
+				names = append(names, "delete "+a.(clientgotesting.DeleteAction).GetName())
+			}
+		}
+		client.ClearActions()
+		return names
+	}
+
+	if err := r.reconcileConfig(ctx, bc); err != nil {
+		t.Fatalf("Failed to reconcile config: %v", err)
+	}
+	want := []string{
+		"create " + resources.TargetsConfigName(bc.Name, 0),
+		"create " + resources.TargetsConfigName(bc.Name, otherShard),
+		"delete " + resources.TargetsConfigName(bc.Name, staleShard),
+	}
+	if otherShard > staleShard {
+		want[1], want[2] = want[2], want[1]
+	}
+	if diff := cmp.Diff(want, configMapActions()); diff != "" {
+		t.Errorf("Unexpected ConfigMap actions (-want, +got): %s", diff)
+	}
+	for shard, wantBroker := range map[int]*brokerv1beta1.Broker{0: broker, otherShard: otherBroker} {
+		cm, err := client.CoreV1().ConfigMaps(testNS).Get(resources.TargetsConfigName(bc.Name, shard), metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get ConfigMap of shard %d: %v", shard, err)
+		}
+		var got config.TargetsConfig
+		if err := proto.Unmarshal(cm.BinaryData[targetsCMKey], &got); err != nil {
+			t.Fatalf("Failed to deserialize the binary data in ConfigMap: %v", err)
+		}
+		if _, ok := got.Brokers[config.BrokerKey(wantBroker.Namespace, wantBroker.Name)]; !ok || len(got.Brokers) != 1 {
+			t.Errorf("Shard %d got brokers %v, want only %s/%s", shard, got.Brokers, wantBroker.Namespace, wantBroker.Name)
+		}
+	}
+
+	// Nothing is rebuilt until a broker or trigger changes, then only its shard is.
+	if err := r.reconcileConfig(ctx, bc); err != nil {
+		t.Fatalf("Failed to reconcile config: %v", err)
+	}
+	if got := configMapActions(); len(got) != 0 {
+		t.Errorf("Unexpected ConfigMap actions without changes: %v", got)
+	}
+	r.shards.namespaceChanged(otherNS)
+	if err := r.reconcileConfig(ctx, bc); err != nil {
+		t.Fatalf("Failed to reconcile config: %v", err)
+	}
+	// The lister doesn't see the ConfigMap created by the first reconcile.
+	want = []string{"create " + resources.TargetsConfigName(bc.Name, otherShard)}
+	if diff := cmp.Diff(want, configMapActions()); diff != "" {
+		t.Errorf("Unexpected ConfigMap actions (-want, +got): %s", diff)
+	}
+}
+
+func TestBrokerTargetsPublishConfig(t *testing.T) {
+	setReconcilerEnv()
+	bc := NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)
+	objects := []runtime.Object{
+		bc,
+		NewBroker("broker", testNS, WithBrokerSetDefaults),
+		NewBroker("broker", "ns", WithBrokerSetDefaults),
+		NewTrigger("trigger", "ns", "broker", WithTriggerSetDefaults),
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNS,
+			Name:      "fanout-pod",
+			UID:       "fanout-pod-uid",
+			Labels:    resources.CommonLabels(brokerCellName),
+		}},
+	}
+	ctx, _ := SetupFakeContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx, kubeClient := fakekubeclient.With(ctx)
+	// The token of the pod is reviewed when it watches the targets config.
+	kubeClient.PrependReactor("create", "tokenreviews", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		review := action.(clientgotesting.CreateAction).GetObject().(*authv1.TokenReview)
+		review.Status = authv1.TokenReviewStatus{
+			Authenticated: review.Spec.Token == "fanout-pod-token",
+			Audiences:     review.Spec.Audiences,
+			User: authv1.UserInfo{
+				Username: "system:serviceaccount:" + testNS + ":broker",
+				Extra: map[string]authv1.ExtraValue{
+					"authentication.kubernetes.io/pod-name": {"fanout-pod"},
+					"authentication.kubernetes.io/pod-uid":  {"fanout-pod-uid"},
+				},
+			},
+		}
+		return true, review, nil
+	})
+	base := reconciler.NewBase(ctx, controllerAgentName, configmap.NewStaticWatcher())
+	testingListers := NewListers(objects)
+	ls := listers{
+		brokerLister:    testingListers.GetBrokerLister(),
+		triggerLister:   testingListers.GetTriggerLister(),
+		configMapLister: testingListers.GetConfigMapLister(),
+		podLister:       testingListers.GetPodLister(),
+	}
+	r, err := NewReconciler(base, ls, configserver.NewServer(0, kubeClient, testingListers.GetPodLister()))
+	if err != nil {
+		t.Fatalf("Failed to create BrokerCell reconciler: %v", err)
+	}
+	ctx = addressable.WithDuck(ctx)
+	r.uriResolver = resolver.NewURIResolver(ctx, func(types.NamespacedName) {})
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	stream.RegisterTargetsWatcherServer(s, r.configServer.Server)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial targets config server: %v", err)
+	}
+	defer conn.Close()
+
+	if err := r.reconcileConfig(ctx, bc); err != nil {
+		t.Fatalf("Failed to reconcile config: %v", err)
+	}
+	// The watch gets the snapshot once all the shards were published.
+	watchCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer fanout-pod-token")
+	targets, err := stream.NewTargets(watchCtx, stream.NewTargetsWatcherClient(conn), stream.WithBrokerCell(testNS, brokerCellName), stream.WithPod("fanout-pod"))
+	if err != nil {
+		t.Fatalf("Failed to watch targets config: %v", err)
+	}
+	for _, ns := range []string{testNS, "ns"} {
+		if _, ok := targets.GetBroker(ns, "broker"); !ok {
+			t.Errorf("Broker %s/broker is missing from the streamed targets config", ns)
+		}
+	}
+	if _, ok := targets.GetTarget("ns", "broker", "trigger"); !ok {
+		t.Error("Trigger ns/broker/trigger is missing from the streamed targets config")
+	}
+
+	// The handlers which failed in the pods are reported in the status.
+	r.reconcileHandlersHealth(bc)
+	if c := bc.Status.GetCondition(intv1alpha1.BrokerCellConditionHandlers); c == nil || !c.IsTrue() {
+		t.Errorf("Unexpected handlers condition before any failure: %+v", c)
+	}
+	targets.(*stream.Targets).ReportFailedHandlers([]*stream.HandlerHealth{{Key: "ns/broker", LastError: "induced failure", Restarts: 1}})
+	deadline := time.Now().Add(5 * time.Second)
+	for r.configServer.HandlersHealthy(testNS, brokerCellName) == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.reconcileHandlersHealth(bc)
+	c := bc.Status.GetCondition(intv1alpha1.BrokerCellConditionHandlers)
+	if c == nil || !c.IsFalse() || !strings.Contains(c.Message, "fanout-pod: ns/broker: induced failure (restarted 1 times)") {
+		t.Errorf("Unexpected handlers condition after a failure: %+v", c)
+	}
+}
+
+func TestNamespaceRateLimits(t *testing.T) {
+	brokers := []*brokerv1beta1.Broker{
+		NewBroker("broker1", "ns1", WithBrokerAnnotation(brokerv1beta1.NamespaceIngressRateLimitAnnotation, "100")),
+		NewBroker("broker2", "ns1", WithBrokerAnnotation(brokerv1beta1.NamespaceIngressRateLimitAnnotation, "10,50")),
+		NewBroker("broker3", "ns1"),
+		NewBroker("broker4", "ns2", WithBrokerAnnotation(brokerv1beta1.NamespaceIngressRateLimitAnnotation, "invalid")),
+		NewBroker("broker5", "ns3", WithBrokerAnnotation(brokerv1beta1.NamespaceIngressRateLimitAnnotation, "20")),
+	}
+	got := namespaceRateLimits(logtesting.TestContextWithLogger(t), brokers)
+	want := map[string]*config.RateLimit{
+		"ns1": {EventsPerSecond: 10, Burst: 50},
+		"ns3": {EventsPerSecond: 20, Burst: 20},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Unexpected namespace rate limits (-want, +got): %s", diff)
+	}
+}
+
+func TestIngressAuthArgs(t *testing.T) {
+	r := &Reconciler{env: envConfig{
+		IngressImage:          "ingress",
+		IngressPort:           8080,
+		IngressAuthMode:       "oidc",
+		IngressAuthAudience:   "https://broker.example.com",
+		IngressAuthOIDCIssuer: "https://issuer.example.com",
+	}}
+	d := resources.MakeIngressDeployment(r.makeIngressArgs(NewBrokerCell(brokerCellName, testNS)))
+	got := make(map[string]string)
+	for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+		got[env.Name] = env.Value
+	}
+	want := map[string]string{
+		"AUTH_MODE":        "oidc",
+		"AUTH_AUDIENCE":    "https://broker.example.com",
+		"AUTH_OIDC_ISSUER": "https://issuer.example.com",
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("ingress env %s got=%q, want=%q", name, got[name], value)
+		}
+	}
+
+	// The defaults of the ingress apply when the auth settings aren't set.
+	r.env = envConfig{IngressImage: "ingress", IngressPort: 8080}
+	d = resources.MakeIngressDeployment(r.makeIngressArgs(NewBrokerCell(brokerCellName, testNS)))
+	for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+		if _, ok := want[env.Name]; ok {
+			t.Errorf("ingress env %s got=%q, want unset", env.Name, env.Value)
+		}
+	}
+}
+
+func TestClaimCheckArgs(t *testing.T) {
+	r := &Reconciler{env: envConfig{
+		IngressImage:    "ingress",
+		FanoutImage:     "fanout",
+		RetryImage:      "retry",
+		IngressPort:     8080,
+		ClaimCheckStore: "gs://bucket/claimcheck",
+		ClaimCheckTTL:   48 * time.Hour,
+	}}
+	bc := NewBrokerCell(brokerCellName, testNS)
+	deployments := map[string]*appsv1.Deployment{
+		"ingress": resources.MakeIngressDeployment(r.makeIngressArgs(bc)),
+		"fanout":  resources.MakeFanoutDeployment(r.makeFanoutArgs(bc)),
+		"retry":   resources.MakeRetryDeployment(r.makeRetryArgs(bc)),
+	}
+	for name, d := range deployments {
+		got := make(map[string]string)
+		for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+			got[env.Name] = env.Value
+		}
+		if got["CLAIM_CHECK_STORE"] != "gs://bucket/claimcheck" {
+			t.Errorf("%s env CLAIM_CHECK_STORE got=%q, want=%q", name, got["CLAIM_CHECK_STORE"], "gs://bucket/claimcheck")
+		}
+		// Only the ingress offloads payloads, so only it deletes them.
+		wantTTL := ""
+		if name == "ingress" {
+			wantTTL = "48h0m0s"
+		}
+		if got["CLAIM_CHECK_TTL"] != wantTTL {
+			t.Errorf("%s env CLAIM_CHECK_TTL got=%q, want=%q", name, got["CLAIM_CHECK_TTL"], wantTTL)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// targetsConfigServerAddress returns the address of the server streaming the targets
+// config, or empty if it is disabled.
+func (r *Reconciler) targetsConfigServerAddress() string {
+	if r.configServer == nil {
+		return ""
+	}
+	return r.configServer.Address()
+}
+
+// targetsConfigServerCA returns the PEM certificate of the CA of the server streaming
+// the targets config, or empty if it is disabled.
+func (r *Reconciler) targetsConfigServerCA() string {
+	if r.configServer == nil {
+		return ""
+	}
+	return string(r.configServer.CACert())
+}
+
+// publishTargetsConfig streams the shard of the targets config to the data plane pods of the
+// brokercell if the targets config server is enabled.
+func (r *Reconciler) publishTargetsConfig(bcKey string, shard int, brokerTargets config.Targets) {
+	if r.configServer == nil {
+		return
+	}
+	targets := &config.TargetsConfig{Brokers: make(map[string]*config.Broker)}
+	brokerTargets.RangeBrokers(func(b *config.Broker) bool {
+		targets.Brokers[b.Key()] = b
+		return true
+	})
+	r.configServer.UpdateShard(bcKey, shard, targets)
+}
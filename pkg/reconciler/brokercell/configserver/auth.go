@@ -0,0 +1,96 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configserver
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+)
+
+const (
+	serviceAccountPrefix = "system:serviceaccount:"
+	// The extra info of the tokens bound to a pod.
+	podNameExtraKey = "authentication.kubernetes.io/pod-name"
+	podUIDExtraKey  = "authentication.kubernetes.io/pod-uid"
+)
+
+// authorize implements stream.Authorizer. The caller must authenticate with a
+// service account token bound to the pod of the request, and the pod must be
+// a data plane pod of the brokercell of the request.
+func (s *Server) authorize(ctx context.Context, req *stream.WatchRequest) error {
+	token := stream.BearerToken(ctx)
+	if token == "" {
+		return status.Error(codes.Unauthenticated, "a service account token is required")
+	}
+	review, err := s.kubeClient.AuthenticationV1().TokenReviews().CreateContext(ctx, &authv1.TokenReview{
+		Spec: authv1.TokenReviewSpec{Token: token, Audiences: []string{stream.TokenAudience}},
+	})
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to review the token: %v", err)
+	}
+	if !review.Status.Authenticated || !contains(review.Status.Audiences, stream.TokenAudience) {
+		return status.Error(codes.Unauthenticated, "invalid service account token")
+	}
+
+	user := review.Status.User
+	namespace := strings.SplitN(strings.TrimPrefix(user.Username, serviceAccountPrefix), ":", 2)[0]
+	podName, podUID := user.Extra[podNameExtraKey], user.Extra[podUIDExtraKey]
+	if !strings.HasPrefix(user.Username, serviceAccountPrefix) || len(podName) != 1 || len(podUID) != 1 {
+		return status.Error(codes.PermissionDenied, "the token isn't bound to a pod")
+	}
+	if namespace != req.BrokercellNamespace || podName[0] != req.Pod {
+		return status.Errorf(codes.PermissionDenied, "the token of pod %s/%s can't watch as pod %s/%s", namespace, podName[0], req.BrokercellNamespace, req.Pod)
+	}
+	pod, err := s.pod(namespace, req.Pod)
+	if err != nil {
+		return status.Errorf(codes.PermissionDenied, "failed to get pod %s/%s: %v", namespace, req.Pod, err)
+	}
+	if string(pod.UID) != podUID[0] || !labels.SelectorFromSet(resources.CommonLabels(req.BrokercellName)).Matches(labels.Set(pod.Labels)) {
+		return status.Errorf(codes.PermissionDenied, "pod %s/%s isn't a data plane pod of the brokercell", namespace, req.Pod)
+	}
+	return nil
+}
+
+// pod gets a pod from the lister, or from the API server if the lister doesn't
+// have it yet.
+func (s *Server) pod(namespace, name string) (*corev1.Pod, error) {
+	pod, err := s.podLister.Pods(namespace).Get(name)
+	if apierrs.IsNotFound(err) {
+		return s.kubeClient.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+	}
+	return pod, err
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/eventing/pkg/logging"
+	"knative.dev/pkg/system"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+)
+
+const (
+	// certsSecretName is the name of the secret holding the certificates of the
+	// server in the namespace of the controller.
+	certsSecretName = "config-server-certs"
+	// renewBefore is how long before they expire the certificates are renewed.
+	renewBefore = 30 * 24 * time.Hour
+	// renewCheckPeriod is how often the certificates are checked for renewal.
+	renewCheckPeriod = 12 * time.Hour
+)
+
+// certs holds the serving certificate of the server and the PEM certificate of
+// its CA.
+type certs struct {
+	serving *tls.Certificate
+	ca      []byte
+}
+
+// loadCerts loads the certificates of the server from the secret. They are
+// created if the secret doesn't exist, and renewed if they are invalid or about
+// to expire.
+func (s *Server) loadCerts(ctx context.Context) (*certs, error) {
+	secrets := s.kubeClient.CoreV1().Secrets(system.Namespace())
+	secret, err := secrets.Get(certsSecretName, metav1.GetOptions{})
+	if apierrs.IsNotFound(err) {
+		secret, err = certresources.MakeSecret(ctx, certsSecretName, system.Namespace(), controllerServiceName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create targets config server certificates: %w", err)
+		}
+		secret, err = secrets.Create(secret)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get targets config server certificates: %w", err)
+	}
+	c, err := parseCerts(secret.Data)
+	if err == nil && time.Until(c.serving.Leaf.NotAfter) > renewBefore {
+		return c, nil
+	}
+
+	logging.FromContext(ctx).Info("Renewing targets config server certificates", zap.NamedError("reason", err))
+	fresh, err := certresources.MakeSecret(ctx, certsSecretName, system.Namespace(), controllerServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create targets config server certificates: %w", err)
+	}
+	secret = secret.DeepCopy()
+	secret.Data = fresh.Data
+	if secret, err = secrets.Update(secret); err != nil {
+		return nil, fmt.Errorf("failed to update targets config server certificates: %w", err)
+	}
+	return parseCerts(secret.Data)
+}
+
+// parseCerts parses the certificates of the data of the secret.
+func parseCerts(data map[string][]byte) (*certs, error) {
+	serving, err := tls.X509KeyPair(data[certresources.ServerCert], data[certresources.ServerKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid serving certificate: %w", err)
+	}
+	if serving.Leaf, err = x509.ParseCertificate(serving.Certificate[0]); err != nil {
+		return nil, fmt.Errorf("invalid serving certificate: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data[certresources.CACert]) {
+		return nil, fmt.Errorf("invalid CA certificate")
+	}
+	return &certs{serving: &serving, ca: data[certresources.CACert]}, nil
+}
+
+// renewCerts renews the certificates periodically until the context is done.
+func (s *Server) renewCerts(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(renewCheckPeriod):
+		}
+		c, err := s.loadCerts(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to renew targets config server certificates", zap.Error(err))
+			continue
+		}
+		s.certs.Store(c)
+	}
+}
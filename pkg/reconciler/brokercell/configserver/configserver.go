@@ -0,0 +1,267 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package configserver provides the server streaming the targets config of the
+// brokercells to their data plane pods, shared by the BrokerCell, Broker and
+// Trigger controllers.
+package configserver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"knative.dev/eventing/pkg/logging"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	podinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/pod"
+	"knative.dev/pkg/system"
+
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+)
+
+// controllerServiceName is the name of the service of the controller serving the targets config.
+const controllerServiceName = "controller"
+
+// connectGracePeriod is how long a started data plane pod is given to watch
+// the targets config before it's waited for.
+const connectGracePeriod = 30 * time.Second
+
+type envConfig struct {
+	// Port is the port of the server. The server is disabled if it is 0.
+	Port int `envconfig:"CONFIG_SERVER_PORT"`
+}
+
+// Server streams the targets config of the brokercells and tracks the versions
+// applied by their data plane pods. It serves over TLS, and only lets the data
+// plane pods of a brokercell watch its targets config.
+type Server struct {
+	*stream.Server
+	port       int
+	kubeClient kubernetes.Interface
+	podLister  corev1listers.PodLister
+	// certs holds the *certs of the server once started.
+	certs atomic.Value
+}
+
+// NewServer creates a Server listening on the given port once started. The pods
+// of the brokercells are listed with the given lister.
+func NewServer(port int, kubeClient kubernetes.Interface, podLister corev1listers.PodLister) *Server {
+	s := &Server{
+		port:       port,
+		kubeClient: kubeClient,
+		podLister:  podLister,
+	}
+	s.Server = stream.NewServer(resources.TargetsConfigShards, s.authorize)
+	return s
+}
+
+// Start serves the targets config until the context is done.
+func (s *Server) Start(ctx context.Context) error {
+	c, err := s.loadCerts(ctx)
+	if err != nil {
+		return err
+	}
+	s.certs.Store(c)
+	go s.renewCerts(ctx)
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on targets config server port: %w", err)
+	}
+	gs := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return s.certs.Load().(*certs).serving, nil
+		},
+	})))
+	stream.RegisterTargetsWatcherServer(gs, s.Server)
+	go func() {
+		if err := gs.Serve(lis); err != nil {
+			logging.FromContext(ctx).Error("Targets config server stopped", zap.Error(err))
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		gs.Stop()
+	}()
+	return nil
+}
+
+// Address returns the address of the server for the data plane pods.
+func (s *Server) Address() string {
+	return fmt.Sprintf("%s.%s.svc:%d", controllerServiceName, system.Namespace(), s.port)
+}
+
+// CACert returns the PEM certificate of the CA of the server for the data plane
+// pods, or nil if the server isn't started.
+func (s *Server) CACert() []byte {
+	if c, ok := s.certs.Load().(*certs); ok {
+		return c.ca
+	}
+	return nil
+}
+
+// BrokerApplied returns nil if all the running pods of the brokercell applied the
+// latest change of the broker, or an error explaining why not.
+func (s *Server) BrokerApplied(bcNamespace, bcName, brokerKey string) error {
+	_, version, ok := s.Broker(bcNamespace+"/"+bcName, brokerKey)
+	if !ok {
+		return fmt.Errorf("the targets config doesn't have the broker yet")
+	}
+	return s.applied(bcNamespace, bcName, version)
+}
+
+// TargetApplied returns nil if all the running pods of the brokercell applied the
+// latest change of the broker of the target and have the target, or an error
+// explaining why not.
+func (s *Server) TargetApplied(bcNamespace, bcName, brokerKey, targetName string) error {
+	b, version, ok := s.Broker(bcNamespace+"/"+bcName, brokerKey)
+	if !ok {
+		return fmt.Errorf("the targets config doesn't have the broker yet")
+	}
+	if _, ok := b.Targets[targetName]; !ok {
+		return fmt.Errorf("the targets config doesn't have the trigger yet")
+	}
+	return s.applied(bcNamespace, bcName, version)
+}
+
+// TargetReplayed returns true if all the running retry pods of the brokercell
+// reported the replay of the target caught up, that is they replayed nothing
+// for a quiet period.
+func (s *Server) TargetReplayed(bcNamespace, bcName, targetKey string) bool {
+	pods, err := s.podLister.Pods(bcNamespace).List(labels.SelectorFromSet(resources.Labels(bcName, resources.RetryName)))
+	if err != nil {
+		return false
+	}
+	replayed := s.ReplayedPods(bcNamespace+"/"+bcName, targetKey)
+	running := 0
+	for _, p := range pods {
+		if p.DeletionTimestamp != nil || p.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		running++
+		if !replayed[p.Name] {
+			return false
+		}
+	}
+	return running > 0
+}
+
+// HandlersHealthy returns nil if no pod of the brokercell reported failed
+// handlers, or an error listing them.
+func (s *Server) HandlersHealthy(bcNamespace, bcName string) error {
+	var failed []string
+	for pod, handlers := range s.FailedHandlers(bcNamespace + "/" + bcName) {
+		for _, h := range handlers {
+			failed = append(failed, fmt.Sprintf("%s: %s: %s (restarted %d times)", pod, h.Key, h.LastError, h.Restarts))
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("handlers failed in the data plane pods: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// applied returns nil if all the running pods of the brokercell applied at least
+// the given version. The pods which aren't ready or started within the connect
+// grace period are only counted once they applied it.
+func (s *Server) applied(bcNamespace, bcName string, version int64) error {
+	pods, err := s.podLister.Pods(bcNamespace).List(labels.SelectorFromSet(resources.CommonLabels(bcName)))
+	if err != nil {
+		return fmt.Errorf("failed to list the data plane pods: %w", err)
+	}
+	applied := s.AppliedVersions(bcNamespace + "/" + bcName)
+	running := 0
+	var behind []string
+	for _, p := range pods {
+		if p.DeletionTimestamp != nil || p.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if v, ok := applied[p.Name]; ok && v >= version {
+			running++
+			continue
+		}
+		if !waitedFor(p) {
+			continue
+		}
+		running++
+		behind = append(behind, p.Name)
+	}
+	if running == 0 {
+		return fmt.Errorf("no data plane pods are running")
+	}
+	if len(behind) > 0 {
+		sort.Strings(behind)
+		return fmt.Errorf("data plane pods %s haven't applied the targets config generation %d", strings.Join(behind, ", "), version)
+	}
+	return nil
+}
+
+// waitedFor returns true if the pod is ready and started before the connect
+// grace period.
+func waitedFor(p *corev1.Pod) bool {
+	if p.Status.StartTime == nil || time.Since(p.Status.StartTime.Time) < connectGracePeriod {
+		return false
+	}
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Singleton holds the Server shared by the controllers.
+type Singleton struct {
+	setup  sync.Once
+	server *Server
+}
+
+// Server returns the shared Server, or nil if it's disabled. The Server is
+// created and started the first time it's called.
+func (s *Singleton) Server(ctx context.Context) *Server {
+	s.setup.Do(func() {
+		logger := logging.FromContext(ctx)
+		var env envConfig
+		if err := envconfig.Process("BROKER_CELL", &env); err != nil {
+			logger.Fatal("Failed to process targets config server env", zap.Error(err))
+		}
+		if env.Port <= 0 {
+			return
+		}
+		s.server = NewServer(env.Port, kubeclient.Get(ctx), podinformer.Get(ctx).Lister())
+		if err := s.server.Start(ctx); err != nil {
+			logger.Fatal("Failed to start targets config server", zap.Error(err))
+		}
+	})
+	return s.server
+}
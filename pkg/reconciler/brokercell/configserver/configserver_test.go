@@ -0,0 +1,398 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	"knative.dev/pkg/system"
+	_ "knative.dev/pkg/system/testing"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+	testingListers "github.com/google/knative-gcp/pkg/reconciler/testing"
+)
+
+const (
+	bcNamespace = "bc-ns"
+	bcName      = "bc"
+	brokerKey   = "ns/broker"
+)
+
+// pod creates a pod of the brokercell, ready and started before the connect
+// grace period if it's running.
+func pod(name string, phase corev1.PodPhase) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: bcNamespace,
+			Name:      name,
+			UID:       types.UID(name + "-uid"),
+			Labels:    resources.CommonLabels(bcName),
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+	if phase == corev1.PodRunning {
+		started := metav1.NewTime(time.Now().Add(-time.Hour))
+		p.Status.StartTime = &started
+		p.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	}
+	return p
+}
+
+// newServer creates a Server with the given pods. The token reviews accept the
+// tokens formatted as namespace/pod, bound to the pod.
+func newServer(pods ...runtime.Object) *Server {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		review := action.(clientgotesting.CreateAction).GetObject().(*authv1.TokenReview)
+		parts := strings.Split(review.Spec.Token, "/")
+		if len(parts) != 2 {
+			return true, review, nil
+		}
+		review.Status = authv1.TokenReviewStatus{
+			Authenticated: true,
+			Audiences:     review.Spec.Audiences,
+			User: authv1.UserInfo{
+				Username: "system:serviceaccount:" + parts[0] + ":broker",
+				Extra: map[string]authv1.ExtraValue{
+					podNameExtraKey: {parts[1]},
+					podUIDExtraKey:  {parts[1] + "-uid"},
+				},
+			},
+		}
+		return true, review, nil
+	})
+	ls := testingListers.NewListers(pods)
+	return NewServer(0, client, ls.GetPodLister())
+}
+
+// publish publishes all the shards of the targets config, with the broker in the first one.
+func publish(s *Server, b *config.Broker) {
+	for i := 0; i < resources.TargetsConfigShards; i++ {
+		targets := &config.TargetsConfig{Brokers: make(map[string]*config.Broker)}
+		if i == 0 {
+			targets.Brokers[b.Key()] = b
+		}
+		s.UpdateShard(bcNamespace+"/"+bcName, i, targets)
+	}
+}
+
+// watch starts a data plane pod watching the targets config from the server.
+func watch(ctx context.Context, t *testing.T, s *Server, podName string) *stream.Targets {
+	t.Helper()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+bcNamespace+"/"+podName)
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	stream.RegisterTargetsWatcherServer(gs, s.Server)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial targets config server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	targets, err := stream.NewTargets(ctx, stream.NewTargetsWatcherClient(conn), stream.WithBrokerCell(bcNamespace, bcName), stream.WithPod(podName))
+	if err != nil {
+		t.Fatalf("Failed to watch targets config: %v", err)
+	}
+	return targets.(*stream.Targets)
+}
+
+func waitForApplied(t *testing.T, f func() error) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := f()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Targets config not applied: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForAck waits until the pod acknowledged the targets config.
+func waitForAck(t *testing.T, s *Server, podName string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if s.AppliedVersions(bcNamespace + "/" + bcName)[podName] > 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Pod %s didn't acknowledge the targets config", podName)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func assertError(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Unexpected error, got %v, want containing %q", err, want)
+	}
+}
+
+func TestApplied(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newServer(
+		pod("pod-1", corev1.PodRunning),
+		pod("pod-2", corev1.PodRunning),
+		pod("pod-3", corev1.PodPending),
+	)
+	b := &config.Broker{Namespace: "ns", Name: "broker", Targets: map[string]*config.Target{
+		"trigger": {Namespace: "ns", Name: "trigger", Broker: "broker"},
+	}}
+
+	assertError(t, s.BrokerApplied(bcNamespace, bcName, brokerKey), "doesn't have the broker yet")
+
+	publish(s, b)
+	watch(ctx, t, s, "pod-1")
+	waitForAck(t, s, "pod-1")
+	assertError(t, s.BrokerApplied(bcNamespace, bcName, brokerKey), "data plane pods pod-2 haven't applied")
+	assertError(t, s.TargetApplied(bcNamespace, bcName, brokerKey, "trigger"), "data plane pods pod-2 haven't applied")
+	assertError(t, s.TargetApplied(bcNamespace, bcName, brokerKey, "other"), "doesn't have the trigger yet")
+
+	// The pending pod-3 isn't waited for.
+	watch(ctx, t, s, "pod-2")
+	waitForApplied(t, func() error { return s.BrokerApplied(bcNamespace, bcName, brokerKey) })
+	waitForApplied(t, func() error { return s.TargetApplied(bcNamespace, bcName, brokerKey, "trigger") })
+}
+
+func TestAppliedConnectingPods(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	young := pod("pod-2", corev1.PodRunning)
+	started := metav1.NewTime(time.Now())
+	young.Status.StartTime = &started
+	unready := pod("pod-3", corev1.PodRunning)
+	unready.Status.Conditions[0].Status = corev1.ConditionFalse
+	s := newServer(pod("pod-1", corev1.PodRunning), young, unready)
+	publish(s, &config.Broker{Namespace: "ns", Name: "broker"})
+
+	// The pods still connecting aren't waited for.
+	watch(ctx, t, s, "pod-1")
+	waitForApplied(t, func() error { return s.BrokerApplied(bcNamespace, bcName, brokerKey) })
+}
+
+func TestAppliedNoRunningPods(t *testing.T) {
+	s := newServer(pod("pod-1", corev1.PodPending))
+	publish(s, &config.Broker{Namespace: "ns", Name: "broker"})
+	assertError(t, s.BrokerApplied(bcNamespace, bcName, brokerKey), "no data plane pods are running")
+}
+
+func TestHandlersHealthy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newServer(pod("pod-1", corev1.PodRunning))
+	publish(s, &config.Broker{Namespace: "ns", Name: "broker"})
+	targets := watch(ctx, t, s, "pod-1")
+	waitForAck(t, s, "pod-1")
+	if err := s.HandlersHealthy(bcNamespace, bcName); err != nil {
+		t.Errorf("HandlersHealthy got unexpected error: %v", err)
+	}
+
+	targets.ReportFailedHandlers([]*stream.HandlerHealth{{Key: brokerKey, LastError: "subscription not found", Restarts: 2}})
+	waitForApplied(t, func() error {
+		if s.HandlersHealthy(bcNamespace, bcName) == nil {
+			return errors.New("failed handlers not reported yet")
+		}
+		return nil
+	})
+	assertError(t, s.HandlersHealthy(bcNamespace, bcName), "pod-1: ns/broker: subscription not found (restarted 2 times)")
+
+	targets.ReportFailedHandlers(nil)
+	waitForApplied(t, func() error { return s.HandlersHealthy(bcNamespace, bcName) })
+}
+
+func TestAuthorize(t *testing.T) {
+	other := pod("other", corev1.PodRunning)
+	other.Labels = resources.CommonLabels("other-bc")
+	s := newServer(pod("pod-1", corev1.PodRunning), other)
+	req := &stream.WatchRequest{BrokercellNamespace: bcNamespace, BrokercellName: bcName, Pod: "pod-1"}
+
+	tests := []struct {
+		name  string
+		token string
+		req   *stream.WatchRequest
+		want  codes.Code
+	}{{
+		name:  "pod of the brokercell",
+		token: bcNamespace + "/pod-1",
+		req:   req,
+		want:  codes.OK,
+	}, {
+		name: "no token",
+		req:  req,
+		want: codes.Unauthenticated,
+	}, {
+		name:  "invalid token",
+		token: "invalid",
+		req:   req,
+		want:  codes.Unauthenticated,
+	}, {
+		name:  "token of another pod",
+		token: bcNamespace + "/other",
+		req:   req,
+		want:  codes.PermissionDenied,
+	}, {
+		name:  "token of another namespace",
+		token: "ns/pod-1",
+		req:   req,
+		want:  codes.PermissionDenied,
+	}, {
+		name:  "pod of another brokercell",
+		token: bcNamespace + "/other",
+		req:   &stream.WatchRequest{BrokercellNamespace: bcNamespace, BrokercellName: bcName, Pod: "other"},
+		want:  codes.PermissionDenied,
+	}, {
+		name:  "missing pod",
+		token: bcNamespace + "/deleted",
+		req:   &stream.WatchRequest{BrokercellNamespace: bcNamespace, BrokercellName: bcName, Pod: "deleted"},
+		want:  codes.PermissionDenied,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+tc.token))
+			}
+			if got := status.Code(s.authorize(ctx, tc.req)); got != tc.want {
+				t.Errorf("authorize got code %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadCerts(t *testing.T) {
+	ctx := context.Background()
+	s := newServer()
+	created, err := s.loadCerts(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create certificates: %v", err)
+	}
+	loaded, err := s.loadCerts(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+	if !bytes.Equal(created.ca, loaded.ca) {
+		t.Error("Certificates were created again instead of loaded from the secret")
+	}
+
+	// The certificates about to expire are renewed.
+	key, cert, ca, err := certresources.CreateCerts(ctx, controllerServiceName, system.Namespace(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create certificates: %v", err)
+	}
+	secrets := s.kubeClient.CoreV1().Secrets(system.Namespace())
+	secret, err := secrets.Get(certsSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get certificates secret: %v", err)
+	}
+	secret.Data = map[string][]byte{certresources.ServerKey: key, certresources.ServerCert: cert, certresources.CACert: ca}
+	if _, err := secrets.Update(secret); err != nil {
+		t.Fatalf("Failed to update certificates secret: %v", err)
+	}
+	renewed, err := s.loadCerts(ctx)
+	if err != nil {
+		t.Fatalf("Failed to renew certificates: %v", err)
+	}
+	if bytes.Equal(renewed.ca, ca) || time.Until(renewed.serving.Leaf.NotAfter) < renewBefore {
+		t.Errorf("Certificates expiring at %v were not renewed", renewed.serving.Leaf.NotAfter)
+	}
+}
+
+func TestTargetReplayed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	retryPod := func(name string, phase corev1.PodPhase) *corev1.Pod {
+		p := pod(name, phase)
+		p.Labels = resources.Labels(bcName, resources.RetryName)
+		return p
+	}
+	s := newServer(
+		retryPod("retry-1", corev1.PodRunning),
+		retryPod("retry-2", corev1.PodRunning),
+		retryPod("retry-3", corev1.PodPending),
+		pod("fanout", corev1.PodRunning),
+	)
+	publish(s, &config.Broker{Namespace: "ns", Name: "broker"})
+	const targetKey = "ns/broker/trigger"
+	replayed := func() error {
+		if !s.TargetReplayed(bcNamespace, bcName, targetKey) {
+			return errors.New("replay not caught up yet")
+		}
+		return nil
+	}
+
+	retry1 := watch(ctx, t, s, "retry-1")
+	retry2 := watch(ctx, t, s, "retry-2")
+	waitForAck(t, s, "retry-1")
+	waitForAck(t, s, "retry-2")
+	retry1.ReportReplayed(targetKey, true)
+	time.Sleep(100 * time.Millisecond)
+	if s.TargetReplayed(bcNamespace, bcName, targetKey) {
+		t.Error("TargetReplayed got true before all the running retry pods caught up")
+	}
+
+	// The pending retry pod and the fanout pod aren't waited for.
+	retry2.ReportReplayed(targetKey, true)
+	waitForApplied(t, replayed)
+
+	// An event replayed again withdraws the report.
+	retry1.ReportReplayed(targetKey, false)
+	waitForApplied(t, func() error {
+		if replayed() == nil {
+			return errors.New("replay still caught up")
+		}
+		return nil
+	})
+}
+
+func TestTargetReplayedNoRunningPods(t *testing.T) {
+	p := pod("retry-1", corev1.PodPending)
+	p.Labels = resources.Labels(bcName, resources.RetryName)
+	s := newServer(p)
+	if s.TargetReplayed(bcNamespace, bcName, "ns/broker/trigger") {
+		t.Error("TargetReplayed got true without running retry pods")
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"go.uber.org/zap"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	brokerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1beta1/broker"
 	triggerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1beta1/trigger"
 	brokercellinformer "github.com/google/knative-gcp/pkg/client/injection/informers/intevents/v1alpha1/brokercell"
@@ -31,7 +33,9 @@ import (
 	v1alpha1brokercell "github.com/google/knative-gcp/pkg/client/injection/reconciler/intevents/v1alpha1/brokercell"
 	"github.com/google/knative-gcp/pkg/reconciler"
 	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/configserver"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/eventing/pkg/logging"
@@ -42,6 +46,9 @@ import (
 	serviceinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/service"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/resolver"
 )
 
 const (
@@ -50,10 +57,21 @@ const (
 	controllerAgentName = "brokercell-controller"
 )
 
-// NewController creates a Reconciler for BrokerCell and returns the result of NewImpl.
-func NewController(
+type Constructor injection.ControllerConstructor
+
+// NewConstructor creates a constructor to make a BrokerCell controller.
+func NewConstructor(css *configserver.Singleton, gcpas *gcpauth.StoreSingleton) Constructor {
+	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+		return newController(ctx, cmw, css.Server(ctx), gcpas.Store(ctx, cmw))
+	}
+}
+
+// newController creates a Reconciler for BrokerCell and returns the result of NewImpl.
+func newController(
 	ctx context.Context,
 	cmw configmap.Watcher,
+	configServer *configserver.Server,
+	gcpas *gcpauth.Store,
 ) *controller.Impl {
 	logger := logging.FromContext(ctx)
 
@@ -69,19 +87,46 @@ func NewController(
 	}
 
 	base := reconciler.NewBase(ctx, controllerAgentName, cmw)
-	r, err := NewReconciler(base, ls)
+	r, err := NewReconciler(base, ls, configServer)
 	if err != nil {
 		logger.Fatal("Failed to create BrokerCell reconciler", zap.Error(err))
 	}
-	impl := v1alpha1brokercell.NewImpl(ctx, r)
+	// The triggers read the delivery service accounts and audiences allowed in their namespace
+	// from the GCP auth defaults.
+	impl := v1alpha1brokercell.NewImpl(ctx, r, func(*controller.Impl) controller.Options {
+		return controller.Options{ConfigStore: gcpas}
+	})
+	r.uriResolver = resolver.NewURIResolver(ctx, func(key types.NamespacedName) {
+		// The key is the broker that owns the dead letter sink.
+		r.shards.namespaceChanged(key.Namespace)
+		// TODO(#866) Select the brokercell that's associated with the given broker.
+		impl.EnqueueKey(types.NamespacedName{Namespace: key.Namespace, Name: brokerresources.DefaultBrokerCellName})
+	})
 
 	logger.Info("Setting up event handlers.")
 
-	brokercellinformer.Get(ctx).Informer().AddEventHandlerWithResyncPeriod(controller.HandleAll(impl.Enqueue), reconciler.DefaultResyncPeriod)
+	brokercellinformer.Get(ctx).Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
+		AddFunc: impl.Enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Rebuild the whole targets config on periodic resyncs in case a shard was modified.
+			if oldObj.(*intv1alpha1.BrokerCell).ResourceVersion == newObj.(*intv1alpha1.BrokerCell).ResourceVersion {
+				if key, err := cache.MetaNamespaceKeyFunc(newObj); err == nil {
+					r.shards.reset(key)
+				}
+			}
+			impl.Enqueue(newObj)
+		},
+		DeleteFunc: impl.Enqueue,
+	}, reconciler.DefaultResyncPeriod)
 
 	// Watch brokers and triggers to invoke configmap update immediately.
 	brokerinformer.Get(ctx).Informer().AddEventHandler(controller.HandleAll(
 		func(obj interface{}) {
+			// Deleted objects may be tombstones, the namespace of the broker is enough to
+			// find the shard of the targets config to rebuild.
+			if b, err := kmeta.DeletionHandlingAccessor(obj); err == nil {
+				r.shards.namespaceChanged(b.GetNamespace())
+			}
 			if b, ok := obj.(*brokerv1beta1.Broker); ok {
 				// TODO(#866) Select the brokercell that's associated with the given broker.
 				impl.EnqueueKey(types.NamespacedName{Namespace: b.Namespace, Name: brokerresources.DefaultBrokerCellName})
@@ -90,6 +135,9 @@ func NewController(
 	))
 	triggerinformer.Get(ctx).Informer().AddEventHandler(controller.HandleAll(
 		func(obj interface{}) {
+			if t, err := kmeta.DeletionHandlingAccessor(obj); err == nil {
+				r.shards.namespaceChanged(t.GetNamespace())
+			}
 			if t, ok := obj.(*brokerv1beta1.Trigger); ok {
 				b, err := brokerinformer.Get(ctx).Lister().Brokers(t.Namespace).Get(t.Spec.Broker)
 				if err != nil {
@@ -111,6 +159,31 @@ func NewController(
 	hpainformer.Get(ctx).Informer().AddEventHandler(handleResourceUpdate(impl))
 	// 4. Watch the broker targets configmap.
 	configmapinformer.Get(ctx).Informer().AddEventHandler(handleResourceUpdate(impl))
+	// Rewrite all the shards of the targets config if one of them was deleted.
+	// Rewrite the targets config of every brokercell when the allowed delivery authentications change.
+	cmw.Watch(gcpauth.ConfigMapName(), func(*corev1.ConfigMap) {
+		r.shards.resetAll()
+		impl.GlobalResync(brokercellinformer.Get(ctx).Informer())
+	})
+	configmapinformer.Get(ctx).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			cm, err := kmeta.DeletionHandlingAccessor(obj)
+			if err != nil || cm.GetLabels()["role"] != resources.TargetsConfigRole {
+				return
+			}
+			r.shards.reset(cm.GetNamespace() + "/" + cm.GetLabels()[resources.BrokerCellLabelKey])
+		},
+	})
+
+	if configServer != nil {
+		// Update the health of the handlers in the brokercell status once the data plane pods
+		// report a change.
+		configServer.OnHealthChanged(func(bcKey string) {
+			if namespace, name, err := cache.SplitMetaNamespaceKey(bcKey); err == nil {
+				impl.EnqueueKey(types.NamespacedName{Namespace: namespace, Name: name})
+			}
+		})
+	}
 
 	return impl
 }
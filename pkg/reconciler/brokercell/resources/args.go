@@ -18,6 +18,7 @@ package resources
 
 import (
 	"fmt"
+	"time"
 
 	"knative.dev/pkg/kmeta"
 
@@ -32,10 +33,24 @@ const (
 	// RetryName is the name used for the retry container.
 	RetryName          = "retry"
 	BrokerCellLabelKey = "brokerCell"
+
+	// adminSecretName is the name of the secret holding the token of the admin API of
+	// the fanout and retry pods in the namespace of the brokercell.
+	adminSecretName = "broker-admin"
+	adminSecretKey  = "token"
+
+	// targetsConfigTokenDir is the directory of the service account token of the data
+	// plane pods for the server streaming the targets config.
+	targetsConfigTokenDir = "/var/run/cloud-run-events/targets-config-server"
 )
 
 var (
-	optionalSecretVolume = true
+	optionalSecretVolume       = true
+	optionalTargetsConfigShard = true
+	// targetsConfigTokenExpiration is the lifetime of the service account token of the
+	// data plane pods for the server streaming the targets config. The kubelet rotates
+	// it before it expires.
+	targetsConfigTokenExpiration int64 = 3600
 )
 
 // Args are the common arguments to create a Broker's data plane Deployment.
@@ -45,12 +60,32 @@ type Args struct {
 	Image              string
 	ServiceAccountName string
 	MetricsPort        int
+	// TargetsConfigServer is the address of the server streaming the targets config,
+	// empty if the targets config is read from the volume.
+	TargetsConfigServer string
+	// TargetsConfigServerCA is the PEM certificate of the CA of the server streaming
+	// the targets config.
+	TargetsConfigServerCA string
+	// ClaimCheckStore is the URL of the store for the event payloads offloaded by
+	// the ingress, empty if payloads aren't offloaded.
+	ClaimCheckStore string
 }
 
 // IngressArgs are the arguments to create a Broker's ingress Deployment.
 type IngressArgs struct {
 	Args
 	Port int
+	// AuthMode is how the ingress authenticates requests: "none", "oidc" or
+	// "tokenreview". Empty means the default of the ingress.
+	AuthMode string
+	// AuthAudience is the audience the bearer tokens must have.
+	AuthAudience string
+	// AuthOIDCIssuer is the issuer of the OIDC tokens. Empty means the default
+	// of the ingress.
+	AuthOIDCIssuer string
+	// ClaimCheckTTL is how long the offloaded payloads are kept. Zero means the
+	// default of the ingress.
+	ClaimCheckTTL time.Duration
 }
 
 // FanoutArgs are the arguments to create a Broker's fanout Deployment.
@@ -30,19 +30,46 @@ import (
 const (
 	targetsCMName = "broker-targets"
 	targetsCMKey  = "targets"
+
+	// TargetsConfigRole is the role label of the ConfigMaps holding the targets config.
+	TargetsConfigRole = "broker-targets"
+
+	// TargetsConfigShards is the number of ConfigMaps the targets config is split into to stay
+	// below the size limit of ConfigMaps. The brokers are assigned to the shards by namespace.
+	// Only the first shard always exists, the others are only created if they have brokers.
+	TargetsConfigShards = 16
 )
 
-func MakeTargetsConfig(bc *intv1alpha1.BrokerCell, brokerTargets config.Targets) (*corev1.ConfigMap, error) {
+// TargetsConfigName returns the name of the ConfigMap holding a shard of the targets config.
+// The first shard keeps the name of the ConfigMap from before the config was sharded.
+func TargetsConfigName(brokerCellName string, shard int) string {
+	if shard == 0 {
+		return Name(brokerCellName, targetsCMName)
+	}
+	return Name(brokerCellName, fmt.Sprintf("%s-%d", targetsCMName, shard))
+}
+
+// targetsConfigPath returns the path of a shard of the targets config in the
+// broker config volume. The data plane merges the shards next to the first one.
+func targetsConfigPath(shard int) string {
+	if shard == 0 {
+		return targetsCMKey
+	}
+	return fmt.Sprintf("%s-%d", targetsCMKey, shard)
+}
+
+// MakeTargetsConfig creates the ConfigMap holding a shard of the targets config.
+func MakeTargetsConfig(bc *intv1alpha1.BrokerCell, shard int, brokerTargets config.Targets) (*corev1.ConfigMap, error) {
 	data, err := brokerTargets.Bytes()
 	if err != nil {
 		return nil, fmt.Errorf("error serializing targets config: %w", err)
 	}
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            Name(bc.Name, targetsCMName),
+			Name:            TargetsConfigName(bc.Name, shard),
 			Namespace:       bc.Namespace,
 			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(bc)},
-			Labels:          Labels(bc.Name, "broker-targets"),
+			Labels:          Labels(bc.Name, TargetsConfigRole),
 		},
 		BinaryData: map[string][]byte{targetsCMKey: data},
 		// Write out the text version for debugging purposes only
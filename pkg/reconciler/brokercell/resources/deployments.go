@@ -27,6 +27,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/system"
+
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 )
 
 // MakeIngressDeployment creates the ingress Deployment object.
@@ -34,11 +36,23 @@ func MakeIngressDeployment(args IngressArgs) *appsv1.Deployment {
 	container := containerTemplate(args.Args)
 	// Decorate the container template with ingress port.
 	container.Env = append(container.Env, corev1.EnvVar{Name: "PORT", Value: strconv.Itoa(args.Port)})
+	for _, env := range []corev1.EnvVar{
+		{Name: "AUTH_MODE", Value: args.AuthMode},
+		{Name: "AUTH_AUDIENCE", Value: args.AuthAudience},
+		{Name: "AUTH_OIDC_ISSUER", Value: args.AuthOIDCIssuer},
+	} {
+		if env.Value != "" {
+			container.Env = append(container.Env, env)
+		}
+	}
+	if args.ClaimCheckTTL > 0 {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "CLAIM_CHECK_TTL", Value: args.ClaimCheckTTL.String()})
+	}
 	container.Ports = append(container.Ports, corev1.ContainerPort{Name: "http", ContainerPort: int32(args.Port)})
 	container.ReadinessProbe = &corev1.Probe{
 		Handler: corev1.Handler{
 			HTTPGet: &corev1.HTTPGetAction{
-				Path:   "/healthz",
+				Path:   "/readyz",
 				Port:   intstr.FromInt(args.Port),
 				Scheme: corev1.URISchemeHTTP,
 			},
@@ -95,7 +109,21 @@ func MakeFanoutDeployment(args FanoutArgs) *appsv1.Deployment {
 	container.Env = append(container.Env, corev1.EnvVar{
 		Name:  "MAX_CONCURRENCY_PER_EVENT",
 		Value: "100",
-	})
+	}, adminTokenEnv())
+	// The pod isn't ready once all its handlers failed.
+	container.ReadinessProbe = &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   "/readyz",
+				Port:   intstr.FromInt(handler.DefaultHealthCheckPort),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+		FailureThreshold: 3,
+		PeriodSeconds:    5,
+		SuccessThreshold: 1,
+		TimeoutSeconds:   5,
+	}
 	container.LivenessProbe = &corev1.Probe{
 		Handler: corev1.Handler{
 			HTTPGet: &corev1.HTTPGetAction{
@@ -131,6 +159,21 @@ func MakeRetryDeployment(args RetryArgs) *appsv1.Deployment {
 			ContainerPort: handler.DefaultHealthCheckPort,
 		},
 	)
+	container.Env = append(container.Env, adminTokenEnv())
+	// The pod isn't ready once all its handlers failed.
+	container.ReadinessProbe = &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   "/readyz",
+				Port:   intstr.FromInt(handler.DefaultHealthCheckPort),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+		FailureThreshold: 3,
+		PeriodSeconds:    5,
+		SuccessThreshold: 1,
+		TimeoutSeconds:   5,
+	}
 	container.LivenessProbe = &corev1.Probe{
 		Handler: corev1.Handler{
 			HTTPGet: &corev1.HTTPGetAction{
@@ -148,8 +191,49 @@ func MakeRetryDeployment(args RetryArgs) *appsv1.Deployment {
 	return deploymentTemplate(args.Args, []corev1.Container{container})
 }
 
+// adminTokenEnv returns the env var of the token of the admin API of the fanout and retry
+// pods. The admin API is disabled unless the token is set in the admin secret.
+func adminTokenEnv() corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: "ADMIN_TOKEN",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: adminSecretName},
+				Key:                  adminSecretKey,
+				Optional:             &optionalSecretVolume,
+			},
+		},
+	}
+}
+
 // deploymentTemplate creates a template for data plane deployments.
 func deploymentTemplate(args Args, containers []corev1.Container) *appsv1.Deployment {
+	volumes := []corev1.Volume{
+		{
+			Name:         "broker-config",
+			VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: targetsConfigSources(args.BrokerCell.Name)}},
+		},
+		{
+			Name:         "google-broker-key",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "google-broker-key", Optional: &optionalSecretVolume}},
+		},
+	}
+	if args.TargetsConfigServer != "" {
+		// The pods authenticate to the server streaming the targets config with a token
+		// bound to them.
+		volumes = append(volumes, corev1.Volume{
+			Name: "targets-config-server-token",
+			VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{
+					ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+						Audience:          stream.TokenAudience,
+						ExpirationSeconds: &targetsConfigTokenExpiration,
+						Path:              "token",
+					},
+				}},
+			}},
+		})
+	}
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace:       args.BrokerCell.Namespace,
@@ -163,26 +247,37 @@ func deploymentTemplate(args Args, containers []corev1.Container) *appsv1.Deploy
 				ObjectMeta: metav1.ObjectMeta{Labels: Labels(args.BrokerCell.Name, args.ComponentName)},
 				Spec: corev1.PodSpec{
 					ServiceAccountName: args.ServiceAccountName,
-					Volumes: []corev1.Volume{
-						{
-							Name:         "broker-config",
-							VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: Name(args.BrokerCell.Name, targetsCMName)}}},
-						},
-						{
-							Name:         "google-broker-key",
-							VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "google-broker-key", Optional: &optionalSecretVolume}},
-						},
-					},
-					Containers: containers,
+					Volumes:            volumes,
+					Containers:         containers,
 				},
 			},
 		},
 	}
 }
 
+// targetsConfigSources returns the volume sources of the shards of the targets config.
+// The shards other than the first one are optional, they only exist if they have brokers.
+func targetsConfigSources(brokerCellName string) []corev1.VolumeProjection {
+	sources := []corev1.VolumeProjection{{
+		ConfigMap: &corev1.ConfigMapProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: TargetsConfigName(brokerCellName, 0)},
+		},
+	}}
+	for i := 1; i < TargetsConfigShards; i++ {
+		sources = append(sources, corev1.VolumeProjection{
+			ConfigMap: &corev1.ConfigMapProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: TargetsConfigName(brokerCellName, i)},
+				Items:                []corev1.KeyToPath{{Key: targetsCMKey, Path: targetsConfigPath(i)}},
+				Optional:             &optionalTargetsConfigShard,
+			},
+		})
+	}
+	return sources
+}
+
 // containerTemplate returns a common template for broker data plane containers.
 func containerTemplate(args Args) corev1.Container {
-	return corev1.Container{
+	container := corev1.Container{
 		Image: args.Image,
 		Name:  args.ComponentName,
 		Env: []corev1.EnvVar{
@@ -233,4 +328,23 @@ func containerTemplate(args Args) corev1.Container {
 			},
 		},
 	}
+	if args.ClaimCheckStore != "" {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "CLAIM_CHECK_STORE", Value: args.ClaimCheckStore})
+	}
+	if args.TargetsConfigServer != "" {
+		// Stream the targets config from the controller. The volume is read until the
+		// first snapshot is streamed.
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: "TARGETS_CONFIG_SERVER", Value: args.TargetsConfigServer},
+			corev1.EnvVar{Name: "TARGETS_CONFIG_SERVER_CA", Value: args.TargetsConfigServerCA},
+			corev1.EnvVar{Name: "BROKER_CELL_NAMESPACE", Value: args.BrokerCell.Namespace},
+			corev1.EnvVar{Name: "BROKER_CELL_NAME", Value: args.BrokerCell.Name},
+		)
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "targets-config-server-token",
+			MountPath: targetsConfigTokenDir,
+			ReadOnly:  true,
+		})
+	}
+	return container
 }
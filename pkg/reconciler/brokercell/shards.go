@@ -0,0 +1,85 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"sync"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+)
+
+// shardTracker tracks the shards of the targets config with brokers or triggers that changed
+// since the shards were last written, so that only those shards are rebuilt.
+type shardTracker struct {
+	mux sync.Mutex
+	// versions holds a counter per shard incremented each time one of its brokers or triggers changes.
+	versions [resources.TargetsConfigShards]uint64
+	// written holds the versions of the shards last written for each brokercell. A brokercell
+	// without entry has all its shards rebuilt.
+	written map[string]map[int]uint64
+}
+
+func newShardTracker() *shardTracker {
+	return &shardTracker{written: make(map[string]map[int]uint64)}
+}
+
+// namespaceChanged marks the shard of the brokers of the namespace as changed.
+func (t *shardTracker) namespaceChanged(namespace string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.versions[config.ShardIndex(namespace, resources.TargetsConfigShards)]++
+}
+
+// reset marks all the shards of the brokercell as changed.
+func (t *shardTracker) reset(bcKey string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	delete(t.written, bcKey)
+}
+
+// resetAll marks all the shards of every brokercell as changed.
+func (t *shardTracker) resetAll() {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.written = make(map[string]map[int]uint64)
+}
+
+// dirty returns the current versions of the shards of the brokercell that changed since they
+// were last written.
+func (t *shardTracker) dirty(bcKey string) map[int]uint64 {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	dirty := make(map[int]uint64)
+	written := t.written[bcKey]
+	for shard, v := range t.versions {
+		if w, ok := written[shard]; !ok || w != v {
+			dirty[shard] = v
+		}
+	}
+	return dirty
+}
+
+// synced records that the shard of the brokercell was written at the version.
+func (t *shardTracker) synced(bcKey string, shard int, version uint64) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if _, ok := t.written[bcKey]; !ok {
+		t.written[bcKey] = make(map[int]uint64)
+	}
+	t.written[bcKey][shard] = version
+}
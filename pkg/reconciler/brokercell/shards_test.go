@@ -0,0 +1,64 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+)
+
+func TestShardTracker(t *testing.T) {
+	tracker := newShardTracker()
+	const bc1, bc2 = "ns/bc1", "ns/bc2"
+	shard := config.ShardIndex("ns", resources.TargetsConfigShards)
+
+	// All shards are dirty until they are written.
+	dirty := tracker.dirty(bc1)
+	if len(dirty) != resources.TargetsConfigShards {
+		t.Errorf("dirty shards of new brokercell got=%d, want=%d", len(dirty), resources.TargetsConfigShards)
+	}
+	for s, v := range dirty {
+		tracker.synced(bc1, s, v)
+	}
+	if dirty := tracker.dirty(bc1); len(dirty) != 0 {
+		t.Errorf("dirty shards after sync got=%v, want none", dirty)
+	}
+
+	// A change is tracked for every brokercell.
+	tracker.namespaceChanged("ns")
+	dirty = tracker.dirty(bc1)
+	if _, ok := dirty[shard]; !ok || len(dirty) != 1 {
+		t.Errorf("dirty shards after change got=%v, want only shard %d", dirty, shard)
+	}
+	if dirty := tracker.dirty(bc2); len(dirty) != resources.TargetsConfigShards {
+		t.Errorf("dirty shards of other brokercell got=%d, want=%d", len(dirty), resources.TargetsConfigShards)
+	}
+
+	// A change while the shard is being written keeps it dirty.
+	tracker.namespaceChanged("ns")
+	tracker.synced(bc1, shard, dirty[shard])
+	if _, ok := tracker.dirty(bc1)[shard]; !ok {
+		t.Errorf("shard %d changed while written got clean, want dirty", shard)
+	}
+
+	tracker.reset(bc1)
+	if dirty := tracker.dirty(bc1); len(dirty) != resources.TargetsConfigShards {
+		t.Errorf("dirty shards after reset got=%d, want=%d", len(dirty), resources.TargetsConfigShards)
+	}
+}
@@ -23,9 +23,16 @@ import (
 	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
+	"github.com/google/knative-gcp/pkg/broker/eventtransform"
 	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+	"github.com/google/knative-gcp/pkg/utils"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	corev1 "k8s.io/api/core/v1"
+	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
 )
 
 const (
@@ -33,23 +40,86 @@ const (
 	targetsCMKey  = "targets"
 )
 
+func setDeliveryOptions(deliverySpec *config.DeliverySpec, delivery *eventingduckv1beta1.DeliverySpec) {
+	if delivery.Retry != nil {
+		deliverySpec.Retry = *delivery.Retry
+		if deliverySpec.Retry > brokerresources.RetryMaxDeliveryAttempts {
+			deliverySpec.Retry = brokerresources.RetryMaxDeliveryAttempts
+		}
+	}
+	if delivery.BackoffPolicy != nil && *delivery.BackoffPolicy == eventingduckv1beta1.BackoffPolicyLinear {
+		deliverySpec.BackoffPolicy = config.BackoffPolicy_LINEAR
+	} else if delivery.BackoffPolicy != nil {
+		deliverySpec.BackoffPolicy = config.BackoffPolicy_EXPONENTIAL
+	}
+	if delivery.BackoffDelay != nil {
+		if delay, err := utils.ParseISO8601Duration(*delivery.BackoffDelay); err == nil {
+			deliverySpec.BackoffDelay = durationpb.New(delay)
+		}
+	}
+}
+
 func EmptyConfig(t *testing.T, bc *intv1alpha1.BrokerCell) *corev1.ConfigMap {
-	cm, _ := resources.MakeTargetsConfig(bc, memory.NewEmptyTargets())
+	cm, _ := resources.MakeTargetsConfig(bc, 0, memory.NewEmptyTargets())
 	return cm
 }
 
 func Config(t *testing.T, bc *intv1alpha1.BrokerCell, broker *brokerv1beta1.Broker, triggers ...*brokerv1beta1.Trigger) *corev1.ConfigMap {
+	// construct the delivery spec of the broker, the triggers may override it
+	var brokerDeliverySpec *config.DeliverySpec
+	if broker.Spec.Delivery != nil {
+		brokerDeliverySpec = &config.DeliverySpec{}
+		setDeliveryOptions(brokerDeliverySpec, broker.Spec.Delivery)
+		if broker.Spec.Delivery.DeadLetterSink != nil && broker.Spec.Delivery.DeadLetterSink.URI != nil {
+			brokerDeliverySpec.DeadLetter = broker.Spec.Delivery.DeadLetterSink.URI.String()
+		}
+	}
+
 	// construct triggers config
 	targets := make(map[string]*config.Target, len(triggers))
 	for _, t := range triggers {
 		state := config.State_UNKNOWN
-		if t.Status.IsReady() {
+		if t.IsPaused() {
+			state = config.State_PAUSED
+		} else if t.Status.IsReady() {
 			state = config.State_READY
 		}
 		var filterAttributes map[string]string
 		if t.Spec.Filter != nil && t.Spec.Filter.Attributes != nil {
 			filterAttributes = t.Spec.Filter.Attributes
 		}
+		var filters []*config.Filter
+		if v, ok := t.Annotations[brokerv1beta1.FiltersAnnotation]; ok {
+			filters, _ = eventfilter.Parse(v)
+		}
+		var transforms []*config.Transform
+		if v, ok := t.Annotations[brokerv1beta1.TransformsAnnotation]; ok {
+			transforms, _ = eventtransform.Parse(v)
+		}
+		var replay *config.Replay
+		if start, ok := t.Status.ReplayStartTime(); ok {
+			replay = &config.Replay{
+				Queue: &config.Queue{
+					Topic:        brokerresources.GenerateDecouplingTopicName(broker),
+					Subscription: brokerresources.GenerateReplaySubscriptionName(t),
+				},
+				Until: timestamppb.New(start),
+			}
+		}
+		deliverySpec := brokerDeliverySpec
+		if v, ok := t.Annotations[brokerv1beta1.DeliveryAnnotation]; ok {
+			if delivery, err := brokerv1beta1.ParseDelivery(v); err == nil {
+				deliverySpec = &config.DeliverySpec{}
+				if brokerDeliverySpec != nil {
+					deliverySpec = proto.Clone(brokerDeliverySpec).(*config.DeliverySpec)
+				}
+				setDeliveryOptions(deliverySpec, delivery)
+			}
+		}
+		var deliveryAuth *config.DeliveryAuth
+		if v, ok := t.Annotations[brokerv1beta1.DeliveryAudienceAnnotation]; ok {
+			deliveryAuth, _ = config.ParseDeliveryAuth(v, t.Annotations[brokerv1beta1.DeliveryServiceAccountAnnotation])
+		}
 		target := &config.Target{
 			Id:        string(t.UID),
 			Name:      t.Name,
@@ -62,6 +132,11 @@ func Config(t *testing.T, bc *intv1alpha1.BrokerCell, broker *brokerv1beta1.Brok
 			},
 			State:            state,
 			FilterAttributes: filterAttributes,
+			DeliverySpec:     deliverySpec,
+			Filters:          filters,
+			Transforms:       transforms,
+			DeliveryAuth:     deliveryAuth,
+			Replay:           replay,
 		}
 
 		targets[t.Name] = target
@@ -84,12 +159,36 @@ func Config(t *testing.T, bc *intv1alpha1.BrokerCell, broker *brokerv1beta1.Brok
 		Targets: targets,
 		State:   state,
 	}
+	if v, ok := broker.Annotations[brokerv1beta1.IngressRateLimitAnnotation]; ok {
+		brokerConfig.RateLimit, _ = config.ParseRateLimit(v)
+	}
+	if v, ok := broker.Annotations[brokerv1beta1.AllowedIdentitiesAnnotation]; ok {
+		brokerConfig.AuthPolicy, _ = config.ParseAuthPolicy(v)
+	}
+	if v, ok := broker.Annotations[brokerv1beta1.ClaimCheckThresholdAnnotation]; ok {
+		brokerConfig.ClaimCheckThreshold, _ = config.ParseClaimCheckThreshold(v)
+	}
+	if v, ok := broker.Annotations[brokerv1beta1.OrderingKeyExtensionAnnotation]; ok {
+		brokerConfig.OrderingKeyExtension, _ = config.ParseOrderingKeyExtension(v)
+	}
+	if v, ok := broker.Annotations[brokerv1beta1.DeduplicationWindowAnnotation]; ok {
+		brokerConfig.DeduplicationWindow, _ = config.ParseDeduplicationWindow(v)
+	}
+	if v, ok := broker.Annotations[brokerv1beta1.HopLimitAnnotation]; ok {
+		brokerConfig.HopLimit, _ = config.ParseHopLimit(v)
+	}
+	if v, ok := broker.Annotations[brokerv1beta1.LoopDetectionAnnotation]; ok {
+		brokerConfig.LoopDetection, _ = config.ParseLoopDetection(v)
+	}
+	if v, ok := broker.Annotations[brokerv1beta1.NamespaceIngressRateLimitAnnotation]; ok {
+		brokerConfig.NamespaceRateLimit, _ = config.ParseRateLimit(v)
+	}
 	bt := &config.TargetsConfig{
 		Brokers: map[string]*config.Broker{
 			brokerConfig.Key(): brokerConfig,
 		},
 	}
 	brokerTargets := memory.NewTargets(bt)
-	cm, _ := resources.MakeTargetsConfig(bc, brokerTargets)
+	cm, _ := resources.MakeTargetsConfig(bc, config.ShardIndex(broker.Namespace, resources.TargetsConfigShards), brokerTargets)
 	return cm
 }
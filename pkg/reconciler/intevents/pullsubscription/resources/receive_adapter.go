@@ -26,6 +26,7 @@ import (
 	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/logging"
 
+	"github.com/google/knative-gcp/pkg/apis/duck"
 	"github.com/google/knative-gcp/pkg/apis/intevents"
 	"github.com/google/knative-gcp/pkg/apis/intevents/v1beta1"
 	"github.com/google/knative-gcp/pkg/pubsub/adapter/converters"
@@ -172,6 +173,28 @@ func makeReceiveAdapterPodSpec(ctx context.Context, args *ReceiveAdapterArgs) *c
 		}},
 	}
 
+	// Authenticate the deliveries to the sink if requested.
+	if audience, ok := args.PullSubscription.Annotations[duck.DeliveryAudienceAnnotation]; ok {
+		receiveAdapterContainer.Env = append(
+			receiveAdapterContainer.Env,
+			corev1.EnvVar{
+				Name:  "SINK_AUDIENCE",
+				Value: audience,
+			},
+			corev1.EnvVar{
+				Name:  "SINK_SERVICE_ACCOUNT",
+				Value: args.PullSubscription.Annotations[duck.DeliveryServiceAccountAnnotation],
+			})
+	}
+
+	// Drop the events the sink rejects if requested.
+	if drop, ok := args.PullSubscription.Annotations[duck.DropNonRetryableAnnotation]; ok {
+		receiveAdapterContainer.Env = append(receiveAdapterContainer.Env, corev1.EnvVar{
+			Name:  "DROP_NON_RETRYABLE",
+			Value: drop,
+		})
+	}
+
 	// If there is no secret to embed, return what we have.
 	if args.PullSubscription.Spec.Secret == nil {
 		return &corev1.PodSpec{
@@ -523,3 +523,78 @@ func TestMakeReceiveAdapterWithServiceAccount(t *testing.T) {
 		t.Errorf("unexpected deploy (-want, +got) = %v", diff)
 	}
 }
+
+func TestMakeReceiveAdapterWithDeliveryAuth(t *testing.T) {
+	ps := &v1beta1.PullSubscription{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testname",
+			Namespace: "testnamespace",
+			Annotations: map[string]string{
+				duck.DeliveryAudienceAnnotation:       "https://sink-abc-uc.a.run.app",
+				duck.DeliveryServiceAccountAnnotation: "invoker@my-project.iam.gserviceaccount.com",
+			},
+		},
+		Spec: v1beta1.PullSubscriptionSpec{
+			PubSubSpec: duckv1beta1.PubSubSpec{
+				Project: "eventing-name",
+			},
+			Topic: "topic",
+		},
+	}
+
+	got := MakeReceiveAdapter(context.Background(), &ReceiveAdapterArgs{
+		Image:            "test-image",
+		PullSubscription: ps,
+		SubscriptionID:   "sub-id",
+		SinkURI:          apis.HTTP("sink-uri"),
+	})
+
+	want := map[string]string{
+		"SINK_AUDIENCE":        "https://sink-abc-uc.a.run.app",
+		"SINK_SERVICE_ACCOUNT": "invoker@my-project.iam.gserviceaccount.com",
+	}
+	gotEnv := make(map[string]string)
+	for _, env := range got.Spec.Template.Spec.Containers[0].Env {
+		if _, ok := want[env.Name]; ok {
+			gotEnv[env.Name] = env.Value
+		}
+	}
+	if diff := cmp.Diff(want, gotEnv); diff != "" {
+		t.Errorf("unexpected delivery auth env (-want, +got) = %v", diff)
+	}
+}
+
+func TestMakeReceiveAdapterWithDropNonRetryable(t *testing.T) {
+	ps := &v1beta1.PullSubscription{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testname",
+			Namespace: "testnamespace",
+			Annotations: map[string]string{
+				duck.DropNonRetryableAnnotation: "true",
+			},
+		},
+		Spec: v1beta1.PullSubscriptionSpec{
+			PubSubSpec: duckv1beta1.PubSubSpec{
+				Project: "eventing-name",
+			},
+			Topic: "topic",
+		},
+	}
+
+	got := MakeReceiveAdapter(context.Background(), &ReceiveAdapterArgs{
+		Image:            "test-image",
+		PullSubscription: ps,
+		SubscriptionID:   "sub-id",
+		SinkURI:          apis.HTTP("sink-uri"),
+	})
+
+	var gotDrop string
+	for _, env := range got.Spec.Template.Spec.Containers[0].Env {
+		if env.Name == "DROP_NON_RETRYABLE" {
+			gotDrop = env.Value
+		}
+	}
+	if gotDrop != "true" {
+		t.Errorf("DROP_NON_RETRYABLE got=%q, want=%q", gotDrop, "true")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	eventingduckv1beta1 "knative.dev/eventing/pkg/apis/duck/v1beta1"
 	eventingv1beta1 "knative.dev/eventing/pkg/apis/eventing/v1beta1"
 	"knative.dev/pkg/apis"
 )
@@ -117,6 +118,7 @@ func WithBrokerReadyURI(address *apis.URL) BrokerOption {
 		WithBrokerBrokerCellReady(b)
 		WithBrokerSubscriptionReady(b)
 		WithBrokerTopicReady(b)
+		WithBrokerDataPlaneReady(b)
 		WithBrokerAddressURI(address)(b)
 	}
 }
@@ -145,6 +147,26 @@ func WithBrokerTopicReady(b *brokerv1beta1.Broker) {
 	b.Status.MarkTopicReady()
 }
 
+func WithBrokerDataPlaneReady(b *brokerv1beta1.Broker) {
+	b.Status.MarkDataPlaneReady()
+}
+
+func WithBrokerDataPlaneUnknown(reason, msg string) BrokerOption {
+	return func(b *brokerv1beta1.Broker) {
+		b.Status.MarkDataPlaneUnknown(reason, msg)
+	}
+}
+
+func WithBrokerOrderingEnabled(b *brokerv1beta1.Broker) {
+	b.Status.MarkOrderingEnabled()
+}
+
+func WithBrokerOrderingDisabled(reason, msg string) BrokerOption {
+	return func(b *brokerv1beta1.Broker) {
+		b.Status.MarkOrderingDisabled(reason, msg)
+	}
+}
+
 func WithBrokerClass(bc string) BrokerOption {
 	return func(b *brokerv1beta1.Broker) {
 		annotations := b.GetAnnotations()
@@ -156,6 +178,23 @@ func WithBrokerClass(bc string) BrokerOption {
 	}
 }
 
+func WithBrokerAnnotation(key, value string) BrokerOption {
+	return func(b *brokerv1beta1.Broker) {
+		annotations := b.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string, 1)
+		}
+		annotations[key] = value
+		b.SetAnnotations(annotations)
+	}
+}
+
+func WithBrokerDeliverySpec(ds *eventingduckv1beta1.DeliverySpec) BrokerOption {
+	return func(b *brokerv1beta1.Broker) {
+		b.Spec.Delivery = ds
+	}
+}
+
 func WithBrokerSetDefaults(b *brokerv1beta1.Broker) {
 	b.SetDefaults(context.Background())
 }
@@ -23,6 +23,7 @@ import (
 
 	"cloud.google.com/go/pubsub"
 	"cloud.google.com/go/pubsub/pstest"
+	"github.com/google/go-cmp/cmp"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
@@ -113,6 +114,21 @@ func SubscriptionExists(id string) func(*testing.T, *rtesting.TableRow) {
 	}
 }
 
+// SubscriptionDeadLetterPolicy checks the dead letter policy of the subscription.
+func SubscriptionDeadLetterPolicy(id string, want *pubsub.DeadLetterPolicy) func(*testing.T, *rtesting.TableRow) {
+	return func(t *testing.T, r *rtesting.TableRow) {
+		c := getPubsubClient(r)
+		config, err := c.Subscription(id).Config(context.Background())
+		if err != nil {
+			t.Errorf("Error getting subscription %q config: %v", id, err)
+			return
+		}
+		if diff := cmp.Diff(want, config.DeadLetterPolicy); diff != "" {
+			t.Errorf("Subscription %q dead letter policy (-want,+got): %v", id, diff)
+		}
+	}
+}
+
 func OnlySubscriptions(ids ...string) func(*testing.T, *rtesting.TableRow) {
 	return func(t *testing.T, r *rtesting.TableRow) {
 		c := getPubsubClient(r)
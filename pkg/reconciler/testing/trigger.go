@@ -151,6 +151,52 @@ func WithDependencyAnnotation(dependencyAnnotation string) TriggerOption {
 	}
 }
 
+func WithFiltersAnnotation(filters string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		if t.Annotations == nil {
+			t.Annotations = make(map[string]string)
+		}
+		t.Annotations[brokerv1beta1.FiltersAnnotation] = filters
+	}
+}
+
+func WithTransformsAnnotation(transforms string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		if t.Annotations == nil {
+			t.Annotations = make(map[string]string)
+		}
+		t.Annotations[brokerv1beta1.TransformsAnnotation] = transforms
+	}
+}
+
+func WithDeliveryAnnotation(delivery string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		if t.Annotations == nil {
+			t.Annotations = make(map[string]string)
+		}
+		t.Annotations[brokerv1beta1.DeliveryAnnotation] = delivery
+	}
+}
+
+func WithDeliveryAuthAnnotations(audience, serviceAccount string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		if t.Annotations == nil {
+			t.Annotations = make(map[string]string)
+		}
+		t.Annotations[brokerv1beta1.DeliveryAudienceAnnotation] = audience
+		if serviceAccount != "" {
+			t.Annotations[brokerv1beta1.DeliveryServiceAccountAnnotation] = serviceAccount
+		}
+	}
+}
+
+func WithPausedAnnotation(t *brokerv1beta1.Trigger) {
+	if t.Annotations == nil {
+		t.Annotations = make(map[string]string)
+	}
+	t.Annotations[brokerv1beta1.PausedAnnotation] = "true"
+}
+
 func WithTriggerDependencyReady(t *brokerv1beta1.Trigger) {
 	t.Status.MarkDependencySucceeded()
 }
@@ -191,6 +237,57 @@ func WithTriggerTopicReady(t *brokerv1beta1.Trigger) {
 	t.Status.MarkTopicReady()
 }
 
+func WithTriggerDataPlaneReady(t *brokerv1beta1.Trigger) {
+	t.Status.MarkDataPlaneReady()
+}
+
+func WithTriggerDataPlaneUnknown(reason, msg string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		t.Status.MarkDataPlaneUnknown(reason, msg)
+	}
+}
+
+func WithTriggerDataPlaneFailed(reason, msg string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		t.Status.MarkDataPlaneFailed(reason, msg)
+	}
+}
+
+func WithTriggerOrderingEnabled(t *brokerv1beta1.Trigger) {
+	t.Status.MarkOrderingEnabled()
+}
+
+func WithTriggerPaused(t *brokerv1beta1.Trigger) {
+	t.Status.MarkPaused()
+}
+
+func WithReplayAnnotation(source string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		if t.Annotations == nil {
+			t.Annotations = make(map[string]string)
+		}
+		t.Annotations[brokerv1beta1.ReplayAnnotation] = source
+	}
+}
+
+func WithTriggerReplaying(source string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		t.Status.MarkReplaying(source)
+	}
+}
+
+func WithTriggerReplayed(source string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		t.Status.MarkReplayed(source)
+	}
+}
+
+func WithTriggerReplayFailed(reason, message string) TriggerOption {
+	return func(t *brokerv1beta1.Trigger) {
+		t.Status.MarkReplayFailed(reason, message)
+	}
+}
+
 func WithTriggerDeletionTimestamp(t *brokerv1beta1.Trigger) {
 	deleteTime := metav1.NewTime(time.Unix(1e9, 0))
 	t.ObjectMeta.SetDeletionTimestamp(&deleteTime)
@@ -22,8 +22,10 @@ import (
 
 	"cloud.google.com/go/pubsub"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 
 	"knative.dev/eventing/pkg/apis/eventing"
 	eventingv1beta1 "knative.dev/eventing/pkg/apis/eventing/v1beta1"
@@ -34,15 +36,20 @@ import (
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	pkgcontroller "knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
 	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/pkg/resolver"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
+	"github.com/google/knative-gcp/pkg/broker/config"
 	brokerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1beta1/broker"
 	triggerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1beta1/trigger"
 	triggerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/trigger"
+	brokerlisters "github.com/google/knative-gcp/pkg/client/listers/broker/v1beta1"
 	metadataClient "github.com/google/knative-gcp/pkg/gclient/metadata"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/configserver"
 	"github.com/google/knative-gcp/pkg/utils"
 )
 
@@ -57,7 +64,16 @@ const (
 // filterBroker is the function to filter brokers with proper brokerclass.
 var filterBroker = pkgreconciler.AnnotationFilterFunc(eventingv1beta1.BrokerClassAnnotationKey, brokerv1beta1.BrokerClass, false /*allowUnset*/)
 
-func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+type Constructor injection.ControllerConstructor
+
+// NewConstructor creates a constructor to make a Trigger controller.
+func NewConstructor(css *configserver.Singleton, gcpas *gcpauth.StoreSingleton) Constructor {
+	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+		return newController(ctx, cmw, css.Server(ctx), gcpas.Store(ctx, cmw))
+	}
+}
+
+func newController(ctx context.Context, cmw configmap.Watcher, configServer *configserver.Server, gcpas *gcpauth.Store) *controller.Impl {
 	triggerInformer := triggerinformer.Get(ctx)
 
 	// If there is an error, the projectID will be empty. The reconciler will retry
@@ -86,9 +102,15 @@ func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl
 		brokerLister: brokerinformer.Get(ctx).Lister(),
 		pubsubClient: client,
 		projectID:    projectID,
+		configServer: configServer,
 	}
 
-	impl := triggerreconciler.NewImpl(ctx, r, withAgentAndFinalizer)
+	impl := triggerreconciler.NewImpl(ctx, r, func(impl *pkgcontroller.Impl) pkgcontroller.Options {
+		opts := withAgentAndFinalizer(impl)
+		// The allowed delivery service accounts and audiences are read from the GCP auth defaults.
+		opts.ConfigStore = gcpas
+		return opts
+	})
 	r.kresourceTracker = duck.NewListableTracker(ctx, conditions.Get, impl.EnqueueKey, controller.GetTrackerLease(ctx))
 	r.addressableTracker = duck.NewListableTracker(ctx, addressable.Get, impl.EnqueueKey, controller.GetTrackerLease(ctx))
 	r.uriResolver = resolver.NewURIResolver(ctx, impl.EnqueueKey)
@@ -117,9 +139,49 @@ func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl
 		},
 	)
 
+	// Recheck the delivery authentication of the triggers when the allowed ones change.
+	cmw.Watch(gcpauth.ConfigMapName(), func(*corev1.ConfigMap) {
+		impl.GlobalResync(triggerInformer.Informer())
+	})
+
+	if configServer != nil {
+		// Recheck the triggers whose data plane isn't ready yet or whose replay is in progress once
+		// the data plane pods applied a newer targets config or reported the replays that caught up.
+		configServer.OnApplied(func(string) {
+			impl.FilteredGlobalResync(func(obj interface{}) bool {
+				t, ok := obj.(*brokerv1beta1.Trigger)
+				if !ok {
+					return false
+				}
+				if _, replaying := t.Status.ReplayStartTime(); replaying {
+					return true
+				}
+				// Triggers of other brokers don't have the condition.
+				c := t.Status.GetCondition(brokerv1beta1.TriggerConditionDataPlane)
+				return c != nil && !c.IsTrue()
+			}, triggerInformer.Informer())
+		})
+		// Record on the triggers the events the data plane dropped because they looped back.
+		configServer.OnLoopDetected(loopRecorder(r.Recorder, triggerInformer.Lister()))
+	}
+
 	return impl
 }
 
+// loopRecorder returns a function recording an event on the trigger when the data plane dropped
+// events which looped back to it.
+func loopRecorder(recorder record.EventRecorder, triggerLister brokerlisters.TriggerLister) func(bcKey, targetKey string, count int64) {
+	return func(_, targetKey string, count int64) {
+		namespace, _, name := config.SplitTriggerKey(targetKey)
+		t, err := triggerLister.Triggers(namespace).Get(name)
+		if err != nil {
+			// The trigger was deleted in the meantime.
+			return
+		}
+		recorder.Eventf(t, corev1.EventTypeWarning, "EventLoopDetected", "Dropped %d events which looped back to the Trigger", count)
+	}
+}
+
 func newPubsubClient(ctx context.Context, projectID string) (*pubsub.Client, error) {
 	projectID, err := utils.ProjectID(projectID, metadataClient.NewDefaultMetadataClient())
 	if err != nil {
@@ -21,6 +21,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/metrics"
@@ -28,6 +30,9 @@ import (
 	"knative.dev/pkg/system"
 	tracingconfig "knative.dev/pkg/tracing/config"
 
+	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
+	. "github.com/google/knative-gcp/pkg/reconciler/testing"
+
 	// Fake injection informers
 	_ "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1beta1/broker/fake"
 	_ "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1beta1/trigger/fake"
@@ -43,7 +48,7 @@ import (
 func TestNew(t *testing.T) {
 	ctx, _ := SetupFakeContext(t)
 
-	c := NewController(ctx, configmap.NewStaticWatcher(
+	c := newController(ctx, configmap.NewStaticWatcher(
 		&corev1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      logging.ConfigMapName(),
@@ -65,9 +70,36 @@ func TestNew(t *testing.T) {
 			},
 			Data: map[string]string{},
 		},
-	))
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      gcpauth.ConfigMapName(),
+				Namespace: system.Namespace(),
+			},
+			Data: map[string]string{},
+		},
+	), nil, NewGCPAuthTestStore(t, nil))
 
 	if c == nil {
 		t.Fatal("Expected NewController to return a non-nil value")
 	}
 }
+
+func TestLoopRecorder(t *testing.T) {
+	ls := NewListers([]runtime.Object{NewTrigger("trigger", "ns", "broker")})
+	recorder := record.NewFakeRecorder(2)
+	f := loopRecorder(recorder, ls.GetTriggerLister())
+
+	f("bc-ns/bc", "ns/broker/trigger", 2)
+	// The loops of deleted triggers aren't recorded.
+	f("bc-ns/bc", "ns/broker/deleted", 1)
+	close(recorder.Events)
+
+	var got []string
+	for e := range recorder.Events {
+		got = append(got, e)
+	}
+	want := []string{"Warning EventLoopDetected Dropped 2 events which looped back to the Trigger"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("recorded events got=%q, want=%q", got, want)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/eventing/pkg/logging"
+	"knative.dev/pkg/system"
+
+	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	metadataClient "github.com/google/knative-gcp/pkg/gclient/metadata"
+	"github.com/google/knative-gcp/pkg/reconciler/broker/resources"
+	reconcilerutilspubsub "github.com/google/knative-gcp/pkg/reconciler/utils/pubsub"
+	"github.com/google/knative-gcp/pkg/utils"
+)
+
+const (
+	replaySubscriptionCreated = "ReplaySubscriptionCreated"
+	replaySubscriptionDeleted = "ReplaySubscriptionDeleted"
+
+	// replaySubscriptionExpiration is the minimum expiration of Pub/Sub
+	// subscriptions. The replay subscription is deleted once the replay is
+	// over, the expiration only cleans it up if the trigger is lost.
+	replaySubscriptionExpiration = 24 * time.Hour
+)
+
+// reconcileReplay reconciles the replay requested with the ReplayAnnotation.
+// The replay pulls the events of the broker from a temporary subscription to
+// its decoupling topic, seeked to the time or the snapshot of the annotation.
+// Replaying from a time requires the decoupling topic to retain the events
+// published since then, as declared with the MessageRetentionAnnotation of the
+// broker; the reconciler can't set the retention of the topic. The retry pods deliver the replayed events published
+// before the replay started. The replay is over once every running retry pod
+// reported over the targets config stream that it replayed nothing for a quiet
+// period. Without the targets config server the replay is in progress until
+// the annotation is removed.
+//
+// A replay runs once. A new value of the annotation is ignored until the
+// annotation is removed.
+func (r *Reconciler) reconcileReplay(ctx context.Context, t *brokerv1beta1.Trigger, b *brokerv1beta1.Broker) error {
+	value, ok := t.Annotations[brokerv1beta1.ReplayAnnotation]
+	if !ok {
+		t.Status.ClearReplay()
+		return r.deleteReplaySubscription(ctx, t)
+	}
+	c := t.Status.GetCondition(brokerv1beta1.TriggerConditionReplay)
+	if c != nil && !c.IsUnknown() {
+		// The replay is over.
+		return r.deleteReplaySubscription(ctx, t)
+	}
+	source, err := brokerv1beta1.ParseReplay(value)
+	if err != nil {
+		// The webhook rejects invalid values, but the annotation may predate it.
+		t.Status.MarkReplayFailed("InvalidReplay", "%v", err)
+		return nil
+	}
+
+	client, closeClient, err := r.replayPubsubClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	if _, replaying := t.Status.ReplayStartTime(); !replaying {
+		return r.startReplay(ctx, client, t, b, value, source)
+	}
+	if r.configServer != nil && r.configServer.TargetReplayed(system.Namespace(), resources.DefaultBrokerCellName, config.TriggerKey(t.Namespace, t.Spec.Broker, t.Name)) {
+		if err := r.deleteSubscription(ctx, client, t, resources.GenerateReplaySubscriptionName(t)); err != nil {
+			return err
+		}
+		t.Status.MarkReplayed(value)
+	}
+	return nil
+}
+
+// startReplay creates the replay subscription and seeks it to the source of
+// the replay. The replay fails if the subscription can't be seeked.
+func (r *Reconciler) startReplay(ctx context.Context, client *pubsub.Client, t *brokerv1beta1.Trigger, b *brokerv1beta1.Broker, value string, source *brokerv1beta1.ReplaySource) error {
+	if !source.Time.IsZero() && !replayRetained(t, b, source.Time) {
+		return nil
+	}
+	logger := logging.FromContext(ctx)
+	subID := resources.GenerateReplaySubscriptionName(t)
+	sub := client.Subscription(subID)
+	exists, err := sub.Exists(ctx)
+	if err != nil {
+		logger.Error("Failed to verify Pub/Sub replay subscription exists", zap.Error(err))
+		t.Status.MarkReplayUnknown("ReplaySubscriptionVerificationFailed", "Failed to verify the replay subscription exists: %v", err)
+		return err
+	}
+	if !exists {
+		labels := map[string]string{
+			"resource":  "triggers",
+			"namespace": t.Namespace,
+			"name":      t.Name,
+		}
+		// Replays are ordered if the events of the broker are ordered.
+		_, ordered := b.Annotations[brokerv1beta1.OrderingKeyExtensionAnnotation]
+		sub, err = client.CreateSubscription(ctx, subID, pubsub.SubscriptionConfig{
+			Topic:                 client.Topic(resources.GenerateDecouplingTopicName(b)),
+			Labels:                reconcilerutilspubsub.OrderingLabels(labels, ordered),
+			EnableMessageOrdering: ordered,
+			ExpirationPolicy:      replaySubscriptionExpiration,
+		})
+		if err != nil {
+			logger.Error("Failed to create Pub/Sub replay subscription", zap.Error(err))
+			t.Status.MarkReplayUnknown("ReplaySubscriptionCreationFailed", "Failed to create the replay subscription: %v", err)
+			return err
+		}
+		r.Recorder.Eventf(t, corev1.EventTypeNormal, replaySubscriptionCreated, "Created PubSub replay subscription %q", subID)
+	}
+
+	if source.Snapshot != "" {
+		err = sub.SeekToSnapshot(ctx, client.Snapshot(source.Snapshot))
+	} else {
+		err = sub.SeekToTime(ctx, source.Time)
+	}
+	if err != nil {
+		logger.Error("Failed to seek Pub/Sub replay subscription", zap.Error(err))
+		// Seeking fails the same way on the next attempts, give up the replay.
+		t.Status.MarkReplayFailed("ReplaySeekFailed", "Failed to seek the replay subscription: %v", err)
+		return r.deleteSubscription(ctx, client, t, subID)
+	}
+	t.Status.MarkReplaying(value)
+	return nil
+}
+
+// deleteReplaySubscription deletes the replay subscription of the trigger if it exists.
+func (r *Reconciler) deleteReplaySubscription(ctx context.Context, t *brokerv1beta1.Trigger) error {
+	client, closeClient, err := r.replayPubsubClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+	return r.deleteSubscription(ctx, client, t, resources.GenerateReplaySubscriptionName(t))
+}
+
+func (r *Reconciler) deleteSubscription(ctx context.Context, client *pubsub.Client, t *brokerv1beta1.Trigger, subID string) error {
+	sub := client.Subscription(subID)
+	exists, err := sub.Exists(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to verify Pub/Sub replay subscription exists", zap.Error(err))
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	if err := sub.Delete(ctx); err != nil {
+		logging.FromContext(ctx).Error("Failed to delete Pub/Sub replay subscription", zap.Error(err))
+		return err
+	}
+	r.Recorder.Eventf(t, corev1.EventTypeNormal, replaySubscriptionDeleted, "Deleted PubSub replay subscription %q", subID)
+	return nil
+}
+
+// replayPubsubClient returns the Pub/Sub client of the reconciler, or a new
+// one if it has none. The returned function closes the new client.
+func (r *Reconciler) replayPubsubClient(ctx context.Context) (*pubsub.Client, func(), error) {
+	if r.pubsubClient != nil {
+		return r.pubsubClient, func() {}, nil
+	}
+	projectID, err := utils.ProjectID(r.projectID, metadataClient.NewDefaultMetadataClient())
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to find project id", zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to find project id: %w", err)
+	}
+	client, err := pubsub.NewClient(ctx, projectID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create Pub/Sub client", zap.Error(err))
+		return nil, nil, err
+	}
+	return client, func() { client.Close() }, nil
+}
+
+// replayRetained returns true if the decoupling topic of the broker retains the
+// events published since the time, and marks the replay failed otherwise.
+func replayRetained(t *brokerv1beta1.Trigger, b *brokerv1beta1.Broker, since time.Time) bool {
+	v, ok := b.Annotations[brokerv1beta1.MessageRetentionAnnotation]
+	if !ok {
+		t.Status.MarkReplayFailed("MessageRetentionMissing",
+			"Replaying from a time requires the Pub/Sub topic of the broker to retain its messages, declared with the %s annotation of the broker",
+			brokerv1beta1.MessageRetentionAnnotation)
+		return false
+	}
+	retention, err := brokerv1beta1.ParseMessageRetention(v)
+	if err != nil {
+		t.Status.MarkReplayFailed("InvalidMessageRetention", "%v", err)
+		return false
+	}
+	if since.Before(time.Now().Add(-retention)) {
+		t.Status.MarkReplayFailed("ReplayBeyondRetention",
+			"The Pub/Sub topic of the broker retains its messages for %v, older events can't be replayed", retention)
+		return false
+	}
+	return true
+}
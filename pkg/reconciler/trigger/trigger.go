@@ -30,14 +30,17 @@ import (
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/pkg/resolver"
+	"knative.dev/pkg/system"
 
 	"cloud.google.com/go/pubsub"
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/broker/config"
 	triggerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/trigger"
 	brokerlisters "github.com/google/knative-gcp/pkg/client/listers/broker/v1beta1"
 	metadataClient "github.com/google/knative-gcp/pkg/gclient/metadata"
 	"github.com/google/knative-gcp/pkg/reconciler"
 	"github.com/google/knative-gcp/pkg/reconciler/broker/resources"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/configserver"
 	reconcilerutilspubsub "github.com/google/knative-gcp/pkg/reconciler/utils/pubsub"
 	"github.com/google/knative-gcp/pkg/utils"
 	"knative.dev/eventing/pkg/apis/eventing/v1beta1"
@@ -66,6 +69,9 @@ type Reconciler struct {
 
 	// pubsubClient is used as the Pubsub client when present.
 	pubsubClient *pubsub.Client
+
+	// configServer tracks the targets config applied by the data plane, nil if disabled.
+	configServer *configserver.Server
 }
 
 // Check that TriggerReconciler implements Interface
@@ -106,7 +112,7 @@ func (r *Reconciler) reconcile(ctx context.Context, t *brokerv1beta1.Trigger, b
 		return err
 	}
 
-	if err := r.reconcileRetryTopicAndSubscription(ctx, t); err != nil {
+	if err := r.reconcileRetryTopicAndSubscription(ctx, t, b); err != nil {
 		return err
 	}
 
@@ -114,9 +120,42 @@ func (r *Reconciler) reconcile(ctx context.Context, t *brokerv1beta1.Trigger, b
 		return err
 	}
 
+	if err := r.reconcileReplay(ctx, t, b); err != nil {
+		return err
+	}
+
+	r.reconcileDataPlane(ctx, t)
+
+	if t.IsPaused() {
+		t.Status.MarkPaused()
+	} else {
+		t.Status.ClearPaused()
+	}
+
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, triggerReconciled, "Trigger reconciled: \"%s/%s\"", t.Namespace, t.Name)
 }
 
+// reconcileDataPlane updates the trigger status based on whether the data plane pods
+// applied the latest targets config of the trigger.
+func (r *Reconciler) reconcileDataPlane(ctx context.Context, t *brokerv1beta1.Trigger) {
+	if _, err := t.DeliveryAuth(ctx); err != nil {
+		// The brokercell reconciler leaves the trigger out of the targets config.
+		t.Status.MarkDataPlaneFailed("InvalidDeliveryAuth", "%v", err)
+		return
+	}
+	if r.configServer == nil {
+		// Without the targets config server there is no way to tell, keep the old behavior.
+		t.Status.MarkDataPlaneReady()
+		return
+	}
+	// TODO(#866) Get brokercell based on the label (or annotation) on the broker.
+	if err := r.configServer.TargetApplied(system.Namespace(), resources.DefaultBrokerCellName, config.BrokerKey(t.Namespace, t.Spec.Broker), t.Name); err != nil {
+		t.Status.MarkDataPlaneUnknown("TargetsConfigNotApplied", "%v", err)
+		return
+	}
+	t.Status.MarkDataPlaneReady()
+}
+
 // FinalizeKind frees GCP Broker related resources for this Trigger if applicable. It's called when:
 // 1) the Trigger is being deleted;
 // 2) the Broker of this Trigger is deleted;
@@ -132,6 +171,9 @@ func (r *Reconciler) FinalizeKind(ctx context.Context, t *brokerv1beta1.Trigger)
 	if err := r.deleteRetryTopicAndSubscription(ctx, t); err != nil {
 		return err
 	}
+	if err := r.deleteReplaySubscription(ctx, t); err != nil {
+		return err
+	}
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, triggerFinalized, "Trigger finalized: \"%s/%s\"", t.Namespace, t.Name)
 }
 
@@ -165,7 +207,7 @@ func hasGCPBrokerFinalizer(t *brokerv1beta1.Trigger) bool {
 	return false
 }
 
-func (r *Reconciler) reconcileRetryTopicAndSubscription(ctx context.Context, trig *brokerv1beta1.Trigger) error {
+func (r *Reconciler) reconcileRetryTopicAndSubscription(ctx context.Context, trig *brokerv1beta1.Trigger, b *brokerv1beta1.Broker) error {
 	logger := logging.FromContext(ctx)
 	logger.Debug("Reconciling retry topic")
 	// get ProjectID from metadata
@@ -215,14 +257,28 @@ func (r *Reconciler) reconcileRetryTopicAndSubscription(ctx context.Context, tri
 
 	// Check if PullSub exists, and if not, create it.
 	subID := resources.GenerateRetrySubscriptionName(trig)
+	// Retries are ordered if the events of the broker are ordered.
+	_, ordered := b.Annotations[brokerv1beta1.OrderingKeyExtensionAnnotation]
 	subConfig := pubsub.SubscriptionConfig{
-		Topic:  topic,
-		Labels: labels,
+		Topic:                 topic,
+		Labels:                reconcilerutilspubsub.OrderingLabels(labels, ordered),
+		EnableMessageOrdering: ordered,
+		// Pub/Sub only reports the delivery attempts of subscriptions with a
+		// dead letter policy. The retry pods rely on them to give up after the
+		// retries of the trigger.
+		DeadLetterPolicy: retryDeadLetterPolicy(topic),
 		//TODO(grantr): configure these settings?
 		// AckDeadline
 		// RetentionDuration
 	}
-	if _, err := pubsubReconciler.ReconcileSubscription(ctx, subID, subConfig, trig, &trig.Status); err != nil {
+	sub, err := pubsubReconciler.ReconcileSubscription(ctx, subID, subConfig, trig, &trig.Status)
+	if err != nil {
+		return err
+	}
+	if err := reconcilerutilspubsub.ReconcileOrdering(ctx, sub, ordered, &trig.Status); err != nil {
+		return err
+	}
+	if err := reconcileRetryDeadLetterPolicy(ctx, sub, topic, &trig.Status); err != nil {
 		return err
 	}
 	// TODO(grantr): this isn't actually persisted due to webhook issues.
@@ -232,6 +288,35 @@ func (r *Reconciler) reconcileRetryTopicAndSubscription(ctx context.Context, tri
 	return nil
 }
 
+// retryDeadLetterPolicy returns the dead letter policy of the retry
+// subscription of the topic.
+func retryDeadLetterPolicy(topic *pubsub.Topic) *pubsub.DeadLetterPolicy {
+	return &pubsub.DeadLetterPolicy{
+		DeadLetterTopic:     topic.String(),
+		MaxDeliveryAttempts: resources.RetryMaxDeliveryAttempts,
+	}
+}
+
+// reconcileRetryDeadLetterPolicy sets the dead letter policy of a retry
+// subscription created without it.
+func reconcileRetryDeadLetterPolicy(ctx context.Context, sub *pubsub.Subscription, topic *pubsub.Topic, status *brokerv1beta1.TriggerStatus) error {
+	config, err := sub.Config(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get Pub/Sub subscription Config", zap.Error(err))
+		status.MarkSubscriptionUnknown("SubscriptionConfigUnknown", "Failed to get Pub/Sub subscription Config: %w", err)
+		return err
+	}
+	if config.DeadLetterPolicy != nil {
+		return nil
+	}
+	if _, err := sub.Update(ctx, pubsub.SubscriptionConfigToUpdate{DeadLetterPolicy: retryDeadLetterPolicy(topic)}); err != nil {
+		logging.FromContext(ctx).Error("Failed to set the dead letter policy of the Pub/Sub subscription", zap.Error(err))
+		status.MarkSubscriptionFailed("SubscriptionUpdateFailed", "Failed to set the dead letter policy of the Pub/Sub subscription: %w", err)
+		return err
+	}
+	return nil
+}
+
 func (r *Reconciler) deleteRetryTopicAndSubscription(ctx context.Context, trig *brokerv1beta1.Trigger) error {
 	logger := logging.FromContext(ctx)
 	logger.Debug("Deleting retry topic")
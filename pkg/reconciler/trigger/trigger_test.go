@@ -20,7 +20,9 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"cloud.google.com/go/pubsub"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -36,11 +38,14 @@ import (
 	logtesting "knative.dev/pkg/logging/testing"
 	. "knative.dev/pkg/reconciler/testing"
 	"knative.dev/pkg/resolver"
+	"knative.dev/pkg/system"
 
 	brokerv1beta1 "github.com/google/knative-gcp/pkg/apis/broker/v1beta1"
+	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
 	"github.com/google/knative-gcp/pkg/client/injection/ducks/duck/v1alpha1/resource"
 	triggerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1beta1/trigger"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	"github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	. "github.com/google/knative-gcp/pkg/reconciler/testing"
 )
 
@@ -61,6 +66,11 @@ const (
 var (
 	testKey = fmt.Sprintf("%s/%s", testNS, triggerName)
 
+	decouplingTopic    = resources.GenerateDecouplingTopicName(NewBroker(brokerName, testNS))
+	replaySubscription = resources.GenerateReplaySubscriptionName(NewTrigger(triggerName, testNS, brokerName, WithTriggerUID(testUID)))
+	// replayTime is within the message retention of the brokers replaying.
+	replayTime = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
 	triggerFinalizerUpdatedEvent = Eventf(corev1.EventTypeNormal, "FinalizerUpdate", `Updated "test-trigger" finalizers`)
 	triggerReconciledEvent       = Eventf(corev1.EventTypeNormal, "TriggerReconciled", `Trigger reconciled: "testnamespace/test-trigger"`)
 	triggerFinalizedEvent        = Eventf(corev1.EventTypeNormal, "TriggerFinalized", `Trigger finalized: "testnamespace/test-trigger"`)
@@ -238,6 +248,7 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerSubscriptionReady,
 					WithTriggerTopicReady,
 					WithTriggerDependencyReady,
+					WithTriggerDataPlaneReady,
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
 					WithTriggerSetDefaults,
@@ -256,6 +267,10 @@ func TestAllCasesTrigger(t *testing.T) {
 			PostConditions: []func(*testing.T, *TableRow){
 				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123"),
 				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+				SubscriptionDeadLetterPolicy("cre-tgr_testnamespace_test-trigger_abc123", &pubsub.DeadLetterPolicy{
+					DeadLetterTopic:     "projects/" + testProject + "/topics/cre-tgr_testnamespace_test-trigger_abc123",
+					MaxDeliveryAttempts: resources.RetryMaxDeliveryAttempts,
+				}),
 			},
 		},
 		{
@@ -315,6 +330,197 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerSubscriptionReady,
 					WithTriggerTopicReady,
 					WithTriggerDependencyReady,
+					WithTriggerDataPlaneReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger created, delivery service account allowed",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithDeliveryAuthAnnotations("https://subscriber-abc-uc.a.run.app", "invoker@my-project.iam.gserviceaccount.com"),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithDeliveryAuthAnnotations("https://subscriber-abc-uc.a.run.app", "invoker@my-project.iam.gserviceaccount.com"),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerDataPlaneReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger created, delivery service account not allowed",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithDeliveryAuthAnnotations("https://subscriber-abc-uc.a.run.app", "admin@my-project.iam.gserviceaccount.com"),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithDeliveryAuthAnnotations("https://subscriber-abc-uc.a.run.app", "admin@my-project.iam.gserviceaccount.com"),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerDataPlaneFailed("InvalidDeliveryAuth",
+						`delivery authentication not allowed: service account "admin@my-project.iam.gserviceaccount.com" is not in the delivery service accounts of namespace "testnamespace"`),
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger created, broker is ordered",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithBrokerAnnotation(brokerv1beta1.OrderingKeyExtensionAnnotation, "partitionkey"),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerOrderingEnabled,
+					WithTriggerDependencyReady,
+					WithTriggerDataPlaneReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger created, paused",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithPausedAnnotation,
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithPausedAnnotation,
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerDataPlaneReady,
+					WithTriggerPaused,
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
 					WithTriggerSetDefaults,
@@ -335,6 +541,273 @@ func TestAllCasesTrigger(t *testing.T) {
 				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
 			},
 		},
+		{
+			Name: "Trigger created, replaying",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithBrokerAnnotation(brokerv1beta1.MessageRetentionAnnotation, "168h"),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithReplayAnnotation(replayTime),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithReplayAnnotation(replayTime),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerDataPlaneReady,
+					WithTriggerReplaying(replayTime),
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				Eventf(corev1.EventTypeNormal, "ReplaySubscriptionCreated", `Created PubSub replay subscription %q`, replaySubscription),
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic(decouplingTopic),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics(decouplingTopic, "cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlySubscriptions(replaySubscription, "cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger created, replay from a time without message retention",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithReplayAnnotation("2020-10-01T00:00:00Z"),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithReplayAnnotation("2020-10-01T00:00:00Z"),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerDataPlaneReady,
+					WithTriggerReplayFailed("MessageRetentionMissing", "Replaying from a time requires the Pub/Sub topic of the broker to retain its messages, declared with the events.cloud.google.com/message-retention annotation of the broker"),
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic(decouplingTopic),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics(decouplingTopic, "cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger created, replay from a time beyond message retention",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithBrokerAnnotation(brokerv1beta1.MessageRetentionAnnotation, "168h"),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithReplayAnnotation("2020-10-01T00:00:00Z"),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithReplayAnnotation("2020-10-01T00:00:00Z"),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerDataPlaneReady,
+					WithTriggerReplayFailed("ReplayBeyondRetention", "The Pub/Sub topic of the broker retains its messages for 168h0m0s, older events can't be replayed"),
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic(decouplingTopic),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics(decouplingTopic, "cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger created, replay from a snapshot failed to seek",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithReplayAnnotation("pre-upgrade"),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithReplayAnnotation("pre-upgrade"),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerDataPlaneReady,
+					WithTriggerReplayFailed("ReplaySeekFailed", "Failed to seek the replay subscription: rpc error: code = Unimplemented desc = unhandled Seek target type *pubsub.SeekRequest_Snapshot"),
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				Eventf(corev1.EventTypeNormal, "ReplaySubscriptionCreated", `Created PubSub replay subscription %q`, replaySubscription),
+				Eventf(corev1.EventTypeNormal, "ReplaySubscriptionDeleted", `Deleted PubSub replay subscription %q`, replaySubscription),
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic(decouplingTopic),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics(decouplingTopic, "cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			Name: "Trigger created, replay over",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1beta1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithReplayAnnotation("2020-10-01T00:00:00Z"),
+					WithTriggerReplayed("2020-10-01T00:00:00Z"),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithReplayAnnotation("2020-10-01T00:00:00Z"),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerDataPlaneReady,
+					WithTriggerReplayed("2020-10-01T00:00:00Z"),
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				Eventf(corev1.EventTypeNormal, "ReplaySubscriptionDeleted", `Deleted PubSub replay subscription %q`, replaySubscription),
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					TopicAndSub(decouplingTopic, replaySubscription),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics(decouplingTopic, "cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
 	}
 
 	defer logtesting.ClearAll()
@@ -366,7 +839,21 @@ func TestAllCasesTrigger(t *testing.T) {
 			pubsubClient:       psclient,
 		}
 
-		return triggerreconciler.NewReconciler(ctx, r.Logger, r.RunClientSet, listers.GetTriggerLister(), r.Recorder, r, withAgentAndFinalizer(nil))
+		opts := withAgentAndFinalizer(nil)
+		opts.ConfigStore = NewGCPAuthTestStore(t, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      gcpauth.ConfigMapName(),
+				Namespace: system.Namespace(),
+			},
+			Data: map[string]string{
+				"default-auth-config": `
+  clusterDefaults:
+    deliveryServiceAccounts:
+    - invoker@my-project.iam.gserviceaccount.com
+`,
+			},
+		})
+		return triggerreconciler.NewReconciler(ctx, r.Logger, r.RunClientSet, listers.GetTriggerLister(), r.Recorder, r, opts)
 	}))
 }
 
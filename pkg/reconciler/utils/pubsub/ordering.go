@@ -0,0 +1,72 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"context"
+
+	"cloud.google.com/go/pubsub"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/logging"
+)
+
+// OrderingLabel is the label of subscriptions created with message ordering. The pubsub client
+// doesn't return whether message ordering is enabled in the subscription config.
+const OrderingLabel = "message_ordering"
+
+// OrderingLabels returns the labels of a subscription with the given message ordering.
+func OrderingLabels(labels map[string]string, ordered bool) map[string]string {
+	if !ordered {
+		return labels
+	}
+	l := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		l[k] = v
+	}
+	l[OrderingLabel] = "enabled"
+	return l
+}
+
+// OrderingStatusUpdater is an interface which updates resource status based on whether the
+// resource's pubsub subscription delivers messages in order.
+type OrderingStatusUpdater interface {
+	MarkOrderingEnabled()
+	MarkOrderingDisabled(reason, format string, args ...interface{})
+	ClearOrdering()
+}
+
+// ReconcileOrdering reports whether the subscription delivers messages with the same ordering key
+// in order. Pubsub doesn't allow to enable message ordering on an existing subscription, so a
+// subscription created before ordering was requested stays unordered.
+func ReconcileOrdering(ctx context.Context, sub *pubsub.Subscription, ordered bool, updater OrderingStatusUpdater) error {
+	if !ordered {
+		updater.ClearOrdering()
+		return nil
+	}
+	config, err := sub.Config(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get Pub/Sub subscription Config", zap.Error(err))
+		updater.MarkOrderingDisabled("SubscriptionConfigUnknown", "Failed to get Pub/Sub subscription Config: %v", err)
+		return err
+	}
+	if config.Labels[OrderingLabel] != "enabled" {
+		updater.MarkOrderingDisabled("SubscriptionNotOrdered", "Pub/Sub subscription %q was created without message ordering, it must be recreated to deliver events in order", sub.ID())
+		return nil
+	}
+	updater.MarkOrderingEnabled()
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+
+	reconcilertesting "github.com/google/knative-gcp/pkg/reconciler/testing"
+	utilspubsubtesting "github.com/google/knative-gcp/pkg/reconciler/utils/pubsub/testing"
+)
+
+func TestReconcileOrdering(t *testing.T) {
+	tests := []struct {
+		name          string
+		subOrdered    bool
+		ordered       bool
+		wantCondition *apis.Condition
+	}{{
+		name: "ordering not requested",
+	}, {
+		name:          "ordered sub",
+		subOrdered:    true,
+		ordered:       true,
+		wantCondition: &apis.Condition{Status: corev1.ConditionTrue},
+	}, {
+		name:    "unordered sub",
+		ordered: true,
+		wantCondition: &apis.Condition{
+			Status:  corev1.ConditionFalse,
+			Reason:  "SubscriptionNotOrdered",
+			Message: `Pub/Sub subscription "test-sub" was created without message ordering, it must be recreated to deliver events in order`,
+		},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			client, close := reconcilertesting.TestPubsubClient(ctx, project)
+			defer close()
+			reconcilertesting.Topic(topic)(ctx, t, client)
+			s, err := client.CreateSubscription(ctx, sub, pubsub.SubscriptionConfig{
+				Topic:                 client.Topic(topic),
+				Labels:                OrderingLabels(map[string]string{"name": "test"}, tc.subOrdered),
+				EnableMessageOrdering: tc.subOrdered,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			su := &utilspubsubtesting.OrderingStatusUpdater{OrderingCondition: &apis.Condition{Status: corev1.ConditionUnknown}}
+			if err := ReconcileOrdering(ctx, s, tc.ordered, su); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantCondition, su.OrderingCondition); diff != "" {
+				t.Errorf("Unexpected ordering condition, diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestOrderingLabels(t *testing.T) {
+	labels := map[string]string{"name": "test"}
+	if got := OrderingLabels(labels, false); !cmp.Equal(got, labels) {
+		t.Errorf("Unexpected labels of unordered sub: %v", got)
+	}
+	want := map[string]string{"name": "test", OrderingLabel: "enabled"}
+	if got := OrderingLabels(labels, true); !cmp.Equal(got, want) {
+		t.Errorf("Unexpected labels of ordered sub: %v", got)
+	}
+	if len(labels) != 1 {
+		t.Errorf("Labels were modified: %v", labels)
+	}
+}
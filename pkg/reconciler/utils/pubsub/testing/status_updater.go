@@ -66,3 +66,24 @@ func (su *StatusUpdater) MarkSubscriptionReady() {
 		Status: corev1.ConditionTrue,
 	}
 }
+
+// OrderingStatusUpdater records the ordering condition.
+type OrderingStatusUpdater struct {
+	OrderingCondition *apis.Condition
+}
+
+func (su *OrderingStatusUpdater) MarkOrderingEnabled() {
+	su.OrderingCondition = &apis.Condition{
+		Status: corev1.ConditionTrue,
+	}
+}
+func (su *OrderingStatusUpdater) MarkOrderingDisabled(reason, format string, args ...interface{}) {
+	su.OrderingCondition = &apis.Condition{
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+func (su *OrderingStatusUpdater) ClearOrdering() {
+	su.OrderingCondition = nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testKey struct{}
+
+func TestDetachContext(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), testKey{}, "value"), time.Minute)
+	ctx := DetachContext(parent)
+	cancel()
+
+	if got := ctx.Value(testKey{}); got != "value" {
+		t.Errorf("Value got=%v, want=value", got)
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("detached context has a deadline")
+	}
+	if ctx.Err() != nil {
+		t.Errorf("Err got=%v, want=nil", ctx.Err())
+	}
+
+	// A child of the detached context is still cancelable.
+	child, cancel := context.WithCancel(ctx)
+	select {
+	case <-child.Done():
+		t.Fatal("child of detached context is done before it's canceled")
+	default:
+	}
+	cancel()
+	<-child.Done()
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package delivery classifies the responses of subscribers to event
+// deliveries. It is shared by the broker and the pubsub receive adapter so
+// that both retry the same responses.
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Outcome is the outcome of an event delivery.
+type Outcome int
+
+const (
+	// Success means the subscriber accepted the event.
+	Success Outcome = iota
+	// Retryable means the delivery failed and may succeed if retried.
+	Retryable
+	// NonRetryable means the subscriber rejected the event and retrying
+	// won't change its mind.
+	NonRetryable
+)
+
+// DefaultRetryableClientErrors are the 4xx status codes retried by default.
+var DefaultRetryableClientErrors = []int{
+	http.StatusRequestTimeout,
+	http.StatusConflict,
+	http.StatusTooManyRequests,
+}
+
+// Classifier classifies the status codes of delivery responses. 2xx are
+// successful, 4xx are not retryable except for the configured ones, and
+// everything else is retryable.
+type Classifier struct {
+	retryableClientErrors map[int]bool
+}
+
+// NewClassifier creates a Classifier retrying the given 4xx status codes.
+func NewClassifier(retryableClientErrors []int) *Classifier {
+	c := &Classifier{retryableClientErrors: make(map[int]bool, len(retryableClientErrors))}
+	for _, code := range retryableClientErrors {
+		c.retryableClientErrors[code] = true
+	}
+	return c
+}
+
+// DefaultClassifier retries the DefaultRetryableClientErrors.
+var DefaultClassifier = NewClassifier(DefaultRetryableClientErrors)
+
+// Classify returns the outcome of a delivery answered with the status code.
+// A nil Classifier behaves like the DefaultClassifier.
+func (c *Classifier) Classify(statusCode int) Outcome {
+	if c == nil {
+		c = DefaultClassifier
+	}
+	switch {
+	case statusCode/100 == 2:
+		return Success
+	case statusCode/100 == 4 && !c.retryableClientErrors[statusCode]:
+		return NonRetryable
+	default:
+		return Retryable
+	}
+}
+
+// ParseStatusCodes parses a comma separated list of 4xx status codes.
+func ParseStatusCodes(s string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code/100 != 4 {
+			return nil, fmt.Errorf("invalid client error status code %q", part)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// RetryAfter returns how long the subscriber asked to wait before retrying,
+// from the Retry-After header of a 429 or 503 response. The header is either
+// a number of seconds or an HTTP date. It returns zero if there is no valid
+// header.
+func RetryAfter(resp *http.Response, now time.Time) time.Duration {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// Error is a delivery answered with a status code other than 2xx.
+type Error struct {
+	StatusCode int
+	Outcome    Outcome
+	// Delay is how long the subscriber asked to wait before retrying.
+	Delay time.Duration
+}
+
+// NewError classifies a failed delivery response.
+func NewError(c *Classifier, resp *http.Response) *Error {
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Outcome:    c.Classify(resp.StatusCode),
+		Delay:      RetryAfter(resp, time.Now()),
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("event delivery failed: HTTP status code %d", e.StatusCode)
+}
+
+// RetryAfter implements RetryAfterError.
+func (e *Error) RetryAfter() time.Duration {
+	return e.Delay
+}
+
+// RetryAfterError is implemented by errors asking to delay the retry.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// NotAttemptedError is implemented by errors of deliveries which were given up
+// before reaching the subscriber.
+type NotAttemptedError interface {
+	error
+	NotAttempted() bool
+}
+
+// Attempted returns false if the delivery which failed with err didn't reach
+// the subscriber. Such deliveries don't count towards the retry limit.
+func Attempted(err error) bool {
+	var nae NotAttemptedError
+	return !errors.As(err, &nae) || !nae.NotAttempted()
+}
+
+// RetryDelay returns the minimum delay before retrying a delivery which
+// failed with err, or zero if err doesn't ask for one.
+func RetryDelay(err error) time.Duration {
+	var rae RetryAfterError
+	if errors.As(err, &rae) {
+		return rae.RetryAfter()
+	}
+	return 0
+}
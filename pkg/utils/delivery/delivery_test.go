@@ -0,0 +1,124 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClassify(t *testing.T) {
+	custom := NewClassifier([]int{http.StatusBadRequest})
+	tests := []struct {
+		classifier *Classifier
+		code       int
+		want       Outcome
+	}{
+		{nil, http.StatusOK, Success},
+		{nil, http.StatusAccepted, Success},
+		{nil, http.StatusBadRequest, NonRetryable},
+		{nil, http.StatusNotFound, NonRetryable},
+		{nil, http.StatusRequestTimeout, Retryable},
+		{nil, http.StatusConflict, Retryable},
+		{nil, http.StatusTooManyRequests, Retryable},
+		{nil, http.StatusInternalServerError, Retryable},
+		{nil, http.StatusServiceUnavailable, Retryable},
+		{custom, http.StatusBadRequest, Retryable},
+		{custom, http.StatusTooManyRequests, NonRetryable},
+	}
+	for _, tt := range tests {
+		if got := tt.classifier.Classify(tt.code); got != tt.want {
+			t.Errorf("Classify(%d) got %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestParseStatusCodes(t *testing.T) {
+	got, err := ParseStatusCodes(" 408, 429,,451")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]int{408, 429, 451}, got); diff != "" {
+		t.Errorf("unexpected codes (-want, +got) = %v", diff)
+	}
+	for _, s := range []string{"abc", "500", "408,200"} {
+		if _, err := ParseStatusCodes(s); err == nil {
+			t.Errorf("ParseStatusCodes(%q) expected an error", s)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		code   int
+		header string
+		want   time.Duration
+	}{
+		{"seconds", http.StatusTooManyRequests, "120", 2 * time.Minute},
+		{"date", http.StatusServiceUnavailable, now.Add(time.Minute).Format(http.TimeFormat), time.Minute},
+		{"past date", http.StatusServiceUnavailable, now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"negative", http.StatusTooManyRequests, "-1", 0},
+		{"invalid", http.StatusTooManyRequests, "soon", 0},
+		{"missing", http.StatusTooManyRequests, "", 0},
+		{"other status", http.StatusInternalServerError, "120", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.code, Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+			if got := RetryAfter(resp, now); got != tt.want {
+				t.Errorf("RetryAfter got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"3"}}}
+	err := NewError(nil, resp)
+	if err.Outcome != Retryable {
+		t.Errorf("unexpected outcome %v", err.Outcome)
+	}
+	if got := RetryDelay(fmt.Errorf("wrapped: %w", err)); got != 3*time.Second {
+		t.Errorf("RetryDelay got %v, want 3s", got)
+	}
+	if got := RetryDelay(fmt.Errorf("other")); got != 0 {
+		t.Errorf("RetryDelay got %v, want 0", got)
+	}
+}
+
+type notAttemptedError struct{}
+
+func (notAttemptedError) Error() string      { return "not attempted" }
+func (notAttemptedError) NotAttempted() bool { return true }
+
+func TestAttempted(t *testing.T) {
+	if !Attempted(fmt.Errorf("other")) {
+		t.Error("Attempted got false, want true")
+	}
+	if Attempted(fmt.Errorf("wrapped: %w", notAttemptedError{})) {
+		t.Error("Attempted got true, want false")
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// iso8601Duration matches the day and time parts of an ISO 8601 duration,
+// e.g. P1DT2H3M4.5S. Years, months and weeks are not supported since their
+// length is not fixed.
+var iso8601Duration = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseISO8601Duration parses an ISO 8601 duration such as PT0.5S, which is
+// the format used by the backoff delay of the delivery spec.
+func ParseISO8601Duration(s string) (time.Duration, error) {
+	m := iso8601Duration.FindStringSubmatch(s)
+	if m == nil || s == "P" || s[len(s)-1] == 'T' {
+		return 0, fmt.Errorf("invalid ISO 8601 duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+1] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(m[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q: %w", s, err)
+		}
+		d += time.Duration(v * float64(unit))
+	}
+	return d, nil
+}
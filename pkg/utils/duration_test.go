@@ -0,0 +1,53 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseISO8601Duration(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "PT1S", want: time.Second},
+		{in: "PT0.5S", want: 500 * time.Millisecond},
+		{in: "PT2M", want: 2 * time.Minute},
+		{in: "P1DT2H3M4S", want: 26*time.Hour + 3*time.Minute + 4*time.Second},
+		{in: "P1D", want: 24 * time.Hour},
+		{in: "", wantErr: true},
+		{in: "P", wantErr: true},
+		{in: "PT", wantErr: true},
+		{in: "P1DT", wantErr: true},
+		{in: "1s", wantErr: true},
+		{in: "P1Y", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseISO8601Duration(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error got=%v, wantErr=%v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("duration got=%v, want=%v", got, tc.want)
+			}
+		})
+	}
+}
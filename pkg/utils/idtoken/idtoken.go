@@ -0,0 +1,186 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package idtoken mints Google-signed OIDC ID tokens to authenticate the
+// deliveries of events to private subscribers, such as Cloud Run services or
+// IAP-protected endpoints.
+package idtoken
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	iamcredentials "google.golang.org/api/iamcredentials/v1"
+	googleidtoken "google.golang.org/api/idtoken"
+	"google.golang.org/api/option"
+)
+
+// defaultLifetime is used if the expiry of a token can't be read from it.
+// Google-signed ID tokens are valid for one hour, leave a little buffer.
+const defaultLifetime = 55 * time.Minute
+
+// Source mints ID tokens.
+type Source interface {
+	// Token returns an ID token for the audience. If serviceAccount is not
+	// empty, the token is minted for it by impersonation with the default
+	// credentials. Otherwise the token is minted for the default credentials.
+	Token(ctx context.Context, audience, serviceAccount string) (string, error)
+}
+
+type key struct {
+	audience       string
+	serviceAccount string
+}
+
+// cachingSource keeps a token source per audience and service account, which
+// caches the token until it is about to expire.
+type cachingSource struct {
+	// ctx is used by the token sources to refresh the tokens, so it must
+	// live as long as the source.
+	ctx            context.Context
+	newTokenSource func(ctx context.Context, audience, serviceAccount string) (oauth2.TokenSource, error)
+
+	mu      sync.Mutex
+	sources map[key]oauth2.TokenSource
+}
+
+// NewSource creates a Source using the default credentials of the data plane.
+// ctx must live as long as the source.
+func NewSource(ctx context.Context, opts ...option.ClientOption) Source {
+	return &cachingSource{
+		ctx: ctx,
+		newTokenSource: func(ctx context.Context, audience, serviceAccount string) (oauth2.TokenSource, error) {
+			if serviceAccount == "" {
+				return googleidtoken.NewTokenSource(ctx, audience, opts...)
+			}
+			return newImpersonatedTokenSource(ctx, audience, serviceAccount, opts...)
+		},
+		sources: make(map[key]oauth2.TokenSource),
+	}
+}
+
+// Token implements Source.
+func (s *cachingSource) Token(ctx context.Context, audience, serviceAccount string) (string, error) {
+	ts, err := s.tokenSource(audience, serviceAccount)
+	if err != nil {
+		return "", err
+	}
+	tok, err := ts.Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to mint ID token for audience %q: %w", audience, err)
+	}
+	return tok.AccessToken, nil
+}
+
+func (s *cachingSource) tokenSource(audience, serviceAccount string) (oauth2.TokenSource, error) {
+	k := key{audience: audience, serviceAccount: serviceAccount}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ts, ok := s.sources[k]; ok {
+		return ts, nil
+	}
+	// Failures are not cached, the next call tries again.
+	ts, err := s.newTokenSource(s.ctx, audience, serviceAccount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ID token source for audience %q: %w", audience, err)
+	}
+	s.sources[k] = ts
+	return ts, nil
+}
+
+// SetAuthorization mints an ID token for the audience and service account and
+// sets it as the bearer token of the request.
+func SetAuthorization(ctx context.Context, s Source, req *http.Request, audience, serviceAccount string) error {
+	tok, err := s.Token(ctx, audience, serviceAccount)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+tok)
+	return nil
+}
+
+// impersonatedTokenSource mints ID tokens for a service account with the IAM
+// Credentials API. The default credentials need the Service Account Token
+// Creator role on the service account.
+type impersonatedTokenSource struct {
+	ctx            context.Context
+	service        *iamcredentials.Service
+	audience       string
+	serviceAccount string
+}
+
+func newImpersonatedTokenSource(ctx context.Context, audience, serviceAccount string, opts ...option.ClientOption) (oauth2.TokenSource, error) {
+	service, err := iamcredentials.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.ReuseTokenSource(nil, &impersonatedTokenSource{
+		ctx:            ctx,
+		service:        service,
+		audience:       audience,
+		serviceAccount: serviceAccount,
+	}), nil
+}
+
+// Token implements oauth2.TokenSource.
+func (s *impersonatedTokenSource) Token() (*oauth2.Token, error) {
+	resp, err := s.service.Projects.ServiceAccounts.GenerateIdToken(
+		"projects/-/serviceAccounts/"+s.serviceAccount,
+		&iamcredentials.GenerateIdTokenRequest{Audience: s.audience, IncludeEmail: true},
+	).Context(s.ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ID token for service account %q: %w", s.serviceAccount, err)
+	}
+	expiry, err := expiryOf(resp.Token)
+	if err != nil {
+		expiry = time.Now().Add(defaultLifetime)
+	}
+	return &oauth2.Token{
+		AccessToken: resp.Token,
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+	}, nil
+}
+
+// expiryOf reads the expiry of a JWT without verifying it.
+func expiryOf(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("malformed JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed JWT payload: %w", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("malformed JWT claims: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, errors.New("JWT has no expiry")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package idtoken
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+func fakeJWT(t *testing.T, exp time.Time) string {
+	t.Helper()
+	claims, err := json.Marshal(map[string]interface{}{"exp": exp.Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(claims) + ".c2ln"
+}
+
+func TestCachingSource(t *testing.T) {
+	var created int32
+	fail := true
+	s := &cachingSource{
+		ctx: context.Background(),
+		newTokenSource: func(ctx context.Context, audience, serviceAccount string) (oauth2.TokenSource, error) {
+			if fail {
+				return nil, errors.New("no credentials")
+			}
+			atomic.AddInt32(&created, 1)
+			return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: audience + "/" + serviceAccount}), nil
+		},
+		sources: make(map[key]oauth2.TokenSource),
+	}
+	ctx := context.Background()
+
+	if _, err := s.Token(ctx, "aud", ""); err == nil {
+		t.Error("Token got nil error, want error")
+	}
+	fail = false
+	for i := 0; i < 2; i++ {
+		got, err := s.Token(ctx, "aud", "")
+		if err != nil {
+			t.Fatalf("Token got unexpected error: %v", err)
+		}
+		if want := "aud/"; got != want {
+			t.Errorf("Token got=%q, want=%q", got, want)
+		}
+	}
+	got, err := s.Token(ctx, "aud", "sa@p.iam.gserviceaccount.com")
+	if err != nil {
+		t.Fatalf("Token got unexpected error: %v", err)
+	}
+	if want := "aud/sa@p.iam.gserviceaccount.com"; got != want {
+		t.Errorf("Token got=%q, want=%q", got, want)
+	}
+	// Token sources are cached per audience and service account, failures aren't.
+	if got := atomic.LoadInt32(&created); got != 2 {
+		t.Errorf("created token sources got=%d, want=2", got)
+	}
+}
+
+func TestImpersonatedToken(t *testing.T) {
+	const serviceAccount = "invoker@my-project.iam.gserviceaccount.com"
+	token := fakeJWT(t, time.Now().Add(time.Hour))
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if want := fmt.Sprintf("/v1/projects/-/serviceAccounts/%s:generateIdToken", serviceAccount); req.URL.Path != want {
+			t.Errorf("request path got=%q, want=%q", req.URL.Path, want)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if body["audience"] != "https://subscriber" {
+			t.Errorf("request audience got=%v, want=%q", body["audience"], "https://subscriber")
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": token})
+	}))
+	defer srv.Close()
+
+	s := NewSource(context.Background(), option.WithEndpoint(srv.URL+"/"), option.WithoutAuthentication())
+	req := httptest.NewRequest(http.MethodPost, "http://subscriber", nil)
+	for i := 0; i < 2; i++ {
+		if err := SetAuthorization(context.Background(), s, req, "https://subscriber", serviceAccount); err != nil {
+			t.Fatalf("SetAuthorization got unexpected error: %v", err)
+		}
+	}
+	if got, want := req.Header.Get("Authorization"), "Bearer "+token; got != want {
+		t.Errorf("Authorization header got=%q, want=%q", got, want)
+	}
+	// The token is cached until it expires.
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("generateIdToken calls got=%d, want=1", got)
+	}
+}
+
+func TestExpiryOf(t *testing.T) {
+	exp := time.Unix(1600000000, 0)
+	got, err := expiryOf(fakeJWT(t, exp))
+	if err != nil {
+		t.Fatalf("expiryOf got unexpected error: %v", err)
+	}
+	if !got.Equal(exp) {
+		t.Errorf("expiryOf got=%v, want=%v", got, exp)
+	}
+	for _, token := range []string{"", "a.b", "a.!!!.c", "a." + base64.RawURLEncoding.EncodeToString([]byte("{}")) + ".c"} {
+		if _, err := expiryOf(token); err == nil {
+			t.Errorf("expiryOf(%q) got nil error, want error", token)
+		}
+	}
+}